@@ -0,0 +1,67 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/spf13/cobra"
+	"github.com/xn3cr0nx/bitgodine/internal/storage/kv/badger"
+	"github.com/xn3cr0nx/bitgodine/internal/storage/kv/bolt"
+	"github.com/xn3cr0nx/bitgodine/internal/storage/kv/migrate"
+	"github.com/xn3cr0nx/bitgodine/pkg/logger"
+)
+
+var (
+	migrateFrom, migrateTo string
+	migrateBatch           int
+)
+
+// migrateCmd represents the migrate command
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Copies the badger index into a bolt store",
+	Long: `Copies all the keys of the badger index into a bolt store,
+	to switch the db backend without parsing the blockchain again.
+	The badger store is opened read only, the parser must be stopped.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		src, err := badger.NewBadger(badger.Conf(migrateFrom), true)
+		if err != nil {
+			logger.Error("Bitgodine Migrate", err, logger.Params{"from": migrateFrom})
+			os.Exit(-1)
+		}
+		defer src.Close()
+
+		dst, err := bolt.NewBolt(bolt.Conf(migrateTo), false)
+		if err != nil {
+			logger.Error("Bitgodine Migrate", err, logger.Params{"to": migrateTo})
+			os.Exit(-1)
+		}
+		defer dst.Close()
+
+		start := time.Now()
+		copied, err := migrate.BadgerToBolt(src, dst, migrateBatch, func(copied int) {
+			logger.Info("Bitgodine Migrate", "Copying keys", logger.Params{"copied": copied, "elapsed": time.Since(start).String()})
+		})
+		if err != nil {
+			logger.Error("Bitgodine Migrate", err, logger.Params{"copied": copied})
+			os.Exit(-1)
+		}
+
+		logger.Info("Bitgodine Migrate", "Migration completed", logger.Params{"copied": copied, "elapsed": time.Since(start).String()})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(migrateCmd)
+
+	hd, err := homedir.Dir()
+	if err != nil {
+		panic(err)
+	}
+	bitgodineFolder := filepath.Join(hd, ".bitgodine")
+	migrateCmd.Flags().StringVar(&migrateFrom, "from", filepath.Join(bitgodineFolder, "badger"), "Sets the path to the badger db files to copy")
+	migrateCmd.Flags().StringVar(&migrateTo, "to", filepath.Join(bitgodineFolder, "bolt", "bitgodine.db"), "Sets the path to the bolt db file to create")
+	migrateCmd.Flags().IntVar(&migrateBatch, "batch", migrate.DefaultBatchSize, "Sets the number of keys written per transaction")
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/xn3cr0nx/bitgodine/internal/block"
+	"github.com/xn3cr0nx/bitgodine/internal/storage/kv"
+	"github.com/xn3cr0nx/bitgodine/pkg/logger"
+)
+
+var reencodeFrom int32
+
+// reencodeCmd represents the reencode command
+var reencodeCmd = &cobra.Command{
+	Use:   "reencode",
+	Short: "Rewrites stored blocks and transactions in the binary format",
+	Long: `Rewrites the blocks and transactions stored in the legacy encoding
+	in the compact binary format. Records already re-encoded are skipped,
+	so an interrupted run can be resumed. The parser must be stopped.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		db, err := kv.NewDB()
+		if err != nil {
+			logger.Error("Bitgodine Reencode", err, logger.Params{})
+			os.Exit(-1)
+		}
+		defer db.Close()
+
+		start := time.Now()
+		records, err := block.Reencode(db, reencodeFrom, func(height int32, records int) {
+			logger.Info("Bitgodine Reencode", "Re-encoding records", logger.Params{"height": height, "records": records, "elapsed": time.Since(start).String()})
+		})
+		if err != nil {
+			logger.Error("Bitgodine Reencode", err, logger.Params{"records": records})
+			os.Exit(-1)
+		}
+
+		logger.Info("Bitgodine Reencode", "Re-encoding completed", logger.Params{"records": records, "elapsed": time.Since(start).String()})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(reencodeCmd)
+
+	reencodeCmd.Flags().Int32Var(&reencodeFrom, "from", 0, "Sets the block height re-encoding starts from")
+}
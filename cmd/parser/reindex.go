@@ -0,0 +1,47 @@
+package main
+
+import (
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/xn3cr0nx/bitgodine/internal/block"
+	"github.com/xn3cr0nx/bitgodine/internal/storage/kv"
+	"github.com/xn3cr0nx/bitgodine/pkg/logger"
+)
+
+var reindexFrom int32
+
+// reindexCmd represents the reindex command
+var reindexCmd = &cobra.Command{
+	Use:   "reindex",
+	Short: "Builds the address index of the stored blocks",
+	Long: `Builds the address index, funding and spending entries with their
+	value, from the blocks and transactions already stored. Entries are
+	overwritten, so an interrupted run can be resumed. The parser must be stopped.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		db, err := kv.NewDB()
+		if err != nil {
+			logger.Error("Bitgodine Reindex", err, logger.Params{})
+			os.Exit(-1)
+		}
+		defer db.Close()
+
+		start := time.Now()
+		entries, err := block.ReindexAddresses(db, reindexFrom, func(height int32, entries int) {
+			logger.Info("Bitgodine Reindex", "Indexing addresses", logger.Params{"height": height, "entries": entries, "elapsed": time.Since(start).String()})
+		})
+		if err != nil {
+			logger.Error("Bitgodine Reindex", err, logger.Params{"entries": entries})
+			os.Exit(-1)
+		}
+
+		logger.Info("Bitgodine Reindex", "Indexing completed", logger.Params{"entries": entries, "elapsed": time.Since(start).String()})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(reindexCmd)
+
+	reindexCmd.Flags().Int32Var(&reindexFrom, "from", 0, "Sets the block height indexing starts from")
+}
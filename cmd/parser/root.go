@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 
 	"github.com/btcsuite/btcd/chaincfg"
@@ -11,11 +12,19 @@ import (
 	homedir "github.com/mitchellh/go-homedir"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"github.com/xn3cr0nx/bitgodine/internal/block"
 	"github.com/xn3cr0nx/bitgodine/internal/errorx"
+	"github.com/xn3cr0nx/bitgodine/internal/feed"
+	"github.com/xn3cr0nx/bitgodine/internal/migration"
 	"github.com/xn3cr0nx/bitgodine/internal/parser/bitcoin"
+	broker "github.com/xn3cr0nx/bitgodine/internal/storage/broker/kafka"
+	"github.com/xn3cr0nx/bitgodine/internal/storage/db/postgres"
 	"github.com/xn3cr0nx/bitgodine/internal/storage/kv"
+	"github.com/xn3cr0nx/bitgodine/internal/watchlist"
 	"github.com/xn3cr0nx/bitgodine/pkg/cache"
 	"github.com/xn3cr0nx/bitgodine/pkg/logger"
+	"github.com/xn3cr0nx/bitgodine/pkg/mailer"
+	"github.com/xn3cr0nx/bitgodine/pkg/meter"
 
 	_ "net/http/pprof"
 )
@@ -70,7 +79,14 @@ var rootCmd = &cobra.Command{
 		}
 		defer db.Close()
 
-		skippedBlocksStorage := bitcoin.NewSkipped()
+		if viper.GetBool("parser.metrics") {
+			if _, err := meter.NewMeter(&meter.Config{Name: "bitgodine_parser"}); err != nil {
+				logger.Error("Bitgodine", err, logger.Params{})
+				os.Exit(-1)
+			}
+		}
+
+		reorder := bitcoin.NewReorder()
 		chain := bitcoin.NewBlockchain(db, network)
 
 		var client *rpcclient.Client
@@ -87,7 +103,30 @@ var rootCmd = &cobra.Command{
 		}
 
 		interrupt := make(chan int)
-		bp := bitcoin.NewParser(chain, client, db, skippedBlocksStorage, nil, c, interrupt)
+		bp := bitcoin.NewParser(chain, client, db, reorder, nil, c, interrupt)
+		var listeners bitcoin.Listeners
+		if viper.GetBool("parser.watchlist.enabled") {
+			watcher, err := newWatcher(bp.Blocks())
+			if err != nil {
+				logger.Error("Bitgodine", err, logger.Params{})
+				os.Exit(-1)
+			}
+			listeners = append(listeners, watcher)
+		}
+		if viper.GetBool("parser.feed.enabled") {
+			bus, err := feed.NewPublisherBus()
+			if err != nil {
+				logger.Error("Bitgodine", err, logger.Params{})
+				os.Exit(-1)
+			}
+			listener := feed.NewListener(bus, bp.Blocks(), db, c, viper.GetBool("parser.feed.analysis"))
+			listener.Synced = bp.Synced
+			listener.Start()
+			listeners = append(listeners, listener)
+		}
+		if len(listeners) > 0 {
+			bp.SetListener(listeners)
+		}
 
 		if err := bp.InfinitelyParse(); err != nil {
 			logger.Error("Bitgodine", err, logger.Params{})
@@ -99,6 +138,41 @@ var rootCmd = &cobra.Command{
 	},
 }
 
+// newWatcher sets up the watchlist alerting on parsed blocks, email and kafka channels
+// are enabled only when configured
+func newWatcher(blocks block.Service) (*watchlist.Watcher, error) {
+	pg, err := postgres.NewPg(postgres.Conf())
+	if err != nil {
+		return nil, err
+	}
+	if err := migration.Migration(pg); err != nil {
+		return nil, err
+	}
+
+	notifiers := map[string]watchlist.Notifier{
+		watchlist.Webhook: watchlist.NewWebhookNotifier(viper.GetDuration("parser.watchlist.timeout")),
+	}
+	if viper.GetString("mailer.key") != "" {
+		client, err := mailer.NewClient(mailer.Conf())
+		if err != nil {
+			return nil, err
+		}
+		notifiers[watchlist.Email] = &watchlist.MailNotifier{Client: client}
+	}
+	if brokers := viper.GetStringSlice("kafka.brokers"); len(brokers) > 0 {
+		k, err := broker.NewKafka(brokers, viper.GetString("parser.watchlist.topic"))
+		if err != nil {
+			return nil, err
+		}
+		notifiers[watchlist.Kafka] = &watchlist.KafkaNotifier{Publisher: k}
+	}
+
+	watcher := watchlist.NewWatcher(pg, blocks, notifiers)
+	watcher.Timeout = viper.GetDuration("parser.watchlist.timeout")
+	watcher.Start(viper.GetInt("parser.watchlist.workers"))
+	return watcher, nil
+}
+
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
@@ -152,12 +226,23 @@ func initConfig() {
 	viper.SetDefault("blocksDir", hd)
 	viper.SetDefault("db", filepath.Join(bitgodineFolder, "badger"))
 	viper.SetDefault("dbDir", filepath.Join(bitgodineFolder, "badger", "skipped"))
+	viper.SetDefault("bolt", filepath.Join(bitgodineFolder, "bolt", "bitgodine.db"))
 	viper.SetDefault("btcHost", "localhost:8333")
 	viper.SetDefault("btcEp", "ws")
 	viper.SetDefault("btcUser", "bitcoinrpc")
 	viper.SetDefault("btcPass", "pass")
 	viper.SetDefault("btcCerts", "~/.bitcoin/rpc.cert")
 	viper.SetDefault("restored", 50000)
+	viper.SetDefault("parser.workers", runtime.NumCPU())
+	viper.SetDefault("parser.buffer", 5000)
+	viper.SetDefault("parser.metrics", true)
+	viper.SetDefault("parser.watchlist.enabled", false)
+	viper.SetDefault("parser.watchlist.topic", "watchlist-alerts")
+	viper.SetDefault("parser.watchlist.timeout", watchlist.DefaultDeliveryTimeout)
+	viper.SetDefault("parser.watchlist.workers", watchlist.DefaultDeliveryWorkers)
+	viper.SetDefault("parser.feed.enabled", false)
+	viper.SetDefault("parser.feed.analysis", true)
+	viper.SetDefault("feed.topic", feed.DefaultTopic)
 
 	viper.BindPFlag("debug", rootCmd.PersistentFlags().Lookup("debug"))
 	viper.BindPFlag("realtime", rootCmd.PersistentFlags().Lookup("realtime"))
@@ -67,6 +67,18 @@ func initConfig() {
 	viper.BindPFlag("http.port", rootCmd.Flags().Lookup("port"))
 	viper.BindPFlag("http.host", rootCmd.Flags().Lookup("host"))
 
+	viper.SetDefault("server.trace.workers", 8)
+	viper.SetDefault("server.trace.nodes", 1000)
+	viper.SetDefault("server.trace.timeout", 30*time.Second)
+	viper.SetDefault("server.graphql.complexity", 1000)
+	viper.SetDefault("server.graphql.depth", 10)
+	viper.SetDefault("server.graphql.workers", 8)
+	viper.SetDefault("server.broadcast", false)
+	viper.SetDefault("server.auth.activationURL", "http://localhost:3000/api/activate/")
+	viper.SetDefault("server.auth.resetURL", "http://localhost:3000/reset-password?token=")
+	viper.SetDefault("server.ratelimit.enabled", false)
+	viper.SetDefault("server.ratelimit.store", "memory")
+
 	viper.AutomaticEnv()
 
 	if value, ok := os.LookupEnv("config"); ok {
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/xn3cr0nx/bitgodine/internal/migration"
+	"github.com/xn3cr0nx/bitgodine/internal/storage/db/postgres"
+	"github.com/xn3cr0nx/bitgodine/internal/tag"
+	"github.com/xn3cr0nx/bitgodine/pkg/logger"
+)
+
+var (
+	importFormat, importSource, importType string
+	importMapping                          tag.Mapping
+	rollback                               string
+)
+
+// importCmd represents the import command
+var importCmd = &cobra.Command{
+	Use:   "import [files]",
+	Short: "Imports tags from local files",
+	Long: `Imports the tags of OFAC SDN xml lists, csv and json label files
+	mapping their columns, or WalletExplorer dumps. Each file is imported as a
+	batch, skipping tags already present with the same address, type and message.
+	A batch can be rolled back with the --rollback flag.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		pg, err := postgres.NewPg(postgres.Conf())
+		if err != nil {
+			logger.Error("Spider Import", err, logger.Params{})
+			os.Exit(-1)
+		}
+		if err := migration.Migration(pg); err != nil {
+			logger.Error("Spider Import", err, logger.Params{})
+			os.Exit(-1)
+		}
+		service := tag.NewService(pg, nil)
+
+		if rollback != "" {
+			deleted, err := service.RollbackBatch(rollback)
+			if err != nil {
+				logger.Error("Spider Import", err, logger.Params{"batch": rollback})
+				os.Exit(-1)
+			}
+			logger.Info("Spider Import", "Batch rolled back", logger.Params{"batch": rollback, "deleted": deleted})
+			return
+		}
+
+		if importSource == "" {
+			importSource = importFormat
+		}
+		for _, path := range args {
+			f, err := os.Open(path)
+			if err != nil {
+				logger.Error("Spider Import", err, logger.Params{"file": path})
+				os.Exit(-1)
+			}
+			tags, err := tag.Parse(f, importFormat, importMapping.WithDefaults(), importType)
+			f.Close()
+			if err != nil {
+				logger.Error("Spider Import", err, logger.Params{"file": path})
+				os.Exit(-1)
+			}
+
+			batch := &tag.Batch{Source: importSource, Format: importFormat, File: filepath.Base(path)}
+			if err := service.ImportTags(batch, tags); err != nil {
+				logger.Error("Spider Import", err, logger.Params{"file": path})
+				os.Exit(-1)
+			}
+			logger.Info("Spider Import", "File imported", logger.Params{"file": path, "batch": batch.ID.String(), "imported": batch.Imported, "duplicated": batch.Duplicated, "rejected": batch.Rejected})
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(importCmd)
+
+	importCmd.Flags().StringVar(&importFormat, "format", tag.CSV, "Sets the files format, ofac, csv, json or walletexplorer")
+	importCmd.Flags().StringVar(&importSource, "source", "", "Sets the source the tags are recorded with, the format by default")
+	importCmd.Flags().StringVar(&importType, "type", "", "Sets the type of the tags without one")
+	importCmd.Flags().StringVar(&importMapping.Address, "address-column", "", "Sets the address column or key")
+	importCmd.Flags().StringVar(&importMapping.Message, "message-column", "", "Sets the message column or key")
+	importCmd.Flags().StringVar(&importMapping.Nickname, "nickname-column", "", "Sets the nickname column or key")
+	importCmd.Flags().StringVar(&importMapping.Type, "type-column", "", "Sets the type column or key")
+	importCmd.Flags().StringVar(&importMapping.Link, "link-column", "", "Sets the link column or key")
+	importCmd.Flags().StringVar(&importMapping.Verified, "verified-column", "", "Sets the verified column or key")
+	importCmd.Flags().StringVar(&rollback, "rollback", "", "Rolls back the batch with the id instead of importing")
+}
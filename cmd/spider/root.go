@@ -1,15 +1,18 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 
-	"github.com/robfig/cron/v3"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"github.com/xn3cr0nx/bitgodine/internal/errorx"
 	"github.com/xn3cr0nx/bitgodine/internal/migration"
+	"github.com/xn3cr0nx/bitgodine/internal/spider"
 	"github.com/xn3cr0nx/bitgodine/internal/spider/bitcoinabuse"
 	"github.com/xn3cr0nx/bitgodine/internal/spider/checkbitcoinaddress"
 	"github.com/xn3cr0nx/bitgodine/internal/spider/walletexplorer"
@@ -47,106 +50,49 @@ var rootCmd = &cobra.Command{
 			os.Exit(-1)
 		}
 
-		target := viper.GetString("target")
-		if target != "" {
-			if target == "bitcoinabuse" {
-				btcabuse := bitcoinabuse.NewSpider(pg)
-				if err := btcabuse.Sync(); err != nil {
-					logger.Error("Spider", err, logger.Params{})
-					os.Exit(-1)
-				}
-				logger.Info("Spider", "bitcoinabuse sync ended, waiting for next schedule", logger.Params{"target": "bitcoinabuse.com"})
-				return
-			}
-
-			if target == "checkbitcoinaddress" {
-				checkbtcaddr := checkbitcoinaddress.NewSpider(pg)
-				if err := checkbtcaddr.Sync(); err != nil {
-					logger.Error("Spider", err, logger.Params{})
-					os.Exit(-1)
-				}
-				logger.Info("Spider", "checkbitcoinaddress sync ended, waiting for next schedule", logger.Params{"target": "bitcoinabuse.com"})
-				return
-			}
-
-			if target == "walletexplorer" {
-				wexplorer := walletexplorer.NewSpider(pg)
-				if err := wexplorer.Sync(); err != nil {
-					logger.Error("Spider", err, logger.Params{})
-					os.Exit(-1)
-				}
-				logger.Info("Spider", "walletexplorer sync ended, waiting for next schedule", logger.Params{"target": "walletexplorer.com"})
-				return
-			}
-		}
-
-		if !viper.GetBool("cron") {
-			btcabuse := bitcoinabuse.NewSpider(pg)
-			if err := btcabuse.Sync(); err != nil {
+		registry := spider.NewRegistry()
+		for _, src := range []spider.Source{
+			bitcoinabuse.NewSpider(),
+			checkbitcoinaddress.NewSpider(),
+			walletexplorer.NewSpider(),
+		} {
+			if err := registry.Register(src); err != nil {
 				logger.Error("Spider", err, logger.Params{})
 				os.Exit(-1)
 			}
-			logger.Info("Spider", "bitcoinabuse sync ended, waiting for next schedule", logger.Params{"target": "bitcoinabuse.com", "crontime": viper.GetString("spider.crontime")})
-
-			checkbtcaddr := checkbitcoinaddress.NewSpider(pg)
-			if err := checkbtcaddr.Sync(); err != nil {
-				logger.Error("Spider", err, logger.Params{})
-				os.Exit(-1)
-			}
-			logger.Info("Spider", "checkbitcoinaddress sync ended, waiting for next schedule", logger.Params{"target": "bitcoinabuse.com", "crontime": viper.GetString("spider.crontime")})
-
-			wexplorer := walletexplorer.NewSpider(pg)
-			if err := wexplorer.Sync(); err != nil {
-				logger.Error("Spider", err, logger.Params{})
-				os.Exit(-1)
-			}
-			logger.Info("Spider", "walletexplorer sync ended, waiting for next schedule", logger.Params{"target": "walletexplorer.com", "crontime": viper.GetString("spider.crontime")})
-			return
 		}
-
-		c := cron.New()
-		defer c.Stop()
-
-		logger.Info("Spider", "Scheduling spider crawler", logger.Params{"target": "bitcoinabuse.com", "crontime": viper.GetString("spider.crontime")})
-		if _, err = c.AddFunc(viper.GetString("spider.crontime"), func() {
-			btcabuse := bitcoinabuse.NewSpider(pg)
-			if err := btcabuse.Sync(); err != nil {
-				logger.Error("Spider", err, logger.Params{})
+		scheduler := spider.NewScheduler(spider.NewService(pg), registry, spider.Conf())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		interrupt := make(chan os.Signal, 1)
+		signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+		go func() {
+			<-interrupt
+			cancel()
+		}()
+
+		if target := viper.GetString("target"); target != "" {
+			if err := scheduler.Run(ctx, target); err != nil {
+				logger.Error("Spider", err, logger.Params{"target": target})
 				os.Exit(-1)
 			}
-			logger.Info("Spider", "bitcoinabuse sync ended, waiting for next schedule", logger.Params{"target": "bitcoinabuse.com", "crontime": viper.GetString("spider.crontime")})
-		}); err != nil {
-			logger.Error("Spider", err, logger.Params{})
-			os.Exit(-1)
+			logger.Info("Spider", "Sync ended", logger.Params{"target": target})
+			return
 		}
 
-		logger.Info("Spider", "Scheduling spider crawler", logger.Params{"target": "checkbitcoinaddress.com", "crontime": viper.GetString("spider.crontime")})
-		if _, err = c.AddFunc(viper.GetString("spider.crontime"), func() {
-			checkbtcaddr := checkbitcoinaddress.NewSpider(pg)
-			if err := checkbtcaddr.Sync(); err != nil {
-				logger.Error("Spider", err, logger.Params{})
+		if !viper.GetBool("cron") {
+			if err := scheduler.RunAll(ctx); err != nil {
 				os.Exit(-1)
 			}
-			logger.Info("Spider", "checkbitcoinaddress sync ended, waiting for next schedule", logger.Params{"target": "bitcoinabuse.com", "crontime": viper.GetString("spider.crontime")})
-		}); err != nil {
-			logger.Error("Spider", err, logger.Params{})
-			os.Exit(-1)
+			logger.Info("Spider", "Sync ended", logger.Params{})
+			return
 		}
 
-		logger.Info("Spider", "Scheduling spider crawler", logger.Params{"target": "walletexplorer.com", "crontime": viper.GetString("spider.crontime")})
-		if _, err = c.AddFunc(viper.GetString("spider.crontime"), func() {
-			wexplorer := walletexplorer.NewSpider(pg)
-			if err := wexplorer.Sync(); err != nil {
-				logger.Error("Spider", err, logger.Params{})
-				os.Exit(-1)
-			}
-			logger.Info("Spider", "walletexplorer sync ended, waiting for next schedule", logger.Params{"target": "walletexplorer.com", "crontime": viper.GetString("spider.crontime")})
-		}); err != nil {
+		if err := scheduler.Start(ctx); err != nil {
 			logger.Error("Spider", err, logger.Params{})
 			os.Exit(-1)
 		}
-
-		c.Run()
 	},
 }
 
@@ -179,6 +125,10 @@ func initConfig() {
 	viper.SetDefault("target", "")
 	viper.BindPFlag("target", rootCmd.PersistentFlags().Lookup("target"))
 
+	viper.SetDefault("spider.retries", 3)
+	viper.SetDefault("spider.backoff", "30s")
+	viper.SetDefault("spider.walletexplorer.pages", 100)
+
 	viper.SetEnvPrefix("spider")
 	viper.AutomaticEnv()
 	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
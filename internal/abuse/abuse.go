@@ -1,10 +1,13 @@
 package abuse
 
 import (
+	"fmt"
 	"os"
+	"time"
 
 	"github.com/fatih/structs"
 	"github.com/olekukonko/tablewriter"
+	"github.com/xn3cr0nx/bitgodine/internal/cluster"
 	"github.com/xn3cr0nx/bitgodine/internal/errorx"
 	"github.com/xn3cr0nx/bitgodine/internal/storage/db/postgres"
 	"github.com/xn3cr0nx/bitgodine/pkg/cache"
@@ -18,6 +21,7 @@ type Service interface {
 	GetAbuse(address string, output bool) (abuses []Model, err error)
 	GetAbusedCluster(address string) (clusters []AbusedCluster, err error)
 	GetAbusedClusterSet(address string) (clusters []Model, err error)
+	GetRisk(address string) (risk ClusterRisk, err error)
 }
 
 type service struct {
@@ -39,7 +43,7 @@ func printAbusesTable(abuses []Model) {
 	table.SetBorder(false)
 
 	for _, abuse := range abuses {
-		table.Append([]string{abuse.Address, abuse.AbuseTypeID, abuse.AbuseTypeOther, abuse.Abuser, abuse.Description, abuse.FromCountry, abuse.FromCountryCode})
+		table.Append([]string{abuse.Address, abuse.AbuseTypeID, abuse.AbuseTypeOther, abuse.Abuser, abuse.Description, abuse.FromCountry, abuse.FromCountryCode, abuse.Category})
 	}
 	table.Render()
 }
@@ -57,9 +61,30 @@ func (s *service) GetAbuses(output bool) (abuses []Model, err error) {
 	return
 }
 
-// CreateAbuse creates a new abuse record
+// CreateAbuse creates a new abuse record, unless already reported, evicting the cached risk and abuses
+// of the cluster the address belongs to
 func (s *service) CreateAbuse(t *Model) (err error) {
-	err = s.Repository.Model(&Model{}).Create(t).Error
+	fresh, err := Fresh(s.Repository.DB, []Model{*t})
+	if err != nil {
+		return
+	}
+	if len(fresh) == 0 {
+		return fmt.Errorf("abuse of %s %w", t.Address, errorx.ErrAlreadyExists)
+	}
+	if err = s.Repository.Model(&Model{}).Create(t).Error; err != nil {
+		return
+	}
+	if s.Cache == nil {
+		return
+	}
+	clusters, err := cluster.Of(s.Repository.DB, []string{t.Address})
+	if err != nil {
+		return
+	}
+	for _, id := range clusters {
+		s.Cache.Del(riskKey(id))
+		s.Cache.Del(abusesKey(id))
+	}
 	return
 }
 
@@ -91,22 +116,100 @@ func (s *service) GetAbusedCluster(address string) (clusters []AbusedCluster, er
 	return
 }
 
-// GetAbusedClusterSet retrieves crossed data between clusters and abuses
+// abusesKey cache key of the abuses reported for the cluster
+func abusesKey(cluster uint64) string {
+	return fmt.Sprintf("ca_%d", cluster)
+}
+
+// riskKey cache key of the risk of the cluster
+func riskKey(cluster uint64) string {
+	return fmt.Sprintf("cr_%d", cluster)
+}
+
+// GetAbusedClusterSet retrieves the abuses reported for the cluster the address belongs to,
+// normalized and without repeated reports
 func (s *service) GetAbusedClusterSet(address string) (clusters []Model, err error) {
-	if cached, ok := s.Cache.Get("ca_" + address); ok {
+	ids, err := cluster.Of(s.Repository.DB, []string{address})
+	if err != nil || len(ids) == 0 {
+		return
+	}
+	id := ids[0]
+	if cached, ok := s.Cache.Get(abusesKey(id)); ok {
 		clusters = cached.([]Model)
 		return
 	}
 
-	err = s.Repository.Raw(`SELECT abuses.abuser FROM "abuses" 
+	var abuses []Model
+	err = s.Repository.Raw(`SELECT abuses.* FROM "abuses" 
 		LEFT JOIN "clusters" 
 		ON abuses.address=clusters.address 
-		WHERE clusters.cluster=(
-		SELECT cluster FROM clusters WHERE address = ? LIMIT 1
-	) GROUP BY abuses.abuser`, address).Scan(&clusters).Error
+		WHERE clusters.cluster = ? AND clusters.deleted_at IS NULL AND abuses.deleted_at IS NULL`, id).Scan(&abuses).Error
+	if err != nil {
+		return
+	}
+	clusters = Dedupe(abuses, nil)
+
+	if !s.Cache.Set(abusesKey(id), clusters, 1) {
+		logger.Error("Cache", errorx.ErrCache, logger.Params{"address": address, "cluster": id})
+	}
+	return
+}
+
+// clusterRisk cached risk of the cluster, shared by its members
+type clusterRisk struct {
+	Risk     Risk
+	Reported int
+}
+
+// GetRisk scores the risk of the address and of the cluster it belongs to from the abuses reported
+// for them. An address not clusterized is a cluster on its own
+func (s *service) GetRisk(address string) (risk ClusterRisk, err error) {
+	var abuses []Model
+	if err = s.Repository.Where("address = ?", address).Find(&abuses).Error; err != nil {
+		return
+	}
+	conf, now := RiskConf(), time.Now()
+	risk = ClusterRisk{Address: address, AddressRisk: Score(Dedupe(abuses, nil), now, conf)}
+
+	clusters, err := cluster.Of(s.Repository.DB, []string{address})
+	if err != nil {
+		return
+	}
+	if len(clusters) == 0 {
+		members := Dedupe(abuses, nil)
+		risk.ClusterRisk = Score(members, now, conf)
+		if len(members) > 0 {
+			risk.Reported = 1
+		}
+		return
+	}
+	id := clusters[0]
+	risk.Cluster = &id
+	if s.Cache != nil {
+		if cached, ok := s.Cache.Get(riskKey(id)); ok {
+			risk.ClusterRisk, risk.Reported = cached.(clusterRisk).Risk, cached.(clusterRisk).Reported
+			return
+		}
+	}
+
+	var members []Model
+	err = s.Repository.Raw(`SELECT abuses.* FROM "abuses" 
+		WHERE abuses.address IN (
+			SELECT address FROM clusters WHERE cluster = ? AND deleted_at IS NULL
+		) AND abuses.deleted_at IS NULL`, id).Scan(&members).Error
+	if err != nil {
+		return
+	}
+	members = Dedupe(members, nil)
+	risk.ClusterRisk = Score(members, now, conf)
+	reported := make(map[string]bool)
+	for _, a := range members {
+		reported[a.Address] = true
+	}
+	risk.Reported = len(reported)
 
-	if !s.Cache.Set("ca_"+address, clusters, 1) {
-		logger.Error("Cache", errorx.ErrCache, logger.Params{"address": address})
+	if s.Cache != nil && !s.Cache.SetWithTTL(riskKey(id), clusterRisk{risk.ClusterRisk, risk.Reported}, 1, time.Hour) {
+		logger.Error("Cache", errorx.ErrCache, logger.Params{"address": address, "cluster": id})
 	}
 	return
 }
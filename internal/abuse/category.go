@@ -0,0 +1,116 @@
+package abuse
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// Categories the reported abuses are normalized to
+const (
+	Ransomware = "ransomware"
+	Darknet    = "darknet"
+	Mixer      = "mixer"
+	Blackmail  = "blackmail"
+	Sextortion = "sextortion"
+	Scam       = "scam"
+	Other      = "other"
+)
+
+// types maps the bitcoinabuse abuse type ids to the categories
+var types = map[string]string{
+	"1":  Ransomware,
+	"2":  Darknet,
+	"3":  Mixer,
+	"4":  Blackmail,
+	"5":  Sextortion,
+	"99": Other,
+}
+
+// keywords refine the category of the abuses reported as other by their free text, in order
+var keywords = []struct {
+	word     string
+	category string
+}{
+	{"ransom", Ransomware},
+	{"sextortion", Sextortion},
+	{"porn", Sextortion},
+	{"webcam", Sextortion},
+	{"blackmail", Blackmail},
+	{"extortion", Blackmail},
+	{"darknet", Darknet},
+	{"dark web", Darknet},
+	{"market", Darknet},
+	{"mixer", Mixer},
+	{"tumbler", Mixer},
+	{"scam", Scam},
+	{"fraud", Scam},
+	{"phishing", Scam},
+	{"ponzi", Scam},
+	{"giveaway", Scam},
+	{"investment", Scam},
+}
+
+// Severity of each category, the weight of a report in the risk score
+var Severity = map[string]float64{
+	Ransomware: 1,
+	Darknet:    0.8,
+	Scam:       0.8,
+	Sextortion: 0.7,
+	Blackmail:  0.7,
+	Mixer:      0.5,
+	Other:      0.3,
+}
+
+// Categorize returns the category of the abuse type, looking in the reported text if other or unknown
+func Categorize(abuseType, text string) string {
+	category, ok := types[strings.TrimSpace(abuseType)]
+	if ok && category != Other {
+		return category
+	}
+	if _, known := Severity[strings.ToLower(strings.TrimSpace(abuseType))]; known {
+		return strings.ToLower(strings.TrimSpace(abuseType))
+	}
+	text = strings.ToLower(text)
+	for _, k := range keywords {
+		if strings.Contains(text, k.word) {
+			return k.category
+		}
+	}
+	return Other
+}
+
+// Fingerprint identifies the reports of the same abuse, by address, category and description
+// regardless of case and spacing
+func Fingerprint(address, category, description string) string {
+	description = strings.Join(strings.Fields(strings.ToLower(description)), " ")
+	sum := sha256.Sum256([]byte(address + "\x00" + category + "\x00" + description))
+	return hex.EncodeToString(sum[:16])
+}
+
+// Normalize sets the category and the fingerprint of the abuse, if missing
+func (m *Model) Normalize() {
+	if m.Category == "" {
+		m.Category = Categorize(m.AbuseTypeID, m.AbuseTypeOther+" "+m.Description)
+	}
+	if m.Fingerprint == "" {
+		m.Fingerprint = Fingerprint(m.Address, m.Category, m.Description)
+	}
+}
+
+// Dedupe returns the abuses not reported more than once, nor among the existing fingerprints
+func Dedupe(abuses []Model, existing map[string]bool) (unique []Model) {
+	seen := make(map[string]bool, len(existing)+len(abuses))
+	for f := range existing {
+		seen[f] = true
+	}
+	for _, a := range abuses {
+		a.Normalize()
+		if seen[a.Fingerprint] {
+			continue
+		}
+		seen[a.Fingerprint] = true
+		unique = append(unique, a)
+	}
+	return
+}
@@ -16,9 +16,38 @@ type Model struct {
 	Description     string    `json:"description"`
 	FromCountry     string    `json:"from_country"`
 	FromCountryCode string    `json:"from_country_code"`
+	Category        string    `json:"category" gorm:"index"`
+	Fingerprint     string    `json:"-" gorm:"size:32;index"`
 } //@name Abuse
 
+// BeforeCreate generates the abuse id and normalizes its category
+func (m *Model) BeforeCreate(tx *gorm.DB) (err error) {
+	if m.ID == uuid.Nil {
+		m.ID, err = uuid.NewV4()
+	}
+	m.Normalize()
+	return
+}
+
 // TableName defines default table name
 func (m Model) TableName() string {
 	return "abuses"
 }
+
+// Backfill normalizes the abuses stored before their category and fingerprint were recorded
+func Backfill(db *gorm.DB) (err error) {
+	var abuses []Model
+	return db.Where("category IS NULL OR category = '' OR fingerprint IS NULL OR fingerprint = ''").
+		FindInBatches(&abuses, 500, func(tx *gorm.DB, batch int) error {
+			for i := range abuses {
+				abuses[i].Normalize()
+				if err := db.Model(&Model{}).Where("id = ?", abuses[i].ID).UpdateColumns(map[string]interface{}{
+					"category":    abuses[i].Category,
+					"fingerprint": abuses[i].Fingerprint,
+				}).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+}
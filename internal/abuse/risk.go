@@ -0,0 +1,124 @@
+package abuse
+
+import (
+	"math"
+	"time"
+
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+)
+
+// Risk levels of the score
+const (
+	RiskNone   = "none"
+	RiskLow    = "low"
+	RiskMedium = "medium"
+	RiskHigh   = "high"
+)
+
+// freshChunk number of addresses looked up at once deduplicating reports
+const freshChunk = 1000
+
+// RiskConfig parameters of the risk score
+type RiskConfig struct {
+	// HalfLife age after which a report weighs half
+	HalfLife time.Duration
+}
+
+// RiskConf returns the risk configuration, half life of 180 days by default
+func RiskConf() RiskConfig {
+	halfLife := viper.GetDuration("abuse.risk.halflife")
+	if halfLife <= 0 {
+		halfLife = 180 * 24 * time.Hour
+	}
+	return RiskConfig{HalfLife: halfLife}
+}
+
+// Risk of an address or a cluster based on the abuses reported
+type Risk struct {
+	Score      float64        `json:"score"`
+	Level      string         `json:"level"`
+	Reports    int            `json:"reports"`
+	Categories map[string]int `json:"categories,omitempty"`
+	LastReport *time.Time     `json:"last_report,omitempty"`
+} //@name AbuseRisk
+
+// ClusterRisk risk of the address and of the cluster it belongs to, with the number of members reported
+type ClusterRisk struct {
+	Address     string  `json:"address"`
+	Cluster     *uint64 `json:"cluster,omitempty"`
+	AddressRisk Risk    `json:"address_risk"`
+	ClusterRisk Risk    `json:"cluster_risk"`
+	Reported    int     `json:"reported"`
+} //@name AbuseClusterRisk
+
+// Score computes the risk of the reports. Each report weighs the severity of its category, halved
+// every half life since reported, and the score grows with the overall weight as 1 - e^-weight,
+// so that many reports or a few recent severe ones approach 1
+func Score(abuses []Model, at time.Time, conf RiskConfig) (risk Risk) {
+	risk.Level = RiskNone
+	var weight float64
+	for _, a := range abuses {
+		a.Normalize()
+		severity, ok := Severity[a.Category]
+		if !ok {
+			severity = Severity[Other]
+		}
+		age := at.Sub(a.CreatedAt)
+		if age < 0 || a.CreatedAt.IsZero() {
+			age = 0
+		}
+		weight += severity * math.Pow(0.5, float64(age)/float64(conf.HalfLife))
+
+		if risk.Categories == nil {
+			risk.Categories = make(map[string]int)
+		}
+		risk.Categories[a.Category]++
+		risk.Reports++
+		if !a.CreatedAt.IsZero() && (risk.LastReport == nil || a.CreatedAt.After(*risk.LastReport)) {
+			last := a.CreatedAt
+			risk.LastReport = &last
+		}
+	}
+	if risk.Reports == 0 {
+		return
+	}
+
+	risk.Score = math.Round((1-math.Exp(-weight))*1000) / 1000
+	switch {
+	case risk.Score >= 0.7:
+		risk.Level = RiskHigh
+	case risk.Score >= 0.3:
+		risk.Level = RiskMedium
+	default:
+		risk.Level = RiskLow
+	}
+	return
+}
+
+// Fresh returns the abuses not already reported with the same fingerprint, nor repeated
+func Fresh(db *gorm.DB, abuses []Model) (fresh []Model, err error) {
+	unique := Dedupe(abuses, nil)
+	for from := 0; from < len(unique); from += freshChunk {
+		to := from + freshChunk
+		if to > len(unique) {
+			to = len(unique)
+		}
+		addresses := make([]string, 0, to-from)
+		for _, a := range unique[from:to] {
+			addresses = append(addresses, a.Address)
+		}
+		var stored []Model
+		if err = db.Select("address", "abuse_type_id", "abuse_type_other", "description", "category", "fingerprint").
+			Where("address IN ?", addresses).Find(&stored).Error; err != nil {
+			return
+		}
+		existing := make(map[string]bool, len(stored))
+		for _, a := range stored {
+			a.Normalize()
+			existing[a.Fingerprint] = true
+		}
+		fresh = append(fresh, Dedupe(unique[from:to], existing)...)
+	}
+	return
+}
@@ -0,0 +1,89 @@
+package abuse
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+type TestRiskSuite struct {
+	suite.Suite
+	now  time.Time
+	conf RiskConfig
+}
+
+func (suite *TestRiskSuite) SetupTest() {
+	suite.now = time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	suite.conf = RiskConfig{HalfLife: 30 * 24 * time.Hour}
+}
+
+func (suite *TestRiskSuite) report(address, abuseType, description string, age time.Duration) Model {
+	return Model{
+		Model:       gorm.Model{CreatedAt: suite.now.Add(-age)},
+		Address:     address,
+		AbuseTypeID: abuseType,
+		Description: description,
+	}
+}
+
+func (suite *TestRiskSuite) TestCategorize() {
+	assert.Equal(suite.T(), Ransomware, Categorize("1", ""))
+	assert.Equal(suite.T(), Sextortion, Categorize("5", "whatever"))
+	assert.Equal(suite.T(), Scam, Categorize("99", "Fake Elon giveaway"))
+	assert.Equal(suite.T(), Ransomware, Categorize("", "my files were encrypted, ransom note"))
+	assert.Equal(suite.T(), Darknet, Categorize("darknet", ""))
+	assert.Equal(suite.T(), Other, Categorize("99", "no idea"))
+}
+
+func (suite *TestRiskSuite) TestDedupe() {
+	abuses := []Model{
+		suite.report("1A", "4", "Pay me  or else", 0),
+		suite.report("1A", "4", "pay me or else", time.Hour),
+		suite.report("1A", "1", "pay me or else", 0),
+		suite.report("1B", "4", "pay me or else", 0),
+	}
+	existing := map[string]bool{Fingerprint("1B", Blackmail, "Pay me or else"): true}
+	unique := Dedupe(abuses, existing)
+	require.Len(suite.T(), unique, 2)
+	assert.Equal(suite.T(), Blackmail, unique[0].Category)
+	assert.Equal(suite.T(), Ransomware, unique[1].Category)
+	assert.NotEmpty(suite.T(), unique[0].Fingerprint)
+}
+
+func (suite *TestRiskSuite) TestScoreNone() {
+	risk := Score(nil, suite.now, suite.conf)
+	assert.Equal(suite.T(), 0.0, risk.Score)
+	assert.Equal(suite.T(), RiskNone, risk.Level)
+	assert.Nil(suite.T(), risk.LastReport)
+}
+
+func (suite *TestRiskSuite) TestScoreWeights() {
+	recent := Score([]Model{suite.report("1A", "1", "", 0)}, suite.now, suite.conf)
+	old := Score([]Model{suite.report("1A", "1", "", 90*24*time.Hour)}, suite.now, suite.conf)
+	mild := Score([]Model{suite.report("1A", "3", "", 0)}, suite.now, suite.conf)
+	many := Score([]Model{
+		suite.report("1A", "3", "a", 0),
+		suite.report("1A", "3", "b", 0),
+		suite.report("1A", "3", "c", 0),
+	}, suite.now, suite.conf)
+
+	assert.Greater(suite.T(), recent.Score, old.Score)
+	assert.Greater(suite.T(), recent.Score, mild.Score)
+	assert.Greater(suite.T(), many.Score, mild.Score)
+	assert.InDelta(suite.T(), 0.632, recent.Score, 0.001)
+	assert.Equal(suite.T(), RiskMedium, recent.Level)
+	assert.Equal(suite.T(), RiskLow, old.Level)
+	assert.Equal(suite.T(), RiskHigh, many.Level)
+	assert.Equal(suite.T(), map[string]int{Mixer: 3}, many.Categories)
+	assert.Equal(suite.T(), 3, many.Reports)
+	require.NotNil(suite.T(), recent.LastReport)
+	assert.True(suite.T(), suite.now.Equal(*recent.LastReport))
+}
+
+func TestRisk(t *testing.T) {
+	suite.Run(t, new(TestRiskSuite))
+}
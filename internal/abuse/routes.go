@@ -1,21 +1,26 @@
 package abuse
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/labstack/echo/v4"
+	"github.com/xn3cr0nx/bitgodine/internal/apikey"
+	"github.com/xn3cr0nx/bitgodine/internal/errorx"
+	"github.com/xn3cr0nx/bitgodine/internal/ratelimit"
 	"github.com/xn3cr0nx/bitgodine/pkg/validator"
 )
 
 // Routes mounts all /abuse based routes on the main group
 func Routes(g *echo.Group, s Service) {
-	r := g.Group("/abuses", validator.JWT())
+	r := g.Group("/abuses", apikey.Auth(apikey.ReadTags), ratelimit.Middleware(ratelimit.Tags))
 
 	r.GET("", getAbuses(s))
-	r.POST("", createAbuse(s))
+	r.POST("", createAbuse(s), apikey.Auth(apikey.WriteTags), validator.Role(validator.Analyst))
 	r.GET("/:address", getAbusesByAddress(s))
 	r.GET("/cluster/:address", getAbusedCluster(s))
 	r.GET("/cluster/:address/set", getAbusedClusterSet(s))
+	r.GET("/cluster/:address/risk", getClusterRisk(s))
 }
 
 // getAbuses godoc
@@ -79,6 +84,9 @@ func createAbuse(s Service) func(echo.Context) error {
 		}
 
 		if err := s.CreateAbuse(b); err != nil {
+			if errors.Is(err, errorx.ErrAlreadyExists) {
+				return echo.NewHTTPError(http.StatusConflict, err.Error())
+			}
 			return err
 		}
 
@@ -193,3 +201,36 @@ func getAbusedClusterSet(s Service) func(echo.Context) error {
 		return c.JSON(http.StatusOK, clusters)
 	}
 }
+
+// getClusterRisk godoc
+// @ID get-abused-cluster-risk
+//
+// @Router /abuses/cluster/:address/risk [get]
+// @Summary Get abuse risk
+// @Description get the risk score of the address and of its cluster, weighing the number, recency and category severity of the abuses reported
+// @Tags abuses
+//
+// @Security ApiKeyAuth
+//
+// @Accept  json
+// @Produce  json
+//
+// @Param address path string true "address"
+//
+// @Success 200 {object} ClusterRisk
+// @Success 500 {string} string
+func getClusterRisk(s Service) func(echo.Context) error {
+	return func(c echo.Context) error {
+		address := c.Param("address")
+		if err := c.Echo().Validator.(*validator.CustomValidator).Var(address, "required,btc_addr|btc_addr_bech32"); err != nil {
+			return err
+		}
+
+		risk, err := s.GetRisk(address)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, risk)
+	}
+}
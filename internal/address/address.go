@@ -7,6 +7,7 @@ import (
 
 	"github.com/xn3cr0nx/bitgodine/internal/errorx"
 	"github.com/xn3cr0nx/bitgodine/internal/storage/kv"
+	"github.com/xn3cr0nx/bitgodine/internal/storage/kv/iterator"
 	"github.com/xn3cr0nx/bitgodine/pkg/cache"
 	"github.com/xn3cr0nx/bitgodine/pkg/logger"
 )
@@ -14,7 +15,11 @@ import (
 // Service interface exports available methods for tx service
 type Service interface {
 	GetOccurences(address string) (occurences []string, err error)
+	GetOccurencesPage(address string, limit int, afterTxID string) (occurences []string, err error)
 	GetFirstOccurenceHeight(address string) (height int32, err error)
+	GetHistory(address string, limit int, cursor string) (entries []Entry, err error)
+	GetUtxos(address string) (utxos []Utxo, err error)
+	GetBalance(address string) (balance *Balance, err error)
 }
 
 type service struct {
@@ -38,13 +43,26 @@ func IsBitcoinAddress(text string) bool {
 
 // GetOccurences returnes an array containing the transactions where the address appears in the blockchain
 func (s *service) GetOccurences(address string) (occurences []string, err error) {
-	occurences, err = s.Kv.ReadKeysWithPrefix(address + "_")
-	if err != nil {
-		return
+	return s.occurences(address, &iterator.Options{Prefix: address + "_", KeysOnly: true})
+}
+
+// GetOccurencesPage returnes up to limit transactions where the address appears, ordered by txid
+// and starting after the last seen one
+func (s *service) GetOccurencesPage(address string, limit int, afterTxID string) (occurences []string, err error) {
+	opts := &iterator.Options{Prefix: address + "_", Limit: limit, KeysOnly: true}
+	if afterTxID != "" {
+		opts.StartAfter = address + "_" + afterTxID
 	}
-	for _, o := range occurences {
-		o = o[strings.LastIndex(o, "_")+1:]
+	return s.occurences(address, opts)
+}
+
+func (s *service) occurences(address string, opts *iterator.Options) (occurences []string, err error) {
+	it := s.Kv.Iterate(opts)
+	defer it.Close()
+	for it.Next() {
+		occurences = append(occurences, strings.TrimPrefix(it.Key(), address+"_"))
 	}
+	err = it.Err()
 	return
 }
 
@@ -59,6 +77,10 @@ func (s *service) GetFirstOccurenceHeight(address string) (height int32, err err
 	if err != nil {
 		return
 	}
+	if len(resp) == 0 {
+		err = errorx.ErrKeyNotFound
+		return
+	}
 	h, err := strconv.Atoi(string(resp))
 	if err != nil {
 		return
@@ -70,3 +92,73 @@ func (s *service) GetFirstOccurenceHeight(address string) (height int32, err err
 	}
 	return
 }
+
+// GetHistory returnes up to limit address index entries, the most recent first, starting after the cursor
+func (s *service) GetHistory(address string, limit int, cursor string) (entries []Entry, err error) {
+	opts := &iterator.Options{Prefix: IndexPrefix + address + "_", Reverse: true, Limit: limit}
+	if cursor != "" {
+		opts.StartAfter = opts.Prefix + cursor
+	}
+	err = s.entries(address, opts, func(e Entry) {
+		entries = append(entries, e)
+	})
+	return
+}
+
+// GetUtxos returnes the unspent outputs of the address, computed from the address index
+func (s *service) GetUtxos(address string) (utxos []Utxo, err error) {
+	spent := make(map[string]bool)
+	var funding []Entry
+	err = s.entries(address, &iterator.Options{Prefix: IndexPrefix + address + "_"}, func(e Entry) {
+		if e.Direction == Spending {
+			spent[e.SpentTxID+"_"+strconv.Itoa(int(e.SpentVout))] = true
+			return
+		}
+		funding = append(funding, e)
+	})
+	if err != nil {
+		return
+	}
+	for _, e := range funding {
+		if spent[e.TxID+"_"+strconv.Itoa(int(e.Index))] {
+			continue
+		}
+		utxos = append(utxos, Utxo{TxID: e.TxID, Vout: e.Index, Height: e.Height, Value: e.Value})
+	}
+	return
+}
+
+// GetBalance returnes funded and spent amounts of the address, computed from the address index
+func (s *service) GetBalance(address string) (balance *Balance, err error) {
+	balance = &Balance{Address: address}
+	txs := make(map[string]bool)
+	err = s.entries(address, &iterator.Options{Prefix: IndexPrefix + address + "_"}, func(e Entry) {
+		txs[e.TxID] = true
+		if e.Direction == Spending {
+			balance.Spent += e.Value
+			balance.SpentCount++
+			return
+		}
+		balance.Funded += e.Value
+		balance.FundedCount++
+	})
+	if err != nil {
+		return nil, err
+	}
+	balance.Balance = balance.Funded - balance.Spent
+	balance.Transactions = len(txs)
+	return
+}
+
+func (s *service) entries(address string, opts *iterator.Options, fn func(Entry)) (err error) {
+	it := s.Kv.Iterate(opts)
+	defer it.Close()
+	for it.Next() {
+		e, err := unmarshalEntry(address, it.Key(), it.Value())
+		if err != nil {
+			return err
+		}
+		fn(e)
+	}
+	return it.Err()
+}
@@ -0,0 +1,148 @@
+package address
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/xn3cr0nx/bitgodine/internal/errorx"
+	"github.com/xn3cr0nx/bitgodine/internal/tx"
+	"github.com/xn3cr0nx/bitgodine/pkg/encoding"
+	"github.com/xn3cr0nx/bitgodine/pkg/logger"
+)
+
+// Direction of the address index entry, funding the address or spending from it
+type Direction string
+
+const (
+	// Funding entry of an output paying the address
+	Funding Direction = "funding"
+	// Spending entry of an input spending an output of the address
+	Spending Direction = "spending"
+)
+
+// IndexPrefix prefix of the address index keys, followed by address, zero padded height, txid,
+// direction and index so that entries of an address are ordered by height
+const IndexPrefix = "ai_"
+
+// Entry of the address index. Index is the vout for funding entries and the vin for spending ones,
+// SpentTxID and SpentVout are the outpoint spent by spending entries
+type Entry struct {
+	TxID      string    `json:"txid"`
+	Height    int32     `json:"height"`
+	Direction Direction `json:"direction"`
+	Index     uint32    `json:"index"`
+	Value     int64     `json:"value"`
+	SpentTxID string    `json:"spent_txid,omitempty"`
+	SpentVout uint32    `json:"spent_vout,omitempty"`
+} //@name AddressEntry
+
+// Utxo unspent output of the address
+type Utxo struct {
+	TxID   string `json:"txid"`
+	Vout   uint32 `json:"vout"`
+	Height int32  `json:"height"`
+	Value  int64  `json:"value"`
+} //@name Utxo
+
+// Balance of the address computed from the index
+type Balance struct {
+	Address      string `json:"address"`
+	Funded       int64  `json:"funded"`
+	FundedCount  int    `json:"funded_count"`
+	Spent        int64  `json:"spent"`
+	SpentCount   int    `json:"spent_count"`
+	Balance      int64  `json:"balance"`
+	Transactions int    `json:"tx_count"`
+} //@name AddressBalance
+
+// Prevout returns the output spent by an input
+type Prevout func(txid string, vout uint32) (tx.Output, error)
+
+// Cursor returns the position of the entry in the address history, to resume pagination after it
+func (e *Entry) Cursor() string {
+	d := "f"
+	if e.Direction == Spending {
+		d = "s"
+	}
+	return fmt.Sprintf("%010d_%s_%s%06d", e.Height, e.TxID, d, e.Index)
+}
+
+// IndexKey returns the key of the entry in the address index
+func IndexKey(address string, e *Entry) string {
+	return IndexPrefix + address + "_" + e.Cursor()
+}
+
+// marshalEntry encodes the value of the entry, the rest is in the key
+func marshalEntry(e *Entry) []byte {
+	w := encoding.NewWriter(16)
+	w.Varint(e.Value)
+	if e.Direction == Spending {
+		w.Hex(e.SpentTxID)
+		w.Uvarint(uint64(e.SpentVout))
+	}
+	return w.Bytes()
+}
+
+// unmarshalEntry decodes an entry from its key and value
+func unmarshalEntry(address, key string, value []byte) (e Entry, err error) {
+	parts := strings.Split(strings.TrimPrefix(key, IndexPrefix+address+"_"), "_")
+	if len(parts) != 3 || len(parts[2]) < 2 {
+		err = fmt.Errorf("%w: malformed address index key %s", errorx.ErrInvalidArgument, key)
+		return
+	}
+	height, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return
+	}
+	index, err := strconv.Atoi(parts[2][1:])
+	if err != nil {
+		return
+	}
+	e = Entry{TxID: parts[1], Height: int32(height), Direction: Funding, Index: uint32(index)}
+	if parts[2][0] == 's' {
+		e.Direction = Spending
+	}
+
+	r := encoding.NewReader(value)
+	e.Value = r.Varint()
+	if e.Direction == Spending {
+		e.SpentTxID = r.Hex()
+		e.SpentVout = uint32(r.Uvarint())
+	}
+	err = r.Err()
+	return
+}
+
+// IndexTx adds to the batch the address index entries of the transaction, resolving
+// the outputs spent by its inputs through prevout. Inputs spending unknown outputs are
+// skipped, the index can be rebuilt once they're stored
+func IndexTx(batch map[string][]byte, t *tx.Tx, height int32, prevout Prevout) (err error) {
+	for _, out := range t.Vout {
+		if out.ScriptpubkeyAddress == "" {
+			continue
+		}
+		entry := Entry{TxID: t.TxID, Height: height, Direction: Funding, Index: out.Index, Value: out.Value}
+		batch[IndexKey(out.ScriptpubkeyAddress, &entry)] = marshalEntry(&entry)
+	}
+	for i, in := range t.Vin {
+		if in.IsCoinbase {
+			continue
+		}
+		spent, e := prevout(in.TxID, in.Vout)
+		if errors.Is(e, errorx.ErrKeyNotFound) || errors.Is(e, errorx.ErrOutOfRange) {
+			logger.Warn("Address Index", "Spent output not found", logger.Params{"tx": t.TxID, "txid": in.TxID, "vout": in.Vout})
+			continue
+		}
+		if e != nil {
+			return fmt.Errorf("prevout %s:%d: %w", in.TxID, in.Vout, e)
+		}
+		if spent.ScriptpubkeyAddress == "" {
+			continue
+		}
+		entry := Entry{TxID: t.TxID, Height: height, Direction: Spending, Index: uint32(i), Value: spent.Value, SpentTxID: in.TxID, SpentVout: in.Vout}
+		batch[IndexKey(spent.ScriptpubkeyAddress, &entry)] = marshalEntry(&entry)
+	}
+	return
+}
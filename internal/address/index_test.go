@@ -0,0 +1,107 @@
+package address_test
+
+import (
+	"github.com/stretchr/testify/mock"
+
+	"github.com/xn3cr0nx/bitgodine/internal/address"
+	"github.com/xn3cr0nx/bitgodine/internal/errorx"
+	"github.com/xn3cr0nx/bitgodine/internal/storage/kv"
+	"github.com/xn3cr0nx/bitgodine/internal/storage/kv/iterator"
+	"github.com/xn3cr0nx/bitgodine/internal/tx"
+	"github.com/xn3cr0nx/bitgodine/pkg/logger"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Testing address index", func() {
+	var (
+		service address.Service
+		batch   map[string][]byte
+		funding tx.Tx
+	)
+
+	BeforeEach(func() {
+		logger.Setup()
+		batch = make(map[string][]byte)
+		funding = tx.Tx{
+			TxID: "aa",
+			Vin:  []tx.Input{{IsCoinbase: true}},
+			Vout: []tx.Output{
+				{ScriptpubkeyAddress: "1A", Value: 5000, Index: 0},
+				{ScriptpubkeyAddress: "1A", Value: 3000, Index: 1},
+				{Value: 0, Index: 2},
+			},
+		}
+		spending := tx.Tx{
+			TxID: "bb",
+			Vin:  []tx.Input{{TxID: "aa", Vout: 0}, {TxID: "missing", Vout: 0}},
+			Vout: []tx.Output{
+				{ScriptpubkeyAddress: "1B", Value: 4000, Index: 0},
+				{ScriptpubkeyAddress: "1A", Value: 900, Index: 1},
+			},
+		}
+		prevout := func(txid string, vout uint32) (tx.Output, error) {
+			if txid == funding.TxID {
+				return funding.Vout[vout], nil
+			}
+			return tx.Output{}, errorx.ErrKeyNotFound
+		}
+		Expect(address.IndexTx(batch, &funding, 1, prevout)).To(Succeed())
+		Expect(address.IndexTx(batch, &spending, 2, prevout)).To(Succeed())
+
+		db := kv.NewDBMock()
+		db.On("Iterate", mock.Anything).Return(func(opts *iterator.Options) iterator.Iterator {
+			return iterator.FromMap(batch, opts)
+		})
+		service = address.NewService(db, nil)
+	})
+
+	It("Should index funding and spending entries", func() {
+		Expect(batch).To(HaveLen(5))
+		Expect(batch).To(HaveKey(address.IndexPrefix + "1A_0000000002_bb_s000000"))
+		Expect(batch).To(HaveKey(address.IndexPrefix + "1B_0000000002_bb_f000000"))
+	})
+
+	It("Should compute the balance from the index", func() {
+		balance, err := service.GetBalance("1A")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(*balance).To(Equal(address.Balance{
+			Address:      "1A",
+			Funded:       8900,
+			FundedCount:  3,
+			Spent:        5000,
+			SpentCount:   1,
+			Balance:      3900,
+			Transactions: 2,
+		}))
+	})
+
+	It("Should list unspent outputs from the index", func() {
+		utxos, err := service.GetUtxos("1A")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(utxos).To(ConsistOf(
+			address.Utxo{TxID: "aa", Vout: 1, Height: 1, Value: 3000},
+			address.Utxo{TxID: "bb", Vout: 1, Height: 2, Value: 900},
+		))
+	})
+
+	It("Should page the history the most recent first", func() {
+		entries, err := service.GetHistory("1A", 2, "")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(entries).To(Equal([]address.Entry{
+			{TxID: "bb", Height: 2, Direction: address.Spending, Index: 0, Value: 5000, SpentTxID: "aa", SpentVout: 0},
+			{TxID: "bb", Height: 2, Direction: address.Funding, Index: 1, Value: 900},
+		}))
+
+		entries, err = service.GetHistory("1A", 2, entries[1].Cursor())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(entries).To(HaveLen(2))
+		Expect(entries[0].TxID).To(Equal("aa"))
+		Expect(entries[0].Index).To(Equal(uint32(1)))
+
+		entries, err = service.GetHistory("1A", 2, entries[1].Cursor())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(entries).To(BeEmpty())
+	})
+})
@@ -0,0 +1,52 @@
+package address_test
+
+import (
+	"github.com/stretchr/testify/mock"
+
+	"github.com/xn3cr0nx/bitgodine/internal/address"
+	"github.com/xn3cr0nx/bitgodine/internal/storage/kv"
+	"github.com/xn3cr0nx/bitgodine/internal/storage/kv/iterator"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Testing address history pagination", func() {
+	var (
+		service address.Service
+	)
+
+	BeforeEach(func() {
+		values := map[string][]byte{
+			"addr_a":  []byte("1"),
+			"addr_b":  []byte("2"),
+			"addr_c":  []byte("3"),
+			"other_d": []byte("4"),
+		}
+		db := kv.NewDBMock()
+		db.On("Iterate", mock.Anything).Return(func(opts *iterator.Options) iterator.Iterator {
+			return iterator.FromMap(values, opts)
+		})
+		service = address.NewService(db, nil)
+	})
+
+	It("Should get all the address occurences as txids", func() {
+		occurences, err := service.GetOccurences("addr")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(occurences).To(Equal([]string{"a", "b", "c"}))
+	})
+
+	It("Should get pages of occurences after the last seen txid", func() {
+		occurences, err := service.GetOccurencesPage("addr", 2, "")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(occurences).To(Equal([]string{"a", "b"}))
+
+		occurences, err = service.GetOccurencesPage("addr", 2, "b")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(occurences).To(Equal([]string{"c"}))
+
+		occurences, err = service.GetOccurencesPage("addr", 2, "c")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(occurences).To(BeEmpty())
+	})
+})
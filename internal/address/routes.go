@@ -4,14 +4,22 @@ import (
 	"net/http"
 
 	"github.com/labstack/echo/v4"
+	"github.com/xn3cr0nx/bitgodine/internal/apikey"
+	"github.com/xn3cr0nx/bitgodine/internal/ratelimit"
 	"github.com/xn3cr0nx/bitgodine/pkg/validator"
 )
 
 // Routes mounts all /address based routes on the main group
 func Routes(g *echo.Group, s Service) {
-	r := g.Group("/address")
+	r := g.Group("/address", apikey.Auth(apikey.ReadBlocks), ratelimit.Middleware(ratelimit.Blocks))
 
-	r.GET("/:address", func(c echo.Context) error {
+	r.GET("/:address", addressBalance(s))
+	r.GET("/:address/history", addressHistory(s))
+
+	r.GET("/:address/txs", addressTxs(s))
+	r.GET("/:address/txs/chain/:last_seen_txid", addressTxs(s))
+
+	r.GET("/:address/txs/mempool", func(c echo.Context) error {
 		address := c.Param("address")
 		if err := c.Echo().Validator.(*validator.CustomValidator).Var(address, "required,btc_addr|btc_addr_bech32"); err != nil {
 			return err
@@ -19,35 +27,159 @@ func Routes(g *echo.Group, s Service) {
 		return c.JSON(http.StatusOK, "OK")
 	})
 
-	r.GET("/:address/txs", func(c echo.Context) error {
+	r.GET("/:address/utxo", addressUtxos(s))
+}
+
+// PageSize number of transactions returned per page of address history
+const PageSize = 25
+
+// addressTxs godoc
+// @ID address-txs
+//
+// @Router /address/{address}/txs/chain/{last_seen_txid} [get]
+// @Summary Address transactions
+// @Description get the transactions of the address ordered by txid, 25 per page, after the last seen txid
+// @Tags address
+//
+// @Security ApiKeyAuth
+//
+// @Accept  json
+// @Produce  json
+//
+// @Param address path string true "Address"
+// @Param last_seen_txid path string false "Last txid of the previous page"
+// @Success 200 {array} string
+// @Success 500 {string} string
+func addressTxs(s Service) func(echo.Context) error {
+	return func(c echo.Context) error {
 		address := c.Param("address")
 		if err := c.Echo().Validator.(*validator.CustomValidator).Var(address, "required,btc_addr|btc_addr_bech32"); err != nil {
 			return err
 		}
-		return c.JSON(http.StatusOK, "OK")
-	})
+		last := c.Param("last_seen_txid")
+		if err := c.Echo().Validator.(*validator.CustomValidator).Var(last, "omitempty,hexadecimal,len=64"); err != nil {
+			return err
+		}
+		txs, err := s.GetOccurencesPage(address, PageSize, last)
+		if err != nil {
+			return err
+		}
+		if txs == nil {
+			txs = []string{}
+		}
+		return c.JSON(http.StatusOK, txs)
+	}
+}
 
-	r.GET("/:address/txs/chain/:last_seen_txid", func(c echo.Context) error {
+// addressBalance godoc
+// @ID address-balance
+//
+// @Router /address/{address} [get]
+// @Summary Address balance
+// @Description get funded and spent amounts of the address from the address index
+// @Tags address
+//
+// @Security ApiKeyAuth
+//
+// @Accept  json
+// @Produce  json
+//
+// @Param address path string true "Address"
+// @Success 200 {object} Balance
+// @Success 500 {string} string
+func addressBalance(s Service) func(echo.Context) error {
+	return func(c echo.Context) error {
 		address := c.Param("address")
 		if err := c.Echo().Validator.(*validator.CustomValidator).Var(address, "required,btc_addr|btc_addr_bech32"); err != nil {
 			return err
 		}
-		return c.JSON(http.StatusOK, "OK")
-	})
+		balance, err := s.GetBalance(address)
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, balance)
+	}
+}
+
+// addressHistory godoc
+// @ID address-history
+//
+// @Router /address/{address}/history [get]
+// @Summary Address history
+// @Description get the funding and spending entries of the address, the most recent first
+// @Tags address
+//
+// @Security ApiKeyAuth
+//
+// @Accept  json
+// @Produce  json
+//
+// @Param address path string true "Address"
+// @Param limit query int false "Entries per page, 25 by default"
+// @Param cursor query string false "Cursor of the last entry of the previous page"
+// @Success 200 {array} Entry
+// @Success 500 {string} string
+func addressHistory(s Service) func(echo.Context) error {
+	return func(c echo.Context) error {
+		type Query struct {
+			Limit  int    `query:"limit" validate:"omitempty,gt=0,lte=1000"`
+			Cursor string `query:"cursor" validate:"omitempty,printascii"`
+		}
+		q := new(Query)
+		if err := validator.Struct(&c, q); err != nil {
+			return err
+		}
+		if q.Limit == 0 {
+			q.Limit = PageSize
+		}
 
-	r.GET("/:address/txs/mempool", func(c echo.Context) error {
 		address := c.Param("address")
 		if err := c.Echo().Validator.(*validator.CustomValidator).Var(address, "required,btc_addr|btc_addr_bech32"); err != nil {
 			return err
 		}
-		return c.JSON(http.StatusOK, "OK")
-	})
+		entries, err := s.GetHistory(address, q.Limit, q.Cursor)
+		if err != nil {
+			return err
+		}
+		if entries == nil {
+			entries = []Entry{}
+		}
+		if len(entries) == q.Limit {
+			c.Response().Header().Set("X-Next-Cursor", entries[len(entries)-1].Cursor())
+		}
+		return c.JSON(http.StatusOK, entries)
+	}
+}
 
-	r.GET("/:address/utxo", func(c echo.Context) error {
+// addressUtxos godoc
+// @ID address-utxo
+//
+// @Router /address/{address}/utxo [get]
+// @Summary Address unspent outputs
+// @Description get the unspent outputs of the address from the address index
+// @Tags address
+//
+// @Security ApiKeyAuth
+//
+// @Accept  json
+// @Produce  json
+//
+// @Param address path string true "Address"
+// @Success 200 {array} Utxo
+// @Success 500 {string} string
+func addressUtxos(s Service) func(echo.Context) error {
+	return func(c echo.Context) error {
 		address := c.Param("address")
 		if err := c.Echo().Validator.(*validator.CustomValidator).Var(address, "required,btc_addr|btc_addr_bech32"); err != nil {
 			return err
 		}
-		return c.JSON(http.StatusOK, "OK")
-	})
+		utxos, err := s.GetUtxos(address)
+		if err != nil {
+			return err
+		}
+		if utxos == nil {
+			utxos = []Utxo{}
+		}
+		return c.JSON(http.StatusOK, utxos)
+	}
 }
@@ -25,6 +25,7 @@ import (
 type Service interface {
 	AnalyzeTx(txid string, heuristicsList heuristics.Mask, analysisType string) (vuln interface{}, err error)
 	AnalyzeBlocks(from, to int32, heuristicsList heuristics.Mask, analysisType, criteria, chart string, force bool) (err error)
+	Lint(raw, packet string) (report *Lint, err error)
 }
 
 type service struct {
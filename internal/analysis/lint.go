@@ -0,0 +1,232 @@
+package analysis
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/btcsuite/btcutil/psbt"
+
+	"github.com/xn3cr0nx/bitgodine/internal/block"
+	"github.com/xn3cr0nx/bitgodine/internal/errorx"
+	"github.com/xn3cr0nx/bitgodine/internal/heuristics"
+	"github.com/xn3cr0nx/bitgodine/internal/heuristics/shadow"
+	"github.com/xn3cr0nx/bitgodine/internal/parser/bitcoin"
+	"github.com/xn3cr0nx/bitgodine/internal/tx"
+)
+
+// LintWarning describes a privacy leak detected by an heuristic on a transaction not broadcasted yet
+type LintWarning struct {
+	Heuristic string   `json:"heuristic"`
+	Outputs   []uint32 `json:"outputs"`
+	Message   string   `json:"message"`
+	Fix       string   `json:"fix"`
+}
+
+// Lint privacy report of a transaction not broadcasted yet
+type Lint struct {
+	TxID       string              `json:"txid"`
+	Fee        int64               `json:"fee"`
+	Heuristics map[string][]uint32 `json:"heuristics"`
+	Change     *uint32             `json:"change,omitempty"`
+	Likelihood float64             `json:"likelihood,omitempty"`
+	Warnings   []LintWarning       `json:"warnings"`
+	Tx         tx.Tx               `json:"tx"`
+}
+
+// lintHeuristics list of heuristics that can be applied to a transaction before it is broadcasted,
+// since they only depend on the transaction itself and on its prevouts
+func lintHeuristics() []heuristics.Heuristic {
+	return []heuristics.Heuristic{
+		heuristics.PowerOfTen,
+		heuristics.OptimalChange,
+		heuristics.AddressType,
+		heuristics.AddressReuse,
+		heuristics.Shadow,
+		heuristics.Locktime,
+	}
+}
+
+// lintMessages explanation and suggested fix for each linted heuristic
+var lintMessages = map[heuristics.Heuristic][2]string{
+	heuristics.PowerOfTen: {
+		"output value is a round amount, round amounts tell payments apart from change",
+		"avoid round payment amounts or add a small random offset to them",
+	},
+	heuristics.OptimalChange: {
+		"output value is smaller than every input, an optimal wallet would not have needed all the inputs to pay it",
+		"select inputs so that the change is not smaller than the smallest input, or drop unnecessary inputs",
+	},
+	heuristics.AddressType: {
+		"output shares the script type of all the inputs while the other outputs do not",
+		"use a change address of the same script type of the payment output",
+	},
+	heuristics.AddressReuse: {
+		"output address is reused from the inputs",
+		"send the change to a fresh address",
+	},
+	heuristics.Shadow: {
+		"output address already appeared in the blockchain while other outputs are fresh",
+		"never reuse addresses, generate a fresh address for every output you control",
+	},
+	heuristics.Locktime: {
+		"locktime is set, spending the change with the same locktime policy will link it to this transaction",
+		"use the same locktime policy of the most common wallets, or spend the change with a different one",
+	},
+}
+
+// DecodeRawTx decodes a hex encoded serialized transaction
+func DecodeRawTx(raw string) (msg *wire.MsgTx, err error) {
+	b, err := hex.DecodeString(strings.TrimSpace(raw))
+	if err != nil {
+		err = fmt.Errorf("%w: %s", errorx.ErrInvalidArgument, err.Error())
+		return
+	}
+	msg = wire.NewMsgTx(wire.TxVersion)
+	if err = msg.Deserialize(bytes.NewReader(b)); err != nil {
+		err = fmt.Errorf("%w: %s", errorx.ErrInvalidArgument, err.Error())
+		return
+	}
+	return
+}
+
+// DecodePsbt decodes a BIP174 partially signed transaction, both base64 and hex encoding are accepted,
+// and returns the unsigned transaction it wraps
+func DecodePsbt(packet string) (msg *wire.MsgTx, err error) {
+	packet = strings.TrimSpace(packet)
+	var p *psbt.Packet
+	if b, e := hex.DecodeString(packet); e == nil {
+		p, err = psbt.NewFromRawBytes(bytes.NewReader(b), false)
+	} else if _, e := base64.StdEncoding.DecodeString(packet); e == nil {
+		p, err = psbt.NewFromRawBytes(strings.NewReader(packet), true)
+	} else {
+		err = fmt.Errorf("%w: psbt is neither hex nor base64 encoded", errorx.ErrInvalidArgument)
+		return
+	}
+	if err != nil {
+		err = fmt.Errorf("%w: %s", errorx.ErrInvalidArgument, err.Error())
+		return
+	}
+	msg = p.UnsignedTx
+	return
+}
+
+// Lint applies to the passed transaction (raw hex or psbt) all the heuristics that can be evaluated
+// before it is broadcasted, reporting the output most likely to be flagged as change
+func (s *service) Lint(raw, packet string) (report *Lint, err error) {
+	var msg *wire.MsgTx
+	if packet != "" {
+		msg, err = DecodePsbt(packet)
+	} else {
+		msg, err = DecodeRawTx(raw)
+	}
+	if err != nil {
+		return
+	}
+
+	txs, err := bitcoin.PrepareTransactions(s.Kv, []*btcutil.Tx{btcutil.NewTx(msg)})
+	if err != nil {
+		return
+	}
+	transaction := txs[0]
+
+	// resolve prevouts from the index, heuristics need them to be stored
+	txService := tx.NewService(s.Kv, s.Cache)
+	var in int64
+	for _, input := range transaction.Vin {
+		if input.IsCoinbase {
+			continue
+		}
+		spent, e := txService.GetFromHash(input.TxID)
+		if e != nil {
+			err = fmt.Errorf("prevout %s:%d %w", input.TxID, input.Vout, e)
+			return
+		}
+		if int(input.Vout) >= len(spent.Vout) {
+			err = fmt.Errorf("prevout %s:%d %w", input.TxID, input.Vout, errorx.ErrOutOfRange)
+			return
+		}
+		in += spent.Vout[input.Vout].Value
+	}
+	var out int64
+	for _, output := range transaction.Vout {
+		out += output.Value
+	}
+	transaction.Fee = float32(in - out)
+
+	tip, err := block.NewService(s.Kv, s.Cache).ReadHeight()
+	if err != nil {
+		return
+	}
+
+	report = &Lint{
+		TxID:       transaction.TxID,
+		Fee:        in - out,
+		Heuristics: make(map[string][]uint32),
+		Tx:         transaction,
+	}
+	analyzed := make(heuristics.Map)
+	for _, h := range lintHeuristics() {
+		var c []uint32
+		switch h {
+		case heuristics.Shadow:
+			c, err = (&shadow.ShadowAddress{Kv: s.Kv, Cache: s.Cache}).ChangeOutputAtHeight(&transaction, tip+1)
+		case heuristics.Locktime:
+			// spending transactions don't exist yet, the locktime can only be reported as a fingerprint
+			if transaction.Locktime != 0 {
+				report.Warnings = append(report.Warnings, LintWarning{
+					Heuristic: h.String(),
+					Message:   lintMessages[h][0],
+					Fix:       lintMessages[h][1],
+				})
+			}
+			continue
+		default:
+			c, err = h.Implementation(s.Kv, s.Cache).ChangeOutput(&transaction)
+		}
+		if err != nil {
+			// heuristic not applicable to the transaction
+			err = nil
+			continue
+		}
+		// flagging every output doesn't tell the change apart
+		if len(c) == 0 || len(c) == len(transaction.Vout) {
+			continue
+		}
+		report.Heuristics[h.String()] = c
+		if len(c) == 1 {
+			analyzed[h] = c[0]
+		}
+		report.Warnings = append(report.Warnings, LintWarning{
+			Heuristic: h.String(),
+			Outputs:   c,
+			Message:   lintMessages[h][0],
+			Fix:       lintMessages[h][1],
+		})
+	}
+
+	likelihood, err := MajorityVotingOutput(analyzed)
+	if err != nil {
+		// no heuristic told the change apart, the report has no likely change
+		if errors.Is(err, ErrUnfeasibleTx) {
+			err = nil
+		}
+		return
+	}
+	for output, masks := range likelihood {
+		for _, perc := range masks {
+			if perc > report.Likelihood {
+				vout := output
+				report.Change = &vout
+				report.Likelihood = perc
+			}
+		}
+	}
+
+	return
+}
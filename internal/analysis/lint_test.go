@@ -0,0 +1,169 @@
+package analysis
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/btcsuite/btcutil/psbt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/xn3cr0nx/bitgodine/internal/errorx"
+	"github.com/xn3cr0nx/bitgodine/internal/heuristics"
+	"github.com/xn3cr0nx/bitgodine/internal/storage/kv"
+	"github.com/xn3cr0nx/bitgodine/internal/tx"
+	"github.com/xn3cr0nx/bitgodine/pkg/cache"
+	"github.com/xn3cr0nx/bitgodine/pkg/encoding"
+	"github.com/xn3cr0nx/bitgodine/pkg/logger"
+)
+
+type TestLintSuite struct {
+	suite.Suite
+	db      *kv.DBMock
+	service *service
+	reused  btcutil.Address
+	fresh   btcutil.Address
+	prevout tx.Tx
+	target  *wire.MsgTx
+}
+
+func testAddress(seed byte) btcutil.Address {
+	hash := bytes.Repeat([]byte{seed}, 20)
+	addr, _ := btcutil.NewAddressPubKeyHash(hash, &chaincfg.MainNetParams)
+	return addr
+}
+
+func (suite *TestLintSuite) SetupSuite() {
+	logger.Setup()
+
+	c, err := cache.NewCache(nil)
+	require.Nil(suite.T(), err)
+	suite.db = kv.NewDBMock()
+	suite.service = NewService(suite.db, c)
+
+	suite.reused = testAddress(1)
+	suite.fresh = testAddress(2)
+	suite.prevout = tx.Tx{
+		TxID: "a1075db55d416d3ca199f55b6084e2115b9345e16c5cf302fc80e9d5fbf5d48d",
+		Vout: []tx.Output{{
+			ScriptpubkeyType:    "pubkeyhash",
+			ScriptpubkeyAddress: suite.reused.EncodeAddress(),
+			Value:               100000,
+		}},
+	}
+	prevout, err := encoding.Marshal(suite.prevout)
+	require.Nil(suite.T(), err)
+
+	suite.db.On("Read", suite.prevout.TxID).Return(prevout, nil)
+	suite.db.On("Read", "last").Return([]byte("100"), nil)
+	suite.db.On("ReadFirstValueByPrefix", suite.reused.EncodeAddress()+"_").Return([]byte("10"), nil)
+	suite.db.On("ReadFirstValueByPrefix", suite.fresh.EncodeAddress()+"_").Return(nil, nil)
+	suite.db.On("Read", mock.Anything).Return(nil, errorx.ErrKeyNotFound)
+
+	suite.target = suite.buildTx(uint32(0))
+}
+
+func (suite *TestLintSuite) buildTx(locktime uint32) *wire.MsgTx {
+	msg := wire.NewMsgTx(wire.TxVersion)
+	prev, _ := chainhash.NewHashFromStr(suite.prevout.TxID)
+	msg.AddTxIn(wire.NewTxIn(wire.NewOutPoint(prev, 0), nil, nil))
+	payment, _ := txscript.PayToAddrScript(suite.fresh)
+	change, _ := txscript.PayToAddrScript(suite.reused)
+	msg.AddTxOut(wire.NewTxOut(50000, payment))
+	msg.AddTxOut(wire.NewTxOut(49123, change))
+	msg.LockTime = locktime
+	return msg
+}
+
+func (suite *TestLintSuite) TestDecodeRawTx() {
+	var buf bytes.Buffer
+	require.Nil(suite.T(), suite.target.Serialize(&buf))
+
+	msg, err := DecodeRawTx(hex.EncodeToString(buf.Bytes()))
+	require.Nil(suite.T(), err)
+	assert.Equal(suite.T(), suite.target.TxHash(), msg.TxHash())
+
+	_, err = DecodeRawTx("not hex")
+	assert.True(suite.T(), errors.Is(err, errorx.ErrInvalidArgument))
+}
+
+func (suite *TestLintSuite) TestDecodePsbt() {
+	var outpoints []*wire.OutPoint
+	for _, in := range suite.target.TxIn {
+		outpoint := in.PreviousOutPoint
+		outpoints = append(outpoints, &outpoint)
+	}
+	p, err := psbt.New(outpoints, suite.target.TxOut, suite.target.Version, suite.target.LockTime, []uint32{wire.MaxTxInSequenceNum})
+	require.Nil(suite.T(), err)
+	b64, err := p.B64Encode()
+	require.Nil(suite.T(), err)
+
+	msg, err := DecodePsbt(b64)
+	require.Nil(suite.T(), err)
+	assert.Equal(suite.T(), suite.target.TxHash(), msg.TxHash())
+
+	var buf bytes.Buffer
+	require.Nil(suite.T(), p.Serialize(&buf))
+	msg, err = DecodePsbt(hex.EncodeToString(buf.Bytes()))
+	require.Nil(suite.T(), err)
+	assert.Equal(suite.T(), suite.target.TxHash(), msg.TxHash())
+
+	_, err = DecodePsbt("cHNidP8=")
+	assert.True(suite.T(), errors.Is(err, errorx.ErrInvalidArgument))
+}
+
+func (suite *TestLintSuite) TestLint() {
+	var buf bytes.Buffer
+	require.Nil(suite.T(), suite.target.Serialize(&buf))
+
+	report, err := suite.service.Lint(hex.EncodeToString(buf.Bytes()), "")
+	require.Nil(suite.T(), err)
+	assert.Equal(suite.T(), suite.target.TxHash().String(), report.TxID)
+	assert.Equal(suite.T(), int64(877), report.Fee)
+	assert.Equal(suite.T(), []uint32{0}, report.Heuristics[heuristics.PowerOfTen.String()])
+	assert.Equal(suite.T(), []uint32{1}, report.Heuristics[heuristics.AddressReuse.String()])
+	assert.Equal(suite.T(), []uint32{1}, report.Heuristics[heuristics.Shadow.String()])
+	assert.NotContains(suite.T(), report.Heuristics, heuristics.AddressType.String())
+	require.NotNil(suite.T(), report.Change)
+	assert.Equal(suite.T(), uint32(1), *report.Change)
+	assert.Len(suite.T(), report.Warnings, 3)
+}
+
+func (suite *TestLintSuite) TestLintLocktime() {
+	var buf bytes.Buffer
+	require.Nil(suite.T(), suite.buildTx(650000).Serialize(&buf))
+
+	report, err := suite.service.Lint(hex.EncodeToString(buf.Bytes()), "")
+	require.Nil(suite.T(), err)
+	var locktime bool
+	for _, w := range report.Warnings {
+		if w.Heuristic == heuristics.Locktime.String() {
+			locktime = true
+			assert.Empty(suite.T(), w.Outputs)
+		}
+	}
+	assert.True(suite.T(), locktime)
+}
+
+func (suite *TestLintSuite) TestLintMissingPrevout() {
+	msg := suite.buildTx(0)
+	msg.TxIn[0].PreviousOutPoint.Index = 3
+	var buf bytes.Buffer
+	require.Nil(suite.T(), msg.Serialize(&buf))
+
+	_, err := suite.service.Lint(hex.EncodeToString(buf.Bytes()), "")
+	assert.True(suite.T(), errors.Is(err, errorx.ErrOutOfRange))
+}
+
+func TestLint(t *testing.T) {
+	suite.Run(t, new(TestLintSuite))
+}
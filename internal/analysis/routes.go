@@ -1,9 +1,13 @@
 package analysis
 
 import (
+	"errors"
 	"net/http"
 
+	"github.com/xn3cr0nx/bitgodine/internal/apikey"
+	"github.com/xn3cr0nx/bitgodine/internal/errorx"
 	"github.com/xn3cr0nx/bitgodine/internal/heuristics"
+	"github.com/xn3cr0nx/bitgodine/internal/ratelimit"
 	"github.com/xn3cr0nx/bitgodine/pkg/validator"
 
 	"github.com/labstack/echo/v4"
@@ -11,9 +15,10 @@ import (
 
 // Routes mounts /analysis based routes on the main group
 func Routes(g *echo.Group, s Service) {
-	r := g.Group("/analysis", validator.JWT())
+	r := g.Group("/analysis", apikey.Auth(apikey.RunAnalysis), ratelimit.Middleware(ratelimit.Analysis))
 	r.GET("/:txid", analysisID(s))
 	r.GET("/blocks", analysisBlocks(s))
+	r.POST("/lint", analysisLint(s))
 }
 
 // analysisID godoc
@@ -148,3 +153,43 @@ func analysisBlocks(s Service) func(echo.Context) error {
 		return c.JSON(http.StatusOK, "ok")
 	}
 }
+
+// analysisLint godoc
+// @ID analysis-lint
+//
+// @Router /analysis/lint [post]
+// @Summary Lint transaction
+// @Description apply pre broadcast heuristics to a raw transaction or a psbt, reporting the likely change output and suggested fixes
+// @Tags analysis
+//
+// @Security ApiKeyAuth
+//
+// @Accept  json
+// @Produce  json
+//
+// @Param body body object true "raw hex transaction or psbt (base64 or hex)"
+//
+// @Success 200 {object} Lint
+// @Success 400 {string} string
+// @Success 500 {string} string
+func analysisLint(s Service) func(echo.Context) error {
+	return func(c echo.Context) error {
+		type Body struct {
+			Raw  string `json:"raw" validate:"required_without=Psbt,omitempty,hexadecimal"`
+			Psbt string `json:"psbt" validate:"required_without=Raw"`
+		}
+		b := new(Body)
+		if err := validator.Struct(&c, b); err != nil {
+			return err
+		}
+
+		report, err := s.Lint(b.Raw, b.Psbt)
+		if err != nil {
+			if errors.Is(err, errorx.ErrInvalidArgument) {
+				return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+			}
+			return err
+		}
+		return c.JSON(http.StatusOK, report)
+	}
+}
@@ -0,0 +1,193 @@
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/xn3cr0nx/bitgodine/internal/errorx"
+	"github.com/xn3cr0nx/bitgodine/internal/storage/db/postgres"
+)
+
+// KeyPrefix identifies bitgodine api keys
+const KeyPrefix = "bg"
+
+// DefaultExpiry default validity of a new api key
+const DefaultExpiry = 90 * 24 * time.Hour
+
+// LastUsedInterval minimum interval between two updates of the key last usage
+const LastUsedInterval = time.Minute
+
+var (
+	// ErrInvalidKey the key is malformed, unknown, revoked or expired
+	ErrInvalidKey = errors.New("invalid api key")
+	// ErrScope the key has not been granted the scope
+	ErrScope = errors.New("api key scope not granted")
+	// ErrOwner the owner of the key is blocked or not activated
+	ErrOwner = errors.New("api key owner blocked or not activated")
+)
+
+// Service interface exports available methods for api key service
+type Service interface {
+	CreateKey(userID, name string, scopes []string, expiry time.Duration) (created *Created, err error)
+	GetKeys(userID string) (keys []Model, err error)
+	RevokeKey(userID, ID string) (err error)
+	Verify(key string) (apikey *Model, err error)
+}
+
+type service struct {
+	Repository *postgres.Pg
+}
+
+// NewService instantiates a new Service layer for customer
+func NewService(r *postgres.Pg) *service {
+	return &service{
+		Repository: r,
+	}
+}
+
+// Generate returns a new random api key in the form bg_<prefix>_<secret> along with its prefix
+func Generate() (key, prefix string, err error) {
+	p := make([]byte, 6)
+	if _, err = rand.Read(p); err != nil {
+		return
+	}
+	secret := make([]byte, 32)
+	if _, err = rand.Read(secret); err != nil {
+		return
+	}
+	prefix = hex.EncodeToString(p)
+	key = strings.Join([]string{KeyPrefix, prefix, base64.RawURLEncoding.EncodeToString(secret)}, "_")
+	return
+}
+
+// Prefix extracts the public prefix of the key
+func Prefix(key string) (prefix string, err error) {
+	// the secret is base64url encoded and can contain the separator
+	parts := strings.SplitN(key, "_", 3)
+	if len(parts) != 3 || parts[0] != KeyPrefix || len(parts[1]) != 12 || parts[2] == "" {
+		err = ErrInvalidKey
+		return
+	}
+	prefix = parts[1]
+	return
+}
+
+// Digest returns the hex encoded sha256 digest of the key. Keys carry 256 random bits, a slow
+// password hash would only add latency to each request
+func Digest(key string) string {
+	digest := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(digest[:])
+}
+
+// Check verifies the key matches the stored digest and the record is still valid
+func Check(apikey *Model, key string) error {
+	if apikey.Revoked || apikey.Expired() || subtle.ConstantTimeCompare([]byte(apikey.Hash), []byte(Digest(key))) != 1 {
+		return ErrInvalidKey
+	}
+	return nil
+}
+
+// CreateKey mints a new api key granted the scopes, returning the secret that is not stored
+func (s *service) CreateKey(userID, name string, scopes []string, expiry time.Duration) (created *Created, err error) {
+	owner, err := uuid.Parse(userID)
+	if err != nil {
+		err = fmt.Errorf("%w: %s", errorx.ErrInvalidArgument, err.Error())
+		return
+	}
+	if expiry <= 0 {
+		expiry = DefaultExpiry
+	}
+	if len(scopes) == 0 {
+		scopes = Scopes()
+	}
+
+	key, prefix, err := Generate()
+	if err != nil {
+		return
+	}
+	apikey := &Model{
+		UserID:    owner,
+		Name:      name,
+		Prefix:    prefix,
+		Hash:      Digest(key),
+		Scopes:    scopes,
+		ExpiresAt: time.Now().Add(expiry),
+	}
+	if err = s.Repository.Model(&Model{}).Create(apikey).Error; err != nil {
+		return
+	}
+	created = &Created{Key: key, APIKey: apikey}
+	return
+}
+
+// GetKeys retrieves the api keys of the user
+func (s *service) GetKeys(userID string) (keys []Model, err error) {
+	err = s.Repository.Where("user_id = ?", userID).Order("created_at desc").Find(&keys).Error
+	return
+}
+
+// RevokeKey revokes an api key of the user, revoked keys are kept for auditing
+func (s *service) RevokeKey(userID, ID string) (err error) {
+	res := s.Repository.Model(&Model{}).Where("id = ? AND user_id = ?", ID, userID).Update("revoked", true)
+	if err = res.Error; err != nil {
+		return
+	}
+	if res.RowsAffected == 0 {
+		err = fmt.Errorf("api key %s %w", ID, errorx.ErrNotFound)
+	}
+	return
+}
+
+// Verify looks up the key by prefix, checks it and tracks its usage at most once per LastUsedInterval. Keys act with
+// the role and the plan of their owner, keys of blocked or not activated owners are rejected
+func (s *service) Verify(key string) (apikey *Model, err error) {
+	prefix, err := Prefix(key)
+	if err != nil {
+		return
+	}
+	apikey = new(Model)
+	if err = s.Repository.Where("prefix = ?", prefix).First(apikey).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			err = ErrInvalidKey
+		}
+		apikey = nil
+		return
+	}
+	if err = Check(apikey, key); err != nil {
+		apikey = nil
+		return
+	}
+
+	var blocked, active bool
+	if err = s.Repository.Table("users").Select("role, plan, is_blocked, is_active").Where("id = ?", apikey.UserID).Row().Scan(&apikey.Role, &apikey.Plan, &blocked, &active); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrInvalidKey
+		}
+		apikey = nil
+		return
+	}
+	if blocked || !active {
+		err = ErrOwner
+		apikey = nil
+		return
+	}
+
+	now := time.Now()
+	if apikey.LastUsed != nil && now.Sub(*apikey.LastUsed) < LastUsedInterval {
+		return
+	}
+	apikey.LastUsed = &now
+	err = s.Repository.Model(apikey).UpdateColumn("last_used", now).Error
+	return
+}
@@ -0,0 +1,69 @@
+package apikey
+
+import (
+	"errors"
+	"net/http"
+
+	token "github.com/dgrijalva/jwt-go"
+	"github.com/labstack/echo/v4"
+	"github.com/spf13/viper"
+
+	"github.com/xn3cr0nx/bitgodine/internal/jwt"
+	"github.com/xn3cr0nx/bitgodine/internal/storage/db/postgres"
+)
+
+// Header request header carrying the api key
+const Header = "X-API-Key"
+
+// Auth middleware accepts either a session jwt or an api key granted all the scopes.
+// Without scopes only session tokens are accepted. Authenticated api keys set the same
+// "user" claims of a session, so handlers don't tell them apart. A key already verified
+// by an outer Auth is only checked against the scopes
+func Auth(scopes ...string) echo.MiddlewareFunc {
+	if !viper.GetBool("server.auth.enabled") {
+		return func(next echo.HandlerFunc) echo.HandlerFunc {
+			return next
+		}
+	}
+	session := jwt.Middleware()
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		withSession := session(next)
+		return func(c echo.Context) error {
+			key := c.Request().Header.Get(Header)
+			if key == "" {
+				return withSession(c)
+			}
+			if len(scopes) == 0 {
+				return echo.NewHTTPError(http.StatusForbidden, "route not available to api keys")
+			}
+			if verified, ok := c.Get("apikey").(*Model); ok && verified != nil {
+				if !verified.Allows(scopes...) {
+					return echo.NewHTTPError(http.StatusForbidden, ErrScope.Error())
+				}
+				return next(c)
+			}
+
+			pg, ok := c.Get("pg").(*postgres.Pg)
+			if !ok || pg == nil {
+				return echo.NewHTTPError(http.StatusServiceUnavailable, "api keys storage not available")
+			}
+			apikey, err := NewService(pg).Verify(key)
+			if err != nil {
+				if errors.Is(err, ErrInvalidKey) {
+					return echo.NewHTTPError(http.StatusUnauthorized, err.Error())
+				}
+				if errors.Is(err, ErrOwner) {
+					return echo.NewHTTPError(http.StatusForbidden, err.Error())
+				}
+				return err
+			}
+			if !apikey.Allows(scopes...) {
+				return echo.NewHTTPError(http.StatusForbidden, ErrScope.Error())
+			}
+
+			c.Set("user", &token.Token{Claims: &jwt.CustomClaims{ID: apikey.UserID.String(), Role: apikey.Role, Plan: apikey.Plan}, Valid: true})
+			c.Set("apikey", apikey)
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package apikey
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+// Scopes an api key can be granted, each route group requires one of them
+const (
+	ReadBlocks   = "read:blocks"
+	RunAnalysis  = "run:analysis"
+	ReadTags     = "read:tags"
+	WriteTags    = "write:tags"
+	BroadcastTxs = "broadcast:txs"
+)
+
+// Scopes returns the list of all the available scopes
+func Scopes() []string {
+	return []string{ReadBlocks, RunAnalysis, ReadTags, WriteTags, BroadcastTxs}
+}
+
+// Model api key struct. The secret is never stored, only its hash and the public prefix used to look it up
+type Model struct {
+	gorm.Model
+	ID        uuid.UUID      `json:"id" gorm:"primarykey;index;unique"`
+	UserID    uuid.UUID      `json:"user_id" gorm:"index;not null"`
+	Name      string         `json:"name"`
+	Prefix    string         `json:"prefix" gorm:"size:16;index;unique;not null"`
+	Hash      string         `json:"-" gorm:"not null"`
+	Scopes    pq.StringArray `json:"scopes" gorm:"type:varchar(32)[]"`
+	ExpiresAt time.Time      `json:"expires_at"`
+	LastUsed  *time.Time     `json:"last_used,omitempty"`
+	Revoked   bool           `json:"revoked" gorm:"default:false"`
+
+	// Role and plan of the key owner, resolved on verification
+	Role string `json:"-" gorm:"-"`
+	Plan string `json:"-" gorm:"-"`
+} //@name APIKey
+
+// BeforeCreate generates the api key id
+func (m *Model) BeforeCreate(tx *gorm.DB) (err error) {
+	m.ID = uuid.New()
+	return
+}
+
+// TableName defines default table name
+func (m *Model) TableName() string {
+	return "api_keys"
+}
+
+// Allows returns true if the key has been granted all the scopes
+func (m *Model) Allows(scopes ...string) bool {
+	for _, scope := range scopes {
+		granted := false
+		for _, s := range m.Scopes {
+			if s == scope {
+				granted = true
+				break
+			}
+		}
+		if !granted {
+			return false
+		}
+	}
+	return true
+}
+
+// Expired returns true if the key expiration is passed
+func (m *Model) Expired() bool {
+	return !m.ExpiresAt.IsZero() && time.Now().After(m.ExpiresAt)
+}
+
+// Created response to the creation of a key, the only time the secret is returned
+type Created struct {
+	Key    string `json:"key"`
+	APIKey *Model `json:"api_key"`
+}
@@ -0,0 +1,108 @@
+package audit
+
+import (
+	"errors"
+	"net/http"
+
+	token "github.com/dgrijalva/jwt-go"
+	"github.com/labstack/echo/v4"
+
+	"github.com/xn3cr0nx/bitgodine/internal/apikey"
+	"github.com/xn3cr0nx/bitgodine/internal/jwt"
+	"github.com/xn3cr0nx/bitgodine/internal/storage/db/postgres"
+	"github.com/xn3cr0nx/bitgodine/pkg/logger"
+)
+
+// Recorder interface to store audit log entries
+type Recorder interface {
+	Record(entry *Model) (err error)
+}
+
+// Service interface exports available methods for audit service
+type Service interface {
+	Recorder
+	GetLogs(userID string, limit, skip int) (logs []Model, err error)
+}
+
+type service struct {
+	Repository *postgres.Pg
+}
+
+// NewService instantiates a new Service layer for customer
+func NewService(r *postgres.Pg) *service {
+	return &service{
+		Repository: r,
+	}
+}
+
+// Record stores the audit log entry
+func (s *service) Record(entry *Model) (err error) {
+	err = s.Repository.Model(&Model{}).Create(entry).Error
+	return
+}
+
+// GetLogs retrieves the audit log, optionally filtered by user, most recent first
+func (s *service) GetLogs(userID string, limit, skip int) (logs []Model, err error) {
+	query := s.Repository.Order("created_at desc").Limit(limit).Offset(limit * skip)
+	if userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+	err = query.Find(&logs).Error
+	return
+}
+
+// mutation returns true for the methods changing the state
+func mutation(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// Middleware writes an audit log entry for each mutation request, after it's been handled so that
+// the user authenticated by route middlewares and the response status are known.
+// Failing to record the entry is logged without failing the request
+func Middleware(r Recorder) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			err := next(c)
+			if !mutation(c.Request().Method) {
+				return err
+			}
+
+			entry := &Model{
+				RequestID: c.Response().Header().Get(echo.HeaderXRequestID),
+				Method:    c.Request().Method,
+				Route:     c.Path(),
+				URI:       c.Request().RequestURI,
+				Status:    c.Response().Status,
+				IP:        c.RealIP(),
+			}
+			if entry.RequestID == "" {
+				entry.RequestID = c.Request().Header.Get(echo.HeaderXRequestID)
+			}
+			if err != nil {
+				entry.Status = http.StatusInternalServerError
+				var he *echo.HTTPError
+				if errors.As(err, &he) {
+					entry.Status = he.Code
+				}
+			}
+			if user, ok := c.Get("user").(*token.Token); ok {
+				if claims, e := jwt.Decode(user); e == nil {
+					entry.UserID = claims.ID
+					entry.Role = claims.Role
+				}
+			}
+			if key, ok := c.Get("apikey").(*apikey.Model); ok {
+				entry.APIKeyID = key.ID.String()
+			}
+
+			if e := r.Record(entry); e != nil {
+				logger.Error("Audit", e, logger.Params{"request_id": entry.RequestID, "route": entry.Route})
+			}
+			return err
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package audit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	token "github.com/dgrijalva/jwt-go"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/xn3cr0nx/bitgodine/internal/apikey"
+	"github.com/xn3cr0nx/bitgodine/internal/jwt"
+)
+
+type recorder struct {
+	entries []*Model
+}
+
+func (r *recorder) Record(entry *Model) error {
+	r.entries = append(r.entries, entry)
+	return nil
+}
+
+type TestAuditSuite struct {
+	suite.Suite
+	recorder *recorder
+	router   *echo.Echo
+	key      *apikey.Model
+}
+
+func (suite *TestAuditSuite) SetupTest() {
+	suite.recorder = new(recorder)
+	suite.key = &apikey.Model{ID: uuid.New()}
+
+	suite.router = echo.New()
+	suite.router.Use(middleware.RequestID())
+	api := suite.router.Group("/api", Middleware(suite.recorder))
+	authenticated := func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Set("user", &token.Token{Claims: &jwt.CustomClaims{ID: "user-id", Role: "analyst"}, Valid: true})
+			c.Set("apikey", suite.key)
+			return next(c)
+		}
+	}
+	api.GET("/tags", func(c echo.Context) error { return c.NoContent(http.StatusOK) }, authenticated)
+	api.POST("/tags", func(c echo.Context) error { return c.NoContent(http.StatusCreated) }, authenticated)
+	api.DELETE("/blocks/tip", func(c echo.Context) error { return echo.NewHTTPError(http.StatusForbidden) })
+}
+
+func (suite *TestAuditSuite) serve(method, path string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	suite.router.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+	return rec
+}
+
+func (suite *TestAuditSuite) TestReadsNotRecorded() {
+	suite.serve(http.MethodGet, "/api/tags")
+	assert.Empty(suite.T(), suite.recorder.entries)
+}
+
+func (suite *TestAuditSuite) TestMutationRecorded() {
+	rec := suite.serve(http.MethodPost, "/api/tags?source=test")
+	require.Len(suite.T(), suite.recorder.entries, 1)
+	entry := suite.recorder.entries[0]
+	assert.Equal(suite.T(), "user-id", entry.UserID)
+	assert.Equal(suite.T(), "analyst", entry.Role)
+	assert.Equal(suite.T(), suite.key.ID.String(), entry.APIKeyID)
+	assert.Equal(suite.T(), rec.Header().Get(echo.HeaderXRequestID), entry.RequestID)
+	assert.NotEmpty(suite.T(), entry.RequestID)
+	assert.Equal(suite.T(), http.MethodPost, entry.Method)
+	assert.Equal(suite.T(), "/api/tags", entry.Route)
+	assert.Equal(suite.T(), "/api/tags?source=test", entry.URI)
+	assert.Equal(suite.T(), http.StatusCreated, entry.Status)
+}
+
+func (suite *TestAuditSuite) TestRejectedMutationRecorded() {
+	suite.serve(http.MethodDelete, "/api/blocks/tip")
+	require.Len(suite.T(), suite.recorder.entries, 1)
+	entry := suite.recorder.entries[0]
+	assert.Empty(suite.T(), entry.UserID)
+	assert.Equal(suite.T(), http.StatusForbidden, entry.Status)
+}
+
+func TestAudit(t *testing.T) {
+	suite.Run(t, new(TestAuditSuite))
+}
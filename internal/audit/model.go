@@ -0,0 +1,32 @@
+package audit
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Model audit log entry of a mutation request
+type Model struct {
+	gorm.Model
+	ID        uuid.UUID `json:"id" gorm:"primarykey;index;unique"`
+	UserID    string    `json:"user_id,omitempty" gorm:"index"`
+	Role      string    `json:"role,omitempty"`
+	APIKeyID  string    `json:"api_key_id,omitempty"`
+	RequestID string    `json:"request_id" gorm:"index"`
+	Method    string    `json:"method" gorm:"not null"`
+	Route     string    `json:"route" gorm:"index"`
+	URI       string    `json:"uri"`
+	Status    int       `json:"status"`
+	IP        string    `json:"ip"`
+} //@name AuditLog
+
+// BeforeCreate generates the audit log entry id
+func (m *Model) BeforeCreate(tx *gorm.DB) (err error) {
+	m.ID = uuid.New()
+	return
+}
+
+// TableName defines default table name
+func (m *Model) TableName() string {
+	return "audit_logs"
+}
@@ -0,0 +1,58 @@
+package audit
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/xn3cr0nx/bitgodine/pkg/validator"
+)
+
+// Routes mounts all /audit based routes on the main group
+func Routes(g *echo.Group, s Service) {
+	r := g.Group("/audit", validator.JWT(), validator.Role(validator.Admin))
+
+	r.GET("", getLogs(s))
+}
+
+// getLogs godoc
+// @ID get-audit-logs
+//
+// @Router /audit [get]
+// @Summary Get audit log
+// @Description get the audit log of mutation requests, most recent first
+// @Tags audit
+//
+// @Security ApiKeyAuth
+//
+// @Accept  json
+// @Produce  json
+//
+// @Param user query string false "User ID"
+// @Param limit query int false "Limit"
+// @Param skip query int false "Skip"
+//
+// @Success 200 {array} Model
+// @Success 500 {string} string
+func getLogs(s Service) func(echo.Context) error {
+	return func(c echo.Context) error {
+		type Query struct {
+			User  string `query:"user" validate:"omitempty,uuid"`
+			Limit int    `query:"limit" validate:"omitempty,gt=0"`
+			Skip  int    `query:"skip" validate:"omitempty,gte=0"`
+		}
+		q := new(Query)
+		if err := validator.Struct(&c, q); err != nil {
+			return err
+		}
+		if q.Limit == 0 {
+			q.Limit = 50
+		}
+
+		logs, err := s.GetLogs(q.User, q.Limit, q.Skip)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, logs)
+	}
+}
@@ -1,34 +1,51 @@
 package auth
 
 import (
+	"fmt"
 	"net/http"
 	"strings"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
+	"github.com/sendgrid/rest"
 	"github.com/spf13/viper"
+	"github.com/xn3cr0nx/bitgodine/internal/apikey"
 	"github.com/xn3cr0nx/bitgodine/internal/jwt"
 	"github.com/xn3cr0nx/bitgodine/internal/password"
 	"github.com/xn3cr0nx/bitgodine/internal/storage/db/postgres"
+	"github.com/xn3cr0nx/bitgodine/internal/storage/kv"
 	"github.com/xn3cr0nx/bitgodine/internal/user"
+	"github.com/xn3cr0nx/bitgodine/pkg/mailer"
+	"github.com/xn3cr0nx/bitgodine/pkg/validator"
 )
 
 // Service interface exports available methods for user service
 type Service interface {
 	Login(body *LoginBody) (*LoginResp, error)
 	Signup(body *SignupBody) (*SignupResp, error)
-	GenerateAPIKey(ID, email string) (string, error)
+	Activate(token string) error
+	Resend(email string) error
+	Forgot(email string) error
+	ForgotConfirm(token, password string) error
+	GenerateAPIKey(ID, name string, scopes []string, expiry time.Duration) (*apikey.Created, error)
+	GetAPIKeys(ID string) ([]apikey.Model, error)
+	RevokeAPIKey(ID, keyID string) error
 	ChangePassword(ID, oldPassword, newPassword string) error
 }
 
 type service struct {
 	Repository *postgres.Pg
+	Kv         kv.DB
+	Mailer     *mailer.Client
 }
 
 // NewService instantiates a new Service layer for customer
-func NewService(r *postgres.Pg) *service {
+func NewService(r *postgres.Pg, db kv.DB, m *mailer.Client) *service {
 	return &service{
 		Repository: r,
+		Kv:         db,
+		Mailer:     m,
 	}
 }
 
@@ -43,8 +60,14 @@ func (s *service) Login(body *LoginBody) (*LoginResp, error) {
 	if !password.Verify(user.Password, body.Password) {
 		return nil, echo.NewHTTPError(http.StatusUnauthorized, echo.ErrUnauthorized)
 	}
+	if user.IsBlocked {
+		return nil, echo.NewHTTPError(http.StatusForbidden, "account blocked")
+	}
+	if !user.IsActive {
+		return nil, echo.NewHTTPError(http.StatusForbidden, "account not activated, check your email")
+	}
 
-	t, err := jwt.NewToken(user.ID.String(), user.Email, time.Hour*24)
+	t, err := jwt.NewToken(user.ID.String(), user.Email, user.Role, user.Plan, time.Hour*24)
 	if err != nil {
 		return nil, echo.NewHTTPError(http.StatusInternalServerError, echo.ErrValidatorNotRegistered)
 	}
@@ -63,7 +86,8 @@ func (s *service) Login(body *LoginBody) (*LoginResp, error) {
 		IsActive:  user.IsActive,
 		Lang:      user.Lang,
 		IsBlocked: user.IsBlocked,
-		APIKeys:   user.APIKeys,
+		Role:      user.Role,
+		Plan:      user.Plan,
 	}
 	resp := &LoginResp{
 		Token: t,
@@ -89,74 +113,128 @@ func (s *service) Signup(body *SignupBody) (*SignupResp, error) {
 		LastName:  body.LastName,
 		Username:  body.Username,
 		Lang:      "en",
+		Role:      validator.Viewer,
 		IsActive:  verified,
 	}
 
-	// TODO: save uuid
-
 	userService := user.NewService(s.Repository)
 	if err := userService.CreateUser(u); err != nil {
 		return nil, err
 	}
+	if verified {
+		return &SignupResp{"Signed up"}, nil
+	}
 
-	// token := random.String(32)
-	// _, err = e.Redis.Set(token, u.ID.String(), 3600*time.Second).Result()
-	// if err != nil {
-	// 	log.LogError(c, action, err, nil)
-	// 	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-	// 	return
-	// }
-	// url := fmt.Sprintf("%s/verification?key=%s", strings.Join([]string{viper.GetString("http.host"), viper.GetString("http.port")}, ":"), token)
-	// emailNonce := random.String(32)
-	// name := strings.Join([]string{u.FirstName, u.LastName}, " ")
-	// resp, err := mailer.UserVerification(name, u.Email, url, c)
-	// if err != nil || (resp != nil && resp.StatusCode >= 400) {
-	// 	log.LogError(c, action, err, nil)
-	// 	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-	// 	return
-	// }
-	// fields := map[string]interface{}{
-	// 	"MAILER_FUNCTION": "UserVerification",
-	// 	"name":            name,
-	// 	"email":           u.Email,
-	// 	"url":             url,
-	// }
-	// _, err = e.Redis.HMSet("email_nonce:"+emailNonce, fields).Result()
-	// if err != nil {
-	// 	log.LogError(c, action, err, nil)
-	// 	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-	// 	return
-	// }
-	// _, err = e.Redis.Expire("email_nonce:"+emailNonce, 600*time.Second).Result()
-	// if err != nil {
-	// 	log.LogError(c, action, err, nil)
-	// 	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-	// 	return
-	// }
-
-	// c.JSON(http.StatusOK, gin.H{
-	// 	"success":     true,
-	// 	"message":     "Signed up, check your email",
-	// 	"email_nonce": emailNonce,
-	// })
+	if err := s.sendActivation(u); err != nil {
+		return nil, err
+	}
 	return &SignupResp{"Check your email"}, nil
 }
 
-// GenerateAPIKey saves a new long term api key for the user
-func (s *service) GenerateAPIKey(ID, email string) (token string, err error) {
-	token, err = jwt.NewToken(ID, email, 99999999)
+// sendActivation issues a new activation token and sends the verification email
+func (s *service) sendActivation(u *user.Model) (err error) {
+	token, err := IssueToken(s.Kv, Activation, u.ID.String(), ActivationTTL)
 	if err != nil {
 		return
 	}
+	resp, err := s.Mailer.UserVerification(fullName(u), u.Email, viper.GetString("server.auth.activationURL")+token)
+	return checkMail(resp, err)
+}
 
-	userService := user.NewService(s.Repository)
-	if err = userService.NewAPIKey(ID, token); err != nil {
+// Activate activates the account the activation token has been issued to
+func (s *service) Activate(token string) (err error) {
+	ID, err := ConsumeToken(s.Kv, Activation, token)
+	if err != nil {
 		return
 	}
+	err = s.Repository.Model(&user.Model{}).Where("id = ?", ID).Update("is_active", true).Error
+	return
+}
 
+// Resend sends a new verification email to a not yet active account. Unknown emails are ignored
+// to not disclose registered accounts
+func (s *service) Resend(email string) (err error) {
+	u, err := user.NewService(s.Repository).GetUserByEmail(strings.ToLower(email))
+	if err != nil || u.ID == uuid.Nil || u.IsActive {
+		return
+	}
+	err = s.sendActivation(u)
 	return
 }
 
+// Forgot sends the password reset link. Unknown emails are ignored to not disclose registered accounts
+func (s *service) Forgot(email string) (err error) {
+	u, err := user.NewService(s.Repository).GetUserByEmail(strings.ToLower(email))
+	if err != nil || u.ID == uuid.Nil || u.IsBlocked {
+		return
+	}
+	token, err := IssueToken(s.Kv, Reset, u.ID.String(), ResetTTL)
+	if err != nil {
+		return
+	}
+	resp, err := s.Mailer.ResetPasswordReq(fullName(u), u.Email, viper.GetString("server.auth.resetURL")+token)
+	return checkMail(resp, err)
+}
+
+// ForgotConfirm sets the new password of the account the reset token has been issued to,
+// revoking the api keys and the sessions of the account
+func (s *service) ForgotConfirm(token, newPassword string) (err error) {
+	ID, err := ConsumeToken(s.Kv, Reset, token)
+	if err != nil {
+		return
+	}
+	hash, err := password.Hash(newPassword)
+	if err != nil {
+		return
+	}
+	userService := user.NewService(s.Repository)
+	if err = s.Repository.Model(&user.Model{}).Where("id = ?", ID).Update("password", hash).Error; err != nil {
+		return
+	}
+	if err = s.Repository.Model(&apikey.Model{}).Where("user_id = ? AND revoked = ?", ID, false).Update("revoked", true).Error; err != nil {
+		return
+	}
+	if err = jwt.RevokeSessions(s.Kv, ID); err != nil {
+		return
+	}
+	u, err := userService.GetUser(ID)
+	if err != nil {
+		return
+	}
+	resp, err := s.Mailer.ResetPassword(fullName(u), u.Email)
+	return checkMail(resp, err)
+}
+
+func fullName(u *user.Model) string {
+	return strings.Join([]string{u.FirstName, u.LastName}, " ")
+}
+
+// checkMail turns mailer error responses into errors
+func checkMail(resp *rest.Response, err error) error {
+	if err != nil {
+		return err
+	}
+	if resp != nil && resp.StatusCode >= 400 {
+		return fmt.Errorf("mailer responded with status %d: %s", resp.StatusCode, resp.Body)
+	}
+	return nil
+}
+
+// GenerateAPIKey mints a new api key for the user, the secret is returned only once
+func (s *service) GenerateAPIKey(ID, name string, scopes []string, expiry time.Duration) (*apikey.Created, error) {
+	return apikey.NewService(s.Repository).CreateKey(ID, name, scopes, expiry)
+}
+
+// GetAPIKeys lists the api keys of the user, secrets are never returned
+func (s *service) GetAPIKeys(ID string) ([]apikey.Model, error) {
+	return apikey.NewService(s.Repository).GetKeys(ID)
+}
+
+// RevokeAPIKey revokes an api key of the user
+func (s *service) RevokeAPIKey(ID, keyID string) error {
+	return apikey.NewService(s.Repository).RevokeKey(ID, keyID)
+}
+
 // ChangePassword changes user password
 func (s *service) ChangePassword(ID, oldPassword, newPassword string) (err error) {
 	userService := user.NewService(s.Repository)
@@ -0,0 +1,204 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	token "github.com/dgrijalva/jwt-go"
+	"github.com/labstack/echo/v4"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/xn3cr0nx/bitgodine/internal/apikey"
+	"github.com/xn3cr0nx/bitgodine/internal/errorx"
+	"github.com/xn3cr0nx/bitgodine/internal/jwt"
+	"github.com/xn3cr0nx/bitgodine/internal/storage/db/postgres"
+	"github.com/xn3cr0nx/bitgodine/internal/storage/kv"
+	"github.com/xn3cr0nx/bitgodine/internal/user"
+	"github.com/xn3cr0nx/bitgodine/internal/user/preferences"
+	"github.com/xn3cr0nx/bitgodine/pkg/mailer"
+)
+
+const (
+	activationURL = "https://bitgodine.test/activate/"
+	resetURL      = "https://bitgodine.test/reset?token="
+)
+
+type TestAuthSuite struct {
+	suite.Suite
+	pg     *postgres.Pg
+	db     *kv.DBMock
+	store  map[string][]byte
+	mailer *mailer.MockClient
+	s      *service
+}
+
+func (suite *TestAuthSuite) SetupTest() {
+	viper.Set("server.auth.directRegistration", false)
+	viper.Set("server.auth.activationURL", activationURL)
+	viper.Set("server.auth.resetURL", resetURL)
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{DisableForeignKeyConstraintWhenMigrating: true})
+	require.Nil(suite.T(), err)
+	sql, err := db.DB()
+	require.Nil(suite.T(), err)
+	// each connection opens its own in memory db
+	sql.SetMaxOpenConns(1)
+	require.Nil(suite.T(), db.AutoMigrate(&user.Model{}, &preferences.Model{}))
+	// sqlite has no array types, scopes are stored in their text encoding
+	require.Nil(suite.T(), db.Exec(`CREATE TABLE api_keys (id text PRIMARY KEY, created_at datetime, updated_at datetime, deleted_at datetime,
+		user_id text NOT NULL, name text, prefix text NOT NULL UNIQUE, hash text NOT NULL, scopes text, expires_at datetime, last_used datetime,
+		revoked numeric DEFAULT false)`).Error)
+	suite.pg = &postgres.Pg{DB: db}
+
+	suite.store = make(map[string][]byte)
+	suite.db = kv.NewDBMock()
+	suite.db.On("Store", mock.Anything, mock.Anything).Return(func(key string, value []byte) error {
+		suite.store[key] = value
+		return nil
+	})
+	suite.db.On("StoreWithTTL", mock.Anything, mock.Anything, mock.Anything).Return(func(key string, value []byte, ttl time.Duration) error {
+		suite.store[key] = value
+		return nil
+	})
+	suite.db.On("Read", mock.Anything).Return(func(key string) []byte {
+		return suite.store[key]
+	}, func(key string) error {
+		if _, ok := suite.store[key]; !ok {
+			return errorx.ErrKeyNotFound
+		}
+		return nil
+	})
+	suite.db.On("Delete", mock.Anything).Return(func(key string) error {
+		delete(suite.store, key)
+		return nil
+	})
+
+	suite.mailer = mailer.NewMockClient("test")
+	suite.s = NewService(suite.pg, suite.db, &mailer.Client{Client: suite.mailer, Email: "noreply@bitgodine.com"})
+}
+
+func (suite *TestAuthSuite) TearDownTest() {
+	sql, err := suite.pg.DB.DB()
+	require.Nil(suite.T(), err)
+	sql.Close()
+}
+
+// sentToken returns the token carried by the link of the last email sent
+func (suite *TestAuthSuite) sentToken(URL string) string {
+	require.NotEmpty(suite.T(), suite.mailer.Sent)
+	content := suite.mailer.Sent[len(suite.mailer.Sent)-1].Content[0].Value
+	i := strings.Index(content, URL)
+	require.NotEqual(suite.T(), -1, i)
+	return strings.Fields(content[i+len(URL):])[0]
+}
+
+func (suite *TestAuthSuite) signup() {
+	resp, err := suite.s.Signup(&SignupBody{Email: "Satoshi@Bitgodine.com", Password: "Passw0rd", FirstName: "Satoshi", LastName: "Nakamoto", Username: "satoshi"})
+	require.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "Check your email", resp.Message)
+}
+
+func (suite *TestAuthSuite) login(password string) (*LoginResp, int) {
+	resp, err := suite.s.Login(&LoginBody{Email: "satoshi@bitgodine.com", Password: password})
+	if err != nil {
+		var he *echo.HTTPError
+		require.True(suite.T(), errors.As(err, &he))
+		return nil, he.Code
+	}
+	return resp, http.StatusOK
+}
+
+func (suite *TestAuthSuite) TestSignupActivate() {
+	suite.signup()
+	require.Len(suite.T(), suite.mailer.Sent, 1)
+	assert.Equal(suite.T(), "satoshi@bitgodine.com", suite.mailer.Sent[0].Personalizations[0].To[0].Address)
+	activation := suite.sentToken(activationURL)
+
+	_, code := suite.login("Passw0rd")
+	assert.Equal(suite.T(), http.StatusForbidden, code)
+
+	require.Nil(suite.T(), suite.s.Activate(activation))
+	resp, code := suite.login("Passw0rd")
+	require.Equal(suite.T(), http.StatusOK, code)
+	assert.True(suite.T(), resp.User.IsActive)
+	assert.NotEmpty(suite.T(), resp.Token)
+
+	err := suite.s.Activate(activation)
+	assert.True(suite.T(), errors.Is(err, ErrInvalidToken))
+}
+
+func (suite *TestAuthSuite) TestResend() {
+	suite.signup()
+	require.Nil(suite.T(), suite.s.Resend("satoshi@bitgodine.com"))
+	require.Len(suite.T(), suite.mailer.Sent, 2)
+	require.Nil(suite.T(), suite.s.Activate(suite.sentToken(activationURL)))
+
+	// active accounts and unknown emails are ignored
+	require.Nil(suite.T(), suite.s.Resend("satoshi@bitgodine.com"))
+	require.Nil(suite.T(), suite.s.Resend("unknown@bitgodine.com"))
+	assert.Len(suite.T(), suite.mailer.Sent, 2)
+}
+
+func (suite *TestAuthSuite) TestForgotConfirm() {
+	suite.signup()
+	require.Nil(suite.T(), suite.s.Activate(suite.sentToken(activationURL)))
+	resp, code := suite.login("Passw0rd")
+	require.Equal(suite.T(), http.StatusOK, code)
+	session, _, err := new(token.Parser).ParseUnverified(resp.Token, &jwt.CustomClaims{})
+	require.Nil(suite.T(), err)
+	claims := session.Claims.(*jwt.CustomClaims)
+	created, err := suite.s.GenerateAPIKey(claims.ID, "key", nil, 0)
+	require.Nil(suite.T(), err)
+
+	require.Nil(suite.T(), suite.s.Forgot("unknown@bitgodine.com"))
+	require.Len(suite.T(), suite.mailer.Sent, 1)
+	require.Nil(suite.T(), suite.s.Forgot("satoshi@bitgodine.com"))
+	require.Len(suite.T(), suite.mailer.Sent, 2)
+	reset := suite.sentToken(resetURL)
+
+	err = suite.s.ForgotConfirm(reset+"x", "NewPassw0rd")
+	assert.True(suite.T(), errors.Is(err, ErrInvalidToken))
+	require.Nil(suite.T(), suite.s.ForgotConfirm(reset, "NewPassw0rd"))
+	assert.Len(suite.T(), suite.mailer.Sent, 3)
+	err = suite.s.ForgotConfirm(reset, "OtherPassw0rd")
+	assert.True(suite.T(), errors.Is(err, ErrInvalidToken))
+
+	_, code = suite.login("Passw0rd")
+	assert.Equal(suite.T(), http.StatusUnauthorized, code)
+	_, code = suite.login("NewPassw0rd")
+	assert.Equal(suite.T(), http.StatusOK, code)
+
+	_, err = apikey.NewService(suite.pg).Verify(created.Key)
+	assert.True(suite.T(), errors.Is(err, apikey.ErrInvalidKey))
+
+	// the session has been issued before the reset
+	claims.IssuedAt = time.Now().Add(-time.Minute).Unix()
+	revoked, err := jwt.Revoked(suite.db, claims)
+	require.Nil(suite.T(), err)
+	assert.True(suite.T(), revoked)
+}
+
+func (suite *TestAuthSuite) TestLoginBlocked() {
+	suite.signup()
+	require.Nil(suite.T(), suite.s.Activate(suite.sentToken(activationURL)))
+	require.Nil(suite.T(), suite.pg.Model(&user.Model{}).Where("email = ?", "satoshi@bitgodine.com").Update("is_blocked", true).Error)
+
+	_, code := suite.login("Passw0rd")
+	assert.Equal(suite.T(), http.StatusForbidden, code)
+
+	require.Nil(suite.T(), suite.s.Forgot("satoshi@bitgodine.com"))
+	assert.Len(suite.T(), suite.mailer.Sent, 1)
+}
+
+func TestAuth(t *testing.T) {
+	suite.Run(t, new(TestAuthSuite))
+}
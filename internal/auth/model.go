@@ -2,8 +2,6 @@ package auth
 
 import (
 	"time"
-
-	"github.com/lib/pq"
 )
 
 // LoginBody encoded email and password authentication
@@ -21,10 +19,11 @@ type UserResp struct {
 
 	LastLogin time.Time `json:"last_login" validate:""`
 
-	IsActive  bool           `json:"is_active,omitempty" gorm:"default:false"`
-	Lang      string         `json:"lang,omitempty" gorm:"default:'en'"`
-	IsBlocked bool           `json:"isBlocked" gorm:"default:false"`
-	APIKeys   pq.StringArray `json:"apiKeys,omitempty" gorm:"type:varchar(255)[]"`
+	IsActive  bool   `json:"is_active,omitempty" gorm:"default:false"`
+	Lang      string `json:"lang,omitempty" gorm:"default:'en'"`
+	IsBlocked bool   `json:"isBlocked" gorm:"default:false"`
+	Role      string `json:"role"`
+	Plan      string `json:"plan"`
 }
 
 // LoginResp encoded email and password authentication
@@ -47,8 +46,31 @@ type SignupResp struct {
 	Message string `json:"message"`
 }
 
+// GenerateAPIKeyBody body request to generate an api key, granted all the scopes by default
+type GenerateAPIKeyBody struct {
+	Name   string   `json:"name" validate:"omitempty,max=64"`
+	Scopes []string `json:"scopes" validate:"dive,oneof=read:blocks run:analysis read:tags write:tags broadcast:txs"`
+	Expiry int      `json:"expiry" validate:"omitempty,gt=0,lte=365"`
+}
+
+// RevokeAPIKeyBody body request to revoke an api key
+type RevokeAPIKeyBody struct {
+	ID string `json:"id" validate:"required,uuid"`
+}
+
 // ChangePasswordBody body request to change password
 type ChangePasswordBody struct {
 	NewPassword string `json:"new_password" validate:"required,password,nefield=OldPassword,alphanum"`
 	OldPassword string `json:"old_password" validate:"required,password,nefield=NewPassword,alphanum"`
 }
+
+// EmailBody body request carrying the account email
+type EmailBody struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// ForgotConfirmBody body request to reset the password
+type ForgotConfirmBody struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,password,alphanum"`
+}
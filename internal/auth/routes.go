@@ -2,6 +2,7 @@ package auth
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/xn3cr0nx/bitgodine/internal/jwt"
@@ -12,77 +13,14 @@ import (
 func Routes(g *echo.Group, s Service) {
 	g.POST("/login", login(s))
 	g.POST("/signup", signup(s), validator.Recaptcha())
-	g.GET("/generate-api-key", generateAPIKey(s), validator.JWT())
+	g.POST("/generate-api-key", generateAPIKey(s), validator.JWT())
+	g.GET("/api-keys", getAPIKeys(s), validator.JWT())
+	g.POST("/revoke-api-key", revokeAPIKey(s), validator.JWT())
 	g.POST("/change-password", changePassword(s), validator.JWT())
-
-	// r.POST("/forgot", func(c echo.Context) error {
-	// 	b := new(Login)
-	// 	if err := validator.Struct(&c, b); err != nil {
-	// 		return err
-	// 	}
-
-	// 	res, err := chttp.POST(c.Request().RequestURI, b, routes.ProxyAuth(&c))
-	// 	if err != nil {
-	// 		return err
-	// 	}
-	// 	resp := new(models.Response)
-	// 	if err := json.Unmarshal([]byte(res), resp); err != nil {
-	// 		return err
-	// 	}
-	// 	return c.JSON(http.StatusOK, resp)
-	// })
-
-	// r.GET("/activate/:token", func(c echo.Context) error {
-	// 	token := c.Param("token")
-	// 	tags := []string{"required"}
-	// 	if viper.GetBool("auth.enabled") {
-	// 		tags = append(tags, "jwt")
-	// 	}
-	// 	if err := c.Echo().Validator.(*validator.CustomValidator).Var(token, strings.Join(tags, ",")); err != nil {
-	// 		return err
-	// 	}
-	// 	resp, err := Activate(c)
-	// 	if err != nil {
-	// 		return err
-	// 	}
-	// 	return c.JSON(http.StatusOK, resp)
-	// })
-
-	// r.POST("/forgot-confirm", func(c echo.Context) error {
-	// 	type Body struct {
-	// 		Token    string `json:"token" validate:"required"`
-	// 		Password string `json:"password" validate:"required,password,alphanum"`
-	// 	}
-	// 	b := new(Body)
-	// 	if err := validator.Struct(&c, b); err != nil {
-	// 		return err
-	// 	}
-	// 	resp, err := ForgotConfirm(c, b)
-	// 	if err != nil {
-	// 		return err
-	// 	}
-	// 	return c.JSON(http.StatusOK, resp)
-	// })
-
-	// r.POST("/resend", func(c echo.Context) error {
-	// 	type Body struct {
-	// 		Email string `json:"email,omitempty" validate:"required,email"`
-	// 	}
-	// 	b := new(Body)
-	// 	if err := validator.Struct(&c, b); err != nil {
-	// 		return err
-	// 	}
-
-	// 	res, err := chttp.POST(c.Request().RequestURI, b, routes.ProxyAuth(&c))
-	// 	if err != nil {
-	// 		return err
-	// 	}
-	// 	resp := new(models.Response)
-	// 	if err := json.Unmarshal([]byte(res), resp); err != nil {
-	// 		return err
-	// 	}
-	// 	return c.JSON(http.StatusOK, resp)
-	// })
+	g.GET("/activate/:token", activate(s))
+	g.POST("/resend", resend(s), validator.Recaptcha())
+	g.POST("/forgot", forgot(s), validator.Recaptcha())
+	g.POST("/forgot-confirm", forgotConfirm(s))
 
 	// r.GET("/email-api-key", func(c echo.Context) error {
 	// 	res, err := chttp.GET(c.Request().RequestURI, routes.ProxyAuth(&c))
@@ -95,26 +33,6 @@ func Routes(g *echo.Group, s Service) {
 	// 	}
 	// 	return c.JSON(http.StatusOK, resp)
 	// })
-
-	// r.POST("/revoke-api-key", func(c echo.Context) error {
-	// 	type Body struct {
-	// 		APIKeyIndex uint32 `json:"apiKeyIndex" validate:"required,numeric,gte=0"`
-	// 	}
-	// 	b := new(Body)
-	// 	if err := validator.Struct(&c, b); err != nil {
-	// 		return err
-	// 	}
-
-	// 	res, err := chttp.POST(c.Request().RequestURI, b, routes.ProxyAuth(&c))
-	// 	if err != nil {
-	// 		return err
-	// 	}
-	// 	resp := new(models.Response)
-	// 	if err := json.Unmarshal([]byte(res), resp); err != nil {
-	// 		return err
-	// 	}
-	// 	return c.JSON(http.StatusOK, resp)
-	// })
 }
 
 // login godoc
@@ -184,9 +102,9 @@ func signup(s Service) func(echo.Context) error {
 // generateAPIKey godoc
 // @ID generateAPIKey
 //
-// @Router /generate-api-key [get]
+// @Router /generate-api-key [post]
 // @Summary Generate Api Key
-// @Description Generate and new api key for the user
+// @Description Generate a new api key for the user, granted the scopes (all by default). The key is returned only once
 // @Tags auth
 //
 // @Security ApiKeyAuth
@@ -194,17 +112,24 @@ func signup(s Service) func(echo.Context) error {
 // @Accept  json
 // @Produce  json
 //
-// @Success 200 {string} string
+// @Param generateAPIKey body GenerateAPIKeyBody false "generate api key body"
+//
+// @Success 200 {object} apikey.Created
 // @Failure 400 {string} string
 // @Failure 500 {string} string
 func generateAPIKey(s Service) func(echo.Context) error {
 	return func(c echo.Context) error {
+		b := new(GenerateAPIKeyBody)
+		if err := validator.Struct(&c, b); err != nil {
+			return err
+		}
+
 		claims, err := jwt.Decode(c.Get("user"))
 		if err != nil {
 			return nil
 		}
 
-		resp, err := s.GenerateAPIKey(claims.ID, claims.Email)
+		resp, err := s.GenerateAPIKey(claims.ID, b.Name, b.Scopes, time.Duration(b.Expiry)*24*time.Hour)
 		if err != nil {
 			return err
 		}
@@ -213,6 +138,74 @@ func generateAPIKey(s Service) func(echo.Context) error {
 	}
 }
 
+// getAPIKeys godoc
+// @ID getAPIKeys
+//
+// @Router /api-keys [get]
+// @Summary Get Api Keys
+// @Description List the api keys of the user, including revoked and expired ones
+// @Tags auth
+//
+// @Security ApiKeyAuth
+//
+// @Accept  json
+// @Produce  json
+//
+// @Success 200 {array} apikey.Model
+// @Failure 500 {string} string
+func getAPIKeys(s Service) func(echo.Context) error {
+	return func(c echo.Context) error {
+		claims, err := jwt.Decode(c.Get("user"))
+		if err != nil {
+			return nil
+		}
+
+		keys, err := s.GetAPIKeys(claims.ID)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, keys)
+	}
+}
+
+// revokeAPIKey godoc
+// @ID revokeAPIKey
+//
+// @Router /revoke-api-key [post]
+// @Summary Revoke Api Key
+// @Description Revoke an api key of the user
+// @Tags auth
+//
+// @Security ApiKeyAuth
+//
+// @Accept  json
+// @Produce  json
+//
+// @Param revokeAPIKey body RevokeAPIKeyBody true "revoke api key body"
+//
+// @Success 200 {string} string
+// @Failure 400 {string} string
+// @Failure 500 {string} string
+func revokeAPIKey(s Service) func(echo.Context) error {
+	return func(c echo.Context) error {
+		b := new(RevokeAPIKeyBody)
+		if err := validator.Struct(&c, b); err != nil {
+			return err
+		}
+
+		claims, err := jwt.Decode(c.Get("user"))
+		if err != nil {
+			return nil
+		}
+
+		if err := s.RevokeAPIKey(claims.ID, b.ID); err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, "ok")
+	}
+}
+
 // changePassword godoc
 // @ID changePassword
 //
@@ -247,3 +240,123 @@ func changePassword(s Service) func(echo.Context) error {
 		return c.JSON(http.StatusOK, "ok")
 	}
 }
+
+// activate godoc
+// @ID activate
+//
+// @Router /activate/{token} [get]
+// @Summary Activate
+// @Description Activate the account using the token sent by email on signup
+// @Tags auth
+//
+// @Accept  json
+// @Produce  json
+//
+// @Param token path string true "Activation token"
+//
+// @Success 200 {string} string
+// @Failure 400 {string} string
+// @Failure 500 {string} string
+func activate(s Service) func(echo.Context) error {
+	return func(c echo.Context) error {
+		token := c.Param("token")
+		if token == "" {
+			return echo.NewHTTPError(http.StatusBadRequest, "missing token")
+		}
+
+		if err := s.Activate(token); err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, "ok")
+	}
+}
+
+// resend godoc
+// @ID resend
+//
+// @Router /resend [post]
+// @Summary Resend Activation
+// @Description Send again the activation email to a not yet active account
+// @Tags auth
+//
+// @Accept  json
+// @Produce  json
+//
+// @Param resend body EmailBody true "resend body"
+//
+// @Success 200 {object} SignupResp
+// @Failure 400 {string} string
+// @Failure 500 {string} string
+func resend(s Service) func(echo.Context) error {
+	return func(c echo.Context) error {
+		b := new(EmailBody)
+		if err := validator.Struct(&c, b); err != nil {
+			return err
+		}
+
+		if err := s.Resend(b.Email); err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, &SignupResp{"Check your email"})
+	}
+}
+
+// forgot godoc
+// @ID forgot
+//
+// @Router /forgot [post]
+// @Summary Forgot Password
+// @Description Send the password reset link by email
+// @Tags auth
+//
+// @Accept  json
+// @Produce  json
+//
+// @Param forgot body EmailBody true "forgot body"
+//
+// @Success 200 {object} SignupResp
+// @Failure 400 {string} string
+// @Failure 500 {string} string
+func forgot(s Service) func(echo.Context) error {
+	return func(c echo.Context) error {
+		b := new(EmailBody)
+		if err := validator.Struct(&c, b); err != nil {
+			return err
+		}
+
+		if err := s.Forgot(b.Email); err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, &SignupResp{"Check your email"})
+	}
+}
+
+// forgotConfirm godoc
+// @ID forgotConfirm
+//
+// @Router /forgot-confirm [post]
+// @Summary Reset Password
+// @Description Set a new password using the token sent by email
+// @Tags auth
+//
+// @Accept  json
+// @Produce  json
+//
+// @Param forgotConfirm body ForgotConfirmBody true "forgot confirm body"
+//
+// @Success 200 {string} string
+// @Failure 400 {string} string
+// @Failure 500 {string} string
+func forgotConfirm(s Service) func(echo.Context) error {
+	return func(c echo.Context) error {
+		b := new(ForgotConfirmBody)
+		if err := validator.Struct(&c, b); err != nil {
+			return err
+		}
+
+		if err := s.ForgotConfirm(b.Token, b.Password); err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, "ok")
+	}
+}
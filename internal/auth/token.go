@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/xn3cr0nx/bitgodine/internal/errorx"
+	"github.com/xn3cr0nx/bitgodine/internal/storage/kv"
+	"github.com/xn3cr0nx/bitgodine/pkg/encoding"
+)
+
+// Purposes of the short lived tokens sent by email
+const (
+	Activation = "activation"
+	Reset      = "reset"
+)
+
+// Validity of the tokens sent by email
+const (
+	ActivationTTL = 24 * time.Hour
+	ResetTTL      = time.Hour
+)
+
+// ErrInvalidToken the token is unknown, already used or expired
+var ErrInvalidToken = fmt.Errorf("%w: invalid or expired token", errorx.ErrInvalidArgument)
+
+// pendingToken stored token waiting to be consumed
+type pendingToken struct {
+	UserID  string    `json:"user_id"`
+	Expires time.Time `json:"expires"`
+}
+
+// tokenKey returns the storage key of the token, only its hash is stored
+func tokenKey(purpose, token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return "auth_" + purpose + "_" + hex.EncodeToString(hash[:])
+}
+
+// IssueToken stores a new random token for the user, expiring from the store after ttl
+func IssueToken(db kv.DB, purpose, userID string, ttl time.Duration) (token string, err error) {
+	b := make([]byte, 32)
+	if _, err = rand.Read(b); err != nil {
+		return
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+
+	value, err := encoding.Marshal(pendingToken{UserID: userID, Expires: time.Now().Add(ttl)})
+	if err != nil {
+		return
+	}
+	err = db.StoreWithTTL(tokenKey(purpose, token), value, ttl)
+	return
+}
+
+// ConsumeToken returns the user the token has been issued to and deletes it, tokens can be used once.
+// The expiration is checked again for stores deleting expired keys lazily
+func ConsumeToken(db kv.DB, purpose, token string) (userID string, err error) {
+	key := tokenKey(purpose, token)
+	value, err := db.Read(key)
+	if err != nil {
+		if errors.Is(err, errorx.ErrKeyNotFound) {
+			err = ErrInvalidToken
+		}
+		return
+	}
+	if len(value) == 0 {
+		err = ErrInvalidToken
+		return
+	}
+	var pending pendingToken
+	if err = encoding.Unmarshal(value, &pending); err != nil {
+		return
+	}
+	if err = db.Delete(key); err != nil {
+		return
+	}
+	if time.Now().After(pending.Expires) {
+		err = ErrInvalidToken
+		return
+	}
+	userID = pending.UserID
+	return
+}
@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sendgrid/rest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/xn3cr0nx/bitgodine/internal/errorx"
+	"github.com/xn3cr0nx/bitgodine/internal/storage/kv"
+	"github.com/xn3cr0nx/bitgodine/internal/user"
+	"github.com/xn3cr0nx/bitgodine/pkg/mailer"
+)
+
+type TestTokenSuite struct {
+	suite.Suite
+	db    *kv.DBMock
+	store map[string][]byte
+}
+
+func (suite *TestTokenSuite) SetupTest() {
+	suite.store = make(map[string][]byte)
+	suite.db = kv.NewDBMock()
+	suite.db.On("StoreWithTTL", mock.Anything, mock.Anything, mock.Anything).Return(func(key string, value []byte, ttl time.Duration) error {
+		suite.store[key] = value
+		return nil
+	})
+	suite.db.On("Read", mock.Anything).Return(func(key string) []byte {
+		return suite.store[key]
+	}, func(key string) error {
+		if _, ok := suite.store[key]; !ok {
+			return errorx.ErrKeyNotFound
+		}
+		return nil
+	})
+	suite.db.On("Delete", mock.Anything).Return(func(key string) error {
+		delete(suite.store, key)
+		return nil
+	})
+}
+
+func (suite *TestTokenSuite) TestIssueConsume() {
+	token, err := IssueToken(suite.db, Activation, "user", time.Hour)
+	require.Nil(suite.T(), err)
+	assert.NotEmpty(suite.T(), token)
+	for key := range suite.store {
+		assert.NotContains(suite.T(), key, token)
+	}
+
+	ID, err := ConsumeToken(suite.db, Activation, token)
+	require.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "user", ID)
+
+	_, err = ConsumeToken(suite.db, Activation, token)
+	assert.True(suite.T(), errors.Is(err, ErrInvalidToken))
+}
+
+func (suite *TestTokenSuite) TestConsumeWrongPurpose() {
+	token, err := IssueToken(suite.db, Activation, "user", time.Hour)
+	require.Nil(suite.T(), err)
+
+	_, err = ConsumeToken(suite.db, Reset, token)
+	assert.True(suite.T(), errors.Is(err, ErrInvalidToken))
+}
+
+func (suite *TestTokenSuite) TestConsumeExpired() {
+	token, err := IssueToken(suite.db, Reset, "user", -time.Minute)
+	require.Nil(suite.T(), err)
+
+	_, err = ConsumeToken(suite.db, Reset, token)
+	assert.True(suite.T(), errors.Is(err, ErrInvalidToken))
+	assert.True(suite.T(), errors.Is(err, errorx.ErrInvalidArgument))
+	assert.Empty(suite.T(), suite.store)
+}
+
+func (suite *TestTokenSuite) TestSendActivation() {
+	s := NewService(nil, suite.db, &mailer.Client{Client: mailer.NewMockClient("test"), Email: "noreply@bitgodine.com"})
+	err := s.sendActivation(&user.Model{ID: uuid.New(), Email: "user@bitgodine.com", FirstName: "John", LastName: "Doe"})
+	require.Nil(suite.T(), err)
+	assert.Len(suite.T(), suite.store, 1)
+}
+
+func (suite *TestTokenSuite) TestCheckMail() {
+	assert.Nil(suite.T(), checkMail(&rest.Response{StatusCode: 202}, nil))
+	assert.NotNil(suite.T(), checkMail(&rest.Response{StatusCode: 401, Body: "unauthorized"}, nil))
+	assert.NotNil(suite.T(), checkMail(nil, errors.New("connection refused")))
+}
+
+func TestToken(t *testing.T) {
+	suite.Run(t, new(TestTokenSuite))
+}
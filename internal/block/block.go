@@ -5,11 +5,11 @@ import (
 	"fmt"
 	"strconv"
 
+	"github.com/xn3cr0nx/bitgodine/internal/address"
 	"github.com/xn3cr0nx/bitgodine/internal/errorx"
 	"github.com/xn3cr0nx/bitgodine/internal/storage/kv"
 	"github.com/xn3cr0nx/bitgodine/internal/tx"
 	"github.com/xn3cr0nx/bitgodine/pkg/cache"
-	"github.com/xn3cr0nx/bitgodine/pkg/encoding"
 	"github.com/xn3cr0nx/bitgodine/pkg/logger"
 )
 
@@ -30,11 +30,20 @@ type Service interface {
 	GetStoredTxs() (transactions []string, err error)
 	GetTxBlock(hash string) (block *BlockOut, err error)
 	GetTxBlockHeight(hash string) (height int32, err error)
+	GetStatus(hash string) (status *Status, err error)
+	GetRawHeader(hash string) (header []byte, err error)
+	GetRaw(hash string) (raw []byte, err error)
+	GetTxMerkleProof(txid string) (proof *MerkleProof, err error)
+	GetTxMerkleBlockProof(txid string) (proof []byte, err error)
+	Prevout(txs []tx.Tx) address.Prevout
 }
 
+// service Recent is optional, when set the outputs of the stored blocks are kept in memory
+// to resolve the prevouts of the following blocks before their batch is written
 type service struct {
-	Kv    kv.DB
-	Cache *cache.Cache
+	Kv     kv.DB
+	Cache  *cache.Cache
+	Recent *RecentOutputs
 }
 
 // NewService instantiates a new Service layer for customer
@@ -60,37 +69,41 @@ func (s *service) fetchBlockTxs(txs []string) (transactions []tx.Tx, err error)
 // StoreBlock inserts in the db the block as []byte passed
 // for fast research purpose blocks have _ prefix, tx_ for txs prefix and h_ for height prefix
 func (s *service) StoreBlock(b *Block, txs []tx.Tx) (err error) {
-	// b := v.(*Block)
-	// txs := t.([]tx.Tx)
-	blockHash := []byte(b.ID)
-	h := strconv.Itoa(int(b.Height))
-
-	batch := make(map[string][]byte)
-	serialized, err := encoding.Marshal(b)
+	batch, err := s.batch(b, txs)
 	if err != nil {
 		return
 	}
-	batch[b.ID] = serialized
+
+	if err = s.Kv.StoreQueueBatch(batch); err != nil {
+		return
+	}
+	s.Recent.add(b.Height, txs)
+	return
+}
+
+// batch returns all the entries stored for the block, the same entries are deleted rolling it back
+func (s *service) batch(b *Block, txs []tx.Tx) (batch map[string][]byte, err error) {
+	blockHash := []byte(b.ID)
+	h := strconv.Itoa(int(b.Height))
+
+	batch = make(map[string][]byte)
+	batch[b.ID] = Marshal(b)
 	batch[h] = blockHash
 	batch["last"] = []byte(h)
 
-	for _, tx := range txs {
-		serialized, e := encoding.Marshal(tx)
-		if e != nil {
-			err = e
-			return
+	for i := range txs {
+		t := &txs[i]
+		batch[t.TxID] = tx.Marshal(t)
+		batch["_"+t.TxID] = []byte(h)
+		for _, o := range t.Vout {
+			batch[o.ScriptpubkeyAddress+"_"+t.TxID] = []byte(h)
 		}
-		batch[tx.TxID] = serialized
-		batch["_"+tx.TxID] = []byte(h)
-		for _, o := range tx.Vout {
-			batch[o.ScriptpubkeyAddress+"_"+tx.TxID] = []byte(h)
-		}
-		for _, i := range tx.Vin {
-			batch[i.TxID+"_"+fmt.Sprint(i.Vout)] = []byte(tx.TxID)
+		for _, in := range t.Vin {
+			batch[in.TxID+"_"+fmt.Sprint(in.Vout)] = []byte(t.TxID)
 		}
 	}
 
-	err = s.Kv.StoreQueueBatch(batch)
+	err = s.indexAddresses(batch, b.Height, txs)
 	return
 }
 
@@ -100,7 +113,7 @@ func read(db kv.DB, hash string) (block Block, err error) {
 	if err != nil {
 		return
 	}
-	if err = encoding.Unmarshal(r, &block); err != nil {
+	if err = Unmarshal(r, &block); err != nil {
 		return
 	}
 	return
@@ -249,17 +262,46 @@ func (s *service) Remove(block *Block) error {
 	return s.Kv.Delete(block.ID)
 }
 
-// RemoveLast removes the last block stored
-func (s *service) RemoveLast() error {
+// RemoveLast rolls back the last block stored, deleting the block, its transactions, the outputs they
+// spend and their address index entries, then moving the tip to the previous block
+func (s *service) RemoveLast() (err error) {
 	h, err := s.ReadHeight()
 	if err != nil {
-		return err
+		return
+	}
+	last, err := s.GetFromHeight(h)
+	if err != nil {
+		return
 	}
-	block, err := s.GetFromHeight(h)
+	batch, err := s.batch(&last.Block, last.Transactions)
 	if err != nil {
-		return err
+		return
 	}
-	return s.Kv.Delete(block.ID)
+
+	// the tip is moved first, so that an interrupted rollback doesn't leave a tip without its entries
+	if h > 0 {
+		err = s.Kv.Store("last", []byte(strconv.Itoa(int(h-1))))
+	} else {
+		err = s.Kv.Delete("last")
+	}
+	if err != nil {
+		return
+	}
+	delete(batch, "last")
+	for key := range batch {
+		if err = s.Kv.Delete(key); err != nil {
+			return
+		}
+	}
+
+	s.Recent.remove(h)
+	if s.Cache != nil {
+		for _, t := range last.Transactions {
+			s.Cache.Del(t.TxID)
+			s.Cache.Del("h_" + t.TxID)
+		}
+	}
+	return
 }
 
 // GetStoredTxs returnes all the stored transactions hashes
@@ -310,3 +352,110 @@ func (s *service) GetTxBlockHeight(hash string) (height int32, err error) {
 	}
 	return
 }
+
+// GetStatus returns the status of the block, blocks not stored are not in the best chain
+func (s *service) GetStatus(hash string) (status *Status, err error) {
+	status = new(Status)
+	b, err := s.GetFromHash(hash)
+	if err != nil {
+		if errors.Is(err, errorx.ErrNotFound) {
+			err = nil
+		}
+		return
+	}
+	best, err := s.Kv.Read(strconv.Itoa(int(b.Height)))
+	if err != nil {
+		if errors.Is(err, errorx.ErrNotFound) {
+			err = nil
+		}
+		return
+	}
+	if string(best) != hash {
+		return
+	}
+	status.InBestChain = true
+	status.Height = &b.Height
+	next, err := s.Kv.Read(strconv.Itoa(int(b.Height + 1)))
+	if err != nil {
+		if errors.Is(err, errorx.ErrNotFound) {
+			err = nil
+		}
+		return
+	}
+	status.NextBest = string(next)
+	return
+}
+
+// GetRawHeader returns the header of the block serialized as on the network
+func (s *service) GetRawHeader(hash string) (header []byte, err error) {
+	b, err := s.GetFromHash(hash)
+	if err != nil {
+		return
+	}
+	return b.RawHeader()
+}
+
+// GetRaw returns the block with its transactions serialized as on the network
+func (s *service) GetRaw(hash string) (raw []byte, err error) {
+	b, err := s.GetFromHashWithTxs(hash)
+	if err != nil {
+		return
+	}
+	return b.Raw()
+}
+
+// txPosition returns the block containing the transaction and its position in the block
+func (s *service) txPosition(txid string) (b Block, pos int, err error) {
+	height, err := s.GetTxBlockHeight(txid)
+	if err != nil {
+		return
+	}
+	b, err = s.ReadFromHeight(height)
+	if err != nil {
+		return
+	}
+	for i, t := range b.Transactions {
+		if t == txid {
+			pos = i
+			return
+		}
+	}
+	err = errorx.ErrTxNotFound
+	return
+}
+
+// GetTxMerkleProof returns the merkle branch proving the inclusion of the transaction in its block
+func (s *service) GetTxMerkleProof(txid string) (proof *MerkleProof, err error) {
+	b, pos, err := s.txPosition(txid)
+	if err != nil {
+		return
+	}
+	leaves, err := txHashes(b.Transactions)
+	if err != nil {
+		return
+	}
+	branch := merkleBranch(leaves, pos)
+	proof = &MerkleProof{BlockHeight: b.Height, Merkle: make([]string, len(branch)), Pos: pos}
+	for i, h := range branch {
+		proof.Merkle[i] = h.String()
+	}
+	return
+}
+
+// GetTxMerkleBlockProof returns the merkle block proving the inclusion of the transaction in its
+// block, serialized as bitcoind gettxoutproof returns it
+func (s *service) GetTxMerkleBlockProof(txid string) (proof []byte, err error) {
+	b, pos, err := s.txPosition(txid)
+	if err != nil {
+		return
+	}
+	header, err := b.Header()
+	if err != nil {
+		return
+	}
+	leaves, err := txHashes(b.Transactions)
+	if err != nil {
+		return
+	}
+	return merkleBlock(header, leaves, pos)
+}
@@ -0,0 +1,129 @@
+package block
+
+import (
+	"strconv"
+
+	"github.com/xn3cr0nx/bitgodine/internal/storage/kv"
+	"github.com/xn3cr0nx/bitgodine/internal/tx"
+	"github.com/xn3cr0nx/bitgodine/pkg/encoding"
+)
+
+// Marshal encodes the block in the compact binary format
+func Marshal(b *Block) []byte {
+	w := encoding.NewWriter(64 + len(b.Transactions)*33)
+	w.Hex(b.ID)
+	w.Varint(int64(b.Height))
+	w.Varint(int64(b.Version))
+	w.Time(b.Timestamp)
+	w.Uvarint(uint64(b.Bits))
+	w.Uvarint(uint64(b.Nonce))
+	w.Hex(b.MerkleRoot)
+	w.Uvarint(uint64(len(b.Transactions)))
+	for _, t := range b.Transactions {
+		w.Hex(t)
+	}
+	w.Varint(int64(b.TxCount))
+	w.Varint(int64(b.Size))
+	w.Varint(int64(b.Weight))
+	w.Hex(b.Previousblockhash)
+	return w.Bytes()
+}
+
+// Unmarshal decodes a block stored either in the binary format or in the legacy one
+func Unmarshal(data []byte, b *Block) error {
+	if !encoding.IsBinary(data) {
+		return encoding.UnmarshalLegacy(data, b)
+	}
+
+	r := encoding.NewReader(data)
+	*b = Block{
+		ID:         r.Hex(),
+		Height:     int32(r.Varint()),
+		Version:    int32(r.Varint()),
+		Timestamp:  r.Time(),
+		Bits:       uint32(r.Uvarint()),
+		Nonce:      uint32(r.Uvarint()),
+		MerkleRoot: r.Hex(),
+	}
+	if n := r.Len(); n > 0 {
+		b.Transactions = make([]string, n)
+	}
+	for i := range b.Transactions {
+		b.Transactions[i] = r.Hex()
+	}
+	b.TxCount = int(r.Varint())
+	b.Size = int(r.Varint())
+	b.Weight = int(r.Varint())
+	b.Previousblockhash = r.Hex()
+	return r.Err()
+}
+
+// ReencodeBatch number of records written per batch re-encoding
+const ReencodeBatch = 10000
+
+// Reencode rewrites in the binary format the legacy records of the blocks from height on and
+// of their transactions. Records already binary are skipped, so it can be resumed. progress
+// is called after every written batch with the last re-encoded height
+func Reencode(db kv.DB, from int32, progress func(height int32, records int)) (records int, err error) {
+	last, err := NewService(db, nil).ReadHeight()
+	if err != nil {
+		return
+	}
+
+	batch := make(map[string][]byte)
+	flush := func(height int32) error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := db.StoreBatch(batch); err != nil {
+			return err
+		}
+		records += len(batch)
+		batch = make(map[string][]byte)
+		if progress != nil {
+			progress(height, records)
+		}
+		return nil
+	}
+
+	for height := from; height <= last; height++ {
+		hash, e := db.Read(strconv.Itoa(int(height)))
+		if e != nil {
+			return records, e
+		}
+		raw, e := db.Read(string(hash))
+		if e != nil {
+			return records, e
+		}
+		var b Block
+		if err = Unmarshal(raw, &b); err != nil {
+			return
+		}
+		if !encoding.IsBinary(raw) {
+			batch[b.ID] = Marshal(&b)
+		}
+
+		for _, txid := range b.Transactions {
+			raw, e := db.Read(txid)
+			if e != nil {
+				return records, e
+			}
+			if encoding.IsBinary(raw) {
+				continue
+			}
+			var t tx.Tx
+			if err = tx.Unmarshal(raw, &t); err != nil {
+				return
+			}
+			batch[txid] = tx.Marshal(&t)
+		}
+
+		if len(batch) >= ReencodeBatch {
+			if err = flush(height); err != nil {
+				return
+			}
+		}
+	}
+	err = flush(last)
+	return
+}
@@ -0,0 +1,107 @@
+package block_test
+
+import (
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcutil"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/xn3cr0nx/bitgodine/internal/block"
+	"github.com/xn3cr0nx/bitgodine/internal/errorx"
+	"github.com/xn3cr0nx/bitgodine/internal/storage/kv"
+	"github.com/xn3cr0nx/bitgodine/internal/test"
+	"github.com/xn3cr0nx/bitgodine/internal/tx"
+	"github.com/xn3cr0nx/bitgodine/pkg/encoding"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Testing blocks binary encoding", func() {
+	var (
+		model block.Block
+	)
+
+	BeforeEach(func() {
+		blk, err := btcutil.NewBlockFromBytes(test.Block181Bytes)
+		Expect(err).ToNot(HaveOccurred())
+		blk.SetHeight(181)
+		model = test.BlockToModel(blk)
+	})
+
+	It("Should round trip blocks", func() {
+		var decoded block.Block
+		Expect(block.Unmarshal(block.Marshal(&model), &decoded)).To(Succeed())
+		Expect(decoded.Timestamp.Equal(model.Timestamp)).To(BeTrue())
+		decoded.Timestamp = model.Timestamp
+		Expect(decoded).To(Equal(model))
+	})
+
+	It("Should decode legacy blocks", func() {
+		legacy, err := encoding.Marshal(model)
+		Expect(err).ToNot(HaveOccurred())
+		var decoded block.Block
+		Expect(block.Unmarshal(legacy, &decoded)).To(Succeed())
+		Expect(decoded.ID).To(Equal(model.ID))
+		Expect(decoded.Transactions).To(Equal(model.Transactions))
+	})
+
+	Context("Re-encoding stored records", func() {
+		var (
+			db    *kv.DBMock
+			store map[string][]byte
+		)
+
+		BeforeEach(func() {
+			store = make(map[string][]byte)
+			db = kv.NewDBMock()
+			db.On("Read", mock.Anything).Return(func(key string) []byte {
+				return store[key]
+			}, func(key string) error {
+				if _, ok := store[key]; !ok {
+					return errorx.ErrKeyNotFound
+				}
+				return nil
+			})
+			db.On("StoreBatch", mock.Anything).Return(func(batch interface{}) error {
+				for k, v := range batch.(map[string][]byte) {
+					store[k] = v
+				}
+				return nil
+			})
+
+			genesis := btcutil.NewBlock(chaincfg.MainNetParams.GenesisBlock)
+			genesis.SetHeight(0)
+			m := test.BlockToModel(genesis)
+			legacy, err := encoding.Marshal(m)
+			Expect(err).ToNot(HaveOccurred())
+			store[m.ID] = legacy
+			store["0"] = []byte(m.ID)
+			store["last"] = []byte("0")
+			for _, t := range genesis.Transactions() {
+				legacy, err := encoding.Marshal(test.TxToModel(t, m.Height, m.ID, m.Timestamp))
+				Expect(err).ToNot(HaveOccurred())
+				store[t.Hash().String()] = legacy
+			}
+		})
+
+		It("Should rewrite legacy records in the binary format", func() {
+			records, err := block.Reencode(db, 0, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(records).To(Equal(2))
+
+			hash := chaincfg.MainNetParams.GenesisHash.String()
+			Expect(encoding.IsBinary(store[hash])).To(BeTrue())
+			var b block.Block
+			Expect(block.Unmarshal(store[hash], &b)).To(Succeed())
+			Expect(b.Transactions).To(HaveLen(1))
+			Expect(encoding.IsBinary(store[b.Transactions[0]])).To(BeTrue())
+			var t tx.Tx
+			Expect(tx.Unmarshal(store[b.Transactions[0]], &t)).To(Succeed())
+			Expect(t.TxID).To(Equal(b.Transactions[0]))
+
+			records, err = block.Reencode(db, 0, nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(records).To(Equal(0))
+		})
+	})
+})
@@ -0,0 +1,175 @@
+package block
+
+import (
+	"sync"
+
+	"github.com/xn3cr0nx/bitgodine/internal/address"
+	"github.com/xn3cr0nx/bitgodine/internal/errorx"
+	"github.com/xn3cr0nx/bitgodine/internal/storage/kv"
+	"github.com/xn3cr0nx/bitgodine/internal/tx"
+)
+
+// RecentBlocks number of blocks whose outputs are kept in memory to resolve the outputs spent by the
+// following ones. Stores queue batches before writing them, so recent transactions may not be readable yet
+const RecentBlocks = 200
+
+// RecentOutputs outputs of the transactions stored in the last blocks
+type RecentOutputs struct {
+	sync.RWMutex
+	outputs map[string][]tx.Output
+	heights map[int32][]string
+}
+
+// NewRecentOutputs returns an empty cache of recent outputs
+func NewRecentOutputs() *RecentOutputs {
+	return &RecentOutputs{
+		outputs: make(map[string][]tx.Output),
+		heights: make(map[int32][]string),
+	}
+}
+
+// add keeps the outputs of the block transactions, forgetting the ones older than RecentBlocks
+func (r *RecentOutputs) add(height int32, txs []tx.Tx) {
+	if r == nil {
+		return
+	}
+	r.Lock()
+	defer r.Unlock()
+	for _, txid := range r.heights[height] {
+		delete(r.outputs, txid)
+	}
+	txids := make([]string, len(txs))
+	for i, t := range txs {
+		r.outputs[t.TxID] = t.Vout
+		txids[i] = t.TxID
+	}
+	r.heights[height] = txids
+	for h, txids := range r.heights {
+		if h > height-RecentBlocks && h <= height {
+			continue
+		}
+		for _, txid := range txids {
+			delete(r.outputs, txid)
+		}
+		delete(r.heights, h)
+	}
+}
+
+// remove forgets the outputs of the block at height
+func (r *RecentOutputs) remove(height int32) {
+	if r == nil {
+		return
+	}
+	r.Lock()
+	defer r.Unlock()
+	for _, txid := range r.heights[height] {
+		delete(r.outputs, txid)
+	}
+	delete(r.heights, height)
+}
+
+func (r *RecentOutputs) get(txid string) ([]tx.Output, bool) {
+	if r == nil {
+		return nil, false
+	}
+	r.RLock()
+	defer r.RUnlock()
+	outputs, ok := r.outputs[txid]
+	return outputs, ok
+}
+
+// Prevout returns a resolver of spent outputs looking in the block transactions,
+// in the recent ones and then in the store
+func (s *service) Prevout(txs []tx.Tx) address.Prevout {
+	block := make(map[string][]tx.Output, len(txs))
+	for _, t := range txs {
+		block[t.TxID] = t.Vout
+	}
+	return func(txid string, vout uint32) (output tx.Output, err error) {
+		outputs, ok := block[txid]
+		if !ok {
+			outputs, ok = s.Recent.get(txid)
+		}
+		if !ok {
+			raw, e := s.Kv.Read(txid)
+			if e != nil {
+				return output, e
+			}
+			var t tx.Tx
+			if err = tx.Unmarshal(raw, &t); err != nil {
+				return
+			}
+			outputs = t.Vout
+		}
+		if int(vout) >= len(outputs) {
+			err = errorx.ErrOutOfRange
+			return
+		}
+		output = outputs[vout]
+		return
+	}
+}
+
+// indexAddresses adds to the batch the address index entries of the block transactions
+func (s *service) indexAddresses(batch map[string][]byte, height int32, txs []tx.Tx) (err error) {
+	resolve := s.Prevout(txs)
+	for i := range txs {
+		if err = address.IndexTx(batch, &txs[i], height, resolve); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// ReindexAddresses builds the address index of the blocks from height on, reading their
+// transactions from the store. progress is called after every written batch with the last indexed height
+func ReindexAddresses(db kv.DB, from int32, progress func(height int32, entries int)) (entries int, err error) {
+	last, err := NewService(db, nil).ReadHeight()
+	if err != nil {
+		return
+	}
+
+	batch := make(map[string][]byte)
+	flush := func(height int32) error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := db.StoreBatch(batch); err != nil {
+			return err
+		}
+		entries += len(batch)
+		batch = make(map[string][]byte)
+		if progress != nil {
+			progress(height, entries)
+		}
+		return nil
+	}
+
+	s := NewService(db, nil)
+	for height := from; height <= last; height++ {
+		b, e := s.ReadFromHeight(height)
+		if e != nil {
+			return entries, e
+		}
+		txs := make([]tx.Tx, len(b.Transactions))
+		for i, txid := range b.Transactions {
+			raw, e := db.Read(txid)
+			if e != nil {
+				return entries, e
+			}
+			if err = tx.Unmarshal(raw, &txs[i]); err != nil {
+				return
+			}
+		}
+		if err = s.indexAddresses(batch, height, txs); err != nil {
+			return
+		}
+		if len(batch) >= ReencodeBatch {
+			if err = flush(height); err != nil {
+				return
+			}
+		}
+	}
+	err = flush(last)
+	return
+}
@@ -0,0 +1,158 @@
+package block_test
+
+import (
+	"strings"
+
+	"github.com/stretchr/testify/mock"
+
+	"github.com/xn3cr0nx/bitgodine/internal/address"
+	"github.com/xn3cr0nx/bitgodine/internal/block"
+	"github.com/xn3cr0nx/bitgodine/internal/errorx"
+	"github.com/xn3cr0nx/bitgodine/internal/storage/kv"
+	"github.com/xn3cr0nx/bitgodine/internal/tx"
+	"github.com/xn3cr0nx/bitgodine/pkg/cache"
+	"github.com/xn3cr0nx/bitgodine/pkg/logger"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Testing blocks address index", func() {
+	var (
+		db     *kv.DBMock
+		store  map[string][]byte
+		blocks []block.Block
+		txs    [][]tx.Tx
+	)
+
+	indexed := func() (keys []string) {
+		for k := range store {
+			if strings.HasPrefix(k, address.IndexPrefix) {
+				keys = append(keys, k)
+			}
+		}
+		return
+	}
+
+	BeforeEach(func() {
+		logger.Setup()
+		store = make(map[string][]byte)
+		db = kv.NewDBMock()
+		db.On("Read", mock.Anything).Return(func(key string) []byte {
+			return store[key]
+		}, func(key string) error {
+			if _, ok := store[key]; !ok {
+				return errorx.ErrKeyNotFound
+			}
+			return nil
+		})
+		save := func(batch interface{}) error {
+			for k, v := range batch.(map[string][]byte) {
+				store[k] = v
+			}
+			return nil
+		}
+		db.On("StoreBatch", mock.Anything).Return(save)
+		db.On("StoreQueueBatch", mock.Anything).Return(save)
+
+		coinbase := tx.Tx{
+			TxID: strings.Repeat("a", 64),
+			Vin:  []tx.Input{{IsCoinbase: true}},
+			Vout: []tx.Output{{ScriptpubkeyAddress: "1Miner", Value: 5000000000}},
+		}
+		spending := tx.Tx{
+			TxID: strings.Repeat("b", 64),
+			Vin:  []tx.Input{{TxID: coinbase.TxID, Vout: 0}},
+			Vout: []tx.Output{
+				{ScriptpubkeyAddress: "1Payee", Value: 1000000000, Index: 0},
+				{ScriptpubkeyAddress: "1Miner", Value: 3999990000, Index: 1},
+			},
+		}
+		blocks = []block.Block{
+			{ID: strings.Repeat("1", 64), Height: 0, Transactions: []string{coinbase.TxID}},
+			{ID: strings.Repeat("2", 64), Height: 1, Transactions: []string{spending.TxID}},
+		}
+		txs = [][]tx.Tx{{coinbase}, {spending}}
+	})
+
+	It("Should index addresses storing blocks", func() {
+		service := block.NewService(db, nil)
+		for i := range blocks {
+			Expect(service.StoreBlock(&blocks[i], txs[i])).To(Succeed())
+		}
+		Expect(indexed()).To(ConsistOf(
+			address.IndexPrefix+"1Miner_0000000000_"+strings.Repeat("a", 64)+"_f000000",
+			address.IndexPrefix+"1Miner_0000000001_"+strings.Repeat("b", 64)+"_s000000",
+			address.IndexPrefix+"1Miner_0000000001_"+strings.Repeat("b", 64)+"_f000001",
+			address.IndexPrefix+"1Payee_0000000001_"+strings.Repeat("b", 64)+"_f000000",
+		))
+	})
+
+	It("Should rebuild the index from stored blocks", func() {
+		service := block.NewService(db, nil)
+		for i := range blocks {
+			Expect(service.StoreBlock(&blocks[i], txs[i])).To(Succeed())
+		}
+		expected := indexed()
+		for _, k := range expected {
+			delete(store, k)
+		}
+
+		entries, err := block.ReindexAddresses(db, 0, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(entries).To(Equal(4))
+		Expect(indexed()).To(ConsistOf(expected))
+	})
+
+	It("Should resolve prevouts of queued blocks from the recent outputs", func() {
+		queued := kv.NewDBMock()
+		queued.On("Read", mock.Anything).Return(nil, errorx.ErrKeyNotFound)
+		queued.On("StoreQueueBatch", mock.Anything).Return(nil)
+		service := block.NewService(queued, nil)
+		service.Recent = block.NewRecentOutputs()
+		Expect(service.StoreBlock(&blocks[0], txs[0])).To(Succeed())
+
+		out, err := service.Prevout(nil)(txs[0][0].TxID, 0)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(out.ScriptpubkeyAddress).To(Equal("1Miner"))
+		_, err = block.NewService(queued, nil).Prevout(nil)(txs[0][0].TxID, 0)
+		Expect(err).To(MatchError(errorx.ErrKeyNotFound))
+	})
+
+	It("Should not keep the outputs of blocks failing to be queued", func() {
+		failing := kv.NewDBMock()
+		failing.On("Read", mock.Anything).Return(nil, errorx.ErrKeyNotFound)
+		failing.On("StoreQueueBatch", mock.Anything).Return(errorx.ErrConfig)
+		service := block.NewService(failing, nil)
+		service.Recent = block.NewRecentOutputs()
+		Expect(service.StoreBlock(&blocks[0], txs[0])).ToNot(Succeed())
+
+		_, err := service.Prevout(nil)(txs[0][0].TxID, 0)
+		Expect(err).To(MatchError(errorx.ErrKeyNotFound))
+	})
+
+	It("Should roll back every entry of the last block", func() {
+		db.On("Store", mock.Anything, mock.Anything).Return(func(k string, v []byte) error {
+			store[k] = v
+			return nil
+		})
+		db.On("Delete", mock.Anything).Return(func(k string) error {
+			delete(store, k)
+			return nil
+		})
+		c, err := cache.NewCache(nil)
+		Expect(err).ToNot(HaveOccurred())
+		service := block.NewService(db, c)
+		Expect(service.StoreBlock(&blocks[0], txs[0])).To(Succeed())
+		stored := make(map[string][]byte, len(store))
+		for k, v := range store {
+			stored[k] = v
+		}
+		Expect(service.StoreBlock(&blocks[1], txs[1])).To(Succeed())
+
+		Expect(service.RemoveLast()).To(Succeed())
+		Expect(store).To(Equal(stored))
+		Expect(service.RemoveLast()).To(Succeed())
+		Expect(store).To(BeEmpty())
+	})
+})
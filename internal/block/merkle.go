@@ -0,0 +1,135 @@
+package block
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+
+	"github.com/xn3cr0nx/bitgodine/internal/errorx"
+)
+
+// txHashes returns the hashes of the transactions ids, in block order
+func txHashes(txids []string) ([]chainhash.Hash, error) {
+	hashes := make([]chainhash.Hash, len(txids))
+	for i, txid := range txids {
+		h, err := chainhash.NewHashFromStr(txid)
+		if err != nil {
+			return nil, fmt.Errorf("%w: tx %s: %s", errorx.ErrInvalidArgument, txid, err)
+		}
+		hashes[i] = *h
+	}
+	return hashes, nil
+}
+
+// merkleParent hashes the concatenation of the children
+func merkleParent(left, right *chainhash.Hash) chainhash.Hash {
+	var buf [chainhash.HashSize * 2]byte
+	copy(buf[:chainhash.HashSize], left[:])
+	copy(buf[chainhash.HashSize:], right[:])
+	return chainhash.DoubleHashH(buf[:])
+}
+
+// merkleBranch returns the siblings of the leaf at pos from the bottom of the tree to the root.
+// The last node of an odd level is paired with itself
+func merkleBranch(leaves []chainhash.Hash, pos int) (branch []chainhash.Hash) {
+	branch = []chainhash.Hash{}
+	level := leaves
+	for len(level) > 1 {
+		sibling := pos ^ 1
+		if sibling >= len(level) {
+			sibling = pos
+		}
+		branch = append(branch, level[sibling])
+
+		next := make([]chainhash.Hash, (len(level)+1)/2)
+		for i := range next {
+			left, right := &level[2*i], &level[2*i]
+			if 2*i+1 < len(level) {
+				right = &level[2*i+1]
+			}
+			next[i] = merkleParent(left, right)
+		}
+		level = next
+		pos /= 2
+	}
+	return
+}
+
+// partialTree partial merkle tree proving the inclusion of the matched leaves, as bitcoind
+// builds it for gettxoutproof
+type partialTree struct {
+	leaves  []chainhash.Hash
+	matches []bool
+	bits    []bool
+	hashes  []*chainhash.Hash
+}
+
+// width returns the number of nodes at height
+func (t *partialTree) width(height uint) int {
+	return (len(t.leaves) + (1 << height) - 1) >> height
+}
+
+// hash returns the hash of the node at height and pos
+func (t *partialTree) hash(height uint, pos int) chainhash.Hash {
+	if height == 0 {
+		return t.leaves[pos]
+	}
+	left := t.hash(height-1, pos*2)
+	right := left
+	if pos*2+1 < t.width(height-1) {
+		right = t.hash(height-1, pos*2+1)
+	}
+	return merkleParent(&left, &right)
+}
+
+// build traverses the tree depth first, descending only the nodes parent of a match
+func (t *partialTree) build(height uint, pos int) {
+	parentOfMatch := false
+	for p := pos << height; p < (pos+1)<<height && p < len(t.leaves); p++ {
+		parentOfMatch = parentOfMatch || t.matches[p]
+	}
+	t.bits = append(t.bits, parentOfMatch)
+	if height == 0 || !parentOfMatch {
+		h := t.hash(height, pos)
+		t.hashes = append(t.hashes, &h)
+		return
+	}
+	t.build(height-1, pos*2)
+	if pos*2+1 < t.width(height-1) {
+		t.build(height-1, pos*2+1)
+	}
+}
+
+// merkleBlock returns the merkle block proving the inclusion of the leaf at pos, serialized as
+// bitcoind gettxoutproof returns it
+func merkleBlock(header *wire.BlockHeader, leaves []chainhash.Hash, pos int) ([]byte, error) {
+	t := &partialTree{leaves: leaves, matches: make([]bool, len(leaves))}
+	t.matches[pos] = true
+	var height uint
+	for t.width(height) > 1 {
+		height++
+	}
+	t.build(height, 0)
+
+	msg := wire.NewMsgMerkleBlock(header)
+	msg.Transactions = uint32(len(leaves))
+	for _, h := range t.hashes {
+		if err := msg.AddTxHash(h); err != nil {
+			return nil, err
+		}
+	}
+	msg.Flags = make([]byte, (len(t.bits)+7)/8)
+	for i, bit := range t.bits {
+		if bit {
+			msg.Flags[i/8] |= 1 << (i % 8)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := msg.BtcEncode(&buf, wire.ProtocolVersion, wire.BaseEncoding); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
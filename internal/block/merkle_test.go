@@ -0,0 +1,98 @@
+package block
+
+import (
+	"bytes"
+
+	"github.com/btcsuite/btcd/blockchain"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// extract walks the partial merkle tree returning its root and the matched leaves
+func extract(msg *wire.MsgMerkleBlock) (root chainhash.Hash, matches []chainhash.Hash) {
+	t := &partialTree{leaves: make([]chainhash.Hash, msg.Transactions)}
+	var height uint
+	for t.width(height) > 1 {
+		height++
+	}
+	bit, hash := 0, 0
+	var walk func(height uint, pos int) chainhash.Hash
+	walk = func(height uint, pos int) chainhash.Hash {
+		parentOfMatch := msg.Flags[bit/8]&(1<<(bit%8)) != 0
+		bit++
+		if height == 0 || !parentOfMatch {
+			h := *msg.Hashes[hash]
+			hash++
+			if height == 0 && parentOfMatch {
+				matches = append(matches, h)
+			}
+			return h
+		}
+		left := walk(height-1, pos*2)
+		right := left
+		if pos*2+1 < t.width(height-1) {
+			right = walk(height-1, pos*2+1)
+		}
+		return merkleParent(&left, &right)
+	}
+	root = walk(height, 0)
+	Expect(hash).To(Equal(len(msg.Hashes)))
+	return
+}
+
+var _ = Describe("Testing merkle proofs", func() {
+	leaves := func(n int) (hashes []chainhash.Hash, root chainhash.Hash) {
+		// transactions differing by locktime, the root is computed by btcd
+		txs := make([]*btcutil.Tx, n)
+		for i := range txs {
+			msg := wire.NewMsgTx(1)
+			msg.LockTime = uint32(i)
+			txs[i] = btcutil.NewTx(msg)
+			hashes = append(hashes, *txs[i].Hash())
+		}
+		store := blockchain.BuildMerkleTreeStore(txs, false)
+		root = *store[len(store)-1]
+		return
+	}
+
+	It("Should fold merkle branches to the root", func() {
+		for n := 1; n <= 9; n++ {
+			hashes, root := leaves(n)
+			for pos := range hashes {
+				h := hashes[pos]
+				p := pos
+				for _, sibling := range merkleBranch(hashes, pos) {
+					sibling := sibling
+					if p%2 == 0 {
+						h = merkleParent(&h, &sibling)
+					} else {
+						h = merkleParent(&sibling, &h)
+					}
+					p /= 2
+				}
+				Expect(h).To(Equal(root), "%d leaves, pos %d", n, pos)
+			}
+		}
+	})
+
+	It("Should build merkle blocks matching a single transaction", func() {
+		for n := 1; n <= 9; n++ {
+			hashes, root := leaves(n)
+			header := wire.NewBlockHeader(1, new(chainhash.Hash), &root, 0, 0)
+			for pos := range hashes {
+				raw, err := merkleBlock(header, hashes, pos)
+				Expect(err).ToNot(HaveOccurred())
+				msg := new(wire.MsgMerkleBlock)
+				Expect(msg.BtcDecode(bytes.NewReader(raw), wire.ProtocolVersion, wire.BaseEncoding)).To(Succeed())
+				Expect(msg.Header.MerkleRoot).To(Equal(root))
+				r, matches := extract(msg)
+				Expect(r).To(Equal(root), "%d leaves, pos %d", n, pos)
+				Expect(matches).To(Equal([]chainhash.Hash{hashes[pos]}))
+			}
+		}
+	})
+})
@@ -39,6 +39,21 @@ type BlockOut struct {
 	Transactions []tx.Tx `json:"transactions"`
 }
 
+// Status of the block in the best chain
+type Status struct {
+	InBestChain bool   `json:"in_best_chain"`
+	Height      *int32 `json:"height,omitempty"`
+	NextBest    string `json:"next_best,omitempty"`
+}
+
+// MerkleProof merkle branch proving the inclusion of a transaction in the block at BlockHeight,
+// from the sibling of the transaction at Pos to the root
+type MerkleProof struct {
+	BlockHeight int32    `json:"block_height"`
+	Merkle      []string `json:"merkle"`
+	Pos         int      `json:"pos"`
+}
+
 // IsHash returns true is the string is a block hash
 func IsHash(text string) bool {
 	re := regexp.MustCompile("^[0]{8}[a-fA-F0-9]{56}$")
@@ -0,0 +1,68 @@
+package block
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+
+	"github.com/xn3cr0nx/bitgodine/internal/errorx"
+)
+
+// Header rebuilds the wire header of the block, hashing to the block id
+func (b *Block) Header() (*wire.BlockHeader, error) {
+	prev := new(chainhash.Hash)
+	if b.Previousblockhash != "" {
+		h, err := chainhash.NewHashFromStr(b.Previousblockhash)
+		if err != nil {
+			return nil, fmt.Errorf("%w: previous block %s: %s", errorx.ErrInvalidArgument, b.Previousblockhash, err)
+		}
+		prev = h
+	}
+	merkleRoot, err := chainhash.NewHashFromStr(b.MerkleRoot)
+	if err != nil {
+		return nil, fmt.Errorf("%w: merkle root %s: %s", errorx.ErrInvalidArgument, b.MerkleRoot, err)
+	}
+	header := wire.NewBlockHeader(b.Version, prev, merkleRoot, b.Bits, b.Nonce)
+	header.Timestamp = b.Timestamp
+	return header, nil
+}
+
+// RawHeader returns the 80 bytes header serialized as on the network
+func (b *Block) RawHeader() ([]byte, error) {
+	header, err := b.Header()
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	buf.Grow(wire.MaxBlockHeaderPayload)
+	if err := header.Serialize(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Raw returns the block with its transactions serialized as on the network
+func (b *BlockOut) Raw() ([]byte, error) {
+	header, err := b.Header()
+	if err != nil {
+		return nil, err
+	}
+	msg := wire.NewMsgBlock(header)
+	for i := range b.Transactions {
+		t, err := b.Transactions[i].MsgTx()
+		if err != nil {
+			return nil, err
+		}
+		if err := msg.AddTransaction(t); err != nil {
+			return nil, err
+		}
+	}
+	var buf bytes.Buffer
+	buf.Grow(msg.SerializeSize())
+	if err := msg.Serialize(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -1,11 +1,14 @@
 package block
 
 import (
+	"encoding/hex"
 	"errors"
 	"net/http"
 	"strconv"
 
+	"github.com/xn3cr0nx/bitgodine/internal/apikey"
 	"github.com/xn3cr0nx/bitgodine/internal/errorx"
+	"github.com/xn3cr0nx/bitgodine/internal/ratelimit"
 	"github.com/xn3cr0nx/bitgodine/pkg/validator"
 
 	"github.com/labstack/echo/v4"
@@ -13,18 +16,25 @@ import (
 
 // Routes mounts all /block, /blocks and /block-height based routes on the main group
 func Routes(g *echo.Group, s Service) {
-	g.GET("/block-height/:height", blockHeight(s), validator.JWT())
+	g.GET("/block-height/:height", blockHeight(s), apikey.Auth(apikey.ReadBlocks))
 
-	r := g.Group("/block", validator.JWT())
+	r := g.Group("/block", apikey.Auth(apikey.ReadBlocks), ratelimit.Middleware(ratelimit.Blocks))
 	r.GET("/:hash", blockHash(s))
-	// r.GET("/:hash/status", blockStatus)
+	r.GET("/:hash/status", blockStatus(s))
+	r.GET("/:hash/header", blockHeader(s))
+	r.GET("/:hash/raw", blockRaw(s))
 	r.GET("/:hash/txs/:start_index", blockHashTxs(s))
 	r.GET("/:hash/txids", blockHashTxIDs(s))
 
-	b := g.Group("/blocks", validator.JWT())
+	b := g.Group("/blocks", apikey.Auth(apikey.ReadBlocks), ratelimit.Middleware(ratelimit.Blocks))
 	b.GET("/tip/height", tipHeight(s))
 	b.GET("/tip/hash", tipHash(s))
+	b.DELETE("/tip", removeTip(s), validator.Role(validator.Admin))
 	b.GET("/:start_height", blocksHeight(s))
+
+	// merkle proofs are built from the block, they're mounted here next to the /tx routes
+	g.GET("/tx/:txid/merkle-proof", txMerkleProof(s), apikey.Auth(apikey.ReadBlocks), ratelimit.Middleware(ratelimit.Blocks))
+	g.GET("/tx/:txid/merkleblock-proof", txMerkleBlockProof(s), apikey.Auth(apikey.ReadBlocks), ratelimit.Middleware(ratelimit.Blocks))
 }
 
 // blockHeight godoc
@@ -62,24 +72,95 @@ func blockHeight(s Service) func(echo.Context) error {
 	}
 }
 
-// TODO: check if block in the best chain
-// func blockStatus(s Service) func(echo.Context) error {
-// 	return func(c echo.Context) error {
-// hash := c.Param("hash")
-// 	if err := c.Echo().Validator.(*validator.CustomValidator).Var(hash, "required,testing"); err != nil {
-// 		return err
-// 	}
-// 	db := c.Get("db")
-// 	b, err := db.(kv.DB).GetFromHash(hash)
-// 	if err != nil {
-// 		if errors.Is(err, errorx.ErrKeyNotFound) {
-// 			return echo.NewHTTPError(http.StatusNotFound, err)
-// 		}
-// 		return err
-// 	}
-// 	return c.JSON(http.StatusOK, b)
-// 	}
-// }
+// blockStatus godoc
+// @ID block-status
+//
+// @Router /block/{hash}/status [get]
+// @Summary Block status
+// @Description get block status in the best chain, with the next block in the best chain
+// @Tags block
+//
+// @Security ApiKeyAuth
+//
+// @Accept  json
+// @Produce  json
+//
+// @Param hash path string true "Block hash"
+// @Success 200 {object} Status
+// @Success 500 {string} string
+func blockStatus(s Service) func(echo.Context) error {
+	return func(c echo.Context) error {
+		hash := c.Param("hash")
+		if err := c.Echo().Validator.(*validator.CustomValidator).Var(hash, "required"); err != nil {
+			return err
+		}
+		status, err := s.GetStatus(hash)
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, status)
+	}
+}
+
+// blockHeader godoc
+// @ID block-header
+//
+// @Router /block/{hash}/header [get]
+// @Summary Block header
+// @Description get block header as hex
+// @Tags block
+//
+// @Security ApiKeyAuth
+//
+// @Produce  plain
+//
+// @Param hash path string true "Block hash"
+// @Success 200 {string} string
+// @Success 404 {string} string
+// @Success 500 {string} string
+func blockHeader(s Service) func(echo.Context) error {
+	return func(c echo.Context) error {
+		hash := c.Param("hash")
+		if err := c.Echo().Validator.(*validator.CustomValidator).Var(hash, "required"); err != nil {
+			return err
+		}
+		header, err := s.GetRawHeader(hash)
+		if err != nil {
+			return err
+		}
+		return c.String(http.StatusOK, hex.EncodeToString(header))
+	}
+}
+
+// blockRaw godoc
+// @ID block-raw
+//
+// @Router /block/{hash}/raw [get]
+// @Summary Block raw
+// @Description get raw block as binary
+// @Tags block
+//
+// @Security ApiKeyAuth
+//
+// @Produce  octet-stream
+//
+// @Param hash path string true "Block hash"
+// @Success 200 {string} string
+// @Success 404 {string} string
+// @Success 500 {string} string
+func blockRaw(s Service) func(echo.Context) error {
+	return func(c echo.Context) error {
+		hash := c.Param("hash")
+		if err := c.Echo().Validator.(*validator.CustomValidator).Var(hash, "required"); err != nil {
+			return err
+		}
+		raw, err := s.GetRaw(hash)
+		if err != nil {
+			return err
+		}
+		return c.Blob(http.StatusOK, echo.MIMEOctetStream, raw)
+	}
+}
 
 // blockHash godoc
 // @ID block-hash
@@ -283,3 +364,88 @@ func tipHash(s Service) func(echo.Context) error {
 		return c.JSON(http.StatusOK, b.ID)
 	}
 }
+
+// removeTip godoc
+// @ID remove-tip
+//
+// @Router /blocks/tip [delete]
+// @Summary Remove tip
+// @Description roll back the last stored block, deleting its transactions, the outputs they spend and their address index entries
+// @Tags blocks
+//
+// @Security ApiKeyAuth
+//
+// @Accept  json
+// @Produce  json
+//
+// @Success 200 {string} ok
+// @Success 500 {string} string
+func removeTip(s Service) func(echo.Context) error {
+	return func(c echo.Context) error {
+		if err := s.RemoveLast(); err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, "ok")
+	}
+}
+
+// txMerkleProof godoc
+// @ID tx-merkle-proof
+//
+// @Router /tx/{txid}/merkle-proof [get]
+// @Summary Tx merkle proof
+// @Description get the merkle branch proving the transaction inclusion in its block
+// @Tags tx
+//
+// @Security ApiKeyAuth
+//
+// @Accept  json
+// @Produce  json
+//
+// @Param txid path string true "Transaction id"
+// @Success 200 {object} MerkleProof
+// @Success 404 {string} string
+// @Success 500 {string} string
+func txMerkleProof(s Service) func(echo.Context) error {
+	return func(c echo.Context) error {
+		txid := c.Param("txid")
+		if err := c.Echo().Validator.(*validator.CustomValidator).Var(txid, "required"); err != nil {
+			return err
+		}
+		proof, err := s.GetTxMerkleProof(txid)
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, proof)
+	}
+}
+
+// txMerkleBlockProof godoc
+// @ID tx-merkleblock-proof
+//
+// @Router /tx/{txid}/merkleblock-proof [get]
+// @Summary Tx merkle block proof
+// @Description get the merkle block proving the transaction inclusion in its block as hex, in the bitcoind gettxoutproof format
+// @Tags tx
+//
+// @Security ApiKeyAuth
+//
+// @Produce  plain
+//
+// @Param txid path string true "Transaction id"
+// @Success 200 {string} string
+// @Success 404 {string} string
+// @Success 500 {string} string
+func txMerkleBlockProof(s Service) func(echo.Context) error {
+	return func(c echo.Context) error {
+		txid := c.Param("txid")
+		if err := c.Echo().Validator.(*validator.CustomValidator).Var(txid, "required"); err != nil {
+			return err
+		}
+		proof, err := s.GetTxMerkleBlockProof(txid)
+		if err != nil {
+			return err
+		}
+		return c.String(http.StatusOK, hex.EncodeToString(proof))
+	}
+}
@@ -6,8 +6,10 @@ import (
 
 	"github.com/fatih/structs"
 	"github.com/olekukonko/tablewriter"
+	"github.com/xn3cr0nx/bitgodine/internal/errorx"
 	"github.com/xn3cr0nx/bitgodine/internal/storage/db/postgres"
 	"github.com/xn3cr0nx/bitgodine/pkg/cache"
+	"github.com/xn3cr0nx/bitgodine/pkg/logger"
 )
 
 // Service interface exports available methods for block service
@@ -15,6 +17,8 @@ type Service interface {
 	GetClusters(output bool) (tags []Model, err error)
 	CreateCluster(t *Model) (err error)
 	GetCluster(address string, output bool) (tags []Model, err error)
+	GetLabels(cluster uint64) (labels []Label, err error)
+	RefreshLabels(clusters ...uint64) (err error)
 }
 
 type service struct {
@@ -71,3 +75,41 @@ func (s *service) GetCluster(address string, output bool) (clusters []Model, err
 
 	return
 }
+
+// labelsKey cache key of the cluster labels
+func labelsKey(cluster uint64) string {
+	return fmt.Sprintf("cl_%d", cluster)
+}
+
+// GetLabels retrieves the materialized labels of the cluster, the most supported first
+func (s *service) GetLabels(cluster uint64) (labels []Label, err error) {
+	if s.Cache != nil {
+		if cached, ok := s.Cache.Get(labelsKey(cluster)); ok {
+			labels = cached.([]Label)
+			return
+		}
+	}
+
+	labels = []Label{}
+	if err = s.Repository.Where("cluster = ?", cluster).Order("support desc, confidence desc").Find(&labels).Error; err != nil {
+		return
+	}
+
+	if s.Cache != nil && !s.Cache.Set(labelsKey(cluster), labels, 1) {
+		logger.Error("Cache", errorx.ErrCache, logger.Params{"cluster": cluster})
+	}
+	return
+}
+
+// RefreshLabels materializes again the labels of the clusters, invalidating the cached ones
+func (s *service) RefreshLabels(clusters ...uint64) (err error) {
+	if err = RefreshLabels(s.Repository.DB, clusters); err != nil {
+		return
+	}
+	if s.Cache != nil {
+		for _, cluster := range clusters {
+			s.Cache.Del(labelsKey(cluster))
+		}
+	}
+	return
+}
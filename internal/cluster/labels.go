@@ -0,0 +1,126 @@
+package cluster
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// labelsChunk number of clusters refreshed at once
+const labelsChunk = 1000
+
+// member tag of a cluster the labels are built from
+type member struct {
+	Cluster    uint64
+	Address    string
+	Nickname   string
+	Type       string
+	Message    string
+	Confidence float64
+	Verified   bool
+}
+
+// Of returns the distinct clusters the addresses belong to
+func Of(db *gorm.DB, addresses []string) (clusters []uint64, err error) {
+	for from := 0; from < len(addresses); from += labelsChunk {
+		to := from + labelsChunk
+		if to > len(addresses) {
+			to = len(addresses)
+		}
+		var chunk []uint64
+		if err = db.Model(&Model{}).Distinct("cluster").Where("address IN ?", addresses[from:to]).Pluck("cluster", &chunk).Error; err != nil {
+			return
+		}
+		clusters = append(clusters, chunk...)
+	}
+	return
+}
+
+// RefreshLabels materializes the labels of the clusters from the valid tags of their members,
+// replacing the previous ones. Clusters left without members lose their labels
+func RefreshLabels(db *gorm.DB, clusters []uint64) (err error) {
+	for from := 0; from < len(clusters); from += labelsChunk {
+		to := from + labelsChunk
+		if to > len(clusters) {
+			to = len(clusters)
+		}
+		ids := clusters[from:to]
+		err = db.Transaction(func(tx *gorm.DB) error {
+			var members []member
+			if err := tx.Raw(`SELECT clusters.cluster, tags.address, tags.nickname, tags.type, tags.message, tags.confidence, tags.verified
+				FROM "tags"
+				INNER JOIN "clusters"
+				ON tags.address=clusters.address
+				WHERE clusters.cluster IN ? AND clusters.deleted_at IS NULL AND tags.deleted_at IS NULL
+				AND (tags.expires_at IS NULL OR tags.expires_at > ?)`, ids, time.Now()).Scan(&members).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("cluster IN ?", ids).Delete(&Label{}).Error; err != nil {
+				return err
+			}
+			if labels := group(members); len(labels) > 0 {
+				return tx.CreateInBatches(labels, 500).Error
+			}
+			return nil
+		})
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// group groups the members tags by cluster, nickname and type, the message standing for the nickname
+// when missing. Each label is attributed to the member carrying its most confident tag
+func group(members []member) (labels []Label) {
+	index := make(map[string]int)
+	supporters := make(map[string]map[string]bool)
+	for _, m := range members {
+		name := m.Nickname
+		if strings.TrimSpace(name) == "" {
+			name = m.Message
+		}
+		k := strings.Join([]string{
+			strconv.FormatUint(m.Cluster, 10),
+			strings.ToLower(strings.TrimSpace(name)),
+			strings.ToLower(strings.TrimSpace(m.Type)),
+		}, "\x00")
+
+		i, ok := index[k]
+		if !ok {
+			i = len(labels)
+			index[k] = i
+			supporters[k] = make(map[string]bool)
+			labels = append(labels, Label{
+				Cluster:  m.Cluster,
+				Nickname: strings.TrimSpace(m.Nickname),
+				Type:     m.Type,
+				Message:  m.Message,
+				Address:  m.Address,
+			})
+		}
+		l := &labels[i]
+		if !ok || m.Confidence > l.Confidence || (m.Confidence == l.Confidence && m.Address < l.Address) {
+			l.Address, l.Message, l.Confidence = m.Address, m.Message, m.Confidence
+		}
+		l.Verified = l.Verified || m.Verified
+		if !supporters[k][m.Address] {
+			supporters[k][m.Address] = true
+			l.Support++
+		}
+	}
+
+	sort.SliceStable(labels, func(i, j int) bool {
+		if labels[i].Cluster != labels[j].Cluster {
+			return labels[i].Cluster < labels[j].Cluster
+		}
+		if labels[i].Support != labels[j].Support {
+			return labels[i].Support > labels[j].Support
+		}
+		return labels[i].Confidence > labels[j].Confidence
+	})
+	return
+}
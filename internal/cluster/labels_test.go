@@ -0,0 +1,51 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type TestLabelsSuite struct {
+	suite.Suite
+}
+
+func (suite *TestLabelsSuite) TestGroup() {
+	members := []member{
+		{Cluster: 1, Address: "1B", Nickname: "Huobi.com", Type: "exchange", Message: "walletexplorer", Confidence: 0.7},
+		{Cluster: 1, Address: "1A", Nickname: "huobi.com ", Type: "Exchange", Message: "manual", Confidence: 0.9, Verified: true},
+		{Cluster: 1, Address: "1A", Nickname: "Huobi.com", Type: "exchange", Message: "duplicated", Confidence: 0.5},
+		{Cluster: 1, Address: "1C", Type: "abuse", Message: "Ransom payment", Confidence: 0.4},
+		{Cluster: 2, Address: "1D", Nickname: "Huobi.com", Type: "exchange", Confidence: 0.7},
+	}
+	labels := group(members)
+	require.Len(suite.T(), labels, 3)
+
+	huobi := labels[0]
+	assert.Equal(suite.T(), uint64(1), huobi.Cluster)
+	assert.Equal(suite.T(), "Huobi.com", huobi.Nickname)
+	assert.Equal(suite.T(), "1A", huobi.Address)
+	assert.Equal(suite.T(), "manual", huobi.Message)
+	assert.Equal(suite.T(), 2, huobi.Support)
+	assert.Equal(suite.T(), 0.9, huobi.Confidence)
+	assert.True(suite.T(), huobi.Verified)
+
+	ransom := labels[1]
+	assert.Equal(suite.T(), uint64(1), ransom.Cluster)
+	assert.Equal(suite.T(), "", ransom.Nickname)
+	assert.Equal(suite.T(), "Ransom payment", ransom.Message)
+	assert.Equal(suite.T(), 1, ransom.Support)
+
+	assert.Equal(suite.T(), uint64(2), labels[2].Cluster)
+	assert.Equal(suite.T(), "1D", labels[2].Address)
+}
+
+func (suite *TestLabelsSuite) TestGroupEmpty() {
+	assert.Empty(suite.T(), group(nil))
+}
+
+func TestLabels(t *testing.T) {
+	suite.Run(t, new(TestLabelsSuite))
+}
@@ -1,6 +1,8 @@
 package cluster
 
 import (
+	"time"
+
 	"github.com/gofrs/uuid"
 	_ "github.com/jinzhu/gorm/dialects/postgres"
 	"gorm.io/gorm"
@@ -10,7 +12,7 @@ import (
 type Model struct {
 	gorm.Model
 	ID      uuid.UUID `json:"id" gorm:"primarykey;index;unique"`
-	Address string    `json:"address" validate:"required,btc_addr|btc_addr_bech32" gorm:"index"`
+	Address string    `json:"address" validate:"required,btc_addr|btc_addr_bech32" gorm:"uniqueIndex:idx_clusters_unique_address"`
 	Cluster uint64    `json:"cluster" validate:"" gorm:"index"`
 } //@name Cluster
 
@@ -18,3 +20,38 @@ type Model struct {
 func (m Model) TableName() string {
 	return "clusters"
 }
+
+// Dedupe removes the duplicated rows of the addresses clusterized before the unique address index,
+// keeping the most recently updated one as the current cluster of the address
+func Dedupe(db *gorm.DB) error {
+	return db.Exec(`DELETE FROM clusters a USING clusters b
+		WHERE a.address = b.address AND (a.updated_at, a.id) < (b.updated_at, b.id)`).Error
+}
+
+// Label of a cluster materialized from the tags of its members. Address is the member carrying the
+// most confident tag of the label, Support the number of members tagged with it
+type Label struct {
+	ID         uuid.UUID `json:"id" gorm:"primarykey"`
+	Cluster    uint64    `json:"cluster" gorm:"index;not null"`
+	Nickname   string    `json:"nickname,omitempty"`
+	Type       string    `json:"type,omitempty" gorm:"index"`
+	Message    string    `json:"message,omitempty"`
+	Address    string    `json:"address" gorm:"size:64;not null"`
+	Support    int       `json:"support"`
+	Confidence float64   `json:"confidence"`
+	Verified   bool      `json:"verified"`
+	UpdatedAt  time.Time `json:"updated_at"`
+} //@name ClusterLabel
+
+// BeforeCreate generates the label id
+func (m *Label) BeforeCreate(tx *gorm.DB) (err error) {
+	if m.ID == uuid.Nil {
+		m.ID, err = uuid.NewV4()
+	}
+	return
+}
+
+// TableName defines default table name
+func (m Label) TableName() string {
+	return "cluster_labels"
+}
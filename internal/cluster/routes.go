@@ -2,18 +2,23 @@ package cluster
 
 import (
 	"net/http"
+	"strconv"
 
 	"github.com/labstack/echo/v4"
+	"github.com/xn3cr0nx/bitgodine/internal/apikey"
+	"github.com/xn3cr0nx/bitgodine/internal/ratelimit"
 	"github.com/xn3cr0nx/bitgodine/pkg/validator"
 )
 
 // Routes mounts all /clusters based routes on the main group
 func Routes(g *echo.Group, s Service) {
-	r := g.Group("/clusters", validator.JWT())
+	r := g.Group("/clusters", apikey.Auth(apikey.ReadTags), ratelimit.Middleware(ratelimit.Tags))
 
 	r.GET("", getClusters(s))
-	r.POST("", createCluster(s))
+	r.POST("", createCluster(s), validator.Role(validator.Admin))
 	r.GET("/:address", getClusterByAddress(s))
+
+	g.GET("/cluster/:id/labels", getClusterLabels(s), apikey.Auth(apikey.ReadTags), ratelimit.Middleware(ratelimit.Tags))
 }
 
 // getClusters godoc
@@ -125,3 +130,40 @@ func getClusterByAddress(s Service) func(echo.Context) error {
 		return c.JSON(http.StatusOK, clusters)
 	}
 }
+
+// getClusterLabels godoc
+// @ID get-cluster-labels
+//
+// @Router /cluster/{id}/labels [get]
+// @Summary Get cluster labels
+// @Description get the labels of the cluster with the member address carrying them and the number of members supporting them
+// @Tags clusters
+//
+// @Security ApiKeyAuth
+//
+// @Accept  json
+// @Produce  json
+//
+// @Param id path integer true "cluster id"
+//
+// @Success 200 {array} Label
+// @Success 500 {string} string
+func getClusterLabels(s Service) func(echo.Context) error {
+	return func(c echo.Context) error {
+		id := c.Param("id")
+		if err := c.Echo().Validator.(*validator.CustomValidator).Var(id, "required,numeric"); err != nil {
+			return err
+		}
+		cluster, err := strconv.ParseUint(id, 10, 64)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+
+		labels, err := s.GetLabels(cluster)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, labels)
+	}
+}
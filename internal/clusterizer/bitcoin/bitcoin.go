@@ -1,6 +1,8 @@
 package bitcoin
 
 import (
+	"sync"
+
 	"github.com/xn3cr0nx/bitgodine/internal/storage/db/postgres"
 	"github.com/xn3cr0nx/bitgodine/internal/storage/kv"
 	"github.com/xn3cr0nx/bitgodine/pkg/cache"
@@ -16,6 +18,7 @@ type Clusterizer struct {
 	cache     *cache.Cache
 	interrupt chan int
 	done      chan int
+	touched   sync.Map
 }
 
 // NewClusterizer return a new instance to Bitcoin blockchain clusterizer
@@ -8,10 +8,13 @@ import (
 	"runtime"
 	"strconv"
 	"sync"
+	"time"
 
 	mapset "github.com/deckarep/golang-set"
+	"github.com/gofrs/uuid"
 	"github.com/spf13/viper"
 	"github.com/xn3cr0nx/bitgodine/internal/block"
+	"github.com/xn3cr0nx/bitgodine/internal/cluster"
 	"github.com/xn3cr0nx/bitgodine/internal/tx"
 	"github.com/xn3cr0nx/bitgodine/pkg/logger"
 	"github.com/xn3cr0nx/bitgodine/pkg/task"
@@ -150,14 +153,42 @@ type Done struct {
 // Cluster cluster struct with validation
 type Cluster struct {
 	gorm.Model
-	Address string `json:"address" validate:"required,btc_addr|btc_addr_bech32" gorm:"index"`
+	Address string `json:"address" validate:"required,btc_addr|btc_addr_bech32" gorm:"uniqueIndex:idx_clusters_unique_address"`
 	Cluster uint64 `json:"cluster" validate:"" gorm:"index"`
 }
 
-// Work executes the sql insert
+// upsert inserts the address in the cluster or moves it there, returning the previous cluster of the address
+// only when the row is written: NULL if inserted, nothing if already in the cluster
+const upsert = `WITH previous AS (SELECT cluster FROM clusters WHERE address = @address)
+	INSERT INTO clusters (id, created_at, updated_at, address, cluster) VALUES (@id, @now, @now, @address, @cluster)
+	ON CONFLICT (address) DO UPDATE SET cluster = EXCLUDED.cluster, updated_at = EXCLUDED.updated_at, deleted_at = NULL
+	WHERE clusters.cluster <> EXCLUDED.cluster OR clusters.deleted_at IS NOT NULL
+	RETURNING (SELECT cluster FROM previous) AS previous`
+
+// Work upserts the address with the root of its cluster, moving it if merged into another cluster.
+// The clusters whose members changed are marked to refresh their labels
 func (w *Done) Work() (err error) {
-	// err = w.c.pg.DB.Save(&Cluster{Cluster: int(w.tag.(uint64)), Address: w.address.(string)}).Error
-	err = w.c.pg.DB.FirstOrCreate(&Cluster{Cluster: w.tag.(uint64), Address: w.address.(string)}, &Cluster{Cluster: w.tag.(uint64), Address: w.address.(string)}).Error
+	address, root := w.address.(string), w.c.clusters.GetParent(w.tag.(uint64))
+	id, err := uuid.NewV4()
+	if err != nil {
+		return
+	}
+	var written []struct{ Previous *uint64 }
+	if err = w.c.pg.DB.Raw(upsert, map[string]interface{}{
+		"id":      id,
+		"now":     time.Now(),
+		"address": address,
+		"cluster": root,
+	}).Scan(&written).Error; err != nil {
+		return
+	}
+	if len(written) == 0 {
+		return
+	}
+	w.c.touched.Store(root, true)
+	if previous := written[0].Previous; previous != nil && *previous != root {
+		w.c.touched.Store(*previous, true)
+	}
 	return
 }
 
@@ -187,6 +218,17 @@ func (c *Clusterizer) Done() (size uint64, err error) {
 		if err = pool.Shutdown(); err != nil {
 			return
 		}
+
+		var touched []uint64
+		c.touched.Range(func(cluster, _ interface{}) bool {
+			touched = append(touched, cluster.(uint64))
+			c.touched.Delete(cluster)
+			return true
+		})
+		logger.Info("Clusterizer", "Refreshing cluster labels", logger.Params{"clusters": len(touched)})
+		if err = cluster.RefreshLabels(c.pg.DB, touched); err != nil {
+			return
+		}
 	}
 
 	logger.Info("Clusterizer", "Exported clusters", logger.Params{"size": c.clusters.GetSize()})
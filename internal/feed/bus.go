@@ -0,0 +1,198 @@
+package feed
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/spf13/viper"
+
+	"github.com/xn3cr0nx/bitgodine/internal/errorx"
+	broker "github.com/xn3cr0nx/bitgodine/internal/storage/broker/kafka"
+	"github.com/xn3cr0nx/bitgodine/pkg/logger"
+)
+
+// DefaultTopic kafka topic the events are published to when feed.topic is not set
+const DefaultTopic = "bitgodine-feed"
+
+// DefaultBuffer events buffered for each subscriber, events are dropped for slower subscribers
+const DefaultBuffer = 1000
+
+// Bus delivers the events published to the subscribers
+type Bus interface {
+	Publish(ctx context.Context, events ...*Event) (err error)
+	Subscribe() (events <-chan *Event, cancel func())
+	Run(ctx context.Context) (err error)
+	Close()
+}
+
+// ErrNoBroker the feed published by the parser would not reach the server without a broker
+var ErrNoBroker = fmt.Errorf("%w: parser.feed.enabled requires kafka.brokers", errorx.ErrConfig)
+
+// NewPublisherBus returns the kafka bus the parser publishes the feed on, failing with ErrNoBroker
+// if kafka.brokers is not set
+func NewPublisherBus() (Bus, error) {
+	if len(viper.GetStringSlice("kafka.brokers")) == 0 {
+		return nil, ErrNoBroker
+	}
+	return NewBus()
+}
+
+// NewBus returns the kafka bus if kafka.brokers is set, the in process bus otherwise, which only
+// delivers the events published within the process
+func NewBus() (Bus, error) {
+	brokers := viper.GetStringSlice("kafka.brokers")
+	if len(brokers) == 0 {
+		return NewMemoryBus(DefaultBuffer), nil
+	}
+	topic := viper.GetString("feed.topic")
+	if topic == "" {
+		topic = DefaultTopic
+	}
+	k, err := broker.NewKafka(brokers, topic)
+	if err != nil {
+		return nil, err
+	}
+	// the feed is live, events published before the subscription are not replayed
+	if err := k.SetOffset(kafka.LastOffset); err != nil {
+		return nil, err
+	}
+	return NewKafkaBus(k, DefaultBuffer), nil
+}
+
+// MemoryBus in process bus fanning out the events to the subscribers
+type MemoryBus struct {
+	mu          sync.RWMutex
+	subscribers map[int]chan *Event
+	next        int
+	buffer      int
+	closed      bool
+}
+
+// NewMemoryBus returns an in process bus buffering up to buffer events for each subscriber
+func NewMemoryBus(buffer int) *MemoryBus {
+	return &MemoryBus{
+		subscribers: make(map[int]chan *Event),
+		buffer:      buffer,
+	}
+}
+
+// Publish delivers the events to the subscribers, dropping them for the subscribers with a full buffer
+func (b *MemoryBus) Publish(ctx context.Context, events ...*Event) (err error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for id, ch := range b.subscribers {
+		dropped := 0
+		for _, e := range events {
+			select {
+			case ch <- e:
+			default:
+				dropped++
+			}
+		}
+		if dropped > 0 {
+			logger.Warn("Feed", "slow subscriber, events dropped", logger.Params{"subscriber": id, "dropped": dropped})
+		}
+	}
+	return
+}
+
+// Subscribe returns the channel receiving the events published and the function canceling the subscription
+func (b *MemoryBus) Subscribe() (events <-chan *Event, cancel func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	ch := make(chan *Event, b.buffer)
+	if b.closed {
+		close(ch)
+		return ch, func() {}
+	}
+	id := b.next
+	b.next++
+	b.subscribers[id] = ch
+
+	var once sync.Once
+	cancel = func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			if _, ok := b.subscribers[id]; ok {
+				delete(b.subscribers, id)
+				close(ch)
+			}
+		})
+	}
+	return ch, cancel
+}
+
+// Close ends the subscriptions, closing their channels
+func (b *MemoryBus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for id, ch := range b.subscribers {
+		delete(b.subscribers, id)
+		close(ch)
+	}
+}
+
+// Run returns when ctx is done, events are published in process
+func (b *MemoryBus) Run(ctx context.Context) (err error) {
+	<-ctx.Done()
+	return
+}
+
+// Broker interface of the kafka topic the events go through
+type Broker interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+	ReadMessage(ctx context.Context) (kafka.Message, error)
+}
+
+// KafkaBus bus publishing the events to a kafka topic, delivering to the subscribers of the
+// process the events read from the topic
+type KafkaBus struct {
+	*MemoryBus
+	Broker Broker
+}
+
+// NewKafkaBus returns a bus publishing through the broker
+func NewKafkaBus(b Broker, buffer int) *KafkaBus {
+	return &KafkaBus{
+		MemoryBus: NewMemoryBus(buffer),
+		Broker:    b,
+	}
+}
+
+// Publish writes the events to the topic in a single batch, keyed by topic
+func (b *KafkaBus) Publish(ctx context.Context, events ...*Event) (err error) {
+	messages := make([]kafka.Message, len(events))
+	for i, e := range events {
+		value, e := json.Marshal(e)
+		if e != nil {
+			return e
+		}
+		messages[i] = kafka.Message{Key: []byte(events[i].Topic), Value: value}
+	}
+	return b.Broker.WriteMessages(ctx, messages...)
+}
+
+// Run reads the events from the topic delivering them to the subscribers until ctx is done.
+// Malformed messages are skipped
+func (b *KafkaBus) Run(ctx context.Context) (err error) {
+	for {
+		m, e := b.Broker.ReadMessage(ctx)
+		if e != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			return e
+		}
+		event := new(Event)
+		if e := json.Unmarshal(m.Value, event); e != nil {
+			logger.Error("Feed", e, logger.Params{"offset": m.Offset})
+			continue
+		}
+		b.MemoryBus.Publish(ctx, event)
+	}
+}
@@ -0,0 +1,128 @@
+// Package feed streams to API clients the blocks and transactions stored by the parser, with the
+// heuristics analysis of the transactions. The parser publishes the events of each block on a bus,
+// in process or through kafka when parser and server run split, and clients subscribe through
+// websocket or server sent events to the topics and the addresses and clusters they follow
+package feed
+
+import (
+	"fmt"
+
+	"github.com/xn3cr0nx/bitgodine/internal/errorx"
+)
+
+// Topics of the events
+const (
+	Blocks   = "blocks"
+	Txs      = "txs"
+	Analysis = "analysis"
+)
+
+// Topics returns the list of topics
+func Topics() []string {
+	return []string{Blocks, Txs, Analysis}
+}
+
+// Event published on the feed for a stored block, one of its transactions or their analysis
+type Event struct {
+	Topic     string      `json:"topic"`
+	Height    int32       `json:"height"`
+	BlockHash string      `json:"block_hash"`
+	Block     *BlockEvent `json:"block,omitempty"`
+	Tx        *TxEvent    `json:"tx,omitempty"`
+	Analysis  *TxAnalysis `json:"analysis,omitempty"`
+} //@name FeedEvent
+
+// BlockEvent new block stored
+type BlockEvent struct {
+	TxCount int `json:"tx_count"`
+} //@name FeedBlock
+
+// TxEvent new transaction stored, with the funds moved by each address
+type TxEvent struct {
+	TxID      string     `json:"txid"`
+	Coinbase  bool       `json:"coinbase"`
+	Coinjoin  bool       `json:"coinjoin"`
+	Movements []Movement `json:"movements"`
+	// Clusters of the addresses, resolved for the subscriptions following clusters
+	Clusters map[string]uint64 `json:"clusters,omitempty"`
+} //@name FeedTx
+
+// Movement of funds of an address, received by the output Vout or spent by the input Vout
+type Movement struct {
+	Kind    string `json:"kind"`
+	Vout    uint32 `json:"vout"`
+	Address string `json:"address"`
+	Value   int64  `json:"value"`
+} //@name FeedMovement
+
+// TxAnalysis heuristics applicable to a new transaction and the output likely to be the change
+type TxAnalysis struct {
+	Heuristics   []string `json:"heuristics"`
+	LikelyChange *uint32  `json:"likely_change,omitempty"`
+} //@name FeedAnalysis
+
+// Subscription topics and entities followed by a client. Transactions and analysis events are
+// filtered by the addresses and clusters followed, all of them are streamed if none is followed
+type Subscription struct {
+	Topics    []string `json:"topics" query:"topics" validate:"omitempty,dive,oneof=blocks txs analysis"`
+	Addresses []string `json:"addresses" query:"addresses" validate:"omitempty,dive,btc_addr|btc_addr_bech32"`
+	Clusters  []uint64 `json:"clusters" query:"clusters"`
+} //@name FeedSubscription
+
+// filter compiled subscription
+type filter struct {
+	topics    map[string]bool
+	addresses map[string]bool
+	clusters  map[uint64]bool
+}
+
+// compile checks the subscription returning its filter, all topics if none is set
+func (s *Subscription) compile() (f *filter, err error) {
+	f = &filter{
+		topics:    make(map[string]bool),
+		addresses: make(map[string]bool),
+		clusters:  make(map[uint64]bool),
+	}
+	topics := s.Topics
+	if len(topics) == 0 {
+		topics = Topics()
+	}
+	for _, t := range topics {
+		if t != Blocks && t != Txs && t != Analysis {
+			return nil, fmt.Errorf("%w: topic %s", errorx.ErrInvalidArgument, t)
+		}
+		f.topics[t] = true
+	}
+	for _, a := range s.Addresses {
+		f.addresses[a] = true
+	}
+	for _, c := range s.Clusters {
+		f.clusters[c] = true
+	}
+	return
+}
+
+// follows returns true if the filter follows any address or cluster
+func (f *filter) follows() bool {
+	return len(f.addresses) > 0 || len(f.clusters) > 0
+}
+
+// match returns true if the event is part of the subscription. Clusters of the transaction
+// addresses must be already resolved to match the followed clusters
+func (f *filter) match(e *Event) bool {
+	if !f.topics[e.Topic] {
+		return false
+	}
+	if e.Tx == nil || !f.follows() {
+		return true
+	}
+	for _, m := range e.Tx.Movements {
+		if f.addresses[m.Address] {
+			return true
+		}
+		if c, ok := e.Tx.Clusters[m.Address]; ok && f.clusters[c] {
+			return true
+		}
+	}
+	return false
+}
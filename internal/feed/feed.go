@@ -0,0 +1,153 @@
+package feed
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/xn3cr0nx/bitgodine/internal/cluster"
+	"github.com/xn3cr0nx/bitgodine/internal/errorx"
+	"github.com/xn3cr0nx/bitgodine/internal/storage/db/postgres"
+	"github.com/xn3cr0nx/bitgodine/pkg/cache"
+	"github.com/xn3cr0nx/bitgodine/pkg/logger"
+)
+
+// clustersTTL expiration of the cached clusters of the addresses
+const clustersTTL = time.Minute
+
+// Service interface exports available methods for feed service
+type Service interface {
+	Subscribe(ctx context.Context, sub *Subscription) (stream *Stream, err error)
+}
+
+type service struct {
+	Repository *postgres.Pg
+	Cache      *cache.Cache
+	Bus        Bus
+}
+
+// NewService instantiates a new Service layer for feed streaming the events of the bus
+func NewService(r *postgres.Pg, c *cache.Cache, b Bus) *service {
+	return &service{
+		Repository: r,
+		Cache:      c,
+		Bus:        b,
+	}
+}
+
+// Stream events of a subscription, closed when the context of the subscription is done
+type Stream struct {
+	Events <-chan *Event
+
+	mu     sync.RWMutex
+	filter *filter
+}
+
+// Update replaces the subscription of the stream
+func (s *Stream) Update(sub *Subscription) error {
+	f, err := sub.compile()
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.filter = f
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *Stream) current() *filter {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.filter
+}
+
+// Subscribe returns the stream of the events matching the subscription until ctx is done.
+// Events are dropped if the stream is not consumed fast enough
+func (s *service) Subscribe(ctx context.Context, sub *Subscription) (stream *Stream, err error) {
+	f, err := sub.compile()
+	if err != nil {
+		return
+	}
+	events, cancel := s.Bus.Subscribe()
+	out := make(chan *Event, DefaultBuffer)
+	stream = &Stream{Events: out, filter: f}
+
+	go func() {
+		defer close(out)
+		defer cancel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case e, ok := <-events:
+				if !ok {
+					return
+				}
+				f := stream.current()
+				if len(f.clusters) > 0 && e.Tx != nil {
+					clustered, err := s.withClusters(e)
+					if err != nil {
+						logger.Error("Feed", err, logger.Params{"txid": e.Tx.TxID})
+						continue
+					}
+					e = clustered
+				}
+				if !f.match(e) {
+					continue
+				}
+				select {
+				case out <- e:
+				default:
+					logger.Warn("Feed", "slow stream, event dropped", logger.Params{"topic": e.Topic})
+				}
+			}
+		}
+	}()
+	return
+}
+
+// withClusters returns a copy of the event with the clusters of the transaction addresses
+func (s *service) withClusters(e *Event) (*Event, error) {
+	if e.Tx.Clusters != nil || s.Repository == nil || s.Repository.DB == nil {
+		return e, nil
+	}
+	clusters := make(map[string]uint64)
+	var missing []string
+	for _, m := range e.Tx.Movements {
+		if _, ok := clusters[m.Address]; ok {
+			continue
+		}
+		if cached, ok := s.Cache.Get("fc_" + m.Address); ok {
+			if c, ok := cached.(uint64); ok {
+				clusters[m.Address] = c
+			}
+			continue
+		}
+		missing = append(missing, m.Address)
+	}
+	if len(missing) > 0 {
+		var found []cluster.Model
+		if err := s.Repository.Select("address, cluster").Where("address IN ?", missing).Find(&found).Error; err != nil {
+			return e, err
+		}
+		for _, c := range found {
+			clusters[c.Address] = c.Cluster
+		}
+		for _, address := range missing {
+			// addresses not clustered are cached too, not to look them up again for each transaction
+			var value interface{} = false
+			if c, ok := clusters[address]; ok {
+				value = c
+			}
+			if !s.Cache.SetWithTTL("fc_"+address, value, 1, clustersTTL) {
+				logger.Error("Feed", errorx.ErrCache, logger.Params{"address": address})
+			}
+		}
+	}
+
+	tx := *e.Tx
+	tx.Clusters = clusters
+	copied := *e
+	copied.Tx = &tx
+	return &copied, nil
+}
@@ -0,0 +1,244 @@
+package feed
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/xn3cr0nx/bitgodine/internal/block"
+	"github.com/xn3cr0nx/bitgodine/internal/errorx"
+	"github.com/xn3cr0nx/bitgodine/internal/storage/kv"
+	"github.com/xn3cr0nx/bitgodine/internal/tx"
+	"github.com/xn3cr0nx/bitgodine/pkg/cache"
+	"github.com/xn3cr0nx/bitgodine/pkg/encoding"
+	"github.com/xn3cr0nx/bitgodine/pkg/logger"
+)
+
+type TestFeedSuite struct {
+	suite.Suite
+	db     *kv.DBMock
+	cache  *cache.Cache
+	source tx.Tx
+	spend  tx.Tx
+}
+
+func txid(c string) string {
+	return strings.Repeat(c, 64)
+}
+
+// fakeBroker in memory kafka topic
+type fakeBroker struct {
+	messages chan kafka.Message
+}
+
+func (b *fakeBroker) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	for _, m := range msgs {
+		b.messages <- m
+	}
+	return nil
+}
+
+func (b *fakeBroker) ReadMessage(ctx context.Context) (kafka.Message, error) {
+	select {
+	case m := <-b.messages:
+		return m, nil
+	case <-ctx.Done():
+		return kafka.Message{}, ctx.Err()
+	}
+}
+
+func (suite *TestFeedSuite) SetupTest() {
+	logger.Setup()
+
+	c, err := cache.NewCache(nil)
+	require.Nil(suite.T(), err)
+	suite.cache = c
+	suite.db = kv.NewDBMock()
+
+	suite.source = tx.Tx{
+		TxID: txid("a"),
+		Vin:  []tx.Input{{IsCoinbase: true}},
+		Vout: []tx.Output{{ScriptpubkeyAddress: "1Source", Value: 100000}},
+	}
+	suite.spend = tx.Tx{
+		TxID: txid("b"),
+		Vin:  []tx.Input{{TxID: suite.source.TxID, Vout: 0}},
+		Vout: []tx.Output{{ScriptpubkeyAddress: "1Dest", Value: 90000}},
+	}
+	b, err := encoding.Marshal(suite.source)
+	require.Nil(suite.T(), err)
+	suite.db.On("Read", suite.source.TxID).Return(b, nil)
+	suite.db.On("Read", mock.Anything).Return(nil, errorx.ErrKeyNotFound)
+}
+
+func (suite *TestFeedSuite) receive(events <-chan *Event) *Event {
+	select {
+	case e := <-events:
+		return e
+	case <-time.After(time.Second):
+		suite.FailNow("event not received")
+		return nil
+	}
+}
+
+func (suite *TestFeedSuite) TestCompile() {
+	f, err := (&Subscription{}).compile()
+	require.Nil(suite.T(), err)
+	assert.Len(suite.T(), f.topics, 3)
+	assert.False(suite.T(), f.follows())
+
+	_, err = (&Subscription{Topics: []string{"mempool"}}).compile()
+	assert.True(suite.T(), errors.Is(err, errorx.ErrInvalidArgument))
+}
+
+func (suite *TestFeedSuite) TestMatch() {
+	block := &Event{Topic: Blocks, Block: &BlockEvent{TxCount: 1}}
+	transaction := &Event{Topic: Txs, Tx: &TxEvent{
+		TxID:      suite.spend.TxID,
+		Movements: []Movement{{Kind: "received", Address: "1Dest"}},
+		Clusters:  map[string]uint64{"1Dest": 7},
+	}}
+
+	f, err := (&Subscription{Topics: []string{Txs}}).compile()
+	require.Nil(suite.T(), err)
+	assert.False(suite.T(), f.match(block))
+	assert.True(suite.T(), f.match(transaction))
+
+	f, err = (&Subscription{Addresses: []string{"1Other"}}).compile()
+	require.Nil(suite.T(), err)
+	assert.True(suite.T(), f.match(block))
+	assert.False(suite.T(), f.match(transaction))
+
+	f, err = (&Subscription{Addresses: []string{"1Dest"}}).compile()
+	require.Nil(suite.T(), err)
+	assert.True(suite.T(), f.match(transaction))
+
+	f, err = (&Subscription{Clusters: []uint64{7}}).compile()
+	require.Nil(suite.T(), err)
+	assert.True(suite.T(), f.match(transaction))
+}
+
+func (suite *TestFeedSuite) TestMemoryBus() {
+	bus := NewMemoryBus(1)
+	first, cancelFirst := bus.Subscribe()
+	second, cancelSecond := bus.Subscribe()
+	defer cancelSecond()
+
+	require.Nil(suite.T(), bus.Publish(context.Background(), &Event{Topic: Blocks, Height: 1}, &Event{Topic: Blocks, Height: 2}))
+	// the second event is dropped, the buffer holds a single event
+	assert.Equal(suite.T(), int32(1), suite.receive(first).Height)
+	assert.Equal(suite.T(), int32(1), suite.receive(second).Height)
+
+	cancelFirst()
+	cancelFirst()
+	_, ok := <-first
+	assert.False(suite.T(), ok)
+
+	bus.Close()
+	_, ok = <-second
+	assert.False(suite.T(), ok)
+	closed, _ := bus.Subscribe()
+	_, ok = <-closed
+	assert.False(suite.T(), ok)
+}
+
+func (suite *TestFeedSuite) TestKafkaBus() {
+	bus := NewKafkaBus(&fakeBroker{messages: make(chan kafka.Message, 10)}, 10)
+	events, cancel := bus.Subscribe()
+	defer cancel()
+
+	ctx, stop := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- bus.Run(ctx)
+	}()
+
+	require.Nil(suite.T(), bus.Publish(ctx, &Event{Topic: Blocks, Height: 3, Block: &BlockEvent{TxCount: 2}}))
+	e := suite.receive(events)
+	assert.Equal(suite.T(), int32(3), e.Height)
+	assert.Equal(suite.T(), 2, e.Block.TxCount)
+
+	stop()
+	assert.Nil(suite.T(), <-done)
+}
+
+func (suite *TestFeedSuite) TestEvents() {
+	l := NewListener(NewMemoryBus(10), block.NewService(suite.db, suite.cache), suite.db, suite.cache, false)
+	events := l.Events(2, txid("f"), []tx.Tx{suite.spend})
+	require.Len(suite.T(), events, 2)
+
+	assert.Equal(suite.T(), Blocks, events[0].Topic)
+	assert.Equal(suite.T(), 1, events[0].Block.TxCount)
+
+	e := events[1]
+	assert.Equal(suite.T(), Txs, e.Topic)
+	assert.Equal(suite.T(), suite.spend.TxID, e.Tx.TxID)
+	assert.False(suite.T(), e.Tx.Coinbase)
+	assert.ElementsMatch(suite.T(), []Movement{
+		{Kind: "spent", Vout: 0, Address: "1Source", Value: 100000},
+		{Kind: "received", Vout: 0, Address: "1Dest", Value: 90000},
+	}, e.Tx.Movements)
+	assert.Nil(suite.T(), events[1].Analysis)
+}
+
+func (suite *TestFeedSuite) TestOnBlockSyncing() {
+	synced := false
+	l := NewListener(NewMemoryBus(10), block.NewService(suite.db, suite.cache), suite.db, suite.cache, true)
+	l.Synced = func() bool { return synced }
+
+	// blocks stored while syncing are neither flushed nor analyzed
+	require.Nil(suite.T(), l.OnBlock(2, txid("f"), []tx.Tx{suite.source, suite.spend}))
+	suite.db.AssertNotCalled(suite.T(), "Flush")
+	assert.Len(suite.T(), l.queue, 0)
+
+	synced = true
+	suite.db.On("Flush").Return(nil)
+	require.Nil(suite.T(), l.OnBlock(2, txid("f"), []tx.Tx{suite.source, suite.spend}))
+	suite.db.AssertCalled(suite.T(), "Flush")
+	require.Len(suite.T(), l.queue, 1)
+	assert.Len(suite.T(), (<-l.queue).txs, 2)
+}
+
+func (suite *TestFeedSuite) TestSubscribe() {
+	bus := NewMemoryBus(10)
+	s := NewService(nil, suite.cache, bus)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := s.Subscribe(ctx, &Subscription{Addresses: []string{"1Dest"}})
+	require.Nil(suite.T(), err)
+
+	l := NewListener(bus, block.NewService(suite.db, suite.cache), suite.db, suite.cache, false)
+	require.Nil(suite.T(), l.OnBlock(2, txid("f"), []tx.Tx{suite.source, suite.spend}))
+
+	// the block and the transaction of the followed address, the source one is filtered
+	assert.Equal(suite.T(), Blocks, suite.receive(stream.Events).Topic)
+	e := suite.receive(stream.Events)
+	assert.Equal(suite.T(), suite.spend.TxID, e.Tx.TxID)
+
+	require.Nil(suite.T(), stream.Update(&Subscription{Topics: []string{Txs}, Addresses: []string{"1Source"}}))
+	require.Nil(suite.T(), l.OnBlock(2, txid("f"), []tx.Tx{suite.source, suite.spend}))
+	assert.Equal(suite.T(), suite.source.TxID, suite.receive(stream.Events).Tx.TxID)
+	assert.Equal(suite.T(), suite.spend.TxID, suite.receive(stream.Events).Tx.TxID)
+
+	b, err := json.Marshal(e)
+	require.Nil(suite.T(), err)
+	assert.Contains(suite.T(), string(b), `"topic":"txs"`)
+
+	cancel()
+	for range stream.Events {
+	}
+}
+
+func TestFeed(t *testing.T) {
+	suite.Run(t, new(TestFeedSuite))
+}
@@ -0,0 +1,169 @@
+package feed
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/xn3cr0nx/bitgodine/internal/analysis"
+	"github.com/xn3cr0nx/bitgodine/internal/block"
+	"github.com/xn3cr0nx/bitgodine/internal/heuristics"
+	"github.com/xn3cr0nx/bitgodine/internal/storage/kv"
+	"github.com/xn3cr0nx/bitgodine/internal/tx"
+	"github.com/xn3cr0nx/bitgodine/internal/watchlist"
+	"github.com/xn3cr0nx/bitgodine/pkg/cache"
+	"github.com/xn3cr0nx/bitgodine/pkg/logger"
+)
+
+const (
+	// DefaultPublishTimeout default time given to the bus to publish the events of a block
+	DefaultPublishTimeout = 30 * time.Second
+	// AnalysisQueue blocks waiting for their transactions to be analyzed, further ones are not analyzed
+	AnalysisQueue = 100
+)
+
+// ErrAnalysisQueueFull the block couldn't be queued for analysis
+var ErrAnalysisQueueFull = errors.New("analysis queue full")
+
+// Listener publishes on the bus the events of each block stored by the parser
+type Listener struct {
+	Bus     Bus
+	Blocks  block.Service
+	Kv      kv.DB
+	Cache   *cache.Cache
+	Timeout time.Duration
+	// Analyze publishes the heuristics analysis of the transactions
+	Analyze bool
+	// Synced tells whether the parser caught up with the chain, blocks stored while syncing are not
+	// analyzed. Every block is analyzed if not set
+	Synced func() bool
+	queue  chan pending
+}
+
+// pending block waiting for the analysis of its transactions
+type pending struct {
+	height int32
+	hash   string
+	txs    []*TxEvent
+}
+
+// NewListener returns a listener publishing on the bus, with the analysis of transactions if analyze.
+// Spent outputs are resolved through the block service the parser stores the blocks with
+func NewListener(b Bus, blocks block.Service, db kv.DB, c *cache.Cache, analyze bool) *Listener {
+	return &Listener{
+		Bus:     b,
+		Blocks:  blocks,
+		Kv:      db,
+		Cache:   c,
+		Timeout: DefaultPublishTimeout,
+		Analyze: analyze,
+		queue:   make(chan pending, AnalysisQueue),
+	}
+}
+
+// Start runs the worker analyzing the queued blocks, in the order they are stored
+func (l *Listener) Start() {
+	go func() {
+		for p := range l.queue {
+			if err := l.publish(l.Analyses(p.height, p.hash, p.txs)...); err != nil {
+				logger.Error("Feed", err, logger.Params{"hash": p.hash, "height": p.height})
+			}
+		}
+	}()
+}
+
+// OnBlock publishes the events of the block, all of them in a single batch, queueing the block for
+// the analysis of its transactions once synced. The analysis reads the transactions and the outputs
+// they spend from the store, so the entries queued by the parser are flushed first
+func (l *Listener) OnBlock(height int32, hash string, transactions []tx.Tx) (err error) {
+	events := l.Events(height, hash, transactions)
+	if err = l.publish(events...); err != nil {
+		return
+	}
+	if !l.Analyze || (l.Synced != nil && !l.Synced()) {
+		return
+	}
+	if err = l.Kv.Flush(); err != nil {
+		return
+	}
+	txs := make([]*TxEvent, 0, len(transactions))
+	for _, e := range events {
+		if e.Topic == Txs {
+			txs = append(txs, e.Tx)
+		}
+	}
+	select {
+	case l.queue <- pending{height, hash, txs}:
+	default:
+		err = ErrAnalysisQueueFull
+	}
+	return
+}
+
+// publish publishes the events on the bus within the timeout
+func (l *Listener) publish(events ...*Event) (err error) {
+	if len(events) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), l.Timeout)
+	defer cancel()
+	return l.Bus.Publish(ctx, events...)
+}
+
+// Events returns the block event followed by the events of each transaction
+func (l *Listener) Events(height int32, hash string, transactions []tx.Tx) (events []*Event) {
+	movements := watchlist.Events(height, hash, transactions, l.Blocks.Prevout(transactions))
+	byTx := make(map[string][]Movement)
+	for _, m := range movements {
+		byTx[m.TxID] = append(byTx[m.TxID], Movement{Kind: m.Kind, Vout: m.Vout, Address: m.Address, Value: m.Value})
+	}
+
+	events = append(events, &Event{Topic: Blocks, Height: height, BlockHash: hash, Block: &BlockEvent{TxCount: len(transactions)}})
+	for _, transaction := range transactions {
+		events = append(events, &Event{Topic: Txs, Height: height, BlockHash: hash, Tx: &TxEvent{
+			TxID:      transaction.TxID,
+			Coinbase:  len(transaction.Vin) > 0 && transaction.Vin[0].IsCoinbase,
+			Coinjoin:  transaction.IsCoinjoin(),
+			Movements: byTx[transaction.TxID],
+		}})
+	}
+	return
+}
+
+// Analyses returns the analysis events of the transactions of the block, coinbase ones excluded
+func (l *Listener) Analyses(height int32, hash string, txs []*TxEvent) (events []*Event) {
+	service := analysis.NewService(l.Kv, l.Cache)
+	for _, txEvent := range txs {
+		if txEvent.Coinbase {
+			continue
+		}
+		a, err := analyze(service, txEvent.TxID)
+		if err != nil {
+			logger.Error("Feed", err, logger.Params{"txid": txEvent.TxID})
+			continue
+		}
+		events = append(events, &Event{Topic: Analysis, Height: height, BlockHash: hash, Tx: txEvent, Analysis: a})
+	}
+	return
+}
+
+// analyze applies all the heuristics to the transaction
+func analyze(service analysis.Service, txid string) (a *TxAnalysis, err error) {
+	all := heuristics.FromListToMask(heuristics.List())
+	applicable, err := service.AnalyzeTx(txid, all, "applicability")
+	if err != nil {
+		return
+	}
+	reliable, err := service.AnalyzeTx(txid, all, "reliability")
+	if err != nil {
+		return
+	}
+	a = &TxAnalysis{Heuristics: applicable.(heuristics.Mask).ToHeuristicsList()}
+	if a.Heuristics == nil {
+		a.Heuristics = []string{}
+	}
+	if vout, e := analysis.ExtractLikelihoodOutput(reliable.(heuristics.Map)); e == nil {
+		a.LikelyChange = &vout
+	}
+	return
+}
@@ -0,0 +1,192 @@
+package feed
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+	"github.com/spf13/viper"
+
+	"github.com/xn3cr0nx/bitgodine/internal/apikey"
+	"github.com/xn3cr0nx/bitgodine/internal/errorx"
+	"github.com/xn3cr0nx/bitgodine/internal/ratelimit"
+	"github.com/xn3cr0nx/bitgodine/pkg/validator"
+)
+
+// keepAlive interval of the pings keeping idle connections open
+const keepAlive = 30 * time.Second
+
+// Routes mounts /feed based routes on the main group
+func Routes(g *echo.Group, s Service) {
+	r := g.Group("/feed", apikey.Auth(apikey.ReadBlocks), ratelimit.Middleware(ratelimit.Blocks))
+	r.GET("/sse", sse(s))
+	r.GET("/ws", ws(s))
+}
+
+// subscribe binds the subscription of the query and subscribes to the feed for the lifetime of the request
+func subscribe(c echo.Context, s Service) (*Stream, error) {
+	sub := new(Subscription)
+	if err := validator.Struct(&c, sub); err != nil {
+		return nil, err
+	}
+	stream, err := s.Subscribe(c.Request().Context(), sub)
+	if err != nil {
+		if errors.Is(err, errorx.ErrInvalidArgument) {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		return nil, err
+	}
+	return stream, nil
+}
+
+// sse godoc
+// @ID feed-sse
+//
+// @Router /feed/sse [get]
+// @Summary Live feed as server sent events
+// @Description stream the new blocks, the transactions touching the addresses and clusters followed and their heuristics analysis. Each event is named after its topic. Transactions and analysis of all the addresses are streamed if none is followed
+// @Tags feed
+//
+// @Security ApiKeyAuth
+//
+// @Produce  text/event-stream
+//
+// @Param topics query []string false "Topics, all if not set" collectionFormat(multi) Enums(blocks, txs, analysis)
+// @Param addresses query []string false "Addresses followed" collectionFormat(multi)
+// @Param clusters query []int false "Clusters followed" collectionFormat(multi)
+//
+// @Success 200 {object} Event
+// @Success 400 {string} string
+// @Success 500 {string} string
+func sse(s Service) func(echo.Context) error {
+	return func(c echo.Context) error {
+		stream, err := subscribe(c, s)
+		if err != nil {
+			return err
+		}
+
+		res := c.Response()
+		res.Header().Set(echo.HeaderContentType, "text/event-stream")
+		res.Header().Set("Cache-Control", "no-cache")
+		res.Header().Set("Connection", "keep-alive")
+		res.WriteHeader(http.StatusOK)
+		res.Flush()
+
+		ticker := time.NewTicker(keepAlive)
+		defer ticker.Stop()
+		for {
+			select {
+			case e, ok := <-stream.Events:
+				if !ok {
+					return nil
+				}
+				data, err := json.Marshal(e)
+				if err != nil {
+					return err
+				}
+				if _, err := fmt.Fprintf(res, "event: %s\ndata: %s\n\n", e.Topic, data); err != nil {
+					return nil
+				}
+			case <-ticker.C:
+				if _, err := fmt.Fprint(res, ": ping\n\n"); err != nil {
+					return nil
+				}
+			}
+			res.Flush()
+		}
+	}
+}
+
+// ws godoc
+// @ID feed-ws
+//
+// @Router /feed/ws [get]
+// @Summary Live feed through websocket
+// @Description stream as json messages the new blocks, the transactions touching the addresses and clusters followed and their heuristics analysis. The subscription can be replaced sending it as a json message
+// @Tags feed
+//
+// @Security ApiKeyAuth
+//
+// @Param topics query []string false "Topics, all if not set" collectionFormat(multi) Enums(blocks, txs, analysis)
+// @Param addresses query []string false "Addresses followed" collectionFormat(multi)
+// @Param clusters query []int false "Clusters followed" collectionFormat(multi)
+//
+// @Success 101 {object} Event
+// @Success 400 {string} string
+// @Success 500 {string} string
+func ws(s Service) func(echo.Context) error {
+	upgrader := websocket.Upgrader{CheckOrigin: checkOrigin}
+	return func(c echo.Context) error {
+		stream, err := subscribe(c, s)
+		if err != nil {
+			return err
+		}
+		conn, err := upgrader.Upgrade(c.Response(), c.Request(), nil)
+		if err != nil {
+			// the upgrader already replied with the error
+			return nil
+		}
+		defer conn.Close()
+
+		// the reader replaces the subscription with the ones received, the connection is done on read errors
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for {
+				sub := new(Subscription)
+				if err := conn.ReadJSON(sub); err != nil {
+					var syntax *json.SyntaxError
+					if errors.As(err, &syntax) {
+						continue
+					}
+					return
+				}
+				if err := c.Validate(sub); err == nil {
+					err = stream.Update(sub)
+				}
+				if err != nil {
+					conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseUnsupportedData, err.Error()), time.Now().Add(time.Second))
+					return
+				}
+			}
+		}()
+
+		ticker := time.NewTicker(keepAlive)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return nil
+			case e, ok := <-stream.Events:
+				if !ok {
+					return nil
+				}
+				if err := conn.WriteJSON(e); err != nil {
+					return nil
+				}
+			case <-ticker.C:
+				if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(keepAlive)); err != nil {
+					return nil
+				}
+			}
+		}
+	}
+}
+
+// checkOrigin allows the requests without origin and the ones from the origins allowed by CORS
+func checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get(echo.HeaderOrigin)
+	if origin == "" {
+		return true
+	}
+	for _, allowed := range viper.GetStringSlice("auth.cors") {
+		if allowed == "*" || allowed == origin {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,187 @@
+package gql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/spf13/viper"
+	"github.com/xn3cr0nx/bitgodine/internal/errorx"
+)
+
+// Default limits of the queries
+const (
+	DefaultComplexity = 1000
+	DefaultDepth      = 10
+	// DefaultPage items returned by paginated lists when first is not set
+	DefaultPage = 10
+	// MaxPage items returned at most by paginated lists
+	MaxPage = 100
+)
+
+// ErrTooComplex the query exceeds the complexity or depth limits
+var ErrTooComplex = fmt.Errorf("%w: query too complex", errorx.ErrInvalidArgument)
+
+// Limits of the queries accepted, protecting the kv store from queries expanding to large parts of the graph
+type Limits struct {
+	Complexity int
+	Depth      int
+}
+
+// Conf returns the limits set as server.graphql.complexity and server.graphql.depth, the defaults if not set
+func Conf() *Limits {
+	limits := &Limits{
+		Complexity: viper.GetInt("server.graphql.complexity"),
+		Depth:      viper.GetInt("server.graphql.depth"),
+	}
+	if limits.Complexity <= 0 {
+		limits.Complexity = DefaultComplexity
+	}
+	if limits.Depth <= 0 {
+		limits.Depth = DefaultDepth
+	}
+	return limits
+}
+
+// weights cost of the fields more expensive than a lookup
+var weights = map[string]int{
+	"analysis": 50,
+	"block":    2,
+}
+
+// fanout estimated items of the lists without pagination
+var fanout = map[string]int{
+	"tags":   5,
+	"abuses": 5,
+	"labels": 5,
+}
+
+// paginated lists, returning first items
+var paginated = map[string]bool{
+	"transactions": true,
+	"addresses":    true,
+	"inputs":       true,
+	"outputs":      true,
+}
+
+// measure walks an operation of the document computing its complexity and depth. Each field costs its
+// weight plus the cost of its selection times the items it returns. Introspection fields are free
+type measure struct {
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]interface{}
+	visiting  map[string]bool
+}
+
+// Measure returns the complexity and depth of the operation of the document, the only one if name is empty
+func Measure(doc *ast.Document, name string, variables map[string]interface{}) (complexity, depth int, err error) {
+	m := &measure{
+		fragments: make(map[string]*ast.FragmentDefinition),
+		variables: variables,
+		visiting:  make(map[string]bool),
+	}
+	var operation *ast.OperationDefinition
+	for _, def := range doc.Definitions {
+		switch d := def.(type) {
+		case *ast.FragmentDefinition:
+			m.fragments[d.Name.Value] = d
+		case *ast.OperationDefinition:
+			if name == "" && operation == nil || d.Name != nil && d.Name.Value == name {
+				operation = d
+			}
+		}
+	}
+	if operation == nil {
+		err = fmt.Errorf("%w: operation %s not found", errorx.ErrInvalidArgument, name)
+		return
+	}
+	complexity, depth = m.selection(operation.SelectionSet)
+	return
+}
+
+// Check returns ErrTooComplex if the operation exceeds the limits
+func (l *Limits) Check(doc *ast.Document, name string, variables map[string]interface{}) error {
+	complexity, depth, err := Measure(doc, name, variables)
+	if err != nil {
+		return err
+	}
+	if complexity > l.Complexity {
+		return fmt.Errorf("%w: complexity %d over %d", ErrTooComplex, complexity, l.Complexity)
+	}
+	if depth > l.Depth {
+		return fmt.Errorf("%w: depth %d over %d", ErrTooComplex, depth, l.Depth)
+	}
+	return nil
+}
+
+func (m *measure) selection(set *ast.SelectionSet) (complexity, depth int) {
+	if set == nil {
+		return
+	}
+	for _, s := range set.Selections {
+		var c, d int
+		switch sel := s.(type) {
+		case *ast.Field:
+			if strings.HasPrefix(sel.Name.Value, "__") {
+				continue
+			}
+			c, d = m.selection(sel.SelectionSet)
+			weight, ok := weights[sel.Name.Value]
+			if !ok {
+				weight = 1
+			}
+			c = weight + m.items(sel)*c
+			d++
+		case *ast.InlineFragment:
+			c, d = m.selection(sel.SelectionSet)
+		case *ast.FragmentSpread:
+			fragment, ok := m.fragments[sel.Name.Value]
+			if !ok || m.visiting[sel.Name.Value] {
+				continue
+			}
+			m.visiting[sel.Name.Value] = true
+			c, d = m.selection(fragment.SelectionSet)
+			m.visiting[sel.Name.Value] = false
+		}
+		complexity += c
+		if d > depth {
+			depth = d
+		}
+	}
+	return
+}
+
+// items returns the items the field is expected to return
+func (m *measure) items(field *ast.Field) int {
+	if n, ok := fanout[field.Name.Value]; ok {
+		return n
+	}
+	if !paginated[field.Name.Value] {
+		return 1
+	}
+	first := DefaultPage
+	for _, arg := range field.Arguments {
+		if arg.Name.Value != "first" {
+			continue
+		}
+		switch v := arg.Value.(type) {
+		case *ast.IntValue:
+			if n, err := strconv.Atoi(v.Value); err == nil {
+				first = n
+			}
+		case *ast.Variable:
+			if n, ok := m.variables[v.Name.Value].(float64); ok {
+				first = int(n)
+			} else if n, ok := m.variables[v.Name.Value].(int); ok {
+				first = n
+			}
+		}
+	}
+	if first < 1 {
+		first = 1
+	}
+	if first > MaxPage {
+		first = MaxPage
+	}
+	return first
+}
@@ -0,0 +1,94 @@
+package gql
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/xn3cr0nx/bitgodine/internal/errorx"
+)
+
+type TestComplexitySuite struct {
+	suite.Suite
+}
+
+func (suite *TestComplexitySuite) parse(query string) *ast.Document {
+	doc, err := parser.Parse(parser.ParseParams{Source: query})
+	require.Nil(suite.T(), err)
+	return doc
+}
+
+func (suite *TestComplexitySuite) TestMeasure() {
+	doc := suite.parse(`{ tx(txid: "a") { txid inputs { prevout { value address { tags { type } } } } } }`)
+	complexity, depth, err := Measure(doc, "", nil)
+	require.Nil(suite.T(), err)
+	// tx 1 + txid 1 + inputs 1 + 10 * (prevout 1 + value 1 + address 1 + tags 1 + 5 * type 1)
+	assert.Equal(suite.T(), 3+DefaultPage*(4+5), complexity)
+	assert.Equal(suite.T(), 6, depth)
+}
+
+func (suite *TestComplexitySuite) TestMeasurePaginated() {
+	doc := suite.parse(`query($n: Int) {
+		block(height: 1) { transactions(first: 50) { txid } }
+		address(address: "1A") { transactions(first: $n) { txid } }
+		cluster(id: "1") { addresses(first: 1000) { address } }
+	}`)
+	complexity, _, err := Measure(doc, "", map[string]interface{}{"n": float64(20)})
+	require.Nil(suite.T(), err)
+	assert.Equal(suite.T(), (2+1+50)+(1+1+20)+(1+1+MaxPage), complexity)
+
+	complexity, _, err = Measure(suite.parse(`{ address(address: "1A") { transactions { txid } } }`), "", nil)
+	require.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 2+DefaultPage, complexity)
+
+	complexity, _, err = Measure(suite.parse(`{ tx(txid: "a") { outputs(first: 1000) { value } inputs(first: 2) { vout } } }`), "", nil)
+	require.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 1+(1+MaxPage)+(1+2), complexity)
+}
+
+func (suite *TestComplexitySuite) TestMeasureFragments() {
+	doc := suite.parse(`
+		query Outputs { tx(txid: "a") { ...out ... on Tx { analysis { txid } } } }
+		query Other { tx(txid: "b") { txid } }
+		fragment out on Tx { outputs { value } }
+	`)
+	complexity, depth, err := Measure(doc, "Outputs", nil)
+	require.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 1+(1+DefaultPage)+(50+1), complexity)
+	assert.Equal(suite.T(), 3, depth)
+
+	complexity, _, err = Measure(doc, "Other", nil)
+	require.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 2, complexity)
+
+	_, _, err = Measure(doc, "Missing", nil)
+	assert.True(suite.T(), errors.Is(err, errorx.ErrInvalidArgument))
+}
+
+func (suite *TestComplexitySuite) TestMeasureIntrospection() {
+	complexity, depth, err := Measure(suite.parse(`{ __schema { types { name fields { name } } } }`), "", nil)
+	require.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 0, complexity)
+	assert.Equal(suite.T(), 0, depth)
+}
+
+func (suite *TestComplexitySuite) TestCheck() {
+	limits := &Limits{Complexity: 30, Depth: 4}
+	assert.Nil(suite.T(), limits.Check(suite.parse(`{ tx(txid: "a") { outputs { value } } }`), "", nil))
+
+	err := limits.Check(suite.parse(`{ tx(txid: "a") { analysis { txid } } }`), "", nil)
+	assert.True(suite.T(), errors.Is(err, ErrTooComplex))
+
+	err = limits.Check(suite.parse(`{ tx(txid: "a") { inputs(first: 1) { prevout { spending { txid } } } } }`), "", nil)
+	assert.True(suite.T(), errors.Is(err, ErrTooComplex))
+	assert.Contains(suite.T(), err.Error(), "depth")
+}
+
+func TestComplexity(t *testing.T) {
+	suite.Run(t, new(TestComplexitySuite))
+}
@@ -0,0 +1,280 @@
+// Package gql exposes blocks, transactions, addresses, clusters and tags through a GraphQL schema.
+// Resolvers batch the lookups of each level of the query through per request loaders, and queries
+// are measured before execution, refusing the ones over the complexity limits
+package gql
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+	"github.com/spf13/viper"
+
+	"github.com/xn3cr0nx/bitgodine/internal/abuse"
+	"github.com/xn3cr0nx/bitgodine/internal/block"
+	"github.com/xn3cr0nx/bitgodine/internal/cluster"
+	"github.com/xn3cr0nx/bitgodine/internal/errorx"
+	"github.com/xn3cr0nx/bitgodine/internal/storage/db/postgres"
+	"github.com/xn3cr0nx/bitgodine/internal/storage/kv"
+	"github.com/xn3cr0nx/bitgodine/internal/tag"
+	"github.com/xn3cr0nx/bitgodine/internal/tx"
+	"github.com/xn3cr0nx/bitgodine/pkg/cache"
+)
+
+// DefaultWorkers concurrent kv reads of a batch
+const DefaultWorkers = 8
+
+// txTTL expiration of the cached transactions, removed from the store when their block is rolled back
+const txTTL = time.Minute
+
+// labelsTTL expiration of the cached tags, abuses and clusters of the addresses, not invalidated on change
+const labelsTTL = time.Minute
+
+// Request GraphQL request body
+type Request struct {
+	Query         string                 `json:"query" query:"query" validate:"required"`
+	Variables     map[string]interface{} `json:"variables"`
+	OperationName string                 `json:"operationName" query:"operationName"`
+} //@name GraphQLRequest
+
+// Service interface exports available methods for graphql service
+type Service interface {
+	Execute(ctx context.Context, req *Request, limits *Limits) (res *graphql.Result, err error)
+}
+
+type service struct {
+	Repository *postgres.Pg
+	Kv         kv.DB
+	Cache      *cache.Cache
+	Schema     graphql.Schema
+}
+
+// NewService instantiates a new Service layer for graphql
+func NewService(r *postgres.Pg, k kv.DB, c *cache.Cache) *service {
+	schema, err := NewSchema()
+	if err != nil {
+		panic(err)
+	}
+	return &service{
+		Repository: r,
+		Kv:         k,
+		Cache:      c,
+		Schema:     schema,
+	}
+}
+
+// Execute checks the request is within the limits and executes it. Errors of the query are returned
+// in the result, err is set only if the request is refused
+func (s *service) Execute(ctx context.Context, req *Request, limits *Limits) (res *graphql.Result, err error) {
+	doc, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{Body: []byte(req.Query), Name: "GraphQL request"})})
+	if err != nil {
+		res = &graphql.Result{Errors: gqlerrors.FormatErrors(err)}
+		err = nil
+		return
+	}
+	if err = limits.Check(doc, req.OperationName, req.Variables); err != nil {
+		return
+	}
+
+	res = graphql.Do(graphql.Params{
+		Schema:         s.Schema,
+		RequestString:  req.Query,
+		VariableValues: req.Variables,
+		OperationName:  req.OperationName,
+		Context:        context.WithValue(ctx, loadersKey{}, s.loaders()),
+	})
+	return
+}
+
+type loadersKey struct{}
+
+// loaders of a request
+type loaders struct {
+	Repository *postgres.Pg
+	Kv         kv.DB
+	Cache      *cache.Cache
+
+	// tx txid to tx.Tx
+	tx *Loader
+	// block height to block.Block, resolving the hash of the height on every request
+	block *Loader
+	// blockHash block hash to block.Block
+	blockHash *Loader
+	// spending txid_vout output to the tx.Tx spending it
+	spending *Loader
+	// txBlock txid to the block.Block containing it
+	txBlock *Loader
+	// cluster address to its cluster
+	cluster *Loader
+	// tags address to []tag.Model
+	tags *Loader
+	// abuses address to []abuse.Model
+	abuses *Loader
+	// labels cluster to []cluster.Label, sharing the cache of the cluster service invalidated on refresh
+	labels *Loader
+}
+
+// then loads with the loader the values referenced by the values found, keyed by the same keys
+func then(refs map[string]interface{}, loader *Loader, key func(ref interface{}) string) (values map[string]interface{}, err error) {
+	keys := make([]string, 0, len(refs))
+	refKeys := make([]string, 0, len(refs))
+	for k, ref := range refs {
+		keys = append(keys, k)
+		refKeys = append(refKeys, key(ref))
+	}
+	loaded, err := loader.LoadMany(refKeys)
+	if err != nil {
+		return
+	}
+	values = make(map[string]interface{}, len(keys))
+	for i, k := range keys {
+		if loaded[i] != nil {
+			values[k] = loaded[i]
+		}
+	}
+	return
+}
+
+func fromContext(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
+
+func (s *service) loaders() *loaders {
+	workers := viper.GetInt("server.graphql.workers")
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+	txService := tx.NewService(s.Kv, s.Cache)
+	blockService := block.NewService(s.Kv, s.Cache)
+
+	l := &loaders{Repository: s.Repository, Kv: s.Kv, Cache: s.Cache}
+	l.tx = NewLoader("gtx_", s.Cache, txTTL, func(keys []string) (map[string]interface{}, error) {
+		return parallel(keys, workers, func(key string) (interface{}, error) {
+			return txService.GetFromHash(key)
+		})
+	})
+	l.blockHash = NewLoader("gbl_", s.Cache, 0, func(keys []string) (map[string]interface{}, error) {
+		return parallel(keys, workers, func(key string) (interface{}, error) {
+			return blockService.GetFromHash(key)
+		})
+	})
+	// block, spending and txBlock resolve references through the tx and block hash loaders, not cached
+	// themselves so that the references of rolled back blocks are not served
+	l.block = NewLoader("", nil, 0, func(keys []string) (map[string]interface{}, error) {
+		hashes, err := parallel(keys, workers, func(key string) (interface{}, error) {
+			if _, err := strconv.Atoi(key); err != nil {
+				return nil, fmt.Errorf("%w: height %s", errorx.ErrInvalidArgument, key)
+			}
+			hash, err := s.Kv.Read(key)
+			if err != nil {
+				return nil, err
+			}
+			return string(hash), nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		return then(hashes, l.blockHash, func(hash interface{}) string { return hash.(string) })
+	})
+	l.spending = NewLoader("", nil, 0, func(keys []string) (map[string]interface{}, error) {
+		ids, err := parallel(keys, workers, func(key string) (interface{}, error) {
+			spending, err := s.Kv.Read(key)
+			if err != nil {
+				return nil, err
+			}
+			return string(spending), nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		return then(ids, l.tx, func(id interface{}) string { return id.(string) })
+	})
+	l.txBlock = NewLoader("", nil, 0, func(keys []string) (map[string]interface{}, error) {
+		heights, err := parallel(keys, workers, func(key string) (interface{}, error) {
+			return blockService.GetTxBlockHeight(key)
+		})
+		if err != nil {
+			return nil, err
+		}
+		return then(heights, l.block, func(height interface{}) string { return strconv.Itoa(int(height.(int32))) })
+	})
+
+	l.cluster = NewLoader("gcl_", s.Cache, labelsTTL, func(keys []string) (values map[string]interface{}, err error) {
+		values = make(map[string]interface{})
+		if s.Repository == nil || s.Repository.DB == nil {
+			return
+		}
+		var clusters []cluster.Model
+		if err = s.Repository.Select("address, cluster").Where("address IN ?", keys).Find(&clusters).Error; err != nil {
+			return
+		}
+		for _, c := range clusters {
+			values[c.Address] = c.Cluster
+		}
+		return
+	})
+	l.tags = NewLoader("gtg_", s.Cache, labelsTTL, func(keys []string) (values map[string]interface{}, err error) {
+		values = make(map[string]interface{})
+		if s.Repository == nil || s.Repository.DB == nil {
+			return
+		}
+		var tags []tag.Model
+		if err = s.Repository.Where("address IN ?", keys).Where("expires_at IS NULL OR expires_at > ?", time.Now()).Find(&tags).Error; err != nil {
+			return
+		}
+		for _, key := range keys {
+			values[key] = []tag.Model{}
+		}
+		for _, t := range tags {
+			values[t.Address] = append(values[t.Address].([]tag.Model), t)
+		}
+		return
+	})
+	l.abuses = NewLoader("gab_", s.Cache, labelsTTL, func(keys []string) (values map[string]interface{}, err error) {
+		values = make(map[string]interface{})
+		if s.Repository == nil || s.Repository.DB == nil {
+			return
+		}
+		var abuses []abuse.Model
+		if err = s.Repository.Where("address IN ?", keys).Find(&abuses).Error; err != nil {
+			return
+		}
+		for _, key := range keys {
+			values[key] = []abuse.Model{}
+		}
+		for _, a := range abuses {
+			values[a.Address] = append(values[a.Address].([]abuse.Model), a)
+		}
+		return
+	})
+	l.labels = NewLoader("cl_", s.Cache, 0, func(keys []string) (values map[string]interface{}, err error) {
+		values = make(map[string]interface{})
+		if s.Repository == nil || s.Repository.DB == nil {
+			return
+		}
+		ids := make([]uint64, 0, len(keys))
+		for _, key := range keys {
+			id, e := strconv.ParseUint(key, 10, 64)
+			if e != nil {
+				return nil, fmt.Errorf("%w: cluster %s", errorx.ErrInvalidArgument, key)
+			}
+			ids = append(ids, id)
+			values[key] = []cluster.Label{}
+		}
+		var labels []cluster.Label
+		if err = s.Repository.Where("cluster IN ?", ids).Order("support desc, confidence desc").Find(&labels).Error; err != nil {
+			return
+		}
+		for _, l := range labels {
+			key := strconv.FormatUint(l.Cluster, 10)
+			values[key] = append(values[key].([]cluster.Label), l)
+		}
+		return
+	})
+	return l
+}
@@ -0,0 +1,171 @@
+package gql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/xn3cr0nx/bitgodine/internal/errorx"
+	"github.com/xn3cr0nx/bitgodine/internal/storage/kv"
+	"github.com/xn3cr0nx/bitgodine/internal/tx"
+	"github.com/xn3cr0nx/bitgodine/pkg/cache"
+	"github.com/xn3cr0nx/bitgodine/pkg/encoding"
+	"github.com/xn3cr0nx/bitgodine/pkg/logger"
+)
+
+type TestGraphQLSuite struct {
+	suite.Suite
+	db      *kv.DBMock
+	service *service
+	limits  *Limits
+	source  tx.Tx
+	other   tx.Tx
+	spend   tx.Tx
+}
+
+func txid(c string) string {
+	return strings.Repeat(c, 64)
+}
+
+func (suite *TestGraphQLSuite) SetupTest() {
+	logger.Setup()
+
+	c, err := cache.NewCache(nil)
+	require.Nil(suite.T(), err)
+	suite.db = kv.NewDBMock()
+	suite.service = NewService(nil, suite.db, c)
+	suite.limits = &Limits{Complexity: DefaultComplexity, Depth: DefaultDepth}
+
+	suite.source = tx.Tx{
+		TxID: txid("a"),
+		Vin:  []tx.Input{{IsCoinbase: true}},
+		Vout: []tx.Output{{ScriptpubkeyAddress: "1Source", Value: 100000}},
+	}
+	suite.other = tx.Tx{
+		TxID: txid("b"),
+		Vin:  []tx.Input{{IsCoinbase: true}},
+		Vout: []tx.Output{{ScriptpubkeyAddress: "1Other", Value: 50000}, {ScriptpubkeyAddress: "1Twice", Value: 20000, Scriptpubkey: "51"}},
+	}
+	suite.spend = tx.Tx{
+		TxID: txid("c"),
+		Vin: []tx.Input{
+			{TxID: suite.source.TxID, Vout: 0},
+			{TxID: suite.other.TxID, Vout: 0},
+			{TxID: suite.other.TxID, Vout: 1},
+		},
+		Vout: []tx.Output{{ScriptpubkeyAddress: "1Dest", Value: 160000}},
+	}
+	for _, t := range []tx.Tx{suite.source, suite.other, suite.spend} {
+		b, err := encoding.Marshal(t)
+		require.Nil(suite.T(), err)
+		suite.db.On("Read", t.TxID).Return(b, nil)
+	}
+	suite.db.On("Read", suite.source.TxID+"_0").Return([]byte(suite.spend.TxID), nil)
+	suite.db.On("Read", mock.Anything).Return(nil, errorx.ErrKeyNotFound)
+}
+
+func (suite *TestGraphQLSuite) execute(query string, variables map[string]interface{}) string {
+	res, err := suite.service.Execute(context.Background(), &Request{Query: query, Variables: variables}, suite.limits)
+	require.Nil(suite.T(), err)
+	require.Empty(suite.T(), res.Errors)
+	b, err := json.Marshal(res.Data)
+	require.Nil(suite.T(), err)
+	return string(b)
+}
+
+// reads returns the times the key was read from the kv store
+func (suite *TestGraphQLSuite) reads(key string) (n int) {
+	for _, call := range suite.db.Calls {
+		if call.Method == "Read" && call.Arguments.String(0) == key {
+			n++
+		}
+	}
+	return
+}
+
+func (suite *TestGraphQLSuite) TestTx() {
+	data := suite.execute(`query($txid: String!) {
+		tx(txid: $txid) {
+			txid
+			coinbase
+			inputs { vout prevout { value address { address } } }
+			outputs { index value address { address } spending { txid } }
+			block { height }
+		}
+	}`, map[string]interface{}{"txid": suite.spend.TxID})
+
+	assert.JSONEq(suite.T(), `{"tx": {
+		"txid": "`+suite.spend.TxID+`",
+		"coinbase": false,
+		"inputs": [
+			{"vout": 0, "prevout": {"value": 100000, "address": {"address": "1Source"}}},
+			{"vout": 0, "prevout": {"value": 50000, "address": {"address": "1Other"}}},
+			{"vout": 1, "prevout": {"value": 20000, "address": {"address": "1Twice"}}}
+		],
+		"outputs": [{"index": 0, "value": 160000, "address": {"address": "1Dest"}, "spending": null}],
+		"block": null
+	}}`, data)
+	// prevouts spent by many inputs are read once
+	assert.Equal(suite.T(), 1, suite.reads(suite.other.TxID))
+	assert.Equal(suite.T(), 1, suite.reads(suite.source.TxID))
+}
+
+func (suite *TestGraphQLSuite) TestSpending() {
+	data := suite.execute(`{
+		tx(txid: "`+suite.source.TxID+`") {
+			outputs { spending { txid inputs { prevout { address { address } } } } }
+		}
+	}`, nil)
+
+	assert.JSONEq(suite.T(), `{"tx": {"outputs": [{"spending": {"txid": "`+suite.spend.TxID+`", "inputs": [
+		{"prevout": {"address": {"address": "1Source"}}},
+		{"prevout": {"address": {"address": "1Other"}}},
+		{"prevout": {"address": {"address": "1Twice"}}}
+	]}}]}}`, data)
+	// the source is cached by the loader when first read
+	assert.Equal(suite.T(), 1, suite.reads(suite.source.TxID))
+}
+
+func (suite *TestGraphQLSuite) TestNotFound() {
+	data := suite.execute(`{ tx(txid: "`+txid("f")+`") { txid } }`, nil)
+	assert.JSONEq(suite.T(), `{"tx": null}`, data)
+}
+
+func (suite *TestGraphQLSuite) TestAddress() {
+	data := suite.execute(`{ address(address: "1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2") { address cluster { id } tags { type } } }`, nil)
+	assert.JSONEq(suite.T(), `{"address": {"address": "1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2", "cluster": null, "tags": null}}`, data)
+
+	res, err := suite.service.Execute(context.Background(), &Request{Query: `{ address(address: "invalid") { address } }`}, suite.limits)
+	require.Nil(suite.T(), err)
+	require.Len(suite.T(), res.Errors, 1)
+	assert.Contains(suite.T(), res.Errors[0].Message, "invalid argument")
+}
+
+func (suite *TestGraphQLSuite) TestLimits() {
+	suite.limits = &Limits{Complexity: 10, Depth: 3}
+	_, err := suite.service.Execute(context.Background(), &Request{
+		Query: `{ tx(txid: "` + suite.spend.TxID + `") { inputs { prevout { value } } } }`,
+	}, suite.limits)
+	assert.True(suite.T(), errors.Is(err, ErrTooComplex))
+	assert.Equal(suite.T(), 0, suite.reads(suite.spend.TxID))
+
+	res, err := suite.service.Execute(context.Background(), &Request{Query: `{ tx(txid: `}, suite.limits)
+	require.Nil(suite.T(), err)
+	assert.NotEmpty(suite.T(), res.Errors)
+}
+
+func (suite *TestGraphQLSuite) TestTxOutputsPage() {
+	data := suite.execute(`{ tx(txid: "`+suite.other.TxID+`") { outputs(skip: 1, first: 1) { index scriptpubkeyAsm } inputs(skip: 1) { vout } } }`, nil)
+	assert.JSONEq(suite.T(), `{"tx": {"outputs": [{"index": 1, "scriptpubkeyAsm": "1"}], "inputs": []}}`, data)
+}
+
+func TestGraphQL(t *testing.T) {
+	suite.Run(t, new(TestGraphQLSuite))
+}
@@ -0,0 +1,154 @@
+package gql
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/xn3cr0nx/bitgodine/internal/errorx"
+	"github.com/xn3cr0nx/bitgodine/pkg/cache"
+	"github.com/xn3cr0nx/bitgodine/pkg/logger"
+)
+
+// Fetch retrieves the values of a batch of keys, keys without a value are left out of the result
+type Fetch func(keys []string) (values map[string]interface{}, err error)
+
+// Loader batches the keys requested while resolving a level of the query, fetching the ones missing
+// from the cache at once when the first of their values is needed. It lives as long as the request,
+// memoizing the values it loaded. Values are cached with the ttl, forever if 0
+type Loader struct {
+	prefix string
+	cache  *cache.Cache
+	ttl    time.Duration
+	fetch  Fetch
+
+	mu      sync.Mutex
+	pending []string
+	results map[string]*result
+}
+
+type result struct {
+	value interface{}
+	err   error
+	done  bool
+}
+
+// NewLoader returns a loader fetching the values with fetch and caching them with the prefix
+func NewLoader(prefix string, c *cache.Cache, ttl time.Duration, fetch Fetch) *Loader {
+	return &Loader{
+		prefix:  prefix,
+		cache:   c,
+		ttl:     ttl,
+		fetch:   fetch,
+		results: make(map[string]*result),
+	}
+}
+
+// Load schedules the key in the next batch, returning a thunk resolving its value, nil if missing
+func (l *Loader) Load(key string) func() (interface{}, error) {
+	l.mu.Lock()
+	r, ok := l.results[key]
+	if !ok {
+		r = new(result)
+		l.results[key] = r
+		l.pending = append(l.pending, key)
+	}
+	l.mu.Unlock()
+
+	return func() (interface{}, error) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		// the keys scheduled after the value was loaded are left to the next level of the query
+		if !r.done {
+			l.dispatch()
+		}
+		return r.value, r.err
+	}
+}
+
+// LoadMany loads the values of the keys in a single batch
+func (l *Loader) LoadMany(keys []string) (values []interface{}, err error) {
+	thunks := make([]func() (interface{}, error), len(keys))
+	for i, key := range keys {
+		thunks[i] = l.Load(key)
+	}
+	values = make([]interface{}, len(keys))
+	for i, thunk := range thunks {
+		if values[i], err = thunk(); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// dispatch fetches the pending keys not cached yet, to be called holding the lock
+func (l *Loader) dispatch() {
+	keys := l.pending
+	l.pending = nil
+
+	var missing []string
+	for _, key := range keys {
+		l.results[key].done = true
+		if l.cache != nil {
+			if cached, ok := l.cache.Get(l.prefix + key); ok {
+				l.results[key].value = cached
+				continue
+			}
+		}
+		missing = append(missing, key)
+	}
+	if len(missing) == 0 {
+		return
+	}
+
+	values, err := l.fetch(missing)
+	for _, key := range missing {
+		r := l.results[key]
+		if err != nil {
+			r.err = err
+			continue
+		}
+		value, ok := values[key]
+		if !ok {
+			continue
+		}
+		r.value = value
+		if l.cache != nil && !l.cache.SetWithTTL(l.prefix+key, value, 1, l.ttl) {
+			logger.Error("GraphQL", errorx.ErrCache, logger.Params{"key": l.prefix + key})
+		}
+	}
+}
+
+// parallel runs fetch for each key with up to workers concurrent calls, collecting the values found.
+// Keys failing with not found are left out, any other error fails the batch
+func parallel(keys []string, workers int, fetch func(key string) (interface{}, error)) (values map[string]interface{}, err error) {
+	if workers < 1 {
+		workers = 1
+	}
+	values = make(map[string]interface{}, len(keys))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, workers)
+	for _, key := range keys {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(key string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			value, e := fetch(key)
+			mu.Lock()
+			defer mu.Unlock()
+			if e != nil {
+				if !errors.Is(e, errorx.ErrNotFound) && err == nil {
+					err = e
+				}
+				return
+			}
+			values[key] = value
+		}(key)
+	}
+	wg.Wait()
+	return
+}
@@ -0,0 +1,125 @@
+package gql
+
+import (
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/xn3cr0nx/bitgodine/internal/errorx"
+	"github.com/xn3cr0nx/bitgodine/pkg/cache"
+	"github.com/xn3cr0nx/bitgodine/pkg/logger"
+)
+
+type TestLoaderSuite struct {
+	suite.Suite
+	cache   *cache.Cache
+	batches [][]string
+	loader  *Loader
+}
+
+func (suite *TestLoaderSuite) SetupTest() {
+	logger.Setup()
+	c, err := cache.NewCache(nil)
+	require.Nil(suite.T(), err)
+	suite.cache = c
+	suite.batches = nil
+	suite.loader = NewLoader("test_", c, 0, func(keys []string) (map[string]interface{}, error) {
+		sorted := append([]string{}, keys...)
+		sort.Strings(sorted)
+		suite.batches = append(suite.batches, sorted)
+		values := make(map[string]interface{})
+		for _, key := range keys {
+			if key != "missing" {
+				values[key] = "value " + key
+			}
+		}
+		return values, nil
+	})
+}
+
+func (suite *TestLoaderSuite) TestBatch() {
+	a, b, again := suite.loader.Load("a"), suite.loader.Load("b"), suite.loader.Load("a")
+	missing := suite.loader.Load("missing")
+
+	value, err := b()
+	require.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "value b", value)
+	value, _ = a()
+	assert.Equal(suite.T(), "value a", value)
+	value, _ = again()
+	assert.Equal(suite.T(), "value a", value)
+	value, err = missing()
+	assert.Nil(suite.T(), err)
+	assert.Nil(suite.T(), value)
+	assert.Equal(suite.T(), [][]string{{"a", "b", "missing"}}, suite.batches)
+
+	values, err := suite.loader.LoadMany([]string{"c", "a", "d"})
+	require.Nil(suite.T(), err)
+	assert.Equal(suite.T(), []interface{}{"value c", "value a", "value d"}, values)
+	assert.Equal(suite.T(), [][]string{{"a", "b", "missing"}, {"c", "d"}}, suite.batches)
+}
+
+func (suite *TestLoaderSuite) TestLevels() {
+	first := suite.loader.Load("a")
+	_, err := first()
+	require.Nil(suite.T(), err)
+
+	// keys scheduled after a batch are fetched together by the next one
+	second, third := suite.loader.Load("b"), suite.loader.Load("c")
+	_, _ = first()
+	_, _ = third()
+	_, _ = second()
+	assert.Equal(suite.T(), [][]string{{"a"}, {"b", "c"}}, suite.batches)
+}
+
+func (suite *TestLoaderSuite) TestCache() {
+	_, err := suite.loader.Load("a")()
+	require.Nil(suite.T(), err)
+	suite.cache.Wait()
+
+	loader := NewLoader("test_", suite.cache, 0, func(keys []string) (map[string]interface{}, error) {
+		suite.batches = append(suite.batches, keys)
+		return map[string]interface{}{}, nil
+	})
+	value, err := loader.Load("a")()
+	require.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "value a", value)
+	_, _ = loader.Load("missing")()
+	assert.Equal(suite.T(), [][]string{{"a"}, {"missing"}}, suite.batches)
+}
+
+func (suite *TestLoaderSuite) TestError() {
+	loader := NewLoader("err_", suite.cache, time.Minute, func(keys []string) (map[string]interface{}, error) {
+		return nil, errorx.ErrUnknown
+	})
+	a, b := loader.Load("a"), loader.Load("b")
+	_, err := a()
+	assert.True(suite.T(), errors.Is(err, errorx.ErrUnknown))
+	_, err = b()
+	assert.True(suite.T(), errors.Is(err, errorx.ErrUnknown))
+}
+
+func (suite *TestLoaderSuite) TestParallel() {
+	values, err := parallel([]string{"a", "b", "missing"}, 2, func(key string) (interface{}, error) {
+		if key == "missing" {
+			return nil, errorx.ErrKeyNotFound
+		}
+		return key, nil
+	})
+	require.Nil(suite.T(), err)
+	assert.Equal(suite.T(), map[string]interface{}{"a": "a", "b": "b"}, values)
+
+	_, err = parallel([]string{"a", "b"}, 0, func(key string) (interface{}, error) {
+		return nil, errorx.ErrUnknown
+	})
+	assert.True(suite.T(), errors.Is(err, errorx.ErrUnknown))
+}
+
+func TestLoader(t *testing.T) {
+	suite.Run(t, new(TestLoaderSuite))
+}
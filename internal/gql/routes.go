@@ -0,0 +1,101 @@
+package gql
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/xn3cr0nx/bitgodine/internal/apikey"
+	"github.com/xn3cr0nx/bitgodine/internal/errorx"
+	"github.com/xn3cr0nx/bitgodine/internal/ratelimit"
+	"github.com/xn3cr0nx/bitgodine/pkg/validator"
+)
+
+// Routes mounts /graphql route on the main group
+func Routes(g *echo.Group, s Service) {
+	r := g.Group("/graphql", apikey.Auth(apikey.ReadBlocks), ratelimit.Middleware(ratelimit.Blocks))
+	r.GET("", queryGet(s))
+	r.POST("", query(s))
+}
+
+// query godoc
+// @ID graphql
+//
+// @Router /graphql [post]
+// @Summary GraphQL query
+// @Description execute a GraphQL query over blocks, transactions, addresses, clusters and tags. Queries over the complexity limits are refused
+// @Tags graphql
+//
+// @Security ApiKeyAuth
+//
+// @Accept  json
+// @Produce  json
+//
+// @Param request body Request true "GraphQL request"
+//
+// @Success 200 {object} object
+// @Success 400 {string} string
+// @Success 500 {string} string
+func query(s Service) func(echo.Context) error {
+	return func(c echo.Context) error {
+		req := new(Request)
+		if err := validator.Struct(&c, req); err != nil {
+			return err
+		}
+		return execute(c, s, req)
+	}
+}
+
+// queryGet godoc
+// @ID graphql-get
+//
+// @Router /graphql [get]
+// @Summary GraphQL query
+// @Description execute a GraphQL query passed as query parameters
+// @Tags graphql
+//
+// @Security ApiKeyAuth
+//
+// @Accept  json
+// @Produce  json
+//
+// @Param query query string true "GraphQL query"
+// @Param operationName query string false "Operation executed"
+// @Param variables query string false "Variables as JSON object"
+//
+// @Success 200 {object} object
+// @Success 400 {string} string
+// @Success 500 {string} string
+func queryGet(s Service) func(echo.Context) error {
+	return func(c echo.Context) error {
+		type Query struct {
+			Query         string `query:"query" validate:"required"`
+			OperationName string `query:"operationName"`
+			Variables     string `query:"variables" validate:"omitempty,json"`
+		}
+		q := new(Query)
+		if err := validator.Struct(&c, q); err != nil {
+			return err
+		}
+
+		req := &Request{Query: q.Query, OperationName: q.OperationName}
+		if q.Variables != "" {
+			if err := json.Unmarshal([]byte(q.Variables), &req.Variables); err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+			}
+		}
+		return execute(c, s, req)
+	}
+}
+
+func execute(c echo.Context, s Service, req *Request) error {
+	res, err := s.Execute(c.Request().Context(), req, Conf())
+	if err != nil {
+		if errors.Is(err, errorx.ErrInvalidArgument) {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		return err
+	}
+	return c.JSON(http.StatusOK, res)
+}
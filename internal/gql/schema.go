@@ -0,0 +1,533 @@
+package gql
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/graphql-go/graphql"
+
+	"github.com/xn3cr0nx/bitgodine/internal/abuse"
+	"github.com/xn3cr0nx/bitgodine/internal/address"
+	"github.com/xn3cr0nx/bitgodine/internal/analysis"
+	"github.com/xn3cr0nx/bitgodine/internal/block"
+	"github.com/xn3cr0nx/bitgodine/internal/cluster"
+	"github.com/xn3cr0nx/bitgodine/internal/errorx"
+	"github.com/xn3cr0nx/bitgodine/internal/heuristics"
+	"github.com/xn3cr0nx/bitgodine/internal/tag"
+	"github.com/xn3cr0nx/bitgodine/internal/tx"
+)
+
+// output of a transaction, with the transaction it belongs to
+type output struct {
+	TxID                string
+	Index               uint32
+	Value               int64
+	Scriptpubkey        string
+	ScriptpubkeyAsm     string
+	ScriptpubkeyType    string
+	ScriptpubkeyAddress string
+}
+
+func newOutput(txid string, index uint32, o tx.Output) output {
+	asm := o.ScriptpubkeyAsm
+	if asm == "" {
+		asm = tx.Disasm(o.Scriptpubkey)
+	}
+	return output{
+		TxID:                txid,
+		Index:               index,
+		Value:               o.Value,
+		Scriptpubkey:        o.Scriptpubkey,
+		ScriptpubkeyAsm:     asm,
+		ScriptpubkeyType:    o.ScriptpubkeyType,
+		ScriptpubkeyAddress: o.ScriptpubkeyAddress,
+	}
+}
+
+// Analysis heuristics applicable to a transaction and the change outputs they detect
+type Analysis struct {
+	TxID         string
+	Heuristics   []string
+	Changes      []Change
+	LikelyChange *uint32
+}
+
+// Change output detected as change by an heuristic
+type Change struct {
+	Heuristic string
+	Vout      uint32
+}
+
+// clusterID cluster id, a string in the schema not fitting GraphQL 32 bit integers
+type clusterID uint64
+
+func (c clusterID) String() string {
+	return strconv.FormatUint(uint64(c), 10)
+}
+
+// NewSchema returns the GraphQL schema. Fields named after the model fields use the default resolver
+func NewSchema() (graphql.Schema, error) {
+	var blockType, txType, outputType, addressType *graphql.Object
+
+	tagType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Tag",
+		Description: "Label attached to an address",
+		Fields: graphql.Fields{
+			"id":       &graphql.Field{Type: graphql.String},
+			"address":  &graphql.Field{Type: graphql.String},
+			"message":  &graphql.Field{Type: graphql.String},
+			"nickname": &graphql.Field{Type: graphql.String},
+			"type":     &graphql.Field{Type: graphql.String},
+			"category": &graphql.Field{Type: graphql.String, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return tag.Category(p.Source.(tag.Model).Type), nil
+			}},
+			"link":       &graphql.Field{Type: graphql.String},
+			"verified":   &graphql.Field{Type: graphql.Boolean},
+			"source":     &graphql.Field{Type: graphql.String},
+			"confidence": &graphql.Field{Type: graphql.Float},
+			"firstSeen":  &graphql.Field{Type: graphql.DateTime},
+			"lastSeen":   &graphql.Field{Type: graphql.DateTime},
+		},
+	})
+
+	abuseType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Abuse",
+		Description: "Abuse reported for an address",
+		Fields: graphql.Fields{
+			"id":      &graphql.Field{Type: graphql.String},
+			"address": &graphql.Field{Type: graphql.String},
+			"category": &graphql.Field{Type: graphql.String, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				a := p.Source.(abuse.Model)
+				a.Normalize()
+				return a.Category, nil
+			}},
+			"abuser":      &graphql.Field{Type: graphql.String},
+			"description": &graphql.Field{Type: graphql.String},
+			"fromCountry": &graphql.Field{Type: graphql.String},
+			"reportedAt": &graphql.Field{Type: graphql.DateTime, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(abuse.Model).CreatedAt, nil
+			}},
+		},
+	})
+
+	labelType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "ClusterLabel",
+		Description: "Label of a cluster materialized from the tags of its members",
+		Fields: graphql.Fields{
+			"nickname":   &graphql.Field{Type: graphql.String},
+			"type":       &graphql.Field{Type: graphql.String},
+			"message":    &graphql.Field{Type: graphql.String},
+			"address":    &graphql.Field{Type: graphql.String},
+			"support":    &graphql.Field{Type: graphql.Int},
+			"confidence": &graphql.Field{Type: graphql.Float},
+			"verified":   &graphql.Field{Type: graphql.Boolean},
+		},
+	})
+
+	changeType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Change",
+		Description: "Output detected as change by an heuristic",
+		Fields: graphql.Fields{
+			"heuristic": &graphql.Field{Type: graphql.String},
+			"vout":      &graphql.Field{Type: graphql.Int},
+		},
+	})
+
+	analysisType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Analysis",
+		Description: "Heuristics applicable to a transaction and the change outputs they detect",
+		Fields: graphql.Fields{
+			"txid":         &graphql.Field{Type: graphql.String},
+			"heuristics":   &graphql.Field{Type: graphql.NewList(graphql.String)},
+			"changes":      &graphql.Field{Type: graphql.NewList(changeType)},
+			"likelyChange": &graphql.Field{Type: graphql.Int},
+		},
+	})
+
+	clusterType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Cluster",
+		Description: "Addresses controlled by the same entity",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"id": &graphql.Field{Type: graphql.String, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(clusterID).String(), nil
+				}},
+				"labels": &graphql.Field{Type: graphql.NewList(labelType), Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return fromContext(p.Context).labels.Load(p.Source.(clusterID).String()), nil
+				}},
+				"addresses": &graphql.Field{
+					Type:        graphql.NewList(addressType),
+					Description: "Members of the cluster, paginated",
+					Args:        pageArgs("skip", graphql.Int),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						l := fromContext(p.Context)
+						if l.Repository == nil || l.Repository.DB == nil {
+							return nil, nil
+						}
+						skip, _ := p.Args["skip"].(int)
+						var addresses []string
+						err := l.Repository.Model(&cluster.Model{}).Where("cluster = ?", uint64(p.Source.(clusterID))).
+							Order("address").Offset(skip).Limit(first(p.Args)).Pluck("address", &addresses).Error
+						return addresses, err
+					},
+				},
+			}
+		}),
+	})
+
+	addressType = graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Address",
+		Description: "Bitcoin address",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"address": &graphql.Field{Type: graphql.String, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source, nil
+				}},
+				"cluster": &graphql.Field{Type: clusterType, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					load := fromContext(p.Context).cluster.Load(p.Source.(string))
+					return func() (interface{}, error) {
+						c, err := load()
+						if c == nil || err != nil {
+							return nil, err
+						}
+						return clusterID(c.(uint64)), nil
+					}, nil
+				}},
+				"tags": &graphql.Field{Type: graphql.NewList(tagType), Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return fromContext(p.Context).tags.Load(p.Source.(string)), nil
+				}},
+				"abuses": &graphql.Field{Type: graphql.NewList(abuseType), Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return fromContext(p.Context).abuses.Load(p.Source.(string)), nil
+				}},
+				"transactions": &graphql.Field{
+					Type:        graphql.NewList(txType),
+					Description: "Transactions the address appears in ordered by txid, paginated after the last txid seen",
+					Args:        pageArgs("after", graphql.String),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						l := fromContext(p.Context)
+						after, _ := p.Args["after"].(string)
+						occurences, err := address.NewService(l.Kv, l.Cache).GetOccurencesPage(p.Source.(string), first(p.Args), after)
+						if err != nil {
+							return nil, err
+						}
+						return l.txs(occurences), nil
+					},
+				},
+			}
+		}),
+	})
+
+	outputType = graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Output",
+		Description: "Transaction output",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"txid":             &graphql.Field{Type: graphql.String},
+				"index":            &graphql.Field{Type: graphql.Int},
+				"value":            &graphql.Field{Type: graphql.Float, Description: "Value in satoshis"},
+				"scriptpubkey":     &graphql.Field{Type: graphql.String},
+				"scriptpubkeyAsm":  &graphql.Field{Type: graphql.String},
+				"scriptpubkeyType": &graphql.Field{Type: graphql.String},
+				"address": &graphql.Field{Type: addressType, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if addr := p.Source.(output).ScriptpubkeyAddress; addr != "" {
+						return addr, nil
+					}
+					return nil, nil
+				}},
+				"spending": &graphql.Field{Type: txType, Description: "Transaction spending the output", Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					o := p.Source.(output)
+					return fromContext(p.Context).spending.Load(fmt.Sprintf("%s_%d", o.TxID, o.Index)), nil
+				}},
+			}
+		}),
+	})
+
+	inputType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Input",
+		Description: "Transaction input",
+		Fields: graphql.Fields{
+			"txid": &graphql.Field{Type: graphql.String, Description: "Transaction of the output spent"},
+			"vout": &graphql.Field{Type: graphql.Int},
+			"coinbase": &graphql.Field{Type: graphql.Boolean, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(tx.Input).IsCoinbase, nil
+			}},
+			"scriptsig": &graphql.Field{Type: graphql.String},
+			"sequence":  &graphql.Field{Type: graphql.Float},
+			"witness":   &graphql.Field{Type: graphql.NewList(graphql.String)},
+			"prevout": &graphql.Field{Type: outputType, Description: "Output spent", Resolve: prevout(func(o output) interface{} {
+				return o
+			})},
+			"address": &graphql.Field{Type: addressType, Description: "Address of the output spent", Resolve: prevout(func(o output) interface{} {
+				if o.ScriptpubkeyAddress == "" {
+					return nil
+				}
+				return o.ScriptpubkeyAddress
+			})},
+		},
+	})
+
+	txType = graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Tx",
+		Description: "Bitcoin transaction",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"txid":     &graphql.Field{Type: graphql.String},
+				"version":  &graphql.Field{Type: graphql.Int},
+				"locktime": &graphql.Field{Type: graphql.Float},
+				"size":     &graphql.Field{Type: graphql.Float},
+				"weight":   &graphql.Field{Type: graphql.Float},
+				"fee":      &graphql.Field{Type: graphql.Float},
+				"coinbase": &graphql.Field{Type: graphql.Boolean, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					t := p.Source.(tx.Tx)
+					return len(t.Vin) > 0 && t.Vin[0].IsCoinbase, nil
+				}},
+				"coinjoin": &graphql.Field{Type: graphql.Boolean, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					t := p.Source.(tx.Tx)
+					return t.IsCoinjoin(), nil
+				}},
+				"inputs": &graphql.Field{
+					Type:        graphql.NewList(inputType),
+					Description: "Inputs of the transaction, paginated",
+					Args:        pageArgs("skip", graphql.Int),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						vin := p.Source.(tx.Tx).Vin
+						from, to := window(len(vin), p.Args)
+						return vin[from:to], nil
+					},
+				},
+				"outputs": &graphql.Field{
+					Type:        graphql.NewList(outputType),
+					Description: "Outputs of the transaction, paginated",
+					Args:        pageArgs("skip", graphql.Int),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						t := p.Source.(tx.Tx)
+						from, to := window(len(t.Vout), p.Args)
+						outputs := make([]output, 0, to-from)
+						for i := from; i < to; i++ {
+							outputs = append(outputs, newOutput(t.TxID, uint32(i), t.Vout[i]))
+						}
+						return outputs, nil
+					},
+				},
+				"block": &graphql.Field{Type: blockType, Description: "Block containing the transaction", Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return fromContext(p.Context).txBlock.Load(p.Source.(tx.Tx).TxID), nil
+				}},
+				"analysis": &graphql.Field{Type: analysisType, Description: "Heuristics analysis of the transaction", Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return fromContext(p.Context).analyze(p.Source.(tx.Tx).TxID)
+				}},
+			}
+		}),
+	})
+
+	blockType = graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Block",
+		Description: "Bitcoin block",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"hash": &graphql.Field{Type: graphql.String, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(block.Block).ID, nil
+				}},
+				"height":     &graphql.Field{Type: graphql.Int},
+				"version":    &graphql.Field{Type: graphql.Int},
+				"timestamp":  &graphql.Field{Type: graphql.DateTime},
+				"bits":       &graphql.Field{Type: graphql.Float},
+				"nonce":      &graphql.Field{Type: graphql.Float},
+				"merkleRoot": &graphql.Field{Type: graphql.String},
+				"size":       &graphql.Field{Type: graphql.Int},
+				"weight":     &graphql.Field{Type: graphql.Int},
+				"txCount": &graphql.Field{Type: graphql.Int, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return len(p.Source.(block.Block).Transactions), nil
+				}},
+				"previous": &graphql.Field{Type: blockType, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					b := p.Source.(block.Block)
+					if b.Height == 0 {
+						return nil, nil
+					}
+					return fromContext(p.Context).block.Load(strconv.Itoa(int(b.Height - 1))), nil
+				}},
+				"transactions": &graphql.Field{
+					Type:        graphql.NewList(txType),
+					Description: "Transactions of the block, paginated",
+					Args:        pageArgs("skip", graphql.Int),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						txids := p.Source.(block.Block).Transactions
+						from, to := window(len(txids), p.Args)
+						return fromContext(p.Context).txs(txids[from:to]), nil
+					},
+				},
+			}
+		}),
+	})
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"block": &graphql.Field{
+				Type:        blockType,
+				Description: "Block by hash or height",
+				Args: graphql.FieldConfigArgument{
+					"hash":   &graphql.ArgumentConfig{Type: graphql.String},
+					"height": &graphql.ArgumentConfig{Type: graphql.Int},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					l := fromContext(p.Context)
+					if hash, ok := p.Args["hash"].(string); ok {
+						return notFound(block.NewService(l.Kv, l.Cache).GetFromHash(hash))
+					}
+					if height, ok := p.Args["height"].(int); ok {
+						return l.block.Load(strconv.Itoa(height)), nil
+					}
+					return nil, fmt.Errorf("%w: hash or height required", errorx.ErrInvalidArgument)
+				},
+			},
+			"tx": &graphql.Field{
+				Type:        txType,
+				Description: "Transaction by txid",
+				Args:        graphql.FieldConfigArgument{"txid": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)}},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return fromContext(p.Context).tx.Load(p.Args["txid"].(string)), nil
+				},
+			},
+			"address": &graphql.Field{
+				Type:        addressType,
+				Description: "Address",
+				Args:        graphql.FieldConfigArgument{"address": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)}},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					addr := p.Args["address"].(string)
+					if !address.IsBitcoinAddress(addr) {
+						return nil, fmt.Errorf("%w: address %s", errorx.ErrInvalidArgument, addr)
+					}
+					return addr, nil
+				},
+			},
+			"cluster": &graphql.Field{
+				Type:        clusterType,
+				Description: "Cluster by id",
+				Args:        graphql.FieldConfigArgument{"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)}},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					id, err := strconv.ParseUint(p.Args["id"].(string), 10, 64)
+					if err != nil {
+						return nil, fmt.Errorf("%w: cluster %s", errorx.ErrInvalidArgument, p.Args["id"])
+					}
+					return clusterID(id), nil
+				},
+			},
+			"analysis": &graphql.Field{
+				Type:        analysisType,
+				Description: "Heuristics analysis of a transaction",
+				Args:        graphql.FieldConfigArgument{"txid": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)}},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return fromContext(p.Context).analyze(p.Args["txid"].(string))
+				},
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: query})
+}
+
+// prevout returns a resolver of the field computed from the output spent by the input
+func prevout(f func(o output) interface{}) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		in := p.Source.(tx.Input)
+		if in.IsCoinbase {
+			return nil, nil
+		}
+		load := fromContext(p.Context).tx.Load(in.TxID)
+		return func() (interface{}, error) {
+			spent, err := load()
+			if spent == nil || err != nil {
+				return nil, err
+			}
+			t := spent.(tx.Tx)
+			if int(in.Vout) >= len(t.Vout) {
+				return nil, fmt.Errorf("%w: output %s:%d", errorx.ErrNotFound, in.TxID, in.Vout)
+			}
+			return f(newOutput(t.TxID, in.Vout, t.Vout[in.Vout])), nil
+		}, nil
+	}
+}
+
+// pageArgs returns the arguments of a paginated list, first and the cursor
+func pageArgs(cursor string, cursorType graphql.Input) graphql.FieldConfigArgument {
+	return graphql.FieldConfigArgument{
+		"first": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: DefaultPage, Description: fmt.Sprintf("Items returned, at most %d", MaxPage)},
+		cursor:  &graphql.ArgumentConfig{Type: cursorType},
+	}
+}
+
+// first returns the items to return of a paginated list, bounded to MaxPage
+func first(args map[string]interface{}) int {
+	n, ok := args["first"].(int)
+	if !ok || n < 1 {
+		return DefaultPage
+	}
+	if n > MaxPage {
+		return MaxPage
+	}
+	return n
+}
+
+// window returns the bounds of the page of a list of n items paginated by first and skip
+func window(n int, args map[string]interface{}) (from, to int) {
+	skip, _ := args["skip"].(int)
+	if skip < 0 || skip >= n {
+		return n, n
+	}
+	to = skip + first(args)
+	if to > n {
+		to = n
+	}
+	return skip, to
+}
+
+// txs returns the thunks loading the transactions
+func (l *loaders) txs(txids []string) []interface{} {
+	thunks := make([]interface{}, len(txids))
+	for i, txid := range txids {
+		thunks[i] = l.tx.Load(txid)
+	}
+	return thunks
+}
+
+// analyze applies all the heuristics to the transaction, computing both the heuristics applicable
+// and the change outputs they detect
+func (l *loaders) analyze(txid string) (interface{}, error) {
+	service := analysis.NewService(l.Kv, l.Cache)
+	all := heuristics.FromListToMask(heuristics.List())
+
+	applicable, err := service.AnalyzeTx(txid, all, "applicability")
+	if err != nil {
+		return notFound(nil, err)
+	}
+	reliable, err := service.AnalyzeTx(txid, all, "reliability")
+	if err != nil {
+		return nil, err
+	}
+
+	a := &Analysis{TxID: txid, Heuristics: applicable.(heuristics.Mask).ToHeuristicsList(), Changes: []Change{}}
+	changes := reliable.(heuristics.Map)
+	for h, vout := range changes {
+		a.Changes = append(a.Changes, Change{Heuristic: h.String(), Vout: vout})
+	}
+	sort.Slice(a.Changes, func(i, j int) bool {
+		return a.Changes[i].Heuristic < a.Changes[j].Heuristic
+	})
+	if vout, err := analysis.ExtractLikelihoodOutput(changes); err == nil {
+		a.LikelyChange = &vout
+	}
+	return a, nil
+}
+
+// notFound returns null for missing data, failing on any other error
+func notFound(value interface{}, err error) (interface{}, error) {
+	if err != nil {
+		if errors.Is(err, errorx.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return value, nil
+}
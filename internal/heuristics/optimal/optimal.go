@@ -41,7 +41,7 @@ func (w *Worker) Work() (err error) {
 // ChangeOutput returns the index of the output which value is less than any inputs value, if there is any
 func (h *Optimal) ChangeOutput(transaction *tx.Tx) (c []uint32, err error) {
 	values := make([]int64, len(transaction.Vin))
-	pool := task.New(runtime.NumCPU() / 2)
+	pool := task.New(runtime.NumCPU()/2 + 1)
 	txService := tx.NewService(h.Kv, h.Cache)
 	for i, in := range transaction.Vin {
 		if in.IsCoinbase {
@@ -52,7 +52,7 @@ func (w *Worker) Work() (err error) {
 // ChangeOutput returns the index of the output which appears both in inputs and in outputs based on address reuse heuristic
 func (h *AddressReuse) ChangeOutput(transaction *tx.Tx) (c []uint32, err error) {
 	inputAddresses := make([]string, len(transaction.Vin))
-	pool := task.New(runtime.NumCPU() / 2)
+	pool := task.New(runtime.NumCPU()/2 + 1)
 	txService := tx.NewService(h.Kv, h.Cache)
 	for i, in := range transaction.Vin {
 		if in.IsCoinbase {
@@ -6,10 +6,12 @@
 package shadow
 
 import (
+	"errors"
 	"runtime"
 
 	"github.com/xn3cr0nx/bitgodine/internal/address"
 	"github.com/xn3cr0nx/bitgodine/internal/block"
+	"github.com/xn3cr0nx/bitgodine/internal/errorx"
 	task "github.com/xn3cr0nx/bitgodine/internal/errtask"
 	"github.com/xn3cr0nx/bitgodine/internal/storage/kv"
 	"github.com/xn3cr0nx/bitgodine/internal/tx"
@@ -35,6 +37,10 @@ type Worker struct {
 func (w *Worker) Work() (err error) {
 	firstOccurence, err := w.service.GetFirstOccurenceHeight(w.output.ScriptpubkeyAddress)
 	if err != nil {
+		// address never appeared before, it is a fresh one
+		if errors.Is(err, errorx.ErrKeyNotFound) {
+			err = nil
+		}
 		return
 	}
 	if firstOccurence < w.blockHeight {
@@ -46,12 +52,17 @@ func (w *Worker) Work() (err error) {
 // ChangeOutput returns the index of the output which appears for the first time in the chain based on client behaviour heuristic
 // TODO: violates DRY, just different evaluation in output change, but same operations
 func (h *ShadowAddress) ChangeOutput(transaction *tx.Tx) (c []uint32, err error) {
-	candidates := make([]uint32, len(transaction.Vout))
 	blockHeight, err := block.NewService(h.Kv, h.Cache).GetTxBlockHeight(transaction.TxID)
 	if err != nil {
 		return
 	}
+	return h.ChangeOutputAtHeight(transaction, blockHeight)
+}
 
+// ChangeOutputAtHeight applies the shadow heuristic as if the transaction was included in the block at the passed height.
+// It allows to evaluate transactions not stored yet (e.g. not broadcasted)
+func (h *ShadowAddress) ChangeOutputAtHeight(transaction *tx.Tx, blockHeight int32) (c []uint32, err error) {
+	candidates := make([]uint32, len(transaction.Vout))
 	pool := task.New(runtime.NumCPU())
 	addressService := address.NewService(h.Kv, h.Cache)
 	for vout, out := range transaction.Vout {
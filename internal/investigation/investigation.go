@@ -0,0 +1,186 @@
+package investigation
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+
+	"github.com/xn3cr0nx/bitgodine/internal/errorx"
+	"github.com/xn3cr0nx/bitgodine/internal/storage/db/postgres"
+	"github.com/xn3cr0nx/bitgodine/pkg/validator"
+)
+
+// Service interface exports available methods for investigation service
+type Service interface {
+	GetInvestigations(userID string) (investigations []Model, err error)
+	GetInvestigation(userID, ID string) (investigation *Model, err error)
+	CreateInvestigation(userID string, investigation *Model) (err error)
+	UpdateInvestigation(userID, ID string, investigation *Model) (err error)
+	DeleteInvestigation(userID, ID string) (err error)
+	ShareInvestigation(userID, ID string, body *ShareBody) (share *Share, err error)
+	UnshareInvestigation(userID, ID, shareUserID string) (err error)
+}
+
+type service struct {
+	Repository *postgres.Pg
+}
+
+// NewService instantiates a new Service layer for customer
+func NewService(r *postgres.Pg) *service {
+	return &service{
+		Repository: r,
+	}
+}
+
+// shared returns the subquery of the investigations shared with the user
+func (s *service) shared(userID string) *gorm.DB {
+	return s.Repository.Model(&Share{}).Select("investigation_id").Where("user_id = ?", userID)
+}
+
+// GetInvestigations retrieves the investigations owned by the user or shared with them
+func (s *service) GetInvestigations(userID string) (investigations []Model, err error) {
+	err = s.Repository.Preload("Shares").
+		Where("user_id = ? OR id IN (?)", userID, s.shared(userID)).
+		Order("updated_at desc").
+		Find(&investigations).Error
+	return
+}
+
+// GetInvestigation retrieves an investigation owned by the user or shared with them
+func (s *service) GetInvestigation(userID, ID string) (investigation *Model, err error) {
+	investigation = new(Model)
+	err = s.Repository.Preload("Shares").
+		Where("id = ? AND (user_id = ? OR id IN (?))", ID, userID, s.shared(userID)).
+		First(investigation).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			err = fmt.Errorf("investigation %s %w", ID, errorx.ErrNotFound)
+		}
+		investigation = nil
+	}
+	return
+}
+
+// CreateInvestigation creates a new investigation owned by the user
+func (s *service) CreateInvestigation(userID string, investigation *Model) (err error) {
+	if investigation.UserID, err = validator.ParseUUID(userID); err != nil {
+		return
+	}
+	investigation.Shares = nil
+	err = s.Repository.Model(&Model{}).Create(investigation).Error
+	return
+}
+
+// UpdateInvestigation updates the content of the investigation, allowed to the owner and to users it's shared with in write
+func (s *service) UpdateInvestigation(userID, ID string, investigation *Model) (err error) {
+	stored, err := s.GetInvestigation(userID, ID)
+	if err != nil {
+		return
+	}
+	if Permission(stored, userID) != Write {
+		return echo.NewHTTPError(http.StatusForbidden, "investigation shared read only")
+	}
+	stored.Name = investigation.Name
+	stored.Addresses = investigation.Addresses
+	stored.Transactions = investigation.Transactions
+	stored.Clusters = investigation.Clusters
+	stored.Trace = investigation.Trace
+	stored.Notes = investigation.Notes
+	if err = s.Repository.Omit("Shares").Save(stored).Error; err != nil {
+		return
+	}
+	*investigation = *stored
+	return
+}
+
+// DeleteInvestigation deletes an investigation, allowed to the owner only
+func (s *service) DeleteInvestigation(userID, ID string) (err error) {
+	stored, err := s.GetInvestigation(userID, ID)
+	if err != nil {
+		return
+	}
+	if stored.UserID.String() != userID {
+		return echo.NewHTTPError(http.StatusForbidden, "only the owner can delete the investigation")
+	}
+	err = s.Repository.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("investigation_id = ?", stored.ID).Delete(&Share{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(stored).Error
+	})
+	return
+}
+
+// ShareInvestigation grants the user identified by email access to the investigation, allowed to the owner only.
+// Sharing again with the same user updates the permission
+func (s *service) ShareInvestigation(userID, ID string, body *ShareBody) (share *Share, err error) {
+	stored, err := s.GetInvestigation(userID, ID)
+	if err != nil {
+		return
+	}
+	if stored.UserID.String() != userID {
+		err = echo.NewHTTPError(http.StatusForbidden, "only the owner can share the investigation")
+		return
+	}
+
+	var users []struct{ ID uuid.UUID }
+	if err = s.Repository.Table("users").Select("id").Where("email = ? AND deleted_at IS NULL", strings.ToLower(body.Email)).Scan(&users).Error; err != nil {
+		return
+	}
+	if len(users) == 0 {
+		err = fmt.Errorf("user %s %w", body.Email, errorx.ErrNotFound)
+		return
+	}
+	if users[0].ID == stored.UserID {
+		err = echo.NewHTTPError(http.StatusBadRequest, "cannot share the investigation with its owner")
+		return
+	}
+
+	permission := body.Permission
+	if permission == "" {
+		permission = Read
+	}
+	share = new(Share)
+	err = s.Repository.Where(Share{InvestigationID: stored.ID, UserID: users[0].ID}).
+		Assign(Share{Permission: permission}).
+		FirstOrCreate(share).Error
+	return
+}
+
+// UnshareInvestigation revokes the access of a user to the investigation, allowed to the owner
+// and to the user leaving the investigation. The share is deleted for good, so that it can be granted again
+func (s *service) UnshareInvestigation(userID, ID, shareUserID string) (err error) {
+	stored, err := s.GetInvestigation(userID, ID)
+	if err != nil {
+		return
+	}
+	if stored.UserID.String() != userID && shareUserID != userID {
+		return echo.NewHTTPError(http.StatusForbidden, "only the owner can revoke access to the investigation")
+	}
+	res := s.Repository.Unscoped().Where("investigation_id = ? AND user_id = ?", stored.ID, shareUserID).Delete(&Share{})
+	if err = res.Error; err != nil {
+		return
+	}
+	if res.RowsAffected == 0 {
+		err = fmt.Errorf("share %s %w", shareUserID, errorx.ErrNotFound)
+	}
+	return
+}
+
+// Permission returns the permission the user has been granted on the investigation, owners have write permission
+func Permission(investigation *Model, userID string) string {
+	if investigation.UserID.String() == userID {
+		return Write
+	}
+	for _, share := range investigation.Shares {
+		if share.UserID.String() == userID {
+			return share.Permission
+		}
+	}
+	return ""
+}
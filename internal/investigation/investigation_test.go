@@ -0,0 +1,107 @@
+package investigation
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/xn3cr0nx/bitgodine/internal/storage/db/postgres"
+	"github.com/xn3cr0nx/bitgodine/pkg/validator"
+)
+
+type TestInvestigationSuite struct {
+	suite.Suite
+	owner  uuid.UUID
+	reader uuid.UUID
+	writer uuid.UUID
+	model  Model
+}
+
+func (suite *TestInvestigationSuite) SetupTest() {
+	suite.owner, suite.reader, suite.writer = uuid.New(), uuid.New(), uuid.New()
+	suite.model = Model{
+		UserID:       suite.owner,
+		Name:         "exchange hack",
+		Addresses:    []string{"1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa"},
+		Transactions: []string{strings.Repeat("ab", 32)},
+		Clusters:     []int64{42},
+		Trace:        TraceParams{Model: "haircut", Direction: "forward", Depth: 10},
+		Shares: []Share{
+			{UserID: suite.reader, Permission: Read},
+			{UserID: suite.writer, Permission: Write},
+		},
+	}
+}
+
+func (suite *TestInvestigationSuite) TestPermission() {
+	assert.Equal(suite.T(), Write, Permission(&suite.model, suite.owner.String()))
+	assert.Equal(suite.T(), Read, Permission(&suite.model, suite.reader.String()))
+	assert.Equal(suite.T(), Write, Permission(&suite.model, suite.writer.String()))
+	assert.Equal(suite.T(), "", Permission(&suite.model, uuid.New().String()))
+}
+
+func (suite *TestInvestigationSuite) TestValidation() {
+	v := validator.NewValidator()
+	assert.Nil(suite.T(), v.Validate(&suite.model))
+
+	invalid := suite.model
+	invalid.Transactions = []string{"not a txid"}
+	assert.NotNil(suite.T(), v.Validate(&invalid))
+
+	invalid = suite.model
+	invalid.Trace.Model = "unknown"
+	assert.NotNil(suite.T(), v.Validate(&invalid))
+
+	invalid = suite.model
+	invalid.Name = ""
+	assert.NotNil(suite.T(), v.Validate(&invalid))
+
+	assert.Nil(suite.T(), v.Validate(&ShareBody{Email: "analyst@bitgodine.com"}))
+	assert.NotNil(suite.T(), v.Validate(&ShareBody{Email: "analyst@bitgodine.com", Permission: "admin"}))
+}
+
+func (suite *TestInvestigationSuite) TestShareAgain() {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{DisableForeignKeyConstraintWhenMigrating: true})
+	require.Nil(suite.T(), err)
+	sql, err := db.DB()
+	require.Nil(suite.T(), err)
+	defer sql.Close()
+	// each connection opens its own in memory db
+	sql.SetMaxOpenConns(1)
+	// sqlite has no array types, they are stored in their text encoding
+	require.Nil(suite.T(), db.Exec(`CREATE TABLE users (id text PRIMARY KEY, email text, deleted_at datetime)`).Error)
+	require.Nil(suite.T(), db.Exec(`CREATE TABLE investigations (id text PRIMARY KEY, created_at datetime, updated_at datetime, deleted_at datetime,
+		user_id text NOT NULL, name text NOT NULL, addresses text, transactions text, clusters text, trace_model text, trace_direction text,
+		trace_depth integer, trace_min_value integer, trace_min_fraction real, trace_from integer, trace_to integer, notes text)`).Error)
+	require.Nil(suite.T(), db.AutoMigrate(&Share{}))
+	require.Nil(suite.T(), db.Exec("INSERT INTO users (id, email) VALUES (?, ?), (?, ?)", suite.owner, "owner@bitgodine.com", suite.reader, "reader@bitgodine.com").Error)
+
+	s := NewService(&postgres.Pg{DB: db})
+	investigation := &Model{Name: "exchange hack"}
+	require.Nil(suite.T(), s.CreateInvestigation(suite.owner.String(), investigation))
+	ID := investigation.ID.String()
+
+	share, err := s.ShareInvestigation(suite.owner.String(), ID, &ShareBody{Email: "reader@bitgodine.com", Permission: Write})
+	require.Nil(suite.T(), err)
+	assert.Equal(suite.T(), Write, share.Permission)
+	require.Nil(suite.T(), s.UnshareInvestigation(suite.owner.String(), ID, suite.reader.String()))
+	_, err = s.GetInvestigation(suite.reader.String(), ID)
+	assert.NotNil(suite.T(), err)
+
+	share, err = s.ShareInvestigation(suite.owner.String(), ID, &ShareBody{Email: "reader@bitgodine.com"})
+	require.Nil(suite.T(), err)
+	assert.Equal(suite.T(), Read, share.Permission)
+	shared, err := s.GetInvestigation(suite.reader.String(), ID)
+	require.Nil(suite.T(), err)
+	assert.Equal(suite.T(), Read, Permission(shared, suite.reader.String()))
+}
+
+func TestInvestigation(t *testing.T) {
+	suite.Run(t, new(TestInvestigationSuite))
+}
@@ -0,0 +1,77 @@
+package investigation
+
+import (
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+// Permissions granted to the users an investigation is shared with
+const (
+	Read  = "read"
+	Write = "write"
+)
+
+// TraceParams parameters of the trace the analyst is working on
+type TraceParams struct {
+	Model       string  `json:"model,omitempty" validate:"omitempty,oneof=poison haircut fifo tiho"`
+	Direction   string  `json:"direction,omitempty" validate:"omitempty,oneof=forward backward"`
+	Depth       int     `json:"depth,omitempty" validate:"omitempty,gt=0,lte=100"`
+	MinValue    int64   `json:"min_value,omitempty" validate:"omitempty,gte=0"`
+	MinFraction float64 `json:"min_fraction,omitempty" validate:"omitempty,gte=0,lte=1"`
+	From        int64   `json:"from,omitempty" validate:"omitempty,gte=0"`
+	To          int64   `json:"to,omitempty" validate:"omitempty,gtefield=From"`
+} //@name TraceParams
+
+// Model investigation struct with validation. An investigation is a named workspace holding the entities
+// an analyst is working on, owned by a user and optionally shared with other users
+type Model struct {
+	gorm.Model
+	ID           uuid.UUID      `json:"id" gorm:"primarykey;index;unique"`
+	UserID       uuid.UUID      `json:"user_id" gorm:"index;not null"`
+	Name         string         `json:"name" validate:"required" gorm:"not null"`
+	Addresses    pq.StringArray `json:"addresses,omitempty" validate:"omitempty,dive,required" gorm:"type:text[]"`
+	Transactions pq.StringArray `json:"transactions,omitempty" validate:"omitempty,dive,len=64,hexadecimal" gorm:"type:varchar(64)[]"`
+	Clusters     pq.Int64Array  `json:"clusters,omitempty" validate:"omitempty,dive,gte=0" gorm:"type:bigint[]"`
+	Trace        TraceParams    `json:"trace" validate:"" gorm:"embedded;embeddedPrefix:trace_"`
+	Notes        string         `json:"notes,omitempty" validate:"" gorm:"type:text"`
+
+	Shares []Share `json:"shares,omitempty" validate:"-" gorm:"constraint:OnDelete:CASCADE;foreignKey:InvestigationID;references:ID"`
+} //@name Investigation
+
+// BeforeCreate generates the investigation id
+func (m *Model) BeforeCreate(tx *gorm.DB) (err error) {
+	m.ID = uuid.New()
+	return
+}
+
+// TableName defines default table name
+func (m *Model) TableName() string {
+	return "investigations"
+}
+
+// Share grant of an investigation to a user other than the owner
+type Share struct {
+	gorm.Model
+	ID              uuid.UUID `json:"id" gorm:"primarykey;index;unique"`
+	InvestigationID uuid.UUID `json:"investigation_id" gorm:"uniqueIndex:idx_investigation_share;not null"`
+	UserID          uuid.UUID `json:"user_id" gorm:"uniqueIndex:idx_investigation_share;index;not null"`
+	Permission      string    `json:"permission" gorm:"not null;default:'read'"`
+} //@name InvestigationShare
+
+// BeforeCreate generates the share id
+func (m *Share) BeforeCreate(tx *gorm.DB) (err error) {
+	m.ID = uuid.New()
+	return
+}
+
+// TableName defines default table name
+func (m *Share) TableName() string {
+	return "investigation_shares"
+}
+
+// ShareBody body request to share an investigation with a user, identified by email
+type ShareBody struct {
+	Email      string `json:"email" validate:"required,email"`
+	Permission string `json:"permission" validate:"omitempty,oneof=read write"`
+}
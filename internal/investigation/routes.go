@@ -0,0 +1,297 @@
+package investigation
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/xn3cr0nx/bitgodine/pkg/validator"
+)
+
+// Routes mounts all /investigations based routes on the main group
+func Routes(g *echo.Group, s Service) {
+	r := g.Group("/investigations", validator.JWT())
+
+	r.GET("", getInvestigations(s))
+	r.POST("", createInvestigation(s))
+	r.GET("/:id", getInvestigation(s))
+	r.PUT("/:id", updateInvestigation(s))
+	r.DELETE("/:id", deleteInvestigation(s))
+	r.POST("/:id/shares", shareInvestigation(s))
+	r.DELETE("/:id/shares/:user", unshareInvestigation(s))
+}
+
+// paramID validates and returns an uuid path parameter
+func paramID(c echo.Context, name string) (string, error) {
+	id := c.Param(name)
+	if err := c.Echo().Validator.(*validator.CustomValidator).Var(id, "required,uuid"); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// getInvestigations godoc
+// @ID get-investigations
+//
+// @Router /investigations [get]
+// @Summary Get investigations list
+// @Description get the investigations owned by the authenticated user or shared with them, most recently updated first
+// @Tags investigations
+//
+// @Security ApiKeyAuth
+//
+// @Accept  json
+// @Produce  json
+//
+// @Success 200 {array} Model
+// @Success 500 {string} string
+func getInvestigations(s Service) func(echo.Context) error {
+	return func(c echo.Context) error {
+		ID, err := validator.UserID(c)
+		if err != nil {
+			return err
+		}
+
+		investigations, err := s.GetInvestigations(ID)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, investigations)
+	}
+}
+
+// createInvestigation godoc
+// @ID create-investigation
+//
+// @Router /investigations [post]
+// @Summary Create investigation
+// @Description save a new investigation holding addresses, transactions, clusters, trace parameters and notes
+// @Tags investigations
+//
+// @Security ApiKeyAuth
+//
+// @Accept  json
+// @Produce  json
+//
+// @Param model body Model true "investigation model"
+//
+// @Success 201 {object} Model
+// @Success 500 {string} string
+func createInvestigation(s Service) func(echo.Context) error {
+	return func(c echo.Context) error {
+		ID, err := validator.UserID(c)
+		if err != nil {
+			return err
+		}
+
+		b := new(Model)
+		if err := validator.Struct(&c, b); err != nil {
+			return err
+		}
+
+		if err := s.CreateInvestigation(ID, b); err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusCreated, b)
+	}
+}
+
+// getInvestigation godoc
+// @ID get-investigation
+//
+// @Router /investigations/{id} [get]
+// @Summary Get investigation
+// @Description get an investigation owned by the authenticated user or shared with them
+// @Tags investigations
+//
+// @Security ApiKeyAuth
+//
+// @Accept  json
+// @Produce  json
+//
+// @Param id path string true "investigation id"
+//
+// @Success 200 {object} Model
+// @Success 500 {string} string
+func getInvestigation(s Service) func(echo.Context) error {
+	return func(c echo.Context) error {
+		ID, err := validator.UserID(c)
+		if err != nil {
+			return err
+		}
+		id, err := paramID(c, "id")
+		if err != nil {
+			return err
+		}
+
+		investigation, err := s.GetInvestigation(ID, id)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, investigation)
+	}
+}
+
+// updateInvestigation godoc
+// @ID update-investigation
+//
+// @Router /investigations/{id} [put]
+// @Summary Update investigation
+// @Description update the content of an investigation, allowed to the owner and to users it's shared with in write
+// @Tags investigations
+//
+// @Security ApiKeyAuth
+//
+// @Accept  json
+// @Produce  json
+//
+// @Param id path string true "investigation id"
+// @Param model body Model true "investigation model"
+//
+// @Success 200 {object} Model
+// @Success 500 {string} string
+func updateInvestigation(s Service) func(echo.Context) error {
+	return func(c echo.Context) error {
+		ID, err := validator.UserID(c)
+		if err != nil {
+			return err
+		}
+		id, err := paramID(c, "id")
+		if err != nil {
+			return err
+		}
+
+		b := new(Model)
+		if err := validator.Struct(&c, b); err != nil {
+			return err
+		}
+
+		if err := s.UpdateInvestigation(ID, id, b); err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, b)
+	}
+}
+
+// deleteInvestigation godoc
+// @ID delete-investigation
+//
+// @Router /investigations/{id} [delete]
+// @Summary Delete investigation
+// @Description delete an investigation owned by the authenticated user
+// @Tags investigations
+//
+// @Security ApiKeyAuth
+//
+// @Accept  json
+// @Produce  json
+//
+// @Param id path string true "investigation id"
+//
+// @Success 200 {string} ok
+// @Success 500 {string} string
+func deleteInvestigation(s Service) func(echo.Context) error {
+	return func(c echo.Context) error {
+		ID, err := validator.UserID(c)
+		if err != nil {
+			return err
+		}
+		id, err := paramID(c, "id")
+		if err != nil {
+			return err
+		}
+
+		if err := s.DeleteInvestigation(ID, id); err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, "ok")
+	}
+}
+
+// shareInvestigation godoc
+// @ID share-investigation
+//
+// @Router /investigations/{id}/shares [post]
+// @Summary Share investigation
+// @Description share an investigation owned by the authenticated user with another user, read only by default
+// @Tags investigations
+//
+// @Security ApiKeyAuth
+//
+// @Accept  json
+// @Produce  json
+//
+// @Param id path string true "investigation id"
+// @Param share body ShareBody true "share body"
+//
+// @Success 200 {object} Share
+// @Success 500 {string} string
+func shareInvestigation(s Service) func(echo.Context) error {
+	return func(c echo.Context) error {
+		ID, err := validator.UserID(c)
+		if err != nil {
+			return err
+		}
+		id, err := paramID(c, "id")
+		if err != nil {
+			return err
+		}
+
+		b := new(ShareBody)
+		if err := validator.Struct(&c, b); err != nil {
+			return err
+		}
+
+		share, err := s.ShareInvestigation(ID, id, b)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, share)
+	}
+}
+
+// unshareInvestigation godoc
+// @ID unshare-investigation
+//
+// @Router /investigations/{id}/shares/{user} [delete]
+// @Summary Unshare investigation
+// @Description revoke the access of a user to the investigation. Users can leave the investigations shared with them
+// @Tags investigations
+//
+// @Security ApiKeyAuth
+//
+// @Accept  json
+// @Produce  json
+//
+// @Param id path string true "investigation id"
+// @Param user path string true "user id"
+//
+// @Success 200 {string} ok
+// @Success 500 {string} string
+func unshareInvestigation(s Service) func(echo.Context) error {
+	return func(c echo.Context) error {
+		ID, err := validator.UserID(c)
+		if err != nil {
+			return err
+		}
+		id, err := paramID(c, "id")
+		if err != nil {
+			return err
+		}
+		user, err := paramID(c, "user")
+		if err != nil {
+			return err
+		}
+
+		if err := s.UnshareInvestigation(ID, id, user); err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, "ok")
+	}
+}
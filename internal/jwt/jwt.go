@@ -1,19 +1,29 @@
 package jwt
 
 import (
+	"errors"
 	"fmt"
+	"net/http"
+	"strconv"
 	"time"
 
 	token "github.com/dgrijalva/jwt-go"
+	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/spf13/viper"
 	"github.com/xn3cr0nx/bitgodine/internal/errorx"
+	"github.com/xn3cr0nx/bitgodine/internal/storage/kv"
 )
 
+// ErrRevoked the session has been issued before the sessions of the user were revoked
+var ErrRevoked = errors.New("session revoked")
+
 // CustomClaims custom token object
 type CustomClaims struct {
 	ID    string `json:"id"`
 	Email string `json:"email"`
+	Role  string `json:"role"`
+	Plan  string `json:"plan,omitempty"`
 	token.StandardClaims
 }
 
@@ -26,12 +36,15 @@ func Config() middleware.JWTConfig {
 }
 
 // NewToken returns a new jwt token based on CustomClaims structure
-func NewToken(id, email string, d time.Duration) (string, error) {
+func NewToken(id, email, role, plan string, d time.Duration) (string, error) {
 	claims := &CustomClaims{
 		id,
 		email,
+		role,
+		plan,
 		token.StandardClaims{
 			ExpiresAt: time.Now().Add(d).Unix(),
+			IssuedAt:  time.Now().Unix(),
 		},
 	}
 	token := token.NewWithClaims(token.SigningMethodHS256, claims)
@@ -75,3 +88,56 @@ func Decode(t interface{}) (*CustomClaims, error) {
 	tk := t.(*token.Token)
 	return tk.Claims.(*CustomClaims), nil
 }
+
+// sessionsKey returns the key storing when the sessions of the user have been revoked
+func sessionsKey(ID string) string {
+	return "sessions_" + ID
+}
+
+// RevokeSessions invalidates the sessions issued to the user until now
+func RevokeSessions(db kv.DB, ID string) error {
+	return db.Store(sessionsKey(ID), []byte(strconv.FormatInt(time.Now().Unix(), 10)))
+}
+
+// Revoked returns true if the session has been issued before the sessions of its user were revoked.
+// Sessions without issue time predate the revocation
+func Revoked(db kv.DB, claims *CustomClaims) (revoked bool, err error) {
+	value, err := db.Read(sessionsKey(claims.ID))
+	if err != nil {
+		if errors.Is(err, errorx.ErrKeyNotFound) {
+			err = nil
+		}
+		return
+	}
+	revokedAt, err := strconv.ParseInt(string(value), 10, 64)
+	if err != nil {
+		return
+	}
+	revoked = claims.IssuedAt < revokedAt
+	return
+}
+
+// Middleware authenticates the session jwt, refusing the sessions revoked in the kv store of the context
+func Middleware() echo.MiddlewareFunc {
+	session := middleware.JWTWithConfig(Config())
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return session(func(c echo.Context) error {
+			db, ok := c.Get("db").(kv.DB)
+			if !ok || db == nil {
+				return next(c)
+			}
+			claims, err := Decode(c.Get("user"))
+			if err != nil {
+				return err
+			}
+			revoked, err := Revoked(db, claims)
+			if err != nil {
+				return err
+			}
+			if revoked {
+				return echo.NewHTTPError(http.StatusUnauthorized, ErrRevoked.Error())
+			}
+			return next(c)
+		})
+	}
+}
@@ -7,8 +7,11 @@ import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"github.com/spf13/viper"
+	"github.com/stretchr/testify/mock"
 
+	"github.com/xn3cr0nx/bitgodine/internal/errorx"
 	. "github.com/xn3cr0nx/bitgodine/internal/jwt"
+	"github.com/xn3cr0nx/bitgodine/internal/storage/kv"
 )
 
 var _ = Describe("Jwt", func() {
@@ -21,9 +24,11 @@ var _ = Describe("Jwt", func() {
 		config = CustomClaims{
 			ID:    "1234",
 			Email: "dev@bqtx.com",
+			Role:  "viewer",
+			Plan:  "free",
 		}
 		var err error
-		token, err = NewToken(config.ID, config.Email, 72*time.Hour)
+		token, err = NewToken(config.ID, config.Email, config.Role, config.Plan, 72*time.Hour)
 		Expect(err).ShouldNot(HaveOccurred())
 	})
 
@@ -36,7 +41,7 @@ var _ = Describe("Jwt", func() {
 		})
 
 		It("should generate a new jwt token", func() {
-			t, err := NewToken("1234", "dev@bqtx.com", 72*time.Hour)
+			t, err := NewToken("1234", "dev@bqtx.com", "viewer", "free", 72*time.Hour)
 			Expect(err).ShouldNot(HaveOccurred())
 			Expect(t).To(ContainSubstring("."))
 		})
@@ -69,4 +74,51 @@ var _ = Describe("Jwt", func() {
 			Expect(res.Email).To(Equal(config.Email))
 		})
 	})
+
+	Describe("Revoking sessions", func() {
+		var (
+			db    *kv.DBMock
+			store map[string][]byte
+		)
+
+		BeforeEach(func() {
+			store = make(map[string][]byte)
+			db = kv.NewDBMock()
+			db.On("Store", mock.Anything, mock.Anything).Return(func(key string, value []byte) error {
+				store[key] = value
+				return nil
+			})
+			db.On("Read", mock.Anything).Return(func(key string) []byte {
+				return store[key]
+			}, func(key string) error {
+				if _, ok := store[key]; !ok {
+					return errorx.ErrKeyNotFound
+				}
+				return nil
+			})
+		})
+
+		It("Should accept sessions of users never revoked", func() {
+			revoked, err := Revoked(db, &CustomClaims{ID: "1234"})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(revoked).To(BeFalse())
+		})
+
+		It("Should refuse sessions issued before the revocation only", func() {
+			Expect(RevokeSessions(db, "1234")).To(Succeed())
+			issued := &CustomClaims{ID: "1234", StandardClaims: jwt.StandardClaims{IssuedAt: time.Now().Add(-time.Minute).Unix()}}
+			revoked, err := Revoked(db, issued)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(revoked).To(BeTrue())
+
+			issued.IssuedAt = time.Now().Unix()
+			revoked, err = Revoked(db, issued)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(revoked).To(BeFalse())
+
+			revoked, err = Revoked(db, &CustomClaims{ID: "5678"})
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(revoked).To(BeFalse())
+		})
+	})
 })
@@ -3,11 +3,16 @@ package migration
 import (
 	"github.com/xn3cr0nx/bitgodine/internal/abuse"
 	"github.com/xn3cr0nx/bitgodine/internal/analysis"
+	"github.com/xn3cr0nx/bitgodine/internal/apikey"
+	"github.com/xn3cr0nx/bitgodine/internal/audit"
 	"github.com/xn3cr0nx/bitgodine/internal/cluster"
+	"github.com/xn3cr0nx/bitgodine/internal/investigation"
+	"github.com/xn3cr0nx/bitgodine/internal/spider"
 	"github.com/xn3cr0nx/bitgodine/internal/storage/db/postgres"
 	"github.com/xn3cr0nx/bitgodine/internal/tag"
 	"github.com/xn3cr0nx/bitgodine/internal/user"
 	"github.com/xn3cr0nx/bitgodine/internal/user/preferences"
+	"github.com/xn3cr0nx/bitgodine/internal/watchlist"
 )
 
 // Migration sets up initial migration of tags involved tables
@@ -18,17 +23,98 @@ func Migration(pg *postgres.Pg) (err error) {
 	if !pg.DB.Migrator().HasTable("users") {
 		err = pg.DB.Migrator().CreateTable(&user.Model{})
 	}
+	if !pg.DB.Migrator().HasColumn(&user.Model{}, "Role") {
+		err = pg.DB.Migrator().AddColumn(&user.Model{}, "Role")
+	}
+	if !pg.DB.Migrator().HasColumn(&user.Model{}, "Plan") {
+		err = pg.DB.Migrator().AddColumn(&user.Model{}, "Plan")
+	}
 	if !pg.DB.Migrator().HasTable("analysis") {
 		err = pg.DB.Migrator().CreateTable(&analysis.Model{})
 	}
 	if !pg.DB.Migrator().HasTable("tags") {
 		err = pg.DB.Migrator().CreateTable(&tag.Model{})
 	}
+	if !pg.DB.Migrator().HasColumn(&tag.Model{}, "Source") {
+		err = pg.DB.Migrator().AddColumn(&tag.Model{}, "Source")
+	}
+	if !pg.DB.Migrator().HasColumn(&tag.Model{}, "BatchID") {
+		err = pg.DB.Migrator().AddColumn(&tag.Model{}, "BatchID")
+	}
+	if !pg.DB.Migrator().HasTable("tag_batches") {
+		err = pg.DB.Migrator().CreateTable(&tag.Batch{})
+	}
+	if !pg.DB.Migrator().HasColumn(&tag.Batch{}, "Rejected") {
+		err = pg.DB.Migrator().AddColumn(&tag.Batch{}, "Rejected")
+	}
+	if !pg.DB.Migrator().HasColumn(&tag.Model{}, "Confidence") {
+		err = pg.DB.Migrator().AddColumn(&tag.Model{}, "Confidence")
+	}
+	if !pg.DB.Migrator().HasColumn(&tag.Model{}, "FirstSeen") {
+		err = pg.DB.Migrator().AddColumn(&tag.Model{}, "FirstSeen")
+	}
+	if !pg.DB.Migrator().HasColumn(&tag.Model{}, "LastSeen") {
+		err = pg.DB.Migrator().AddColumn(&tag.Model{}, "LastSeen")
+	}
+	if !pg.DB.Migrator().HasColumn(&tag.Model{}, "ExpiresAt") {
+		err = pg.DB.Migrator().AddColumn(&tag.Model{}, "ExpiresAt")
+	}
+	if !pg.DB.Migrator().HasTable("entities") {
+		err = pg.DB.Migrator().CreateTable(&tag.Entity{})
+	}
+	if !pg.DB.Migrator().HasTable("entity_aliases") {
+		err = pg.DB.Migrator().CreateTable(&tag.Alias{})
+	}
 	if !pg.DB.Migrator().HasTable("abuses") {
 		err = pg.DB.Migrator().CreateTable(&abuse.Model{})
 	}
+	if !pg.DB.Migrator().HasColumn(&abuse.Model{}, "Category") {
+		err = pg.DB.Migrator().AddColumn(&abuse.Model{}, "Category")
+	}
+	if !pg.DB.Migrator().HasColumn(&abuse.Model{}, "Fingerprint") {
+		err = pg.DB.Migrator().AddColumn(&abuse.Model{}, "Fingerprint")
+	}
+	if err == nil {
+		err = abuse.Backfill(pg.DB)
+	}
 	if !pg.DB.Migrator().HasTable("clusters") {
 		err = pg.DB.Migrator().CreateTable(&cluster.Model{})
 	}
+	if !pg.DB.Migrator().HasIndex(&cluster.Model{}, "idx_clusters_unique_address") {
+		if err = cluster.Dedupe(pg.DB); err != nil {
+			return
+		}
+		if err = pg.DB.Migrator().CreateIndex(&cluster.Model{}, "idx_clusters_unique_address"); err != nil {
+			return
+		}
+	}
+	if !pg.DB.Migrator().HasTable("cluster_labels") {
+		err = pg.DB.Migrator().CreateTable(&cluster.Label{})
+	}
+	if !pg.DB.Migrator().HasTable("api_keys") {
+		err = pg.DB.Migrator().CreateTable(&apikey.Model{})
+	}
+	if !pg.DB.Migrator().HasTable("audit_logs") {
+		err = pg.DB.Migrator().CreateTable(&audit.Model{})
+	}
+	if !pg.DB.Migrator().HasTable("watchlists") {
+		err = pg.DB.Migrator().CreateTable(&watchlist.Model{})
+	}
+	if !pg.DB.Migrator().HasTable("alerts") {
+		err = pg.DB.Migrator().CreateTable(&watchlist.Alert{})
+	}
+	if !pg.DB.Migrator().HasTable("investigations") {
+		err = pg.DB.Migrator().CreateTable(&investigation.Model{})
+	}
+	if !pg.DB.Migrator().HasTable("investigation_shares") {
+		err = pg.DB.Migrator().CreateTable(&investigation.Share{})
+	}
+	if err == nil {
+		// soft deleted shares held the unique index, blocking the user from being granted access again
+		err = pg.DB.Unscoped().Where("deleted_at IS NOT NULL").Delete(&investigation.Share{}).Error
+	}
+	if !pg.DB.Migrator().HasTable("spider_states") {
+		err = pg.DB.Migrator().CreateTable(&spider.State{})
+	}
 	return
 }
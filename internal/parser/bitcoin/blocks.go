@@ -22,13 +22,19 @@ type Block struct {
 
 // Store prepares the block struct and and call StoreBlock to store it
 func (b *Block) Store(db kv.DB, height int32) (err error) {
+	_, err = b.store(db, block.NewService(db, nil), height)
+	return
+}
+
+// store stores the block through the block service returning its prepared transactions
+func (b *Block) store(db kv.DB, blocks block.Service, height int32) (transactions []tx.Tx, err error) {
 	b.SetHeight(height)
 	if height%100 == 0 {
 		logger.Info("Parser Blocks", "Block "+strconv.Itoa(int(b.Height())), logger.Params{"hash": b.Hash().String(), "height": b.Height()})
 	}
 	logger.Debug("Parser Blocks", "Storing block", logger.Params{"hash": b.Hash().String(), "height": height})
 
-	transactions, err := PrepareTransactions(db, b.Transactions())
+	transactions, err = PrepareTransactions(db, b.Transactions())
 	if err != nil {
 		return
 	}
@@ -69,7 +75,7 @@ func (b *Block) Store(db kv.DB, height int32) (err error) {
 		Weight:            len(weight),
 		Previousblockhash: b.MsgBlock().Header.PrevBlock.String(),
 	}
-	err = block.NewService(db, nil).StoreBlock(&blk, transactions)
+	err = blocks.StoreBlock(&blk, transactions)
 	return
 }
 
@@ -113,7 +119,8 @@ func ExtractBlockFromFile(file *[]uint8) (blk *Block, err error) {
 		if e != nil {
 			return nil, e
 		}
-		res, e := btcutil.NewBlockFromBytes(block)
+		// the block keeps a reference to its bytes, copied so that the file can be unmapped
+		res, e := btcutil.NewBlockFromBytes(append([]byte(nil), block...))
 		if e != nil {
 			err = fmt.Errorf("%s: %w", ErrBlockFromBytes.Error(), e)
 			return
@@ -47,3 +47,11 @@ func NewClient() (*rpcclient.Client, error) {
 	}
 	return client, nil
 }
+
+// NewRPCClient returns a bitcoin client issuing the requests through HTTP POST, without notifications
+func NewRPCClient() (*rpcclient.Client, error) {
+	conf := ClientConfig()
+	conf.HTTPPostMode = true
+	conf.DisableTLS = conf.Certificates == nil
+	return rpcclient.New(conf, nil)
+}
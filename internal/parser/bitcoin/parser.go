@@ -6,6 +6,7 @@ import (
 	"os"
 	"os/signal"
 	"strconv"
+	"sync/atomic"
 	"time"
 
 	"github.com/btcsuite/btcd/chaincfg/chainhash"
@@ -13,6 +14,7 @@ import (
 	"github.com/spf13/viper"
 	"github.com/xn3cr0nx/bitgodine/internal/block"
 	"github.com/xn3cr0nx/bitgodine/internal/storage/kv"
+	"github.com/xn3cr0nx/bitgodine/internal/tx"
 	"github.com/xn3cr0nx/bitgodine/internal/utxoset"
 	"github.com/xn3cr0nx/bitgodine/pkg/cache"
 	"github.com/xn3cr0nx/bitgodine/pkg/logger"
@@ -26,10 +28,32 @@ type Parser struct {
 	blockchain *Blockchain
 	client     *rpcclient.Client
 	db         kv.DB
-	skipped    *Skipped
+	reorder    *Reorder
 	utxoset    *utxoset.UtxoSet
 	cache      *cache.Cache
 	interrupt  chan int
+	listener   BlockListener
+	blocks     block.Service
+	// synced set once the parser went through all the blockchain files
+	synced int32
+}
+
+// BlockListener receives the transactions of each block stored by the parser
+type BlockListener interface {
+	OnBlock(height int32, hash string, transactions []tx.Tx) error
+}
+
+// Listeners notifies each block to all the listeners, returning the first error after notifying all of them
+type Listeners []BlockListener
+
+// OnBlock notifies the block to the listeners
+func (l Listeners) OnBlock(height int32, hash string, transactions []tx.Tx) (err error) {
+	for _, listener := range l {
+		if e := listener.OnBlock(height, hash, transactions); e != nil && err == nil {
+			err = e
+		}
+	}
+	return
 }
 
 // CheckPoint represents the last parse state
@@ -40,16 +64,48 @@ type CheckPoint struct {
 }
 
 // NewParser return a new instance to Bitcoin blockchai parser
-func NewParser(blockchain *Blockchain, client *rpcclient.Client, db kv.DB, skipped *Skipped, utxoset *utxoset.UtxoSet, c *cache.Cache, interrupt chan int) Parser {
+func NewParser(blockchain *Blockchain, client *rpcclient.Client, db kv.DB, reorder *Reorder, utxoset *utxoset.UtxoSet, c *cache.Cache, interrupt chan int) Parser {
+	blocks := block.NewService(db, c)
+	blocks.Recent = block.NewRecentOutputs()
 	return Parser{
 		blockchain: blockchain,
 		client:     client,
 		db:         db,
-		skipped:    skipped,
+		reorder:    reorder,
 		utxoset:    utxoset,
 		cache:      c,
 		interrupt:  interrupt,
+		blocks:     blocks,
+	}
+}
+
+// Blocks returns the block service storing the parsed blocks, resolving spent outputs
+// from the recent blocks whose batch may not be written yet
+func (p *Parser) Blocks() block.Service {
+	return p.blocks
+}
+
+// SetListener registers the listener notified of each stored block
+func (p *Parser) SetListener(l BlockListener) {
+	p.listener = l
+}
+
+// Synced tells whether the parser caught up with the blockchain files, the blocks stored from then on
+// are the ones just mined
+func (p *Parser) Synced() bool {
+	return atomic.LoadInt32(&p.synced) == 1
+}
+
+// storeBlock stores the block and notifies the listener. A failing listener doesn't stop the parsing
+func (p *Parser) storeBlock(b *Block, height int32) (err error) {
+	transactions, err := b.store(p.db, p.blocks, height)
+	if err != nil || p.listener == nil {
+		return
+	}
+	if e := p.listener.OnBlock(height, b.Hash().String(), transactions); e != nil {
+		logger.Error("Blockchain", e, logger.Params{"hash": b.Hash().String(), "height": height})
 	}
+	return
 }
 
 func handleInterrupt(c chan os.Signal, interrupt chan int) {
@@ -95,11 +151,12 @@ func (p *Parser) InfinitelyParse() (err error) {
 				}
 				return err
 			}
+			atomic.StoreInt32(&p.synced, 1)
 		}
 	}
 }
 
-// Parse goes through the blockchain block by block
+// Parse goes through the blockchain files, decoding them concurrently and storing blocks in height order
 func (p *Parser) Parse() (err error) {
 	var rawChain [][]uint8
 	for _, ref := range p.blockchain.Maps {
@@ -110,152 +167,18 @@ func (p *Parser) Parse() (err error) {
 		return ErrNoBitcoinData
 	}
 
+	p.reorder.Empty()
 	check, err := p.FindCheckPoint(rawChain)
 	if err != nil {
 		return
 	}
 	logger.Info("Blockchain", "Start syncing from block "+Itoa(check.height), logger.Params{})
 
-	for k, file := range rawChain {
-		select {
-		case x, ok := <-p.interrupt:
-			if !ok {
-				err = ErrInterruptUnknown
-			}
-			logger.Info("Blockchain", "Received interrupt signal", logger.Params{"signal": x})
-			err = ErrInterrupt
-			return
-		default:
-			if len(file) == 0 {
-				continue
-			}
-			logger.Info("Blockchain", "Parsing the blockchain", logger.Params{"file": Itoa(int32(k)) + "/" + Itoa(int32(len(p.blockchain.Maps)-1)), "height": Itoa(check.height), "lastBlock": check.goalPrevHash.String()})
-			// if check, err = ParseFile(p, &file, check); err != nil {
-			if check, err = ParseFile(p, check, &file); err != nil {
-				return
-			}
-
-			if err = StoreFileParsed(p.db, k); err != nil {
-				return
-			}
-
-			if err = p.blockchain.Maps[k].Unmap(); err != nil {
-				return
-			}
-		}
-	}
-
-	return
-}
-
-// ParseFile walks through the raw file and extract blocks
-func ParseFile(p *Parser, c CheckPoint, file *[]uint8) (check CheckPoint, err error) {
-	// performance improvement to reduce pointers allocation
-	check = c
-	for len(*file) > 0 {
-		select {
-		case x, ok := <-p.interrupt:
-			if !ok {
-				err = ErrInterruptUnknown
-			}
-			logger.Info("Blockchain", "Received interrupt signal", logger.Params{"signal": x})
-			err = ErrInterrupt
-			return
-
-		default:
-			if _, e := p.skipped.GetBlock(check.goalPrevHash); e == nil {
-				logger.Debug("Blockchain", "(rewind - pre-step) Block "+Itoa(check.height)+" - "+check.lastBlock.MsgBlock().Header.PrevBlock.String()+" -> "+check.lastBlock.Hash().String(), logger.Params{})
-				if err = check.lastBlock.Store(p.db, check.height); err != nil {
-					return
-				}
-				check.height++
-				for {
-					if block, e := p.skipped.GetBlock(check.goalPrevHash); e == nil {
-						p.skipped.DeleteBlock(check.goalPrevHash)
-						logger.Debug("Blockchain", "(rewind) Block "+Itoa(check.height)+" - "+block.MsgBlock().Header.PrevBlock.String()+" -> "+block.Hash().String(), logger.Params{})
-						if err = block.Store(p.db, check.height); err != nil {
-							return
-						}
-						check.height++
-						check.goalPrevHash = block.Hash()
-						check.lastBlock = nil
-						continue
-					}
-					break
-				}
-			}
-
-			block, e := ExtractBlockFromFile(file)
-			if e != nil {
-				if !errors.Is(e, ErrEmptySliceParse) {
-					err = e
-					return
-				}
-				break
-			}
-
-			logger.Debug("Blockchain", "Block candidate for height "+Itoa(check.height)+" - goal_prev_hash = "+check.goalPrevHash.String()+", prev_hash = "+block.MsgBlock().Header.PrevBlock.String()+", cur_hash = "+block.Hash().String(), logger.Params{})
-
-			// Explanation: parsing the dat files means find a not ordinate sequence of  In most cases parsing the next block means
-			// find a block that it's been added to blockchain many blocks after, so at a higher height. This means that, that block, will
-			// be necessaire later when the parsing will reach the preceding block. At that point you will need to have the already parsed
-			// block. This is why the skipped slice is built, is where we keep the unordinate blocks already parsed. If we stop the parsing
-			// process and restart it, we need to restore the skipped block slice too, because otherwire we wouldn't have all the blocks
-			// needed to complete the chain.
-			if !block.MsgBlock().Header.PrevBlock.IsEqual(check.goalPrevHash) {
-				logger.Debug("Blockchain", "Skipped block", logger.Params{"prev": block.MsgBlock().Header.PrevBlock.String()})
-				p.skipped.StoreBlockPrevHash(block)
-
-				// check if last_block.is_some() condition is correctly replaced with checkBlock()
-				if check.lastBlock.CheckBlock() && block.MsgBlock().Header.PrevBlock.String() == check.lastBlock.MsgBlock().Header.PrevBlock.String() {
-					logger.Debug("Blockchain", "Chain split detected: "+check.lastBlock.Hash().String()+"% <-> "+block.Hash().String()+". Detecting main chain and orphan.", logger.Params{})
-
-					firstOrphan := check.lastBlock
-					secondOrphan := block
-
-					for {
-						block, e := ExtractBlockFromFile(file)
-						if err != nil {
-							if !errors.Is(e, ErrEmptySliceParse) {
-								err = e
-								return
-							}
-							break
-						}
-
-						p.skipped.StoreBlockPrevHash(block)
-						if block.MsgBlock().Header.PrevBlock.IsEqual(firstOrphan.Hash()) {
-							// First wins
-							logger.Debug("Blockchain", "Chain split: "+firstOrphan.Hash().String()+" is on the main chain!", logger.Params{})
-							break
-						}
-						if block.MsgBlock().Header.PrevBlock.IsEqual(secondOrphan.Hash()) {
-							// Second wins
-							logger.Debug("Blockchain", "Chain split: "+secondOrphan.Hash().String()+" is on the main chain!", logger.Params{})
-							check.goalPrevHash = secondOrphan.Hash()
-							check.lastBlock = secondOrphan
-							break
-						}
-					}
-				}
-				continue
-			}
-
-			if check.lastBlock.CheckBlock() {
-				logger.Debug("Blockchain", "(last_block) Parsing block "+Itoa(check.height)+" - "+check.lastBlock.MsgBlock().Header.PrevBlock.String()+" -> "+check.lastBlock.Hash().String(), logger.Params{})
-				if err = check.lastBlock.Store(p.db, check.height); err != nil {
-					return
-				}
-				check.height++
-			}
-
-			logger.Debug("Blockchain", "(next_block) Updating block "+Itoa(check.height)+": "+block.Hash().String(), logger.Params{})
-			check.goalPrevHash = block.Hash()
-			check.lastBlock = block
-		}
+	pl, err := newPipeline(p, PipelineConf(), rawChain, check)
+	if err != nil {
+		return
 	}
-
-	return
+	return pl.run()
 }
 
 // FindCheckPoint restores the parsed files' state from last parsing and return a CheckPoint instance the keep parsing
@@ -280,7 +203,7 @@ func (p *Parser) FindCheckPoint(rawChain [][]uint8) (check CheckPoint, err error
 		if err != nil {
 			return
 		}
-		fmt.Println("Restored", p.skipped.Len(), last.ID, check.lastBlock.MsgBlock().Header.PrevBlock.String())
+		fmt.Println("Restored", p.reorder.Len(), last.ID, check.lastBlock.MsgBlock().Header.PrevBlock.String())
 
 		check.height++
 		check.goalPrevHash = check.lastBlock.Hash()
@@ -313,7 +236,7 @@ func restoreFileState(p *Parser, chain [][]uint8, last *block.Block) (b *Block,
 				return
 			}
 			if _, stored := list[b.Hash().String()]; !stored {
-				p.skipped.StoreBlockPrevHash(b)
+				p.reorder.Push(b)
 			}
 		}
 		chain[k] = file
@@ -40,12 +40,12 @@ var _ = Describe("Blockchain", func() {
 		Expect(db).ToNot(BeNil())
 		Expect(err).ToNot(HaveOccurred())
 
-		skippedBlocksStorage := NewSkipped()
+		reorder := NewReorder()
 		utxo = utxoset.Instance(utxoset.Conf("", false))
 		b = bitcoin.NewBlockchain(db, chaincfg.MainNetParams)
 		b.Read("", 0)
 
-		NewParser(b, nil, db, skippedBlocksStorage, utxo, ca, nil)
+		NewParser(b, nil, db, reorder, utxo, ca, nil)
 	})
 
 	AfterEach(func() {
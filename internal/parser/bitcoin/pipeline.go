@@ -0,0 +1,293 @@
+package bitcoin
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/xn3cr0nx/bitgodine/pkg/logger"
+	"github.com/xn3cr0nx/bitgodine/pkg/meter"
+)
+
+// PipelineConfig settings of the parsing pipeline. Workers files are decoded concurrently,
+// and their decoding waits while Buffer blocks are waiting to be committed
+type PipelineConfig struct {
+	Workers int
+	Buffer  int
+}
+
+// PipelineConf returns the pipeline settings from the environment
+func PipelineConf() *PipelineConfig {
+	conf := &PipelineConfig{
+		Workers: viper.GetInt("parser.workers"),
+		Buffer:  viper.GetInt("parser.buffer"),
+	}
+	if conf.Workers <= 0 {
+		conf.Workers = runtime.NumCPU()
+	}
+	if conf.Buffer <= 0 {
+		conf.Buffer = 5000
+	}
+	return conf
+}
+
+// decoded item sent by the decoders to the committer, either a block, the end of a file or an error
+type decoded struct {
+	file  int
+	block *Block
+	done  bool
+	err   error
+}
+
+// throttle applies backpressure to the decoders. While the buffer is full decoders wait before sending
+// each block, except the one decoding the lowest file not yet done, expected to hold the block the
+// committer is waiting for
+type throttle struct {
+	sync.Mutex
+	cond     *sync.Cond
+	buffered int
+	limit    int
+	head     int
+	stopped  bool
+}
+
+func newThrottle(limit int) *throttle {
+	t := &throttle{limit: limit}
+	t.cond = sync.NewCond(t)
+	return t
+}
+
+// wait blocks the decoder of the file while the buffer is full
+func (t *throttle) wait(file int) {
+	t.Lock()
+	for t.buffered >= t.limit && file != t.head && !t.stopped {
+		t.cond.Wait()
+	}
+	t.Unlock()
+}
+
+// advance moves the head to the lowest file not yet done
+func (t *throttle) advance(head int) {
+	t.Lock()
+	t.head = head
+	t.cond.Broadcast()
+	t.Unlock()
+}
+
+func (t *throttle) set(buffered int) {
+	t.Lock()
+	t.buffered = buffered
+	t.cond.Broadcast()
+	t.Unlock()
+}
+
+func (t *throttle) stop() {
+	t.Lock()
+	t.stopped = true
+	t.cond.Broadcast()
+	t.Unlock()
+}
+
+// pipelineMetrics throughput of the pipeline reported through the meter
+type pipelineMetrics struct {
+	decoded      metric.Int64Counter
+	committed    metric.Int64Counter
+	transactions metric.Int64Counter
+	buffered     metric.Int64UpDownCounter
+}
+
+func newPipelineMetrics() (m *pipelineMetrics, err error) {
+	mt := meter.Get("bitgodine_parser")
+	m = new(pipelineMetrics)
+	if m.decoded, err = mt.NewInt64Counter("parser.blocks.decoded", metric.WithDescription("Blocks decoded from the data files")); err != nil {
+		return
+	}
+	if m.committed, err = mt.NewInt64Counter("parser.blocks.committed", metric.WithDescription("Blocks stored in height order")); err != nil {
+		return
+	}
+	if m.transactions, err = mt.NewInt64Counter("parser.transactions.committed", metric.WithDescription("Transactions stored")); err != nil {
+		return
+	}
+	m.buffered, err = mt.NewInt64UpDownCounter("parser.blocks.buffered", metric.WithDescription("Blocks decoded waiting to be committed"))
+	return
+}
+
+// pipeline decodes the data files concurrently and commits their blocks in height order
+type pipeline struct {
+	parser   *Parser
+	conf     *PipelineConfig
+	files    [][]uint8
+	throttle *throttle
+	metrics  *pipelineMetrics
+	tip      chainhash.Hash
+	height   int32
+}
+
+func newPipeline(p *Parser, conf *PipelineConfig, files [][]uint8, check CheckPoint) (*pipeline, error) {
+	metrics, err := newPipelineMetrics()
+	if err != nil {
+		return nil, err
+	}
+	pl := &pipeline{
+		parser:   p,
+		conf:     conf,
+		files:    files,
+		throttle: newThrottle(conf.Buffer),
+		metrics:  metrics,
+		tip:      *check.goalPrevHash,
+		height:   check.height,
+	}
+	// the checkpoint block follows the last stored one and is yet to be stored
+	if check.lastBlock.CheckBlock() {
+		p.reorder.Push(check.lastBlock)
+		pl.tip = check.lastBlock.MsgBlock().Header.PrevBlock
+	}
+	return pl, nil
+}
+
+// decode extracts the blocks of the file sending them to the committer
+func (pl *pipeline) decode(k int, items chan<- decoded, stop <-chan struct{}) {
+	send := func(item decoded) bool {
+		select {
+		case items <- item:
+			return true
+		case <-stop:
+			return false
+		}
+	}
+	file := pl.files[k]
+	for len(file) > 0 {
+		b, err := ExtractBlockFromFile(&file)
+		if err != nil {
+			if errors.Is(err, ErrEmptySliceParse) {
+				break
+			}
+			send(decoded{file: k, err: err})
+			return
+		}
+		pl.metrics.decoded.Add(context.Background(), 1)
+		pl.throttle.wait(k)
+		if !send(decoded{file: k, block: b}) {
+			return
+		}
+	}
+	send(decoded{file: k, done: true})
+}
+
+// commit stores the blocks following the tip available in the reorder buffer
+func (pl *pipeline) commit() (err error) {
+	ctx := context.Background()
+	for {
+		b, ok := pl.parser.reorder.Next(&pl.tip)
+		if !ok {
+			break
+		}
+		if err = pl.parser.storeBlock(b, pl.height); err != nil {
+			return
+		}
+		pl.metrics.committed.Add(ctx, 1)
+		pl.metrics.transactions.Add(ctx, int64(len(b.Transactions())))
+		pl.tip = *b.Hash()
+		pl.height++
+	}
+	buffered := pl.parser.reorder.Len()
+	pl.metrics.buffered.Add(ctx, int64(buffered-pl.throttle.buffered))
+	pl.throttle.set(buffered)
+	return
+}
+
+// run parses all the files, returning once all the blocks found are decoded
+func (pl *pipeline) run() (err error) {
+	items := make(chan decoded, pl.conf.Workers*16)
+	stop := make(chan struct{})
+	files := make(chan int)
+
+	var workers sync.WaitGroup
+	for w := 0; w < pl.conf.Workers; w++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for k := range files {
+				pl.decode(k, items, stop)
+			}
+		}()
+	}
+	go func() {
+		defer close(files)
+		for k := range pl.files {
+			select {
+			case files <- k:
+			case <-stop:
+				return
+			}
+		}
+	}()
+	go func() {
+		workers.Wait()
+		close(items)
+	}()
+	defer func() {
+		close(stop)
+		pl.throttle.stop()
+		for range items {
+		}
+	}()
+
+	start, committed := time.Now(), pl.height
+	done := make([]bool, len(pl.files))
+	next := 0
+	for {
+		select {
+		case x, ok := <-pl.parser.interrupt:
+			if !ok {
+				err = ErrInterruptUnknown
+				return
+			}
+			logger.Info("Blockchain", "Received interrupt signal", logger.Params{"signal": x})
+			err = ErrInterrupt
+			return
+
+		case item, ok := <-items:
+			if !ok {
+				return
+			}
+			if item.err != nil {
+				err = item.err
+				return
+			}
+			if item.block != nil {
+				pl.parser.reorder.Push(item.block)
+				if err = pl.commit(); err != nil {
+					return
+				}
+				continue
+			}
+
+			// files are marked as parsed in order, once all the preceding ones are decoded too
+			done[item.file] = true
+			for next < len(done) && done[next] {
+				if err = StoreFileParsed(pl.parser.db, next); err != nil {
+					return
+				}
+				if err = pl.parser.blockchain.Maps[next].Unmap(); err != nil {
+					return
+				}
+				next++
+			}
+			pl.throttle.advance(next)
+			elapsed := time.Since(start).Seconds()
+			logger.Info("Blockchain", "Parsed file", logger.Params{
+				"file":     Itoa(int32(item.file)) + "/" + Itoa(int32(len(pl.files)-1)),
+				"height":   Itoa(pl.height),
+				"buffered": pl.parser.reorder.Len(),
+				"blocks/s": float64(pl.height-committed) / elapsed,
+			})
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package bitcoin
+
+import (
+	"sync"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+)
+
+// Reorder buffer of the blocks decoded out of order, keyed by the hash of their previous block.
+// Blocks sharing the previous block are competing branches of a chain split
+type Reorder struct {
+	sync.Mutex
+	blocks map[chainhash.Hash][]*Block
+	size   int
+}
+
+// NewReorder creates a new empty reorder buffer
+func NewReorder() *Reorder {
+	return &Reorder{blocks: make(map[chainhash.Hash][]*Block)}
+}
+
+// Len returns the number of buffered blocks
+func (r *Reorder) Len() int {
+	r.Lock()
+	defer r.Unlock()
+	return r.size
+}
+
+// Push buffers the block, ignoring it if already buffered
+func (r *Reorder) Push(b *Block) {
+	r.Lock()
+	defer r.Unlock()
+	prev := b.MsgBlock().Header.PrevBlock
+	for _, candidate := range r.blocks[prev] {
+		if candidate.Hash().IsEqual(b.Hash()) {
+			return
+		}
+	}
+	r.blocks[prev] = append(r.blocks[prev], b)
+	r.size++
+}
+
+// IsStored returns true if a block following the hash is buffered
+func (r *Reorder) IsStored(prev *chainhash.Hash) bool {
+	r.Lock()
+	defer r.Unlock()
+	_, ok := r.blocks[*prev]
+	return ok
+}
+
+// Next pops the block following tip on the main chain. A block is returned only once its own
+// following block is buffered too, so that with a chain split the branch going on is chosen,
+// hence the last block found is kept until the next parsing. Competing branches are dropped
+func (r *Reorder) Next(tip *chainhash.Hash) (*Block, bool) {
+	r.Lock()
+	defer r.Unlock()
+	candidates, ok := r.blocks[*tip]
+	if !ok {
+		return nil, false
+	}
+	var next *Block
+	for _, candidate := range candidates {
+		if _, ok := r.blocks[*candidate.Hash()]; ok {
+			next = candidate
+			break
+		}
+	}
+	if next == nil {
+		return nil, false
+	}
+	delete(r.blocks, *tip)
+	r.size -= len(candidates)
+	return next, true
+}
+
+// Empty drops all the buffered blocks
+func (r *Reorder) Empty() {
+	r.Lock()
+	defer r.Unlock()
+	r.blocks = make(map[chainhash.Hash][]*Block)
+	r.size = 0
+}
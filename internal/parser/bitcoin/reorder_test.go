@@ -0,0 +1,86 @@
+package bitcoin_test
+
+import (
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/xn3cr0nx/bitgodine/internal/parser/bitcoin"
+)
+
+func child(prev *chainhash.Hash, nonce uint32) *bitcoin.Block {
+	msg := wire.NewMsgBlock(wire.NewBlockHeader(1, prev, &chainhash.Hash{}, 0, nonce))
+	return &bitcoin.Block{Block: *btcutil.NewBlock(msg)}
+}
+
+var _ = Describe("Reorder", func() {
+	var (
+		r       *bitcoin.Reorder
+		genesis chainhash.Hash
+	)
+
+	BeforeEach(func() {
+		r = bitcoin.NewReorder()
+	})
+
+	It("Should return blocks in chain order once their child is buffered", func() {
+		first := child(&genesis, 1)
+		second := child(first.Hash(), 2)
+		third := child(second.Hash(), 3)
+
+		r.Push(third)
+		r.Push(first)
+		_, ok := r.Next(&genesis)
+		Expect(ok).To(BeFalse())
+
+		r.Push(second)
+		r.Push(second)
+		Expect(r.Len()).To(Equal(3))
+
+		tip := genesis
+		var chain []string
+		for {
+			b, ok := r.Next(&tip)
+			if !ok {
+				break
+			}
+			chain = append(chain, b.Hash().String())
+			tip = *b.Hash()
+		}
+		Expect(chain).To(Equal([]string{first.Hash().String(), second.Hash().String()}))
+		Expect(r.Len()).To(Equal(1))
+		Expect(r.IsStored(second.Hash())).To(BeTrue())
+	})
+
+	It("Should choose the branch going on with a chain split", func() {
+		first := child(&genesis, 1)
+		orphan := child(first.Hash(), 2)
+		main := child(first.Hash(), 3)
+		next := child(main.Hash(), 4)
+
+		r.Push(first)
+		r.Push(orphan)
+		r.Push(main)
+
+		b, ok := r.Next(&genesis)
+		Expect(ok).To(BeTrue())
+		Expect(b.Hash()).To(Equal(first.Hash()))
+		_, ok = r.Next(first.Hash())
+		Expect(ok).To(BeFalse())
+
+		r.Push(next)
+		b, ok = r.Next(first.Hash())
+		Expect(ok).To(BeTrue())
+		Expect(b.Hash()).To(Equal(main.Hash()))
+		Expect(r.Len()).To(Equal(1))
+	})
+
+	It("Should empty the buffer", func() {
+		r.Push(child(&genesis, 1))
+		r.Empty()
+		Expect(r.Len()).To(Equal(0))
+		Expect(r.IsStored(&genesis)).To(BeFalse())
+	})
+})
@@ -0,0 +1,104 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepSize number of buckets above which full buckets are evicted
+const sweepSize = 10000
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	full   time.Time
+}
+
+type counter struct {
+	count   int64
+	expires time.Time
+}
+
+// MemoryStore in memory store for single node setups
+type MemoryStore struct {
+	mu       sync.Mutex
+	buckets  map[string]*bucket
+	counters map[string]*counter
+}
+
+// NewMemoryStore returns a new in memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets:  make(map[string]*bucket),
+		counters: make(map[string]*counter),
+	}
+}
+
+// Take takes a token from the bucket, refilled for the time passed since the last request
+func (m *MemoryStore) Take(ctx context.Context, key string, rate float64, burst int, now time.Time) (allowed bool, remaining float64, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	b, ok := m.buckets[key]
+	if !ok {
+		if len(m.buckets) >= sweepSize {
+			m.sweep(now)
+		}
+		b = &bucket{tokens: float64(burst), last: now}
+		m.buckets[key] = b
+	}
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(float64(burst), b.tokens+elapsed*rate)
+		b.last = now
+	}
+	if b.tokens >= 1 {
+		b.tokens--
+		allowed = true
+	}
+	b.full = now.Add(time.Duration((float64(burst) - b.tokens) / rate * float64(time.Second)))
+	remaining = b.tokens
+	return
+}
+
+// sweep evicts the buckets that are full again, they are the same as missing ones
+func (m *MemoryStore) sweep(now time.Time) {
+	for key, b := range m.buckets {
+		if !now.Before(b.full) {
+			delete(m.buckets, key)
+		}
+	}
+	for key, c := range m.counters {
+		if !now.Before(c.expires) {
+			delete(m.counters, key)
+		}
+	}
+}
+
+// Incr increments the counter. Counters keys are bound to their period, expired ones are just swept
+func (m *MemoryStore) Incr(ctx context.Context, key string, expires time.Time) (count int64, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, ok := m.counters[key]
+	if !ok {
+		c = &counter{}
+		m.counters[key] = c
+	}
+	c.count++
+	c.expires = expires
+	count = c.count
+	return
+}
+
+// Count returns the value of the counter
+func (m *MemoryStore) Count(ctx context.Context, key string) (count int64, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if c, ok := m.counters[key]; ok {
+		count = c.count
+	}
+	return
+}
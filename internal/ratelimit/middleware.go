@@ -0,0 +1,116 @@
+package ratelimit
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	token "github.com/dgrijalva/jwt-go"
+	"github.com/labstack/echo/v4"
+
+	"github.com/xn3cr0nx/bitgodine/internal/apikey"
+	"github.com/xn3cr0nx/bitgodine/internal/jwt"
+	"github.com/xn3cr0nx/bitgodine/pkg/logger"
+)
+
+// Response headers describing the limits of the request
+const (
+	HeaderLimit          = "X-RateLimit-Limit"
+	HeaderRemaining      = "X-RateLimit-Remaining"
+	HeaderReset          = "X-RateLimit-Reset"
+	HeaderQuota          = "X-RateLimit-Quota"
+	HeaderQuotaRemaining = "X-RateLimit-Quota-Remaining"
+	HeaderQuotaReset     = "X-RateLimit-Quota-Reset"
+)
+
+var (
+	mu      sync.RWMutex
+	limiter *Limiter
+)
+
+// SetLimiter sets the limiter used by the middlewares, nil disables rate limiting
+func SetLimiter(l *Limiter) {
+	mu.Lock()
+	defer mu.Unlock()
+	limiter = l
+}
+
+func current() *Limiter {
+	mu.RLock()
+	defer mu.RUnlock()
+	return limiter
+}
+
+// identity returns the subject owning the bucket, the owner of the quota and the plan of the request.
+// Api keys have their own bucket and consume the quota of their owner, unauthenticated requests are
+// limited by ip with the free plan
+func identity(c echo.Context) (subject, owner, plan string) {
+	plan = Free
+	if user, ok := c.Get("user").(*token.Token); ok {
+		if claims, err := jwt.Decode(user); err == nil && claims.ID != "" {
+			owner = "user:" + claims.ID
+			subject = owner
+			if claims.Plan != "" {
+				plan = claims.Plan
+			}
+		}
+	}
+	if key, ok := c.Get("apikey").(*apikey.Model); ok {
+		subject = "key:" + key.ID.String()
+	}
+	if owner == "" {
+		owner = "ip:" + c.RealIP()
+		subject = owner
+	}
+	return
+}
+
+// Middleware limits the requests to the route group. It must follow the authentication middleware
+// to tell users apart. Failing to account the request is logged without failing it
+func Middleware(group string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			l := current()
+			if l == nil {
+				return next(c)
+			}
+
+			subject, owner, plan := identity(c)
+			res, err := l.Allow(c.Request().Context(), subject, owner, plan, group, time.Now())
+			if err != nil {
+				logger.Error("Rate Limit", err, logger.Params{"group": group, "subject": subject})
+				return next(c)
+			}
+
+			h := c.Response().Header()
+			if res.Limit.Rate > 0 {
+				burst := res.Limit.Burst
+				if burst <= 0 {
+					burst = res.Limit.Rate
+				}
+				h.Set(HeaderLimit, strconv.Itoa(burst))
+				h.Set(HeaderRemaining, strconv.Itoa(res.Remaining))
+				h.Set(HeaderReset, strconv.Itoa(int(res.Reset.Seconds())))
+			}
+			if res.Limit.Quota > 0 {
+				remaining := res.Limit.Quota - res.Used
+				if remaining < 0 {
+					remaining = 0
+				}
+				h.Set(HeaderQuota, strconv.FormatInt(res.Limit.Quota, 10))
+				h.Set(HeaderQuotaRemaining, strconv.FormatInt(remaining, 10))
+				h.Set(HeaderQuotaReset, strconv.Itoa(int(res.QuotaReset.Seconds())))
+			}
+
+			if !res.Allowed {
+				h.Set("Retry-After", strconv.Itoa(int(res.Retry.Seconds())))
+				if res.QuotaExceeded {
+					return echo.NewHTTPError(http.StatusTooManyRequests, "daily quota exceeded")
+				}
+				return echo.NewHTTPError(http.StatusTooManyRequests, "rate limit exceeded")
+			}
+			return next(c)
+		}
+	}
+}
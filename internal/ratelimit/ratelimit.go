@@ -0,0 +1,253 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/xn3cr0nx/bitgodine/internal/errorx"
+	"github.com/xn3cr0nx/bitgodine/internal/storage/kv"
+	"github.com/xn3cr0nx/bitgodine/internal/storage/kv/redis"
+)
+
+// Route groups sharing the same limits
+const (
+	Blocks   = "blocks"
+	Tags     = "tags"
+	Analysis = "analysis"
+	Trace    = "trace"
+
+	// Default limits of the groups not configured in a plan
+	Default = "default"
+)
+
+// Groups returns the list of the limited route groups
+func Groups() []string {
+	return []string{Blocks, Tags, Analysis, Trace}
+}
+
+// Plans users can be subscribed to
+const (
+	Free = "free"
+	Pro  = "pro"
+)
+
+// Storage backends of buckets and counters
+const (
+	Memory = "memory"
+	Redis  = "redis"
+)
+
+// Limit of a route group. Rate is the number of requests per minute, refilling a bucket of Burst
+// requests, and Quota the number of requests per day. Zero values mean unlimited
+type Limit struct {
+	Rate  int   `json:"rate" mapstructure:"rate"`
+	Burst int   `json:"burst" mapstructure:"burst"`
+	Quota int64 `json:"quota" mapstructure:"quota"`
+}
+
+// Plan limits by route group
+type Plan map[string]Limit
+
+// DefaultPlans returns the plans used when none are configured
+func DefaultPlans() map[string]Plan {
+	return map[string]Plan{
+		Free: {
+			Default:  {Rate: 60, Burst: 60, Quota: 10000},
+			Analysis: {Rate: 10, Burst: 5, Quota: 500},
+			Trace:    {Rate: 10, Burst: 5, Quota: 500},
+		},
+		Pro: {
+			Default:  {Rate: 600, Burst: 600},
+			Analysis: {Rate: 120, Burst: 60, Quota: 20000},
+			Trace:    {Rate: 120, Burst: 60, Quota: 20000},
+		},
+	}
+}
+
+// Config rate limiter configuration options
+type Config struct {
+	Enabled bool
+	Store   string
+	Redis   string
+	Plans   map[string]Plan
+}
+
+// Conf returns default rate limiter configuration options
+func Conf() *Config {
+	plans := DefaultPlans()
+	if viper.IsSet("server.ratelimit.plans") {
+		plans = make(map[string]Plan)
+		viper.UnmarshalKey("server.ratelimit.plans", &plans)
+	}
+	return &Config{
+		Enabled: viper.GetBool("server.ratelimit.enabled"),
+		Store:   viper.GetString("server.ratelimit.store"),
+		Redis:   viper.GetString("redis"),
+		Plans:   plans,
+	}
+}
+
+// Store interface of the storage of token buckets and usage counters
+type Store interface {
+	// Take takes a token from the bucket refilled at rate tokens per second, returning the remaining tokens
+	Take(ctx context.Context, key string, rate float64, burst int, now time.Time) (allowed bool, remaining float64, err error)
+	// Incr increments the counter, expiring at the given time
+	Incr(ctx context.Context, key string, expires time.Time) (count int64, err error)
+	// Count returns the value of the counter, zero if missing
+	Count(ctx context.Context, key string) (count int64, err error)
+}
+
+// NewStore returns the configured store. Redis reuses the key value storage if it's a redis instance
+func NewStore(conf *Config, db kv.DB) (Store, error) {
+	switch conf.Store {
+	case "", Memory:
+		return NewMemoryStore(), nil
+	case Redis:
+		if r, ok := db.(*redis.Redis); ok {
+			return NewRedisStore(r), nil
+		}
+		r, err := redis.NewRedis(redis.Conf(conf.Redis))
+		if err != nil {
+			return nil, err
+		}
+		return NewRedisStore(r), nil
+	}
+	return nil, fmt.Errorf("%w: unknown rate limit store %s", errorx.ErrConfig, conf.Store)
+}
+
+// Result of a request accounting
+type Result struct {
+	Limit     Limit
+	Allowed   bool
+	Remaining int
+	// Reset time to wait for a full bucket, Retry time to wait for the next token when not allowed
+	Reset time.Duration
+	Retry time.Duration
+
+	Used          int64
+	QuotaExceeded bool
+	QuotaReset    time.Duration
+}
+
+// Limiter accounts requests against the limits of the plans
+type Limiter struct {
+	Store Store
+	Plans map[string]Plan
+}
+
+// NewLimiter returns a new limiter instance
+func NewLimiter(store Store, plans map[string]Plan) *Limiter {
+	return &Limiter{
+		Store: store,
+		Plans: plans,
+	}
+}
+
+// Limit returns the limit of the group in the plan. Unknown plans fall back to the free one
+// and groups not configured to the plan default
+func (l *Limiter) Limit(plan, group string) Limit {
+	p, ok := l.Plans[plan]
+	if !ok {
+		p = l.Plans[Free]
+	}
+	if limit, ok := p[group]; ok {
+		return limit
+	}
+	return p[Default]
+}
+
+func bucketKey(group, subject string) string {
+	return "ratelimit_bucket_" + group + "_" + subject
+}
+
+func quotaKey(group, owner string, now time.Time) string {
+	return "ratelimit_quota_" + group + "_" + owner + "_" + now.UTC().Format("20060102")
+}
+
+// dayEnd returns the time the daily quotas are reset
+func dayEnd(now time.Time) time.Time {
+	y, m, d := now.UTC().Date()
+	return time.Date(y, m, d+1, 0, 0, 0, 0, time.UTC)
+}
+
+// Allow accounts a request of the subject, an api key or a user, against its bucket and
+// of the owner against its daily quota. Requests rejected by the rate don't consume quota
+func (l *Limiter) Allow(ctx context.Context, subject, owner, plan, group string, now time.Time) (res Result, err error) {
+	res.Limit = l.Limit(plan, group)
+	res.Allowed = true
+
+	if res.Limit.Rate > 0 {
+		burst := res.Limit.Burst
+		if burst <= 0 {
+			burst = res.Limit.Rate
+		}
+		rate := float64(res.Limit.Rate) / 60
+		var remaining float64
+		if res.Allowed, remaining, err = l.Store.Take(ctx, bucketKey(group, subject), rate, burst, now); err != nil {
+			return
+		}
+		res.Remaining = int(math.Floor(remaining))
+		res.Reset = seconds((float64(burst) - remaining) / rate)
+		if !res.Allowed {
+			res.Retry = seconds((1 - remaining) / rate)
+			return
+		}
+	}
+
+	end := dayEnd(now)
+	res.QuotaReset = end.Sub(now)
+	if res.Used, err = l.Store.Incr(ctx, quotaKey(group, owner, now), end.Add(time.Hour)); err != nil {
+		return
+	}
+	if res.Limit.Quota > 0 && res.Used > res.Limit.Quota {
+		res.Allowed = false
+		res.QuotaExceeded = true
+		res.Retry = res.QuotaReset
+	}
+	return
+}
+
+// Usage returns the requests of the owner counted against the daily quota of each group
+func (l *Limiter) Usage(ctx context.Context, owner, plan string, now time.Time) (usage []GroupUsage, err error) {
+	for _, group := range Groups() {
+		u := GroupUsage{Group: group, Limit: l.Limit(plan, group)}
+		if u.Used, err = l.Store.Count(ctx, quotaKey(group, owner, now)); err != nil {
+			return
+		}
+		if u.Quota > 0 {
+			u.Remaining = u.Quota - u.Used
+			if u.Remaining < 0 {
+				u.Remaining = 0
+			}
+		}
+		usage = append(usage, u)
+	}
+	return
+}
+
+// GroupUsage consumption of a route group
+type GroupUsage struct {
+	Group string `json:"group"`
+	Limit
+	Used      int64 `json:"used"`
+	Remaining int64 `json:"remaining,omitempty"`
+} //@name GroupUsage
+
+// UsageResp consumption of the user in the current day
+type UsageResp struct {
+	Plan   string       `json:"plan"`
+	Reset  time.Time    `json:"reset"`
+	Groups []GroupUsage `json:"groups"`
+} //@name Usage
+
+// seconds rounds the duration up to the second
+func seconds(s float64) time.Duration {
+	if s <= 0 {
+		return 0
+	}
+	return time.Duration(math.Ceil(s)) * time.Second
+}
@@ -0,0 +1,181 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	token "github.com/dgrijalva/jwt-go"
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/xn3cr0nx/bitgodine/internal/apikey"
+	"github.com/xn3cr0nx/bitgodine/internal/jwt"
+)
+
+type TestRateLimitSuite struct {
+	suite.Suite
+	limiter *Limiter
+	now     time.Time
+}
+
+func (suite *TestRateLimitSuite) SetupTest() {
+	suite.limiter = NewLimiter(NewMemoryStore(), map[string]Plan{
+		Free: {
+			Default: {Rate: 60, Burst: 2, Quota: 3},
+			Trace:   {Rate: 6, Burst: 1},
+		},
+		Pro: {
+			Default: {},
+		},
+	})
+	suite.now = time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+}
+
+func (suite *TestRateLimitSuite) TearDownTest() {
+	SetLimiter(nil)
+}
+
+func (suite *TestRateLimitSuite) TestLimit() {
+	assert.Equal(suite.T(), Limit{Rate: 6, Burst: 1}, suite.limiter.Limit(Free, Trace))
+	assert.Equal(suite.T(), Limit{Rate: 60, Burst: 2, Quota: 3}, suite.limiter.Limit(Free, Blocks))
+	assert.Equal(suite.T(), Limit{Rate: 60, Burst: 2, Quota: 3}, suite.limiter.Limit("unknown", Blocks))
+	assert.Equal(suite.T(), Limit{}, suite.limiter.Limit(Pro, Trace))
+}
+
+func (suite *TestRateLimitSuite) TestBucket() {
+	ctx := context.Background()
+	res, err := suite.limiter.Allow(ctx, "user:1", "user:1", Free, Trace, suite.now)
+	require.Nil(suite.T(), err)
+	assert.True(suite.T(), res.Allowed)
+	assert.Equal(suite.T(), 0, res.Remaining)
+	assert.Equal(suite.T(), 10*time.Second, res.Reset)
+
+	res, err = suite.limiter.Allow(ctx, "user:1", "user:1", Free, Trace, suite.now.Add(5*time.Second))
+	require.Nil(suite.T(), err)
+	assert.False(suite.T(), res.Allowed)
+	assert.False(suite.T(), res.QuotaExceeded)
+	assert.Equal(suite.T(), 5*time.Second, res.Retry)
+
+	res, err = suite.limiter.Allow(ctx, "user:2", "user:2", Free, Trace, suite.now.Add(5*time.Second))
+	require.Nil(suite.T(), err)
+	assert.True(suite.T(), res.Allowed)
+
+	res, err = suite.limiter.Allow(ctx, "user:1", "user:1", Free, Trace, suite.now.Add(10*time.Second))
+	require.Nil(suite.T(), err)
+	assert.True(suite.T(), res.Allowed)
+	assert.Equal(suite.T(), int64(2), res.Used)
+}
+
+func (suite *TestRateLimitSuite) TestQuota() {
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		res, err := suite.limiter.Allow(ctx, "key:1", "user:1", Free, Blocks, suite.now.Add(time.Duration(i)*time.Minute))
+		require.Nil(suite.T(), err)
+		assert.True(suite.T(), res.Allowed)
+	}
+	res, err := suite.limiter.Allow(ctx, "key:2", "user:1", Free, Blocks, suite.now.Add(3*time.Minute))
+	require.Nil(suite.T(), err)
+	assert.False(suite.T(), res.Allowed)
+	assert.True(suite.T(), res.QuotaExceeded)
+	assert.Equal(suite.T(), 12*time.Hour-3*time.Minute, res.Retry)
+
+	usage, err := suite.limiter.Usage(ctx, "user:1", Free, time.Now())
+	require.Nil(suite.T(), err)
+	require.Len(suite.T(), usage, len(Groups()))
+	for _, u := range usage {
+		if u.Group == Blocks {
+			assert.Equal(suite.T(), int64(0), u.Used, "counters of another day")
+		}
+	}
+}
+
+func (suite *TestRateLimitSuite) TestUsage() {
+	ctx := context.Background()
+	now := time.Now()
+	_, err := suite.limiter.Allow(ctx, "user:1", "user:1", Free, Blocks, now)
+	require.Nil(suite.T(), err)
+
+	usage, err := suite.limiter.Usage(ctx, "user:1", Free, now)
+	require.Nil(suite.T(), err)
+	assert.Equal(suite.T(), GroupUsage{Group: Blocks, Limit: Limit{Rate: 60, Burst: 2, Quota: 3}, Used: 1, Remaining: 2}, usage[0])
+	assert.Equal(suite.T(), int64(0), usage[3].Used)
+}
+
+func (suite *TestRateLimitSuite) request(user *token.Token, key *apikey.Model) *httptest.ResponseRecorder {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(echo.HeaderXRealIP, "10.0.0.1")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	if user != nil {
+		c.Set("user", user)
+	}
+	if key != nil {
+		c.Set("apikey", key)
+	}
+	h := Middleware(Trace)(func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+	if err := h(c); err != nil {
+		var he *echo.HTTPError
+		if errors.As(err, &he) {
+			rec.Code = he.Code
+		}
+	}
+	return rec
+}
+
+func (suite *TestRateLimitSuite) TestMiddleware() {
+	rec := suite.request(nil, nil)
+	assert.Equal(suite.T(), http.StatusOK, rec.Code)
+	assert.Empty(suite.T(), rec.Header().Get(HeaderLimit), "disabled without limiter")
+
+	SetLimiter(suite.limiter)
+	rec = suite.request(nil, nil)
+	assert.Equal(suite.T(), http.StatusOK, rec.Code)
+	assert.Equal(suite.T(), "1", rec.Header().Get(HeaderLimit))
+	assert.Equal(suite.T(), "0", rec.Header().Get(HeaderRemaining))
+	assert.Equal(suite.T(), "10", rec.Header().Get(HeaderReset))
+
+	rec = suite.request(nil, nil)
+	assert.Equal(suite.T(), http.StatusTooManyRequests, rec.Code)
+	assert.NotEmpty(suite.T(), rec.Header().Get("Retry-After"))
+
+	user := &token.Token{Claims: &jwt.CustomClaims{ID: uuid.New().String(), Plan: Pro}, Valid: true}
+	for i := 0; i < 5; i++ {
+		rec = suite.request(user, nil)
+		assert.Equal(suite.T(), http.StatusOK, rec.Code)
+		assert.Empty(suite.T(), rec.Header().Get(HeaderLimit), "pro plan unlimited")
+	}
+}
+
+func (suite *TestRateLimitSuite) TestIdentity() {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(echo.HeaderXRealIP, "10.0.0.1")
+	c := e.NewContext(req, httptest.NewRecorder())
+
+	subject, owner, plan := identity(c)
+	assert.Equal(suite.T(), "ip:10.0.0.1", subject)
+	assert.Equal(suite.T(), subject, owner)
+	assert.Equal(suite.T(), Free, plan)
+
+	ID, keyID := uuid.New(), uuid.New()
+	c.Set("user", &token.Token{Claims: &jwt.CustomClaims{ID: ID.String(), Plan: Pro}, Valid: true})
+	c.Set("apikey", &apikey.Model{ID: keyID, UserID: ID})
+	subject, owner, plan = identity(c)
+	assert.Equal(suite.T(), "key:"+keyID.String(), subject)
+	assert.Equal(suite.T(), "user:"+ID.String(), owner)
+	assert.Equal(suite.T(), Pro, plan)
+}
+
+func TestRateLimit(t *testing.T) {
+	suite.Run(t, new(TestRateLimitSuite))
+}
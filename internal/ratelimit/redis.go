@@ -0,0 +1,79 @@
+package ratelimit
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	goredis "github.com/go-redis/redis/v8"
+
+	"github.com/xn3cr0nx/bitgodine/internal/storage/kv/redis"
+)
+
+// take refills and takes a token from the bucket atomically. Tokens are returned as string
+// since redis truncates lua numbers to integers
+var take = goredis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local bucket = redis.call("HMGET", KEYS[1], "tokens", "last")
+local tokens = tonumber(bucket[1]) or burst
+local last = tonumber(bucket[2]) or now
+if now > last then
+	tokens = math.min(burst, tokens + (now - last) * rate)
+	last = now
+end
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "last", tostring(last))
+redis.call("EXPIRE", KEYS[1], math.ceil((burst - tokens) / rate) + 1)
+return {allowed, tostring(tokens)}
+`)
+
+// RedisStore redis store, sharing buckets and counters among server instances
+type RedisStore struct {
+	*redis.Redis
+}
+
+// NewRedisStore returns a new redis store
+func NewRedisStore(r *redis.Redis) *RedisStore {
+	return &RedisStore{r}
+}
+
+// Take takes a token from the bucket
+func (r *RedisStore) Take(ctx context.Context, key string, rate float64, burst int, now time.Time) (allowed bool, remaining float64, err error) {
+	ts := float64(now.UnixNano()) / float64(time.Second)
+	res, err := take.Run(ctx, r.Client, []string{key},
+		strconv.FormatFloat(rate, 'f', -1, 64), burst, strconv.FormatFloat(ts, 'f', 6, 64)).Result()
+	if err != nil {
+		return
+	}
+	values := res.([]interface{})
+	allowed = values[0].(int64) == 1
+	remaining, err = strconv.ParseFloat(values[1].(string), 64)
+	return
+}
+
+// Incr increments the counter
+func (r *RedisStore) Incr(ctx context.Context, key string, expires time.Time) (count int64, err error) {
+	pipe := r.TxPipeline()
+	incr := pipe.Incr(ctx, key)
+	pipe.ExpireAt(ctx, key, expires)
+	if _, err = pipe.Exec(ctx); err != nil {
+		return
+	}
+	count = incr.Val()
+	return
+}
+
+// Count returns the value of the counter
+func (r *RedisStore) Count(ctx context.Context, key string) (count int64, err error) {
+	count, err = r.Get(ctx, key).Int64()
+	if err == goredis.Nil {
+		err = nil
+	}
+	return
+}
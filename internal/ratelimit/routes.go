@@ -0,0 +1,58 @@
+package ratelimit
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/xn3cr0nx/bitgodine/internal/jwt"
+	"github.com/xn3cr0nx/bitgodine/pkg/validator"
+)
+
+// Routes mounts rate limit routes on the main group
+func Routes(g *echo.Group) {
+	g.GET("/user/usage", getUsage(), validator.JWT())
+}
+
+// getUsage godoc
+// @ID get-usage
+//
+// @Router /user/usage [get]
+// @Summary Get usage
+// @Description get the limits of the plan of the authenticated user and the requests counted against the daily quotas
+// @Tags user
+//
+// @Security ApiKeyAuth
+//
+// @Accept  json
+// @Produce  json
+//
+// @Success 200 {object} UsageResp
+// @Success 500 {string} string
+func getUsage() func(echo.Context) error {
+	return func(c echo.Context) error {
+		l := current()
+		if l == nil {
+			return echo.NewHTTPError(http.StatusNotFound, "rate limiting disabled")
+		}
+		if c.Get("user") == nil {
+			return echo.NewHTTPError(http.StatusUnauthorized, "missing authentication token")
+		}
+		claims, err := jwt.Decode(c.Get("user"))
+		if err != nil {
+			return err
+		}
+		plan := claims.Plan
+		if plan == "" {
+			plan = Free
+		}
+
+		now := time.Now()
+		groups, err := l.Usage(c.Request().Context(), "user:"+claims.ID, plan, now)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, &UsageResp{Plan: plan, Reset: dayEnd(now), Groups: groups})
+	}
+}
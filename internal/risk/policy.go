@@ -0,0 +1,133 @@
+package risk
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/viper"
+	"github.com/xn3cr0nx/bitgodine/internal/errorx"
+)
+
+// DefaultPolicy scoring rules applied when no policy file is configured
+const DefaultPolicy = `
+# hops followed backward through the transaction graph computing the exposure
+hops: 3
+# weight of each factor, the share of the score it contributes at most
+weights:
+  label: 0.6
+  abuse: 0.5
+  direct: 0.8
+  indirect: 0.4
+  coinjoin: 0.3
+# weight of the exposure lost at each hop past the first
+decay: 0.5
+# severity of the categories of labels and abuses, unlisted categories don't contribute
+severity:
+  sanctions: 1
+  ransomware: 1
+  darknet: 0.9
+  scam: 0.8
+  mixer: 0.8
+  sextortion: 0.7
+  blackmail: 0.7
+  gambling: 0.4
+  other: 0.2
+  service: 0.1
+  exchange: 0.05
+  mining: 0
+# score thresholds of the levels
+levels:
+  medium: 0.3
+  high: 0.7
+`
+
+// Weights of the risk factors
+type Weights struct {
+	Label    float64 `mapstructure:"label"`
+	Abuse    float64 `mapstructure:"abuse"`
+	Direct   float64 `mapstructure:"direct"`
+	Indirect float64 `mapstructure:"indirect"`
+	Coinjoin float64 `mapstructure:"coinjoin"`
+}
+
+// Levels score thresholds of the risk levels
+type Levels struct {
+	Medium float64 `mapstructure:"medium"`
+	High   float64 `mapstructure:"high"`
+}
+
+// Policy scoring rules of the risk assessment
+type Policy struct {
+	Hops     int                `mapstructure:"hops"`
+	Weights  Weights            `mapstructure:"weights"`
+	Decay    float64            `mapstructure:"decay"`
+	Severity map[string]float64 `mapstructure:"severity"`
+	Levels   Levels             `mapstructure:"levels"`
+}
+
+// LoadPolicy reads the policy from the yaml file set as risk.policy, the default policy if none
+func LoadPolicy() (policy *Policy, err error) {
+	v := viper.New()
+	v.SetConfigType("yaml")
+	if file := viper.GetString("risk.policy"); file != "" {
+		v.SetConfigFile(file)
+		err = v.ReadInConfig()
+	} else {
+		err = v.ReadConfig(strings.NewReader(DefaultPolicy))
+	}
+	if err != nil {
+		return
+	}
+
+	policy = new(Policy)
+	if err = v.Unmarshal(policy); err != nil {
+		return
+	}
+	err = policy.Validate()
+	return
+}
+
+// Validate checks the policy rules are in range
+func (p *Policy) Validate() error {
+	if p.Hops < 1 {
+		return fmt.Errorf("%w: risk policy hops %d", errorx.ErrConfig, p.Hops)
+	}
+	for name, w := range map[string]float64{
+		"label":    p.Weights.Label,
+		"abuse":    p.Weights.Abuse,
+		"direct":   p.Weights.Direct,
+		"indirect": p.Weights.Indirect,
+		"coinjoin": p.Weights.Coinjoin,
+		"decay":    p.Decay,
+	} {
+		if w < 0 || w > 1 {
+			return fmt.Errorf("%w: risk policy %s weight %v out of [0, 1]", errorx.ErrConfig, name, w)
+		}
+	}
+	for category, s := range p.Severity {
+		if s < 0 || s > 1 {
+			return fmt.Errorf("%w: risk policy %s severity %v out of [0, 1]", errorx.ErrConfig, category, s)
+		}
+	}
+	if p.Levels.Medium <= 0 || p.Levels.High <= p.Levels.Medium || p.Levels.High > 1 {
+		return fmt.Errorf("%w: risk policy levels %v", errorx.ErrConfig, p.Levels)
+	}
+	return nil
+}
+
+// severity returns the severity of the category, 0 if not listed
+func (p *Policy) severity(category string) float64 {
+	return p.Severity[strings.ToLower(category)]
+}
+
+// level returns the level of the score
+func (p *Policy) level(score float64) string {
+	switch {
+	case score >= p.Levels.High:
+		return High
+	case score >= p.Levels.Medium:
+		return Medium
+	default:
+		return Low
+	}
+}
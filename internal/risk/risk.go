@@ -0,0 +1,175 @@
+// Package risk scores the risk of addresses and transactions combining labels, abuse reports,
+// exposure through the transaction graph and coinjoin involvement
+package risk
+
+import (
+	"math"
+	"sort"
+
+	"github.com/xn3cr0nx/bitgodine/internal/abuse"
+	"github.com/xn3cr0nx/bitgodine/internal/tag"
+	"github.com/xn3cr0nx/bitgodine/internal/trace"
+)
+
+// Risk levels of the score
+const (
+	Low    = "low"
+	Medium = "medium"
+	High   = "high"
+)
+
+// Factors contributing to the score
+const (
+	FactorLabel    = "label"
+	FactorAbuse    = "abuse"
+	FactorDirect   = "direct_exposure"
+	FactorIndirect = "indirect_exposure"
+	FactorCoinjoin = "coinjoin"
+)
+
+// Factor contribution to the score
+type Factor struct {
+	Name     string  `json:"name"`
+	Category string  `json:"category,omitempty"`
+	Score    float64 `json:"score"`
+	Detail   string  `json:"detail,omitempty"`
+} //@name RiskFactor
+
+// Exposure share of the funds received coming from a category, directly from the previous hop
+// or indirectly from the further ones, with the amount in satoshis
+type Exposure struct {
+	Category string  `json:"category"`
+	Direct   float64 `json:"direct"`
+	Indirect float64 `json:"indirect"`
+	Value    int64   `json:"value"`
+	// decayed indirect share, weighted by the policy decay for each hop past the first
+	decayed float64
+} //@name RiskExposure
+
+// Assessment risk of an address or transaction with the factors it comes from
+type Assessment struct {
+	Target   string     `json:"target"`
+	Score    float64    `json:"score"`
+	Level    string     `json:"level"`
+	Hops     int        `json:"hops"`
+	Factors  []Factor   `json:"factors"`
+	Exposure []Exposure `json:"exposure"`
+} //@name RiskAssessment
+
+// Evidence collected about the target the assessment is computed from
+type Evidence struct {
+	// Label resolved label of the target cluster
+	Label tag.Resolution
+	// Abuse risk of the abuses reported for the target cluster
+	Abuse abuse.Risk
+	// Exposure breakdown by category
+	Exposure []Exposure
+	// Coinjoin the target takes part in a coinjoin
+	Coinjoin bool
+	// Coinjoins number of coinjoins the funds went through within the hops
+	Coinjoins int
+}
+
+// Evaluate scores the evidence with the policy. Each factor scores its weight times its severity and
+// magnitude, and factors combine as independent probabilities, 1 - Π(1 - factor)
+func Evaluate(policy *Policy, target string, hops int, ev *Evidence) (a *Assessment) {
+	a = &Assessment{Target: target, Hops: hops, Factors: []Factor{}, Exposure: []Exposure{}}
+
+	if ev.Label.Entity != "" {
+		a.Factors = append(a.Factors, Factor{
+			Name:     FactorLabel,
+			Category: ev.Label.Category,
+			Score:    policy.Weights.Label * policy.severity(ev.Label.Category) * ev.Label.Confidence,
+			Detail:   ev.Label.Entity,
+		})
+	}
+	if ev.Abuse.Reports > 0 {
+		a.Factors = append(a.Factors, Factor{
+			Name:   FactorAbuse,
+			Score:  policy.Weights.Abuse * ev.Abuse.Score,
+			Detail: ev.Abuse.Level,
+		})
+	}
+	for _, e := range ev.Exposure {
+		severity := policy.severity(e.Category)
+		if e.Direct > 0 {
+			a.Factors = append(a.Factors, Factor{
+				Name:     FactorDirect,
+				Category: e.Category,
+				Score:    policy.Weights.Direct * severity * math.Min(e.Direct, 1),
+			})
+		}
+		if e.decayed > 0 {
+			a.Factors = append(a.Factors, Factor{
+				Name:     FactorIndirect,
+				Category: e.Category,
+				Score:    policy.Weights.Indirect * severity * math.Min(e.decayed, 1),
+			})
+		}
+		a.Exposure = append(a.Exposure, e)
+	}
+	if ev.Coinjoin {
+		a.Factors = append(a.Factors, Factor{Name: FactorCoinjoin, Score: policy.Weights.Coinjoin, Detail: "direct"})
+	} else if ev.Coinjoins > 0 {
+		a.Factors = append(a.Factors, Factor{Name: FactorCoinjoin, Score: policy.Weights.Coinjoin * policy.Decay, Detail: "indirect"})
+	}
+
+	clean := 1.0
+	factors := a.Factors[:0]
+	for _, f := range a.Factors {
+		if f.Score <= 0 {
+			continue
+		}
+		f.Score = round(f.Score)
+		clean *= 1 - f.Score
+		factors = append(factors, f)
+	}
+	a.Factors = factors
+	sort.SliceStable(a.Factors, func(i, j int) bool {
+		return a.Factors[i].Score > a.Factors[j].Score
+	})
+	a.Score = round(1 - clean)
+	a.Level = policy.level(a.Score)
+	return
+}
+
+// exposure breaks down by category the taint reaching the outputs, as a share of the taint the
+// analysis started from. categories maps the outputs addresses to their category
+func exposure(taint *trace.Taint, categories map[string]string, decay float64) (exposures []Exposure) {
+	if taint == nil || taint.Tainted <= 0 {
+		return
+	}
+	byCategory := make(map[string]*Exposure)
+	for _, out := range taint.Outputs {
+		category, ok := categories[out.Address]
+		if !ok || out.Depth < 1 || out.Tainted <= 0 {
+			continue
+		}
+		e, ok := byCategory[category]
+		if !ok {
+			e = &Exposure{Category: category}
+			byCategory[category] = e
+		}
+		share := float64(out.Tainted) / float64(taint.Tainted)
+		if out.Depth == 1 {
+			e.Direct += share
+		} else {
+			e.Indirect += share
+			e.decayed += share * math.Pow(decay, float64(out.Depth-1))
+		}
+		e.Value += out.Tainted
+	}
+
+	for _, e := range byCategory {
+		e.Direct, e.Indirect = round(math.Min(e.Direct, 1)), round(math.Min(e.Indirect, 1))
+		exposures = append(exposures, *e)
+	}
+	sort.Slice(exposures, func(i, j int) bool {
+		return exposures[i].Category < exposures[j].Category
+	})
+	return
+}
+
+func round(v float64) float64 {
+	return math.Round(v*1000) / 1000
+}
@@ -0,0 +1,141 @@
+package risk
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/xn3cr0nx/bitgodine/internal/abuse"
+	"github.com/xn3cr0nx/bitgodine/internal/errorx"
+	"github.com/xn3cr0nx/bitgodine/internal/tag"
+	"github.com/xn3cr0nx/bitgodine/internal/trace"
+)
+
+type TestRiskSuite struct {
+	suite.Suite
+	policy *Policy
+}
+
+func (suite *TestRiskSuite) SetupTest() {
+	viper.Set("risk.policy", "")
+	policy, err := LoadPolicy()
+	require.Nil(suite.T(), err)
+	suite.policy = policy
+}
+
+func (suite *TestRiskSuite) TestDefaultPolicy() {
+	assert.Equal(suite.T(), 3, suite.policy.Hops)
+	assert.Equal(suite.T(), 0.8, suite.policy.Weights.Direct)
+	assert.Equal(suite.T(), 1.0, suite.policy.severity("Sanctions"))
+	assert.Equal(suite.T(), 0.0, suite.policy.severity("unknown"))
+	assert.Equal(suite.T(), High, suite.policy.level(0.7))
+	assert.Equal(suite.T(), Medium, suite.policy.level(0.3))
+	assert.Equal(suite.T(), Low, suite.policy.level(0.29))
+}
+
+func (suite *TestRiskSuite) TestPolicyFile() {
+	dir, err := ioutil.TempDir("", "risk")
+	require.Nil(suite.T(), err)
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "policy.yaml")
+	require.Nil(suite.T(), ioutil.WriteFile(file, []byte(`
+hops: 5
+weights: {label: 1, abuse: 1, direct: 1, indirect: 1, coinjoin: 0}
+decay: 0.1
+severity: {gambling: 0.9}
+levels: {medium: 0.2, high: 0.5}
+`), 0644))
+	viper.Set("risk.policy", file)
+	policy, err := LoadPolicy()
+	require.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 5, policy.Hops)
+	assert.Equal(suite.T(), 0.9, policy.severity("gambling"))
+	assert.Equal(suite.T(), 0.0, policy.severity("sanctions"))
+
+	require.Nil(suite.T(), ioutil.WriteFile(file, []byte("hops: 2\nweights: {label: 2}\nlevels: {medium: 0.2, high: 0.5}\n"), 0644))
+	_, err = LoadPolicy()
+	assert.True(suite.T(), errors.Is(err, errorx.ErrConfig))
+}
+
+func (suite *TestRiskSuite) TestEvaluateClean() {
+	a := Evaluate(suite.policy, "1Clean", 3, &Evidence{})
+	assert.Equal(suite.T(), 0.0, a.Score)
+	assert.Equal(suite.T(), Low, a.Level)
+	assert.Empty(suite.T(), a.Factors)
+	assert.Empty(suite.T(), a.Exposure)
+}
+
+func (suite *TestRiskSuite) TestEvaluate() {
+	ev := &Evidence{
+		Label: tag.Resolution{Entity: "Huobi.com", Category: tag.CategoryExchange, Confidence: 1},
+		Abuse: abuse.Risk{Score: 0.5, Reports: 2, Level: abuse.RiskMedium},
+		Exposure: []Exposure{
+			{Category: tag.Sanctions, Direct: 0.5},
+			{Category: tag.CategoryMixer, Indirect: 0.5, decayed: 0.25},
+		},
+		Coinjoins: 1,
+	}
+	a := Evaluate(suite.policy, "1Target", 3, ev)
+
+	require.Len(suite.T(), a.Factors, 5)
+	assert.Equal(suite.T(), Factor{Name: FactorDirect, Category: tag.Sanctions, Score: 0.4}, a.Factors[0])
+	assert.Equal(suite.T(), Factor{Name: FactorAbuse, Score: 0.25, Detail: abuse.RiskMedium}, a.Factors[1])
+	assert.Equal(suite.T(), Factor{Name: FactorCoinjoin, Score: 0.15, Detail: "indirect"}, a.Factors[2])
+	assert.Equal(suite.T(), Factor{Name: FactorIndirect, Category: tag.CategoryMixer, Score: 0.08}, a.Factors[3])
+	assert.Equal(suite.T(), Factor{Name: FactorLabel, Category: tag.CategoryExchange, Score: 0.03, Detail: "Huobi.com"}, a.Factors[4])
+	assert.InDelta(suite.T(), 1-0.6*0.75*0.85*0.92*0.97, a.Score, 0.001)
+	assert.Equal(suite.T(), Medium, a.Level)
+	assert.Len(suite.T(), a.Exposure, 2)
+}
+
+func (suite *TestRiskSuite) TestEvaluateCoinjoin() {
+	a := Evaluate(suite.policy, "txid", 1, &Evidence{Coinjoin: true})
+	require.Len(suite.T(), a.Factors, 1)
+	assert.Equal(suite.T(), "direct", a.Factors[0].Detail)
+	assert.Equal(suite.T(), 0.3, a.Score)
+}
+
+func (suite *TestRiskSuite) TestExposure() {
+	taint := &trace.Taint{
+		Tainted: 100000,
+		Outputs: []trace.TaintedOutput{
+			{Address: "1Target", Tainted: 100000, Depth: 0},
+			{Address: "1Sanctioned", Tainted: 60000, Depth: 1},
+			{Address: "1Exchange", Tainted: 40000, Depth: 1},
+			{Address: "1Mixer", Tainted: 30000, Depth: 2},
+			{Address: "1Mixer", Tainted: 10000, Depth: 3},
+			{Address: "1Unknown", Tainted: 10000, Depth: 2},
+		},
+	}
+	categories := map[string]string{
+		"1Target":     tag.CategoryMixer,
+		"1Sanctioned": tag.Sanctions,
+		"1Exchange":   tag.CategoryExchange,
+		"1Mixer":      tag.CategoryMixer,
+	}
+	exposures := exposure(taint, categories, 0.5)
+
+	require.Len(suite.T(), exposures, 3)
+	assert.Equal(suite.T(), Exposure{Category: tag.CategoryExchange, Direct: 0.4, Value: 40000}, exposures[0])
+	mixer := exposures[1]
+	assert.Equal(suite.T(), tag.CategoryMixer, mixer.Category)
+	assert.Equal(suite.T(), 0.0, mixer.Direct)
+	assert.Equal(suite.T(), 0.4, mixer.Indirect)
+	assert.InDelta(suite.T(), 0.3*0.5+0.1*0.25, mixer.decayed, 1e-9)
+	assert.Equal(suite.T(), int64(40000), mixer.Value)
+	assert.Equal(suite.T(), Exposure{Category: tag.Sanctions, Direct: 0.6, Value: 60000}, exposures[2])
+
+	assert.Empty(suite.T(), exposure(nil, categories, 0.5))
+}
+
+func TestRisk(t *testing.T) {
+	suite.Run(t, new(TestRiskSuite))
+}
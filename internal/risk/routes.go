@@ -0,0 +1,102 @@
+package risk
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/xn3cr0nx/bitgodine/internal/apikey"
+	"github.com/xn3cr0nx/bitgodine/internal/errorx"
+	"github.com/xn3cr0nx/bitgodine/internal/ratelimit"
+	"github.com/xn3cr0nx/bitgodine/pkg/validator"
+)
+
+// Routes mounts /risk based routes on the main group
+func Routes(g *echo.Group, s Service) {
+	r := g.Group("/risk", apikey.Auth(apikey.RunAnalysis), ratelimit.Middleware(ratelimit.Trace))
+	r.GET("/address/:address", assessAddress(s))
+	r.GET("/tx/:txid", assessTx(s))
+}
+
+// assessAddress godoc
+// @ID risk-address
+//
+// @Router /risk/address/{address} [get]
+// @Summary Address risk
+// @Description get the risk score of the address, with the contributing factors and the exposure of the funds received by category
+// @Tags risk
+//
+// @Security ApiKeyAuth
+//
+// @Accept  json
+// @Produce  json
+//
+// @Param address path string true "Address"
+// @Param hops query int false "Hops followed computing the exposure, the policy ones by default"
+//
+// @Success 200 {object} Assessment
+// @Success 500 {string} string
+func assessAddress(s Service) func(echo.Context) error {
+	return func(c echo.Context) error {
+		type Query struct {
+			Hops int `query:"hops" validate:"omitempty,gt=0,lte=10"`
+		}
+		q := new(Query)
+		if err := validator.Struct(&c, q); err != nil {
+			return err
+		}
+		address := c.Param("address")
+		if err := c.Echo().Validator.(*validator.CustomValidator).Var(address, "required,btc_addr|btc_addr_bech32"); err != nil {
+			return err
+		}
+
+		assessment, err := s.AssessAddress(c.Request().Context(), address, q.Hops)
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, assessment)
+	}
+}
+
+// assessTx godoc
+// @ID risk-tx
+//
+// @Router /risk/tx/{txid} [get]
+// @Summary Transaction risk
+// @Description get the risk score of the transaction, with the contributing factors and the exposure of the funds spent by category
+// @Tags risk
+//
+// @Security ApiKeyAuth
+//
+// @Accept  json
+// @Produce  json
+//
+// @Param txid path string true "Transaction hash"
+// @Param hops query int false "Hops followed computing the exposure, the policy ones by default"
+//
+// @Success 200 {object} Assessment
+// @Success 500 {string} string
+func assessTx(s Service) func(echo.Context) error {
+	return func(c echo.Context) error {
+		type Query struct {
+			Hops int `query:"hops" validate:"omitempty,gt=0,lte=10"`
+		}
+		q := new(Query)
+		if err := validator.Struct(&c, q); err != nil {
+			return err
+		}
+		txid := c.Param("txid")
+		if err := c.Echo().Validator.(*validator.CustomValidator).Var(txid, "required,hexadecimal,len=64"); err != nil {
+			return err
+		}
+
+		assessment, err := s.AssessTx(c.Request().Context(), txid, q.Hops)
+		if err != nil {
+			if errors.Is(err, errorx.ErrOutOfRange) {
+				return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+			}
+			return err
+		}
+		return c.JSON(http.StatusOK, assessment)
+	}
+}
@@ -0,0 +1,254 @@
+package risk
+
+import (
+	"context"
+	"errors"
+
+	"github.com/spf13/viper"
+
+	"github.com/xn3cr0nx/bitgodine/internal/abuse"
+	"github.com/xn3cr0nx/bitgodine/internal/cluster"
+	"github.com/xn3cr0nx/bitgodine/internal/errorx"
+	"github.com/xn3cr0nx/bitgodine/internal/storage/db/postgres"
+	"github.com/xn3cr0nx/bitgodine/internal/storage/kv"
+	"github.com/xn3cr0nx/bitgodine/internal/tag"
+	"github.com/xn3cr0nx/bitgodine/internal/trace"
+	"github.com/xn3cr0nx/bitgodine/internal/tx"
+	"github.com/xn3cr0nx/bitgodine/pkg/cache"
+)
+
+// lookupChunk number of addresses looked up at once categorizing the exposure
+const lookupChunk = 1000
+
+// Service interface exports available methods for risk service
+type Service interface {
+	AssessAddress(ctx context.Context, address string, hops int) (assessment *Assessment, err error)
+	AssessTx(ctx context.Context, txid string, hops int) (assessment *Assessment, err error)
+}
+
+type service struct {
+	Repository *postgres.Pg
+	Kv         kv.DB
+	Cache      *cache.Cache
+	Policy     *Policy
+}
+
+// NewService instantiates a new Service layer for risk scoring with the policy
+func NewService(r *postgres.Pg, k kv.DB, c *cache.Cache, p *Policy) *service {
+	return &service{
+		Repository: r,
+		Kv:         k,
+		Cache:      c,
+		Policy:     p,
+	}
+}
+
+// hops returns the hops to follow, the policy ones if not set, bounded to the taint engine depth
+func (s *service) hops(hops int) int {
+	if hops <= 0 {
+		hops = s.Policy.Hops
+	}
+	if hops > trace.DefaultTaintDepth {
+		hops = trace.DefaultTaintDepth
+	}
+	return hops
+}
+
+// taint propagates the haircut taint backward from the source through the hops, bounded by the
+// trace nodes, workers and timeout settings
+func (s *service) taint(ctx context.Context, source string, hops int) (*trace.Taint, error) {
+	return trace.NewService(s.Repository, s.Kv, s.Cache).Taint(ctx, source, &trace.TaintParams{
+		Model:     trace.Haircut,
+		Direction: trace.Backward,
+		MaxDepth:  hops,
+		MaxNodes:  viper.GetInt("server.trace.nodes"),
+		Workers:   viper.GetInt("server.trace.workers"),
+		Timeout:   viper.GetDuration("server.trace.timeout"),
+	})
+}
+
+// AssessAddress scores the risk of the funds received by the address, based on its cluster label and
+// abuse reports, the exposure of the funds it received and their coinjoin involvement
+func (s *service) AssessAddress(ctx context.Context, address string, hops int) (assessment *Assessment, err error) {
+	hops = s.hops(hops)
+	ev := new(Evidence)
+	if s.Repository != nil {
+		if ev.Label, err = tag.NewService(s.Repository, s.Cache).GetTaggedClusterSet(address); err != nil {
+			return
+		}
+		r, e := abuse.NewService(s.Repository, s.Cache).GetRisk(address)
+		if e != nil {
+			err = e
+			return
+		}
+		ev.Abuse = r.ClusterRisk
+	}
+
+	taint, err := s.taint(ctx, address, hops)
+	if err != nil {
+		if !errors.Is(err, errorx.ErrNotFound) {
+			return
+		}
+		err = nil
+	}
+	if err = s.collect(ev, taint); err != nil {
+		return
+	}
+
+	assessment = Evaluate(s.Policy, address, hops, ev)
+	return
+}
+
+// AssessTx scores the risk of the funds moved by the transaction, based on the label and abuse reports
+// of the riskiest input cluster, the exposure of the funds spent and their coinjoin involvement. The
+// exposure is the taint reaching the transaction outputs, followed back from its inputs
+func (s *service) AssessTx(ctx context.Context, txid string, hops int) (assessment *Assessment, err error) {
+	hops = s.hops(hops)
+	transaction, err := tx.NewService(s.Kv, s.Cache).GetFromHash(txid)
+	if err != nil {
+		return
+	}
+	taint, err := s.taint(ctx, txid, hops)
+	if err != nil {
+		return
+	}
+
+	ev := new(Evidence)
+	if s.Repository != nil {
+		seen := make(map[string]bool)
+		riskiest := -1.0
+		for _, out := range taint.Outputs {
+			if out.Depth != 1 || out.Address == "" || seen[out.Address] {
+				continue
+			}
+			seen[out.Address] = true
+			res, e := tag.NewService(s.Repository, s.Cache).GetTaggedClusterSet(out.Address)
+			if e != nil {
+				err = e
+				return
+			}
+			if score := s.Policy.severity(res.Category) * res.Confidence; res.Entity != "" && score > riskiest {
+				riskiest, ev.Label = score, res
+			}
+			r, e := abuse.NewService(s.Repository, s.Cache).GetRisk(out.Address)
+			if e != nil {
+				err = e
+				return
+			}
+			if r.ClusterRisk.Score > ev.Abuse.Score {
+				ev.Abuse = r.ClusterRisk
+			}
+		}
+	}
+	if err = s.collect(ev, taint); err != nil {
+		return
+	}
+	ev.Coinjoin = transaction.IsCoinjoin()
+
+	assessment = Evaluate(s.Policy, txid, hops, ev)
+	return
+}
+
+// collect adds to the evidence the exposure of the taint and the coinjoins it went through, the target
+// taking part in a coinjoin if one of the transactions it received from is
+func (s *service) collect(ev *Evidence, taint *trace.Taint) (err error) {
+	if taint == nil {
+		return
+	}
+	categories, err := s.categories(taint)
+	if err != nil {
+		return
+	}
+	ev.Exposure = exposure(taint, categories, s.Policy.Decay)
+
+	txService := tx.NewService(s.Kv, s.Cache)
+	checked := make(map[string]bool)
+	for _, out := range taint.Outputs {
+		if checked[out.TxID] {
+			continue
+		}
+		checked[out.TxID] = true
+		transaction, e := txService.GetFromHash(out.TxID)
+		if e != nil {
+			err = e
+			return
+		}
+		if !transaction.IsCoinjoin() {
+			continue
+		}
+		if out.Depth == 0 {
+			ev.Coinjoin = true
+		} else {
+			ev.Coinjoins++
+		}
+	}
+	return
+}
+
+// categories maps the addresses reached by the taint to their category, the most severe of the
+// abuses reported for them or else the most supported label of their cluster
+func (s *service) categories(taint *trace.Taint) (categories map[string]string, err error) {
+	categories = make(map[string]string)
+	if s.Repository == nil || s.Repository.DB == nil {
+		return
+	}
+
+	clusters := make(map[uint64]bool)
+	var ids []uint64
+	var addresses []string
+	for _, out := range taint.Outputs {
+		if out.Depth < 1 || out.Address == "" {
+			continue
+		}
+		addresses = append(addresses, out.Address)
+		if out.Cluster != nil && !clusters[*out.Cluster] {
+			clusters[*out.Cluster] = true
+			ids = append(ids, *out.Cluster)
+		}
+	}
+
+	labels := make(map[uint64]string)
+	for from := 0; from < len(ids); from += lookupChunk {
+		to := from + lookupChunk
+		if to > len(ids) {
+			to = len(ids)
+		}
+		var chunk []cluster.Label
+		if err = s.Repository.Where("cluster IN ?", ids[from:to]).Order("support desc, confidence desc").Find(&chunk).Error; err != nil {
+			return
+		}
+		for _, l := range chunk {
+			if _, ok := labels[l.Cluster]; !ok {
+				labels[l.Cluster] = tag.Category(l.Type)
+			}
+		}
+	}
+	for _, out := range taint.Outputs {
+		if out.Cluster == nil {
+			continue
+		}
+		if category, ok := labels[*out.Cluster]; ok {
+			categories[out.Address] = category
+		}
+	}
+
+	for from := 0; from < len(addresses); from += lookupChunk {
+		to := from + lookupChunk
+		if to > len(addresses) {
+			to = len(addresses)
+		}
+		var reported []abuse.Model
+		if err = s.Repository.Where("address IN ?", addresses[from:to]).Find(&reported).Error; err != nil {
+			return
+		}
+		severest := make(map[string]float64)
+		for _, a := range reported {
+			a.Normalize()
+			if severity := s.Policy.severity(a.Category); severity >= severest[a.Address] {
+				severest[a.Address] = severity
+				categories[a.Address] = a.Category
+			}
+		}
+	}
+	return
+}
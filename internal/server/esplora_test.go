@@ -0,0 +1,254 @@
+package server
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+	"github.com/btcsuite/btcutil"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/xn3cr0nx/bitgodine/internal/block"
+	"github.com/xn3cr0nx/bitgodine/internal/errorx"
+	"github.com/xn3cr0nx/bitgodine/internal/parser/bitcoin"
+	"github.com/xn3cr0nx/bitgodine/internal/storage/kv"
+	"github.com/xn3cr0nx/bitgodine/internal/tx"
+	"github.com/xn3cr0nx/bitgodine/pkg/cache"
+	"github.com/xn3cr0nx/bitgodine/pkg/logger"
+	"github.com/xn3cr0nx/bitgodine/pkg/validator"
+)
+
+// fixtures recorded esplora responses, each file is the body of the response to the GET
+// request of its path
+const fixtures = "testdata/esplora"
+
+// heights of the fixture blocks, block 1 is indexed by height only to be the next of genesis
+var heights = map[string]int32{
+	"000000000019d6689c085ae165831e934ff763ae46a2a6c172b3f1b60a8ce26f": 0,
+	"00000000839a8e6886ab5951d76f411475428afc90947ee320161bbf18eb6048": 1,
+	"00000000d1145790a8694403d4063f323d499e655c83426834d4ce2f8dd4a2ee": 170,
+}
+
+type TestEsploraSuite struct {
+	suite.Suite
+	router *echo.Echo
+	client *broadcaster
+	raw    map[string][]byte
+}
+
+type broadcaster struct {
+	sent *wire.MsgTx
+	err  error
+}
+
+func (b *broadcaster) SendRawTransaction(t *wire.MsgTx, allowHighFees bool) (*chainhash.Hash, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	b.sent = t
+	h := t.TxHash()
+	return &h, nil
+}
+
+// store indexes the raw block as the parser does, transactions converted by the parser itself
+func store(kvs map[string][]byte, raw []byte, height int32) (txs []tx.Tx, err error) {
+	blk, err := btcutil.NewBlockFromBytes(raw)
+	if err != nil {
+		return
+	}
+	txs, err = bitcoin.PrepareTransactions(nil, blk.Transactions())
+	if err != nil {
+		return
+	}
+	header := blk.MsgBlock().Header
+	b := block.Block{
+		ID:                blk.Hash().String(),
+		Height:            height,
+		Version:           header.Version,
+		Timestamp:         header.Timestamp,
+		Bits:              header.Bits,
+		Nonce:             header.Nonce,
+		MerkleRoot:        header.MerkleRoot.String(),
+		TxCount:           len(txs),
+		Previousblockhash: header.PrevBlock.String(),
+	}
+	h := strconv.Itoa(int(height))
+	for i := range txs {
+		b.Transactions = append(b.Transactions, txs[i].TxID)
+		kvs[txs[i].TxID] = tx.Marshal(&txs[i])
+		kvs["_"+txs[i].TxID] = []byte(h)
+	}
+	kvs[b.ID] = block.Marshal(&b)
+	kvs[h] = []byte(b.ID)
+	return
+}
+
+func (suite *TestEsploraSuite) SetupTest() {
+	logger.Setup()
+
+	c, err := cache.NewCache(nil)
+	require.Nil(suite.T(), err)
+
+	kvs := make(map[string][]byte)
+	suite.raw = make(map[string][]byte)
+	for hash, height := range heights {
+		raw, err := ioutil.ReadFile(filepath.Join(fixtures, "block", hash, "raw"))
+		if os.IsNotExist(err) {
+			kvs[strconv.Itoa(int(height))] = []byte(hash)
+			continue
+		}
+		require.Nil(suite.T(), err)
+		txs, err := store(kvs, raw, height)
+		require.Nil(suite.T(), err)
+		for i := range txs {
+			r, err := txs[i].Raw()
+			require.Nil(suite.T(), err)
+			suite.raw[txs[i].TxID] = r
+		}
+	}
+	db := kv.NewDBMock()
+	db.On("Read", mock.Anything).Return(func(key string) []byte {
+		return kvs[key]
+	}, func(key string) error {
+		if _, ok := kvs[key]; !ok {
+			return errorx.ErrKeyNotFound
+		}
+		return nil
+	})
+
+	suite.router = echo.New()
+	suite.router.Validator = validator.NewValidator()
+	suite.router.HTTPErrorHandler = customHTTPErrorHandler
+	api := suite.router.Group("/api")
+	block.Routes(api, block.NewService(db, c))
+	txService := tx.NewService(db, c)
+	suite.client = new(broadcaster)
+	txService.Client = suite.client
+	tx.Routes(api, txService)
+}
+
+func (suite *TestEsploraSuite) request(method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/api"+path, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	suite.router.ServeHTTP(rec, req)
+	return rec
+}
+
+func (suite *TestEsploraSuite) TestFixtures() {
+	n := 0
+	err := filepath.Walk(fixtures, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		expected, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(fixtures, path)
+		if err != nil {
+			return err
+		}
+		route := "/" + filepath.ToSlash(rel)
+		n++
+
+		rec := suite.request(http.MethodGet, route, "")
+		if !assert.Equal(suite.T(), http.StatusOK, rec.Code, route) {
+			return nil
+		}
+		switch contentType := rec.Header().Get(echo.HeaderContentType); {
+		case strings.HasPrefix(contentType, echo.MIMEApplicationJSON):
+			assert.JSONEq(suite.T(), string(expected), rec.Body.String(), route)
+		case strings.HasPrefix(contentType, echo.MIMETextPlain):
+			assert.Equal(suite.T(), string(expected), rec.Body.String(), route)
+		case contentType == echo.MIMEOctetStream:
+			assert.Equal(suite.T(), expected, rec.Body.Bytes(), route)
+		default:
+			suite.Failf("unexpected content type", "%s: %s", route, contentType)
+		}
+		return nil
+	})
+	require.Nil(suite.T(), err)
+	assert.NotZero(suite.T(), n)
+}
+
+func (suite *TestEsploraSuite) TestTipStatus() {
+	rec := suite.request(http.MethodGet, "/block/00000000d1145790a8694403d4063f323d499e655c83426834d4ce2f8dd4a2ee/status", "")
+	assert.Equal(suite.T(), http.StatusOK, rec.Code)
+	assert.JSONEq(suite.T(), `{"in_best_chain":true,"height":170}`, rec.Body.String())
+}
+
+func (suite *TestEsploraSuite) TestNotFound() {
+	missing := strings.Repeat("0", 56) + "deadbeef"
+	for _, route := range []string{
+		"/block/" + missing + "/header",
+		"/block/" + missing + "/raw",
+		"/tx/" + missing + "/hex",
+		"/tx/" + missing + "/raw",
+		"/tx/" + missing + "/merkle-proof",
+		"/tx/" + missing + "/merkleblock-proof",
+	} {
+		assert.Equal(suite.T(), http.StatusNotFound, suite.request(http.MethodGet, route, "").Code, route)
+	}
+}
+
+func (suite *TestEsploraSuite) TestBroadcast() {
+	txid := "f4184fc596403b9d638783cf57adfe4c75c605f6356fbc91338530e9831e9e16"
+	hex, err := ioutil.ReadFile(filepath.Join(fixtures, "tx", txid, "hex"))
+	require.Nil(suite.T(), err)
+
+	rec := suite.request(http.MethodPost, "/tx", string(hex)+"\n")
+	assert.Equal(suite.T(), http.StatusOK, rec.Code)
+	assert.Equal(suite.T(), txid, rec.Body.String())
+	require.NotNil(suite.T(), suite.client.sent)
+	assert.Equal(suite.T(), txid, suite.client.sent.TxHash().String())
+
+	assert.Equal(suite.T(), http.StatusBadRequest, suite.request(http.MethodPost, "/tx", "zz").Code)
+	assert.Equal(suite.T(), http.StatusBadRequest, suite.request(http.MethodPost, "/tx", "0100").Code)
+
+	suite.client.err = &btcjson.RPCError{Code: btcjson.ErrRPCVerify, Message: "bad-txns-inputs-missingorspent"}
+	rec = suite.request(http.MethodPost, "/tx", string(hex))
+	assert.Equal(suite.T(), http.StatusBadRequest, rec.Code)
+	assert.Contains(suite.T(), rec.Body.String(), "bad-txns-inputs-missingorspent")
+
+	suite.client.err = errors.New("connection refused")
+	assert.Equal(suite.T(), http.StatusInternalServerError, suite.request(http.MethodPost, "/tx", string(hex)).Code)
+}
+
+func (suite *TestEsploraSuite) TestBroadcastDisabled() {
+	c, err := cache.NewCache(nil)
+	require.Nil(suite.T(), err)
+	e := echo.New()
+	e.Validator = validator.NewValidator()
+	e.HTTPErrorHandler = customHTTPErrorHandler
+	tx.Routes(e.Group("/api"), tx.NewService(kv.NewDBMock(), c))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/tx", strings.NewReader("00"))
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(suite.T(), http.StatusServiceUnavailable, rec.Code)
+}
+
+// TestRaw checks the transactions rebuilt from the stored ones hash to their id
+func (suite *TestEsploraSuite) TestRaw() {
+	require.NotEmpty(suite.T(), suite.raw)
+	for txid, raw := range suite.raw {
+		assert.Equal(suite.T(), txid, chainhash.DoubleHashH(raw).String())
+	}
+}
+
+func TestEsplora(t *testing.T) {
+	suite.Run(t, new(TestEsploraSuite))
+}
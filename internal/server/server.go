@@ -13,16 +13,27 @@ import (
 	"github.com/xn3cr0nx/bitgodine/internal/abuse"
 	"github.com/xn3cr0nx/bitgodine/internal/address"
 	"github.com/xn3cr0nx/bitgodine/internal/analysis"
+	"github.com/xn3cr0nx/bitgodine/internal/audit"
 	"github.com/xn3cr0nx/bitgodine/internal/auth"
 	"github.com/xn3cr0nx/bitgodine/internal/block"
 	"github.com/xn3cr0nx/bitgodine/internal/cluster"
 	"github.com/xn3cr0nx/bitgodine/internal/errorx"
+	"github.com/xn3cr0nx/bitgodine/internal/feed"
+	"github.com/xn3cr0nx/bitgodine/internal/gql"
+	"github.com/xn3cr0nx/bitgodine/internal/investigation"
+	"github.com/xn3cr0nx/bitgodine/internal/parser/bitcoin"
+	"github.com/xn3cr0nx/bitgodine/internal/ratelimit"
+	"github.com/xn3cr0nx/bitgodine/internal/risk"
+	"github.com/xn3cr0nx/bitgodine/internal/spider"
 	"github.com/xn3cr0nx/bitgodine/internal/storage/db/postgres"
 	"github.com/xn3cr0nx/bitgodine/internal/storage/kv"
 	"github.com/xn3cr0nx/bitgodine/internal/tag"
 	"github.com/xn3cr0nx/bitgodine/internal/trace"
 	"github.com/xn3cr0nx/bitgodine/internal/tx"
+	"github.com/xn3cr0nx/bitgodine/internal/user"
+	"github.com/xn3cr0nx/bitgodine/internal/watchlist"
 	"github.com/xn3cr0nx/bitgodine/pkg/cache"
+	"github.com/xn3cr0nx/bitgodine/pkg/mailer"
 	"github.com/xn3cr0nx/bitgodine/pkg/meter"
 	"github.com/xn3cr0nx/bitgodine/pkg/pprof"
 	"github.com/xn3cr0nx/bitgodine/pkg/tracer"
@@ -105,7 +116,8 @@ func (s *Server) Listen() {
 	s.router.Use(middleware.GzipWithConfig(middleware.GzipConfig{
 		Level: 5,
 		Skipper: func(c echo.Context) bool {
-			return strings.Contains(c.Request().URL.Path, "swagger")
+			// streamed responses are not buffered by the compression
+			return strings.Contains(c.Request().URL.Path, "swagger") || strings.Contains(c.Request().URL.Path, "/feed/")
 		},
 	}))
 
@@ -124,7 +136,16 @@ func (s *Server) Listen() {
 
 	s.router.GET("/swagger/*", echoSwagger.WrapHandler)
 
-	api := s.router.Group("/api")
+	if conf := ratelimit.Conf(); conf.Enabled {
+		store, err := ratelimit.NewStore(conf, s.db)
+		if err != nil {
+			panic(errors.Wrapf(err, "cannot setup rate limiter"))
+		}
+		ratelimit.SetLimiter(ratelimit.NewLimiter(store, conf.Plans))
+	}
+
+	auditService := audit.NewService(s.pg)
+	api := s.router.Group("/api", audit.Middleware(auditService))
 
 	abuseService := abuse.NewService(s.pg, s.cache)
 	abuse.Routes(api, abuseService)
@@ -132,18 +153,65 @@ func (s *Server) Listen() {
 	address.Routes(api, addressService)
 	analysisService := analysis.NewService(s.db, s.cache)
 	analysis.Routes(api, analysisService)
-	authService := auth.NewService(s.pg)
+	audit.Routes(api, auditService)
+	mail, err := mailer.NewClient(mailer.Conf())
+	if err != nil {
+		panic(errors.Wrapf(err, "cannot setup mailer"))
+	}
+	authService := auth.NewService(s.pg, s.db, mail)
 	auth.Routes(api, authService)
 	blockService := block.NewService(s.db, s.cache)
 	block.Routes(api, blockService)
 	clusterService := cluster.NewService(s.pg, s.cache)
 	cluster.Routes(api, clusterService)
+	bus, err := feed.NewBus()
+	if err != nil {
+		panic(errors.Wrapf(err, "cannot setup feed bus"))
+	}
+	feedCtx, stopFeed := context.WithCancel(context.Background())
+	// streams are closed on shutdown, not to hold it until timeout
+	s.router.Server.RegisterOnShutdown(func() {
+		stopFeed()
+		bus.Close()
+	})
+	go func() {
+		if err := bus.Run(feedCtx); err != nil {
+			s.router.Logger.Error(err)
+		}
+	}()
+	feedService := feed.NewService(s.pg, s.cache, bus)
+	feed.Routes(api, feedService)
+	gqlService := gql.NewService(s.pg, s.db, s.cache)
+	gql.Routes(api, gqlService)
+	investigationService := investigation.NewService(s.pg)
+	investigation.Routes(api, investigationService)
+	policy, err := risk.LoadPolicy()
+	if err != nil {
+		panic(errors.Wrapf(err, "cannot load risk policy"))
+	}
+	riskService := risk.NewService(s.pg, s.db, s.cache, policy)
+	risk.Routes(api, riskService)
+	spiderService := spider.NewService(s.pg)
+	spider.Routes(api, spiderService)
 	tagService := tag.NewService(s.pg, s.cache)
 	tag.Routes(api, tagService)
 	traceService := trace.NewService(s.pg, s.db, s.cache)
 	trace.Routes(api, traceService)
 	txService := tx.NewService(s.db, s.cache)
+	if viper.GetBool("server.broadcast") {
+		client, err := bitcoin.NewRPCClient()
+		if err != nil {
+			panic(errors.Wrapf(err, "cannot setup bitcoin client"))
+		}
+		s.router.Server.RegisterOnShutdown(client.Shutdown)
+		txService.Client = client
+	}
 	tx.Routes(api, txService)
+	userService := user.NewService(s.pg)
+	user.Routes(api, userService)
+	ratelimit.Routes(api)
+	watchlistService := watchlist.NewService(s.pg, s.cache)
+	watchlist.Routes(api, watchlistService)
 
 	// fmt.Println("ROUTES:")
 	// for _, route := range s.router.Routes() {
@@ -187,7 +255,7 @@ func customHTTPErrorHandler(err error, c echo.Context) {
 			}
 		}
 	} else {
-		if errors.Is(err, errorx.ErrKeyNotFound) {
+		if errors.Is(err, errorx.ErrNotFound) {
 			code = http.StatusNotFound
 		}
 	}
@@ -2,6 +2,7 @@
 package bitcoinabuse
 
 import (
+	"context"
 	"encoding/csv"
 	"fmt"
 	"net/http"
@@ -11,8 +12,9 @@ import (
 
 	"github.com/spf13/viper"
 	"github.com/xn3cr0nx/bitgodine/internal/abuse"
+	"github.com/xn3cr0nx/bitgodine/internal/errorx"
 	"github.com/xn3cr0nx/bitgodine/internal/httpx"
-	"github.com/xn3cr0nx/bitgodine/internal/storage/db/postgres"
+	"github.com/xn3cr0nx/bitgodine/internal/spider"
 	"github.com/xn3cr0nx/bitgodine/pkg/logger"
 	"gorm.io/gorm"
 )
@@ -20,40 +22,54 @@ import (
 // Spider creates a web spider with predefined url to crawl
 type Spider struct {
 	target string
-	pg     *postgres.Pg
 }
 
 // NewSpider instance new spider object
-func NewSpider(pg *postgres.Pg) Spider {
+func NewSpider() *Spider {
 	target := fmt.Sprintf("%s/%s?api_token=%s", viper.GetString("spider.bitcoinabuse.url"), viper.GetString("spider.bitcoinabuse.period"), viper.GetString("spider.bitcoinabuse.api"))
-	return Spider{
+	return &Spider{
 		target,
-		pg,
 	}
 }
 
-// updatePeriod replace period url param
-func (s *Spider) updatePeriod(period string) {
-	s.target = strings.Replace(s.target, viper.GetString("spider.bitcoinabuse.period"), period, 1)
+// Name returns the source name
+func (s *Spider) Name() string {
+	return "bitcoinabuse"
 }
 
-// Sync visits the spider's target and extract address tags
-func (s *Spider) Sync() (err error) {
-	var count int64
-	s.pg.DB.Model(&abuse.Model{}).Count(&count)
-	fmt.Println("count abuses", count)
-	if count <= int64(viper.GetInt("bitcoinabuse.reported_abuses")) {
-		s.updatePeriod("forever")
+// Schedule returns the source cron spec
+func (s *Spider) Schedule() string {
+	return spider.Schedule(s.Name())
+}
+
+// period returns the url of the reports in the period
+func (s *Spider) period(period string) string {
+	return strings.Replace(s.target, viper.GetString("spider.bitcoinabuse.period"), period, 1)
+}
+
+// Sync fetches the abuses reported after the last report id synced, the whole
+// reports history on the first sync
+func (s *Spider) Sync(ctx context.Context, since string) (result *spider.Result, err error) {
+	target := s.target
+	last := 0
+	if since == "" {
+		target = s.period("forever")
+	} else if last, err = strconv.Atoi(since); err != nil {
+		return
 	}
 
-	logger.Info("Spider", "Fetching bitcoinabuse reports", logger.Params{"target": s.target})
-	resp, err := http.Get(s.target)
+	logger.Info("Spider", "Fetching bitcoinabuse reports", logger.Params{"since": since})
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
 	if err != nil {
-		return err
+		return
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return
 	}
 	body, err := httpx.ParseResponse(resp)
 	if err != nil {
-		return err
+		return
 	}
 
 	logger.Info("Spider", "Parsing bitcoinabuse new resource", logger.Params{"length": len(body)})
@@ -66,49 +82,58 @@ func (s *Spider) Sync() (err error) {
 	r.LazyQuotes = true
 	records, err := r.ReadAll()
 	if err != nil {
-		return err
+		return
 	}
 
-	newResource := false
+	result = &spider.Result{Cursor: since}
+	if len(records) == 0 {
+		logger.Info("Spider", "Empty bitcoinabuse resource", logger.Params{})
+		return
+	}
+	// the reader requires all the records to have as many fields as the header
+	if len(records[0]) < columns {
+		return nil, fmt.Errorf("%w: bitcoinabuse resource with %d columns, expected %d", errorx.ErrInvalidArgument, len(records[0]), columns)
+	}
+	cursor := last
 	for _, r := range records[1:] {
 		id, e := strconv.Atoi(r[0])
 		if e != nil {
-			return e
+			return nil, e
+		}
+		if id <= last {
+			continue
 		}
 		created, e := time.Parse("2006-01-02 15:04:05", r[8])
 		if e != nil {
-			return e
+			return nil, e
 		}
-		m := gorm.Model{
-			ID:        uint(id),
-			CreatedAt: created,
-		}
-		a := &abuse.Model{
-			Model:           m,
+		a := abuse.Model{
+			Model:           gorm.Model{ID: uint(id), CreatedAt: created},
 			Address:         r[1],
 			AbuseTypeID:     r[2],
-			AbuseTypeOther:  r[3],
-			Abuser:          r[4],
-			Description:     r[5],
+			AbuseTypeOther:  sanitize(r[3]),
+			Abuser:          sanitize(r[4]),
+			Description:     sanitize(r[5]),
 			FromCountry:     r[6],
 			FromCountryCode: r[7],
 		}
-		if count != 0 {
-			if res := s.pg.DB.First(&abuse.Model{}, a.ID); res.Error == nil {
-				continue
-			}
-		}
-		newResource = true
-		if res := s.pg.DB.Create(a); res.Error != nil {
-			if strings.Contains(res.Error.Error(), "invalid byte sequence") {
-				continue
-			}
-			return res.Error
+		result.Abuses = append(result.Abuses, a)
+		if id > cursor {
+			cursor = id
+			result.Cursor = strconv.Itoa(id)
 		}
 	}
-	if !newResource {
+	if len(result.Abuses) == 0 {
 		logger.Info("Spider", "No new abuse in resource", logger.Params{})
 	}
 
 	return
 }
+
+// columns number of fields of a bitcoinabuse report
+const columns = 9
+
+// sanitize removes from reported text the bytes postgres can't store
+func sanitize(text string) string {
+	return strings.ReplaceAll(strings.ToValidUTF8(text, ""), "\x00", "")
+}
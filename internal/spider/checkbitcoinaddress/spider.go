@@ -2,12 +2,14 @@
 package checkbitcoinaddress
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"strings"
 
 	"github.com/gocolly/colly/v2"
 	"github.com/spf13/viper"
-	"github.com/xn3cr0nx/bitgodine/internal/storage/db/postgres"
+	"github.com/xn3cr0nx/bitgodine/internal/spider"
 	"github.com/xn3cr0nx/bitgodine/internal/tag"
 	"github.com/xn3cr0nx/bitgodine/pkg/logger"
 )
@@ -16,67 +18,79 @@ import (
 type Spider struct {
 	crawler *colly.Collector
 	target  string
-	pg      *postgres.Pg
+}
+
+// categories crawled, each resuming from its own last tag
+var categories = []string{"signed-messages", "submitted-links", "bitcoin-otc-profiles", "forum-profiles"}
+
+// mark last tag synced of a category, the cursor is the json map of the categories marks
+type mark struct {
+	Address  string `json:"address"`
+	Link     string `json:"link"`
+	Verified bool   `json:"verified"`
 }
 
 // NewSpider instance new spider object
-func NewSpider(pg *postgres.Pg) Spider {
-	return Spider{
-		crawler: colly.NewCollector(colly.Async(true), colly.CacheDir(viper.GetString("spider.cache"))),
-		target:  viper.GetString("spider.checkbitcoinaddress.url"),
-		pg:      pg,
+func NewSpider() *Spider {
+	return &Spider{
+		target: viper.GetString("spider.checkbitcoinaddress.url"),
 	}
 }
 
-// Sync visits the spider's target and extract address tags
-func (s *Spider) Sync() (err error) {
-	category := "signed-messages"
-	last := &tag.Model{Type: category}
-	s.pg.DB.Where(last).First(last)
-	signedMessages, err := s.ExtractSignedMessages(strings.Join([]string{s.target, category}, "/"), category, last)
-	if err != nil {
-		return
-	}
+// Name returns the source name
+func (s *Spider) Name() string {
+	return "checkbitcoinaddress"
+}
 
-	category = "submitted-links"
-	last = &tag.Model{Type: category}
-	s.pg.DB.Where(last).First(last)
-	s.crawler = colly.NewCollector(colly.Async(true), colly.CacheDir(viper.GetString("spider.cache")))
-	submittedLinks, err := s.ExtractSubmittedLinks(strings.Join([]string{s.target, category}, "/"), category, last)
-	if err != nil {
-		return
+// Schedule returns the source cron spec
+func (s *Spider) Schedule() string {
+	return spider.Schedule(s.Name())
+}
+
+// Sync visits the spider's target and extract address tags published after the last ones synced
+func (s *Spider) Sync(ctx context.Context, since string) (result *spider.Result, err error) {
+	marks := make(map[string]mark)
+	if since != "" {
+		if err = json.Unmarshal([]byte(since), &marks); err != nil {
+			return
+		}
 	}
 
-	category = "bitcoin-otc-profiles"
-	last = &tag.Model{Type: category}
-	s.pg.DB.Where(last).First(last)
-	s.crawler = colly.NewCollector(colly.Async(true), colly.CacheDir(viper.GetString("spider.cache")))
-	otcProfiles, err := s.ExtractOTCProfiles(strings.Join([]string{s.target, category}, "/"), category, last)
-	if err != nil {
-		return
+	result = new(spider.Result)
+	for _, category := range categories {
+		if err = ctx.Err(); err != nil {
+			return
+		}
+		m := marks[category]
+		last := &tag.Model{Address: m.Address, Link: m.Link, Verified: m.Verified, Type: category}
+		s.crawler = colly.NewCollector(colly.Async(true), colly.CacheDir(viper.GetString("spider.cache")))
+
+		var tags []tag.Model
+		switch category {
+		case "signed-messages":
+			tags, err = s.ExtractSignedMessages(strings.Join([]string{s.target, category}, "/"), category, last)
+		case "submitted-links":
+			tags, err = s.ExtractSubmittedLinks(strings.Join([]string{s.target, category}, "/"), category, last)
+		case "bitcoin-otc-profiles":
+			tags, err = s.ExtractOTCProfiles(strings.Join([]string{s.target, category}, "/"), category, last)
+		case "forum-profiles":
+			tags, err = s.ExtractForumProfiles(strings.Join([]string{s.target, category}, "/"), category, last)
+		}
+		if err != nil {
+			return
+		}
+		// pages list the most recent tags first
+		if len(tags) > 0 {
+			marks[category] = mark{Address: tags[0].Address, Link: tags[0].Link, Verified: tags[0].Verified}
+		}
+		result.Tags = append(result.Tags, tags...)
 	}
 
-	category = "forum-profiles"
-	last = &tag.Model{Type: category}
-	s.pg.DB.Where(last).First(last)
-	s.crawler = colly.NewCollector(colly.Async(true), colly.CacheDir(viper.GetString("spider.cache")))
-	forumProfiles, err := s.ExtractForumProfiles(strings.Join([]string{s.target, category}, "/"), category, last)
+	cursor, err := json.Marshal(marks)
 	if err != nil {
 		return
 	}
-
-	var tags []tag.Model
-	tags = append(tags, signedMessages...)
-	tags = append(tags, submittedLinks...)
-	tags = append(tags, otcProfiles...)
-	tags = append(tags, forumProfiles...)
-
-	for _, t := range tags {
-		if res := s.pg.DB.Create(&t); res.Error != nil {
-			return res.Error
-		}
-	}
-
+	result.Cursor = string(cursor)
 	return
 }
 
@@ -123,20 +137,15 @@ func (s *Spider) ExtractSignedMessages(target, category string, last *tag.Model)
 
 // ExtractSubmittedLinks returns the list of submitted links
 func (s *Spider) ExtractSubmittedLinks(target, category string, last *tag.Model) (tags []tag.Model, err error) {
-	tags, err = s.ExtractSignedMessages(target, category, last)
-	for _, t := range tags {
-		t.Type = category
-	}
-	return
+	return s.ExtractSignedMessages(target, category, last)
 }
 
 // ExtractOTCProfiles returns the list of otc profiles
 func (s *Spider) ExtractOTCProfiles(target, category string, last *tag.Model) (tags []tag.Model, err error) {
 	tags, err = s.ExtractSignedMessages(target, category, last)
-	for _, t := range tags {
-		t.Type = category
-		t.Nickname = t.Message
-		t.Message = ""
+	for i := range tags {
+		tags[i].Nickname = tags[i].Message
+		tags[i].Message = ""
 	}
 	return
 }
@@ -0,0 +1,28 @@
+package spider
+
+import (
+	"time"
+)
+
+// State of a source persisted after each run. Errors counts the consecutive failed runs
+type State struct {
+	Source      string     `json:"source" gorm:"primarykey"`
+	Schedule    string     `json:"schedule"`
+	Cursor      string     `json:"cursor"`
+	LastRun     *time.Time `json:"last_run,omitempty"`
+	LastSuccess *time.Time `json:"last_success,omitempty"`
+	Errors      int        `json:"errors" gorm:"default:0"`
+	LastError   string     `json:"last_error,omitempty"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+} //@name SpiderState
+
+// TableName defines default table name
+func (m State) TableName() string {
+	return "spider_states"
+}
+
+// Status health of a source
+type Status struct {
+	State
+	Healthy bool `json:"healthy"`
+} //@name SpiderStatus
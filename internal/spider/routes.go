@@ -0,0 +1,41 @@
+package spider
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/xn3cr0nx/bitgodine/pkg/validator"
+)
+
+// Routes mounts all /spider based routes on the main group
+func Routes(g *echo.Group, s Service) {
+	r := g.Group("/spider", validator.JWT(), validator.Role(validator.Admin))
+
+	r.GET("/status", getStatus(s))
+}
+
+// getStatus godoc
+// @ID get-spider-status
+//
+// @Router /spider/status [get]
+// @Summary Get spider sources status
+// @Description get the state of each spider source, unhealthy if its last run failed
+// @Tags spider
+//
+// @Security ApiKeyAuth
+//
+// @Accept  json
+// @Produce  json
+//
+// @Success 200 {array} Status
+// @Success 500 {string} string
+func getStatus(s Service) func(echo.Context) error {
+	return func(c echo.Context) error {
+		status, err := s.GetStatus()
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, status)
+	}
+}
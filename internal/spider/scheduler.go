@@ -0,0 +1,146 @@
+package spider
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/spf13/viper"
+
+	"github.com/xn3cr0nx/bitgodine/pkg/logger"
+)
+
+// Config scheduler settings. A failing sync is retried Retries times, waiting Backoff
+// doubled at each attempt
+type Config struct {
+	Retries int
+	Backoff time.Duration
+}
+
+// Conf returns the scheduler settings from the environment
+func Conf() *Config {
+	return &Config{
+		Retries: viper.GetInt("spider.retries"),
+		Backoff: viper.GetDuration("spider.backoff"),
+	}
+}
+
+// Scheduler runs the registered sources persisting their results and state
+type Scheduler struct {
+	store    Store
+	registry *Registry
+	conf     *Config
+}
+
+// NewScheduler creates a new scheduler of the registry sources
+func NewScheduler(store Store, registry *Registry, conf *Config) *Scheduler {
+	return &Scheduler{
+		store:    store,
+		registry: registry,
+		conf:     conf,
+	}
+}
+
+// Run syncs the source once, retrying on failure, and updates its state
+func (s *Scheduler) Run(ctx context.Context, name string) (err error) {
+	src, err := s.registry.Get(name)
+	if err != nil {
+		return
+	}
+	state, err := s.store.Load(name)
+	if err != nil {
+		return
+	}
+	state.Schedule = src.Schedule()
+
+	logger.Info("Spider", "Syncing source", logger.Params{"source": name, "since": state.Cursor})
+	var result *Result
+	for attempt := 0; ; attempt++ {
+		if result, err = src.Sync(ctx, state.Cursor); err == nil {
+			for i := range result.Tags {
+				result.Tags[i].Source = name
+			}
+			err = s.store.Persist(result)
+		}
+		if err == nil || attempt >= s.conf.Retries {
+			break
+		}
+
+		wait := s.conf.Backoff << uint(attempt)
+		logger.Warn("Spider", "Source sync failed, retrying", logger.Params{"source": name, "attempt": attempt + 1, "wait": wait.String(), "error": err.Error()})
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	now := time.Now()
+	state.LastRun = &now
+	if err != nil {
+		state.Errors++
+		state.LastError = err.Error()
+	} else {
+		state.Errors = 0
+		state.LastError = ""
+		state.LastSuccess = &now
+		if result.Cursor != "" {
+			state.Cursor = result.Cursor
+		}
+		logger.Info("Spider", "Source synced", logger.Params{"source": name, "tags": len(result.Tags), "abuses": len(result.Abuses), "cursor": state.Cursor})
+	}
+	if e := s.store.Save(state); e != nil && err == nil {
+		err = e
+	}
+	return
+}
+
+// RunAll syncs all the sources once, returning the last error occurred
+func (s *Scheduler) RunAll(ctx context.Context) (err error) {
+	for _, src := range s.registry.Sources() {
+		if e := s.Run(ctx, src.Name()); e != nil {
+			logger.Error("Spider", e, logger.Params{"source": src.Name()})
+			err = e
+		}
+	}
+	return
+}
+
+// cronLogger reports the cron errors, such as recovered panics, through the logger
+type cronLogger struct{}
+
+func (cronLogger) Info(msg string, keysAndValues ...interface{}) {}
+
+func (cronLogger) Error(err error, msg string, keysAndValues ...interface{}) {
+	params := logger.Params{"message": msg}
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		params[fmt.Sprint(keysAndValues[i])] = keysAndValues[i+1]
+	}
+	logger.Error("Spider", err, params)
+}
+
+// Start schedules each source on its own cron spec, blocking until the context is done.
+// Runs of the same source never overlap, a failing or panicking source doesn't affect the others
+func (s *Scheduler) Start(ctx context.Context) (err error) {
+	c := cron.New(cron.WithChain(cron.Recover(cronLogger{}), cron.SkipIfStillRunning(cronLogger{})))
+	for _, src := range s.registry.Sources() {
+		name := src.Name()
+		logger.Info("Spider", "Scheduling source", logger.Params{"source": name, "crontime": src.Schedule()})
+		if _, err = c.AddFunc(src.Schedule(), func() {
+			if err := s.Run(ctx, name); err != nil {
+				logger.Error("Spider", err, logger.Params{"source": name})
+			}
+		}); err != nil {
+			return
+		}
+	}
+
+	c.Start()
+	<-ctx.Done()
+	<-c.Stop().Done()
+	return
+}
@@ -0,0 +1,139 @@
+package spider
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/xn3cr0nx/bitgodine/internal/errorx"
+	"github.com/xn3cr0nx/bitgodine/internal/tag"
+	"github.com/xn3cr0nx/bitgodine/pkg/logger"
+)
+
+type store struct {
+	states map[string]State
+	tags   []tag.Model
+}
+
+func (s *store) Load(source string) (*State, error) {
+	state, ok := s.states[source]
+	if !ok {
+		state = State{Source: source}
+	}
+	return &state, nil
+}
+
+func (s *store) Save(state *State) error {
+	s.states[state.Source] = *state
+	return nil
+}
+
+func (s *store) Persist(result *Result) error {
+	s.tags = append(s.tags, result.Tags...)
+	return nil
+}
+
+type source struct {
+	name     string
+	failures int
+	calls    []string
+}
+
+func (s *source) Name() string {
+	return s.name
+}
+
+func (s *source) Schedule() string {
+	return "@hourly"
+}
+
+func (s *source) Sync(ctx context.Context, since string) (*Result, error) {
+	s.calls = append(s.calls, since)
+	if s.failures > 0 {
+		s.failures--
+		return nil, errors.New("unavailable")
+	}
+	return &Result{Tags: []tag.Model{{Address: "1Tagged", Type: s.name}}, Cursor: since + "x"}, nil
+}
+
+type TestSchedulerSuite struct {
+	suite.Suite
+	store     *store
+	source    *source
+	scheduler *Scheduler
+}
+
+func (suite *TestSchedulerSuite) SetupSuite() {
+	logger.Setup()
+}
+
+func (suite *TestSchedulerSuite) SetupTest() {
+	suite.store = &store{states: make(map[string]State)}
+	suite.source = &source{name: "test"}
+	registry := NewRegistry()
+	require.Nil(suite.T(), registry.Register(suite.source))
+	suite.scheduler = NewScheduler(suite.store, registry, &Config{Retries: 2, Backoff: time.Millisecond})
+}
+
+func (suite *TestSchedulerSuite) TestRegistry() {
+	registry := NewRegistry()
+	require.Nil(suite.T(), registry.Register(&source{name: "b"}))
+	require.Nil(suite.T(), registry.Register(&source{name: "a"}))
+	assert.True(suite.T(), errors.Is(registry.Register(&source{name: "a"}), errorx.ErrAlreadyExists))
+
+	sources := registry.Sources()
+	require.Len(suite.T(), sources, 2)
+	assert.Equal(suite.T(), "a", sources[0].Name())
+	_, err := registry.Get("c")
+	assert.True(suite.T(), errors.Is(err, errorx.ErrNotFound))
+}
+
+func (suite *TestSchedulerSuite) TestResumesFromCursor() {
+	require.Nil(suite.T(), suite.scheduler.Run(context.Background(), "test"))
+	require.Nil(suite.T(), suite.scheduler.Run(context.Background(), "test"))
+
+	assert.Equal(suite.T(), []string{"", "x"}, suite.source.calls)
+	assert.Len(suite.T(), suite.store.tags, 2)
+	state := suite.store.states["test"]
+	assert.Equal(suite.T(), "xx", state.Cursor)
+	assert.Equal(suite.T(), "@hourly", state.Schedule)
+	assert.Equal(suite.T(), 0, state.Errors)
+	assert.NotNil(suite.T(), state.LastSuccess)
+}
+
+func (suite *TestSchedulerSuite) TestRetries() {
+	suite.source.failures = 2
+	require.Nil(suite.T(), suite.scheduler.Run(context.Background(), "test"))
+	assert.Len(suite.T(), suite.source.calls, 3)
+	assert.Equal(suite.T(), 0, suite.store.states["test"].Errors)
+}
+
+func (suite *TestSchedulerSuite) TestFailureKeepsCursor() {
+	require.Nil(suite.T(), suite.scheduler.Run(context.Background(), "test"))
+	suite.source.failures = 3
+	assert.NotNil(suite.T(), suite.scheduler.Run(context.Background(), "test"))
+
+	state := suite.store.states["test"]
+	assert.Equal(suite.T(), "x", state.Cursor)
+	assert.Equal(suite.T(), 1, state.Errors)
+	assert.Equal(suite.T(), "unavailable", state.LastError)
+	assert.True(suite.T(), state.LastRun.After(*state.LastSuccess) || state.LastRun.Equal(*state.LastSuccess))
+}
+
+func (suite *TestSchedulerSuite) TestCancelStopsRetries() {
+	suite.source.failures = 3
+	suite.scheduler.conf.Backoff = time.Hour
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.True(suite.T(), errors.Is(suite.scheduler.Run(ctx, "test"), context.Canceled))
+	assert.Len(suite.T(), suite.source.calls, 1)
+}
+
+func TestScheduler(t *testing.T) {
+	suite.Run(t, new(TestSchedulerSuite))
+}
@@ -0,0 +1,89 @@
+// Package spider framework running the address tags resources crawlers. Each resource is a Source
+// registered in a Registry, synced by the Scheduler resuming from the state persisted after last run
+package spider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/spf13/viper"
+
+	"github.com/xn3cr0nx/bitgodine/internal/abuse"
+	"github.com/xn3cr0nx/bitgodine/internal/errorx"
+	"github.com/xn3cr0nx/bitgodine/internal/tag"
+)
+
+// Result of a source sync. Cursor is the position to resume the next sync from,
+// left empty to keep the previous one
+type Result struct {
+	Tags   []tag.Model
+	Abuses []abuse.Model
+	Cursor string
+}
+
+// Source of address tags and abuses crawled by a spider
+type Source interface {
+	// Name unique name of the source
+	Name() string
+	// Schedule cron spec of the source syncs
+	Schedule() string
+	// Sync fetches the resources published since the cursor returned by the last successful sync,
+	// empty on the first one
+	Sync(ctx context.Context, since string) (*Result, error)
+}
+
+// Schedule returns the cron spec configured for the source, defaulting to the spider one
+func Schedule(name string) string {
+	if spec := viper.GetString("spider." + name + ".crontime"); spec != "" {
+		return spec
+	}
+	return viper.GetString("spider.crontime")
+}
+
+// Registry of the available sources
+type Registry struct {
+	sync.RWMutex
+	sources map[string]Source
+}
+
+// NewRegistry creates a new empty registry
+func NewRegistry() *Registry {
+	return &Registry{sources: make(map[string]Source)}
+}
+
+// Register adds the source to the registry
+func (r *Registry) Register(s Source) error {
+	r.Lock()
+	defer r.Unlock()
+	if _, ok := r.sources[s.Name()]; ok {
+		return fmt.Errorf("source %s %w", s.Name(), errorx.ErrAlreadyExists)
+	}
+	r.sources[s.Name()] = s
+	return nil
+}
+
+// Get returns the source registered with the name
+func (r *Registry) Get(name string) (Source, error) {
+	r.RLock()
+	defer r.RUnlock()
+	s, ok := r.sources[name]
+	if !ok {
+		return nil, fmt.Errorf("source %s %w", name, errorx.ErrNotFound)
+	}
+	return s, nil
+}
+
+// Sources returns the registered sources sorted by name
+func (r *Registry) Sources() (sources []Source) {
+	r.RLock()
+	defer r.RUnlock()
+	for _, s := range r.sources {
+		sources = append(sources, s)
+	}
+	sort.Slice(sources, func(i, j int) bool {
+		return sources[i].Name() < sources[j].Name()
+	})
+	return
+}
@@ -0,0 +1,98 @@
+package spider
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+
+	"github.com/xn3cr0nx/bitgodine/internal/abuse"
+	"github.com/xn3cr0nx/bitgodine/internal/cluster"
+	"github.com/xn3cr0nx/bitgodine/internal/storage/db/postgres"
+	"github.com/xn3cr0nx/bitgodine/internal/tag"
+)
+
+// Store interface to persist the sources state and the resources they sync
+type Store interface {
+	Load(source string) (state *State, err error)
+	Save(state *State) (err error)
+	Persist(result *Result) (err error)
+}
+
+// Service interface exports available methods for spider service
+type Service interface {
+	Store
+	GetStatus() (status []Status, err error)
+}
+
+type service struct {
+	Repository *postgres.Pg
+}
+
+// NewService instantiates a new Service layer for customer
+func NewService(r *postgres.Pg) *service {
+	return &service{
+		Repository: r,
+	}
+}
+
+// Load returns the state of the source, empty if never run
+func (s *service) Load(source string) (state *State, err error) {
+	state = &State{Source: source}
+	err = s.Repository.Where("source = ?", source).First(state).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = nil
+	}
+	return
+}
+
+// Save stores the state of the source
+func (s *service) Save(state *State) (err error) {
+	err = s.Repository.Save(state).Error
+	return
+}
+
+// Persist stores the tags and abuses synced by a source. Tags already stored are skipped,
+// so that a source can sync again resources partially synced
+func (s *service) Persist(result *Result) (err error) {
+	err = s.Repository.Transaction(func(tx *gorm.DB) error {
+		tags, err := tag.Fresh(tx, result.Tags)
+		if err != nil {
+			return err
+		}
+		addresses := make([]string, 0, len(tags))
+		for i := range tags {
+			if err := tx.Create(&tags[i]).Error; err != nil {
+				return err
+			}
+			addresses = append(addresses, tags[i].Address)
+		}
+		abuses, err := abuse.Fresh(tx, result.Abuses)
+		if err != nil {
+			return err
+		}
+		for i := range abuses {
+			if err := tx.Create(&abuses[i]).Error; err != nil {
+				return err
+			}
+		}
+
+		clusters, err := cluster.Of(tx, addresses)
+		if err != nil {
+			return err
+		}
+		return cluster.RefreshLabels(tx, clusters)
+	})
+	return
+}
+
+// GetStatus returns the health of the sources run at least once
+func (s *service) GetStatus() (status []Status, err error) {
+	var states []State
+	if err = s.Repository.Order("source").Find(&states).Error; err != nil {
+		return
+	}
+	for _, state := range states {
+		status = append(status, Status{State: state, Healthy: state.Errors == 0})
+	}
+	return
+}
@@ -2,104 +2,206 @@
 package walletexplorer
 
 import (
-	"fmt"
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
 
 	"github.com/gocolly/colly/v2"
 	"github.com/spf13/viper"
-	"github.com/xn3cr0nx/bitgodine/internal/storage/db/postgres"
+	"github.com/xn3cr0nx/bitgodine/internal/spider"
 	"github.com/xn3cr0nx/bitgodine/internal/tag"
 	"github.com/xn3cr0nx/bitgodine/pkg/logger"
 )
 
+// categories maps the wallet list headings to the tag types
+var categories = map[string]string{
+	"exchanges":       "exchange",
+	"pools":           "pool",
+	"services/others": "service",
+	"gambling":        "gambling",
+	"old/historic":    "historic",
+}
+
+// Wallet listed by walletexplorer with its category
+type Wallet struct {
+	Name     string
+	Category string
+	Link     string
+}
+
 // Spider creates a web spider with predefined url to crawl
 type Spider struct {
 	crawler *colly.Collector
 	target  string
-	pg      *postgres.Pg
+	pages   int
 }
 
 // NewSpider instance new spider object
-func NewSpider(pg *postgres.Pg) Spider {
-	return Spider{
-		crawler: colly.NewCollector(colly.Async(true), colly.CacheDir(viper.GetString("spider.cache"))),
-		target:  viper.GetString("spider.walletexplorer.url"),
-		pg:      pg,
+func NewSpider() *Spider {
+	return &Spider{
+		target: strings.TrimSuffix(viper.GetString("spider.walletexplorer.url"), "/"),
+		pages:  viper.GetInt("spider.walletexplorer.pages"),
+	}
+}
+
+// Name returns the source name
+func (s *Spider) Name() string {
+	return "walletexplorer"
+}
+
+// Schedule returns the source cron spec
+func (s *Spider) Schedule() string {
+	return spider.Schedule(s.Name())
+}
+
+// collector returns a collector aborting its requests once the context is done. Only the wallets list
+// is cached, address pages change as the wallets receive new addresses
+func (s *Spider) collector(ctx context.Context, cached bool) *colly.Collector {
+	c := colly.NewCollector()
+	if cached {
+		c.CacheDir = viper.GetString("spider.cache")
 	}
+	c.OnRequest(func(r *colly.Request) {
+		if ctx.Err() != nil {
+			r.Abort()
+		}
+	})
+	return c
 }
 
-// Sync visits the spider's target and extract address tags
-func (s *Spider) Sync() (err error) {
-	// last := &tag.Model{Type: category}
-	// s.pg.DB.Where(last).First(last)
-	// signedMessages, err := s.ExtractAddresses(strings.Join([]string{s.target, category}, "/"), category, last)
-	_, err = s.ExtractAddresses(s.target)
+// Sync visits the wallets listed by the spider's target and extract their addresses tags.
+// The cursor is the json map of the last address page visited of each wallet, the next sync
+// resumes from there. Each sync visits at most the configured number of pages, if any
+func (s *Spider) Sync(ctx context.Context, since string) (result *spider.Result, err error) {
+	cursor := make(map[string]int)
+	if since != "" {
+		if err = json.Unmarshal([]byte(since), &cursor); err != nil {
+			return
+		}
+	}
+
+	s.crawler = s.collector(ctx, true)
+	wallets, err := s.ExtractWallets(s.target)
 	if err != nil {
 		return
 	}
-	return nil
-}
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	result = new(spider.Result)
+	budget := s.pages
+	for _, wallet := range wallets {
+		if err = ctx.Err(); err != nil {
+			return
+		}
+		if s.pages > 0 && budget <= 0 {
+			break
+		}
+		page := cursor[wallet.Name]
+		if page < 1 {
+			page = 1
+		}
 
-// ExtractAddresses returns the list of signed messages
-func (s *Spider) ExtractAddresses(target string) (tags []tag.Model, err error) {
-	// NOT CURRENTLY IMPLEMENTED
+		s.crawler = s.collector(ctx, false)
+		tags, last, e := s.ExtractAddresses(wallet, page, &budget)
+		if e != nil {
+			err = e
+			return
+		}
+		cursor[wallet.Name] = last
+		result.Tags = append(result.Tags, tags...)
+	}
+
+	c, err := json.Marshal(cursor)
+	if err != nil {
+		return
+	}
+	result.Cursor = string(c)
 	return
-	var reached bool
+}
 
+// ExtractWallets returns the list of wallets with their category
+func (s *Spider) ExtractWallets(target string) (wallets []Wallet, err error) {
 	s.crawler.OnRequest(func(r *colly.Request) {
 		logger.Info("Walletexplorer spider", "Visiting page", logger.Params{"url": r.URL.String()})
 	})
 
-	s.crawler.OnHTML("table tbody tr", func(e *colly.HTMLElement) {
-		if reached {
-			return
+	s.crawler.OnHTML("table.serviceslist td", func(e *colly.HTMLElement) {
+		heading := strings.ToLower(strings.TrimSuffix(strings.TrimSpace(e.ChildText("h3")), ":"))
+		category, ok := categories[heading]
+		if !ok {
+			category = heading
 		}
-		// tag := tag.Model{
-		// 	Address:  e.ChildText("td:nth-child(1)"),
-		// 	Message:  e.ChildText("td:nth-child(3)"),
-		// 	Link:     e.ChildText("td:nth-child(4)"),
-		// 	Type:     category,
-		// 	Verified: verified,
-		// }
-		// if tag.Address == last.Address && tag.Link == last.Link && tag.Verified == last.Verified {
-		// 	reached = true
-		// 	return
-		// }
-		e.ForEachWithBreak("td ul", func(_ int, ce *colly.HTMLElement) bool {
-			ce.ForEachWithBreak("li", func(_ int, le *colly.HTMLElement) bool {
-				return true
+		e.ForEach("ul li", func(_ int, le *colly.HTMLElement) {
+			href := le.ChildAttr("a", "href")
+			if !strings.HasPrefix(href, "/wallet/") {
+				return
+			}
+			wallets = append(wallets, Wallet{
+				Name:     strings.TrimPrefix(href, "/wallet/"),
+				Category: category,
+				Link:     e.Request.AbsoluteURL(href),
 			})
-			return true
 		})
+	})
+
+	err = s.crawler.Visit(target)
+	return
+}
 
-		// tags = append(tags, tag)
+// ExtractAddresses returns the addresses of the wallet tagged with its name, visiting its address
+// pages from the page passed, and the last page visited. budget, if positive, is the number of
+// pages left to visit, decreased for each page
+func (s *Spider) ExtractAddresses(wallet Wallet, page int, budget *int) (tags []tag.Model, last int, err error) {
+	last = page
+	limited := *budget > 0
+
+	s.crawler.OnRequest(func(r *colly.Request) {
+		logger.Info("Walletexplorer spider", "Visiting page", logger.Params{"url": r.URL.String()})
 	})
 
-	// s.NavigateNextPage(target, reached)
+	s.crawler.OnHTML("table tr", func(e *colly.HTMLElement) {
+		href := e.ChildAttr("td:nth-child(1) a", "href")
+		if !strings.HasPrefix(href, "/address/") {
+			return
+		}
+		tags = append(tags, tag.Model{
+			Address:  strings.TrimPrefix(href, "/address/"),
+			Message:  "WalletExplorer wallet " + wallet.Name,
+			Nickname: wallet.Name,
+			Type:     wallet.Category,
+			Link:     wallet.Link,
+		})
+	})
 
-	if err = s.crawler.Visit(target); err != nil {
-		return
-	}
-	s.crawler.Wait()
+	s.crawler.OnResponse(func(r *colly.Response) {
+		if p, e := strconv.Atoi(r.Request.URL.Query().Get("page")); e == nil {
+			last = p
+		}
+		*budget--
+	})
+
+	s.NavigateNextPage(func() bool {
+		return !limited || *budget > 0
+	})
 
+	err = s.crawler.Visit(wallet.Link + "/addresses?page=" + strconv.Itoa(page))
 	return
 }
 
-// NavigateNextPage scrapes pages incrementally extracting next page url from pages bar
-func (s *Spider) NavigateNextPage(target string, reached bool) {
-	s.crawler.OnHTML("div.container", func(e *colly.HTMLElement) {
-		if reached {
-			return
-		}
-		e.ForEachWithBreak("nav ul", func(_ int, ce *colly.HTMLElement) bool {
-			var nth int
-			ce.ForEachWithBreak("li", func(index int, le *colly.HTMLElement) bool {
-				if le.Text == ">" {
-					nth = index
-					return false
-				}
+// NavigateNextPage scrapes pages incrementally following the next page link of the paging bar,
+// as long as more returns true
+func (s *Spider) NavigateNextPage(more func() bool) {
+	s.crawler.OnHTML("div.paging", func(e *colly.HTMLElement) {
+		e.ForEachWithBreak("a", func(_ int, ae *colly.HTMLElement) bool {
+			if !strings.HasPrefix(strings.TrimSpace(ae.Text), "Next") {
 				return true
-			})
-			e.Request.Visit(target + e.ChildAttr(fmt.Sprintf("li:nth-child(%d) a", nth+1), "href"))
+			}
+			if more() {
+				e.Request.Visit(ae.Attr("href"))
+			}
 			return false
 		})
 	})
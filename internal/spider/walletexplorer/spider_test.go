@@ -0,0 +1,117 @@
+package walletexplorer
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/xn3cr0nx/bitgodine/pkg/logger"
+)
+
+// pages served by the fixtures server, by path and page
+var pages = map[string]string{
+	"/":                                     "index.html",
+	"/wallet/Huobi.com/addresses?page=1":    "huobi_1.html",
+	"/wallet/Huobi.com/addresses?page=2":    "huobi_2.html",
+	"/wallet/SomePool.com/addresses?page=1": "somepool_1.html",
+}
+
+type TestSpiderSuite struct {
+	suite.Suite
+	server  *httptest.Server
+	visited []string
+}
+
+func (suite *TestSpiderSuite) SetupSuite() {
+	logger.Setup()
+	suite.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		suite.visited = append(suite.visited, r.URL.RequestURI())
+		page, ok := pages[r.URL.RequestURI()]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeFile(w, r, filepath.Join("testdata", page))
+	}))
+	viper.Set("spider.walletexplorer.url", suite.server.URL)
+}
+
+func (suite *TestSpiderSuite) TearDownSuite() {
+	suite.server.Close()
+}
+
+func (suite *TestSpiderSuite) SetupTest() {
+	suite.visited = nil
+	viper.Set("spider.walletexplorer.pages", 0)
+}
+
+func (suite *TestSpiderSuite) TestExtractWallets() {
+	s := NewSpider()
+	s.crawler = s.collector(context.Background(), true)
+	wallets, err := s.ExtractWallets(s.target)
+	require.Nil(suite.T(), err)
+	assert.Equal(suite.T(), []Wallet{
+		{Name: "Huobi.com", Category: "exchange", Link: suite.server.URL + "/wallet/Huobi.com"},
+		{Name: "SomePool.com", Category: "pool", Link: suite.server.URL + "/wallet/SomePool.com"},
+	}, wallets)
+}
+
+func (suite *TestSpiderSuite) TestSync() {
+	result, err := NewSpider().Sync(context.Background(), "")
+	require.Nil(suite.T(), err)
+
+	require.Len(suite.T(), result.Tags, 4)
+	huobi := result.Tags[0]
+	assert.Equal(suite.T(), "1HuobiAddressOne", huobi.Address)
+	assert.Equal(suite.T(), "Huobi.com", huobi.Nickname)
+	assert.Equal(suite.T(), "exchange", huobi.Type)
+	assert.Equal(suite.T(), suite.server.URL+"/wallet/Huobi.com", huobi.Link)
+	assert.Equal(suite.T(), "1HuobiAddressThree", result.Tags[2].Address)
+	pool := result.Tags[3]
+	assert.Equal(suite.T(), "3PoolAddress", pool.Address)
+	assert.Equal(suite.T(), "pool", pool.Type)
+	assert.JSONEq(suite.T(), `{"Huobi.com": 2, "SomePool.com": 1}`, result.Cursor)
+}
+
+func (suite *TestSpiderSuite) TestSyncResumes() {
+	result, err := NewSpider().Sync(context.Background(), `{"Huobi.com": 2, "SomePool.com": 1}`)
+	require.Nil(suite.T(), err)
+
+	assert.NotContains(suite.T(), suite.visited, "/wallet/Huobi.com/addresses?page=1")
+	require.Len(suite.T(), result.Tags, 2)
+	assert.Equal(suite.T(), "1HuobiAddressThree", result.Tags[0].Address)
+	assert.JSONEq(suite.T(), `{"Huobi.com": 2, "SomePool.com": 1}`, result.Cursor)
+}
+
+func (suite *TestSpiderSuite) TestSyncPagesLimit() {
+	viper.Set("spider.walletexplorer.pages", 1)
+	result, err := NewSpider().Sync(context.Background(), "")
+	require.Nil(suite.T(), err)
+
+	require.Len(suite.T(), result.Tags, 2)
+	assert.JSONEq(suite.T(), `{"Huobi.com": 1}`, result.Cursor)
+	assert.Equal(suite.T(), []string{"/", "/wallet/Huobi.com/addresses?page=1"}, suite.visited)
+
+	result, err = NewSpider().Sync(context.Background(), result.Cursor)
+	require.Nil(suite.T(), err)
+	assert.JSONEq(suite.T(), `{"Huobi.com": 1}`, result.Cursor)
+}
+
+func (suite *TestSpiderSuite) TestSyncCanceled() {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := NewSpider().Sync(ctx, "")
+	assert.Equal(suite.T(), context.Canceled, err)
+	assert.Empty(suite.T(), suite.visited)
+}
+
+func TestSpider(t *testing.T) {
+	suite.Run(t, new(TestSpiderSuite))
+}
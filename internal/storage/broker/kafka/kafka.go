@@ -13,20 +13,26 @@ type Kafka struct {
 	*kafka.Reader
 }
 
+// Partition the partition of the topic messages are written to and read from, so that the reader
+// receives all of them in the order they are written
+const Partition = 0
+
 // NewKafka initialize kafka broker
 func NewKafka(kafkaBrokerUrls []string, topic string) (*Kafka, error) {
 	w := &kafka.Writer{
-		Addr:        kafka.TCP(kafkaBrokerUrls...),
-		Topic:       topic,
-		Balancer:    &kafka.LeastBytes{},
+		Addr:  kafka.TCP(kafkaBrokerUrls...),
+		Topic: topic,
+		Balancer: kafka.BalancerFunc(func(kafka.Message, ...int) int {
+			return Partition
+		}),
 		Compression: kafka.Snappy,
 	}
 
 	r := kafka.NewReader(kafka.ReaderConfig{
 		Brokers:   kafkaBrokerUrls,
 		Topic:     topic,
-		Partition: 0,
-		MinBytes:  10e3, // 10KB
+		Partition: Partition,
+		MinBytes:  1,    // messages are returned as soon as available
 		MaxBytes:  10e6, // 10MB
 	})
 
@@ -2,9 +2,11 @@ package badger
 
 import (
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path"
+	"time"
 
 	"github.com/dgraph-io/badger/v3"
 	"github.com/imdario/mergo"
@@ -57,9 +59,19 @@ func (b *Badger) Store(key string, value []byte) error {
 	})
 }
 
+// StoreWithTTL insert new key-value in badger, expiring after ttl
+func (b *Badger) StoreWithTTL(key string, value []byte, ttl time.Duration) error {
+	return b.Update(func(txn *badger.Txn) error {
+		return txn.SetEntry(badger.NewEntry([]byte(key), value).WithTTL(ttl))
+	})
+}
+
 // StoreBatch insert new key-value in badger
 func (b *Badger) StoreBatch(batch interface{}) (err error) {
-	series := batch.(map[string][]byte)
+	series, ok := batch.(map[string][]byte)
+	if !ok {
+		return fmt.Errorf("%w: batch type is not allowed", errorx.ErrInvalidArgument)
+	}
 	wb := b.NewWriteBatch()
 	defer wb.Cancel()
 
@@ -79,7 +91,10 @@ var counter int
 
 // StoreQueueBatch loads a queue until a threshold to perform a bulk insertion
 func (b *Badger) StoreQueueBatch(v interface{}) (err error) {
-	series := v.(map[string][]byte)
+	series, ok := v.(map[string][]byte)
+	if !ok {
+		return fmt.Errorf("%w: batch type is not allowed", errorx.ErrInvalidArgument)
+	}
 	if queue == nil {
 		queue = make(map[string][]byte, 0)
 	}
@@ -97,6 +112,19 @@ func (b *Badger) StoreQueueBatch(v interface{}) (err error) {
 	return
 }
 
+// Flush stores the queued entries
+func (b *Badger) Flush() (err error) {
+	if len(queue) == 0 {
+		return
+	}
+	if err = b.StoreBatch(queue); err != nil {
+		return
+	}
+	queue = make(map[string][]byte, 0)
+	counter = 0
+	return
+}
+
 // Read extract required value by key
 func (b *Badger) Read(key string) (value []byte, err error) {
 	err = b.View(func(txn *badger.Txn) error {
@@ -147,7 +175,7 @@ func (b *Badger) ReadKeyValues() (value map[string][]byte, err error) {
 		for it.Rewind(); it.Valid(); it.Next() {
 			item := it.Item()
 			err := item.Value(func(val []byte) error {
-				value[string(item.Key())] = val
+				value[string(item.Key())] = append([]byte{}, val...)
 				return nil
 			})
 			if err != nil {
@@ -188,7 +216,7 @@ func (b *Badger) ReadPrefix(prefix string) (value [][]byte, err error) {
 			// k := item.Key()
 			err := item.Value(func(v []byte) error {
 				// fmt.Printf("key=%s, value=%s, %v\n", k, v, v)
-				value = append(value, v)
+				value = append(value, append([]byte{}, v...))
 				return nil
 			})
 			if err != nil {
@@ -212,7 +240,7 @@ func (b *Badger) ReadFirstValueByPrefix(prefix string) (value []byte, err error)
 			// k := item.Key()
 			err := item.Value(func(v []byte) error {
 				// fmt.Printf("key=%s, value=%s, %v\n", k, v, v)
-				value = v
+				value = append([]byte{}, v...)
 				return nil
 			})
 			if err != nil {
@@ -238,7 +266,7 @@ func (b *Badger) ReadPrefixWithKey(prefix string) (value map[string][]byte, err
 			k := item.Key()
 			err := item.Value(func(v []byte) error {
 				// fmt.Printf("key=%s, value=%s, %v\n", k, v, v)
-				value[string(k)] = v
+				value[string(k)] = append([]byte{}, v...)
 				return nil
 			})
 			if err != nil {
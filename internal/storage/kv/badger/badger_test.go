@@ -1,19 +1,21 @@
 package badger_test
 
 import (
-	"encoding/json"
-	"errors"
+	"io/ioutil"
+	"os"
 	"strings"
 
-	"github.com/google/uuid"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"github.com/spf13/viper"
-	"github.com/xn3cr0nx/bitgodine/internal/errorx"
+	"github.com/xn3cr0nx/bitgodine/internal/storage/kv"
 	"github.com/xn3cr0nx/bitgodine/internal/storage/kv/badger"
+	"github.com/xn3cr0nx/bitgodine/internal/storage/kv/kvtest"
 	"github.com/xn3cr0nx/bitgodine/pkg/logger"
 )
 
+var _ kv.DB = (*badger.Badger)(nil)
+
 var _ = Describe("Badger", func() {
 	var dir string
 
@@ -21,6 +23,17 @@ var _ = Describe("Badger", func() {
 		logger.Setup()
 	})
 
+	kvtest.Specs(func() (kv.DB, func()) {
+		dir, err := ioutil.TempDir("", "badger")
+		Expect(err).ToNot(HaveOccurred())
+		db, err := badger.NewBadger(badger.Conf(dir), false)
+		Expect(err).ToNot(HaveOccurred())
+		return db, func() {
+			db.Close()
+			os.RemoveAll(dir)
+		}
+	})
+
 	Context("when creating a new instance", func() {
 		BeforeEach(func() {
 			dir = "./test"
@@ -78,193 +91,4 @@ var _ = Describe("Badger", func() {
 			})
 		})
 	})
-
-	Context("when inserting new elements in the db", func() {
-		var (
-			db *badger.Badger
-		)
-
-		BeforeEach(func() {
-			dir = "./test"
-			var err error
-			db, err = badger.NewBadger(badger.Conf(dir), false)
-			Expect(err).ToNot(HaveOccurred())
-		})
-
-		AfterEach(func() {
-			err := db.Empty()
-			Expect(err).ToNot(HaveOccurred())
-		})
-
-		It("a generic key value element is correctly inserted", func() {
-			key := "test"
-			value := "insert"
-			err := db.Store(key, []byte(value))
-			Expect(err).ToNot(HaveOccurred())
-		})
-
-		It("a key value element with uuid key and json encoded value is correctly inserted", func() {
-			key := uuid.New()
-			value :=
-				struct {
-					Counter int
-					Type    string
-				}{10, "test"}
-
-			encoded, err := json.Marshal(&value)
-			Expect(err).ToNot(HaveOccurred())
-			err = db.Store(key.String(), []byte(encoded))
-			Expect(err).ToNot(HaveOccurred())
-		})
-
-		It("a batch map of key string and value []byte elements are correctly inserted", func() {
-			batch := make(map[string][]byte)
-			batch["test"] = []byte("insert")
-
-			value :=
-				struct {
-					Counter int
-					Type    string
-				}{10, "test"}
-			encoded, err := json.Marshal(&value)
-			Expect(err).ToNot(HaveOccurred())
-
-			batch[uuid.New().String()] = encoded
-			batch[uuid.New().String()] = encoded
-
-			err = db.StoreBatch(batch)
-			Expect(err).ToNot(HaveOccurred())
-		})
-
-		It("a batch map of string int key value elements is not inserted and an error is thrown", func() {
-			batch := make(map[string]int)
-			batch[uuid.New().String()] = 12
-
-			err := db.StoreBatch(batch)
-			Expect(err).To(HaveOccurred())
-			Expect(strings.Contains(err.Error(), "type is not allowed")).To(BeTrue())
-		})
-	})
-
-	Context("when reading from the db", func() {
-		var (
-			db            *badger.Badger
-			UUID, element string
-		)
-
-		BeforeEach(func() {
-			dir = "./test"
-			var err error
-			db, err = badger.NewBadger(badger.Conf(dir), false)
-			Expect(err).ToNot(HaveOccurred())
-
-			UUID = uuid.New().String()
-			element = "test"
-			err = db.Store(UUID, []byte(element))
-			Expect(err).ToNot(HaveOccurred())
-		})
-
-		AfterEach(func() {
-			err := db.Empty()
-			Expect(err).ToNot(HaveOccurred())
-		})
-
-		It("an existing element is correctly retrieved by uuid key", func() {
-			e, err := db.Read(UUID)
-			Expect(err).ToNot(HaveOccurred())
-			Expect(string(e)).To(Equal(element))
-		})
-
-		It("a not existing element is not retrieved by key", func() {
-			_, err := db.Read(uuid.New().String())
-			Expect(err).To(HaveOccurred())
-			Expect(errors.Is(err, errorx.ErrKeyNotFound)).To(BeTrue())
-		})
-
-		It("db keys are correctly retrieve", func() {
-			keys, err := db.ReadKeys()
-			Expect(err).ToNot(HaveOccurred())
-			Expect(keys).To(HaveLen(1))
-			Expect(keys).Should(ConsistOf([]string{UUID}))
-		})
-
-		It("db keys and values are correctly retrieve", func() {
-			keys, err := db.ReadKeyValues()
-			Expect(err).ToNot(HaveOccurred())
-			Expect(keys).To(HaveLen(1))
-			Expect(keys).Should(HaveKeyWithValue(UUID, []byte(element)))
-		})
-
-	})
-
-	Context("when updating in the db", func() {
-		var (
-			db            *badger.Badger
-			UUID, element string
-		)
-
-		BeforeEach(func() {
-			dir = "./test"
-			var err error
-			db, err = badger.NewBadger(badger.Conf(dir), false)
-			Expect(err).ToNot(HaveOccurred())
-
-			UUID = uuid.New().String()
-			element = "test"
-			err = db.Store(UUID, []byte(element))
-			Expect(err).ToNot(HaveOccurred())
-		})
-
-		AfterEach(func() {
-			err := db.Empty()
-			Expect(err).ToNot(HaveOccurred())
-		})
-
-		It("an element is correctly updated", func() {
-			update := "updated"
-			err := db.Store(UUID, []byte(element+update))
-			Expect(err).ToNot(HaveOccurred())
-			updated, err := db.Read(UUID)
-			Expect(err).ToNot(HaveOccurred())
-			Expect(string(updated)).To(Equal(element + update))
-		})
-	})
-
-	Context("when deleting from the db", func() {
-		var (
-			db            *badger.Badger
-			UUID, element string
-		)
-
-		BeforeEach(func() {
-			dir = "./test"
-			var err error
-			db, err = badger.NewBadger(badger.Conf(dir), false)
-			Expect(err).ToNot(HaveOccurred())
-
-			UUID = uuid.New().String()
-			element = "test"
-			err = db.Store(UUID, []byte(element))
-			Expect(err).ToNot(HaveOccurred())
-		})
-
-		AfterEach(func() {
-			err := db.Empty()
-			Expect(err).ToNot(HaveOccurred())
-		})
-
-		It("an element is correctly delete", func() {
-			err := db.Delete(UUID)
-			Expect(err).ToNot(HaveOccurred())
-			_, err = db.Read(UUID)
-			Expect(err).To(HaveOccurred())
-			Expect(errors.Is(err, errorx.ErrKeyNotFound)).To(BeTrue())
-		})
-
-		It("using a not existing key is just a NOP", func() {
-			err := db.Delete(uuid.New().String())
-			Expect(err).ToNot(HaveOccurred())
-		})
-	})
-
 })
@@ -0,0 +1,74 @@
+package badger
+
+import (
+	"bytes"
+
+	"github.com/dgraph-io/badger/v3"
+
+	"github.com/xn3cr0nx/bitgodine/internal/storage/kv/iterator"
+)
+
+// badgerIterator iterates keys within a read only transaction, kept open until the iterator is closed
+type badgerIterator struct {
+	opts   *iterator.Options
+	txn    *badger.Txn
+	it     *badger.Iterator
+	prefix []byte
+	count  int
+	key    string
+	value  []byte
+	err    error
+}
+
+// Iterate returns an iterator over the keys matching the prefix
+func (b *Badger) Iterate(opts *iterator.Options) iterator.Iterator {
+	txn := b.NewTransaction(false)
+	o := badger.DefaultIteratorOptions
+	o.PrefetchValues = !opts.KeysOnly
+	o.Reverse = opts.Reverse
+	o.Prefix = []byte(opts.Prefix)
+	it := txn.NewIterator(o)
+	it.Seek([]byte(opts.Seek()))
+	return &badgerIterator{opts: opts, txn: txn, it: it, prefix: []byte(opts.Prefix)}
+}
+
+func (i *badgerIterator) Next() bool {
+	if i.err != nil || i.opts.Done(i.count) {
+		return false
+	}
+	for ; i.it.Valid() && bytes.HasPrefix(i.it.Item().Key(), i.prefix); i.it.Next() {
+		item := i.it.Item()
+		key := string(item.Key())
+		if i.opts.Skip(key) {
+			continue
+		}
+		i.key, i.value = key, nil
+		if !i.opts.KeysOnly {
+			if i.value, i.err = item.ValueCopy(nil); i.err != nil {
+				return false
+			}
+		}
+		i.count++
+		i.it.Next()
+		return true
+	}
+	return false
+}
+
+func (i *badgerIterator) Key() string {
+	return i.key
+}
+
+func (i *badgerIterator) Value() []byte {
+	return i.value
+}
+
+func (i *badgerIterator) Err() error {
+	return i.err
+}
+
+func (i *badgerIterator) Close() error {
+	i.it.Close()
+	i.txn.Discard()
+	return nil
+}
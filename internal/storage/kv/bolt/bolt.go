@@ -0,0 +1,306 @@
+package bolt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/imdario/mergo"
+	"github.com/spf13/viper"
+	"go.etcd.io/bbolt"
+
+	"github.com/xn3cr0nx/bitgodine/internal/errorx"
+)
+
+// bucket all the keys are stored in, keeping the flat key space of the other stores
+var bucket = []byte("kv")
+
+// expiries bucket of the keys stored with a ttl, sorted by deadline
+var expiries = []byte("expiries")
+
+// queueSize number of batches queued before a bulk insertion
+const queueSize = 100
+
+// Bolt client wrapper
+type Bolt struct {
+	*bbolt.DB
+
+	mu      sync.Mutex
+	queue   map[string][]byte
+	counter int
+}
+
+// Config strcut containing initialization fields
+type Config struct {
+	File string
+}
+
+// Conf returnes default config struct
+func Conf(path string) *Config {
+	file := viper.GetString("bolt")
+	if path != "" {
+		file = path
+	}
+
+	return &Config{
+		File: file,
+	}
+}
+
+// NewBolt creates a new instance of the db
+func NewBolt(conf *Config, readonly bool) (*Bolt, error) {
+	if !readonly {
+		if err := os.MkdirAll(filepath.Dir(conf.File), 0700); err != nil {
+			return nil, err
+		}
+	}
+	db, err := bbolt.Open(conf.File, 0600, &bbolt.Options{
+		Timeout:      time.Second,
+		ReadOnly:     readonly,
+		FreelistType: bbolt.FreelistMapType,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !readonly {
+		err = db.Update(func(tx *bbolt.Tx) error {
+			_, err := tx.CreateBucketIfNotExists(bucket)
+			return err
+		})
+		if err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	return &Bolt{DB: db}, nil
+}
+
+// Store insert new key-value in bolt
+func (b *Bolt) Store(key string, value []byte) error {
+	return b.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bucket).Put([]byte(key), value)
+	})
+}
+
+// StoreWithTTL insert new key-value in bolt, expiring after ttl. Bolt has no native expiration,
+// the keys whose deadline is passed are deleted by the following StoreWithTTL
+func (b *Bolt) StoreWithTTL(key string, value []byte, ttl time.Duration) error {
+	return b.Update(func(tx *bbolt.Tx) error {
+		exp, err := tx.CreateBucketIfNotExists(expiries)
+		if err != nil {
+			return err
+		}
+		bkt := tx.Bucket(bucket)
+
+		now := time.Now()
+		c := exp.Cursor()
+		for k, _ := c.First(); k != nil && int64(binary.BigEndian.Uint64(k[:8])) <= now.UnixNano(); k, _ = c.First() {
+			if err := bkt.Delete(k[8:]); err != nil {
+				return err
+			}
+			if err := exp.Delete(k); err != nil {
+				return err
+			}
+		}
+
+		// deadline prefixed keys sort by expiration
+		deadline := make([]byte, 8, 8+len(key))
+		binary.BigEndian.PutUint64(deadline, uint64(now.Add(ttl).UnixNano()))
+		if err := exp.Put(append(deadline, key...), nil); err != nil {
+			return err
+		}
+		return bkt.Put([]byte(key), value)
+	})
+}
+
+// StoreBatch insert new key-value in bolt in a single transaction
+func (b *Bolt) StoreBatch(batch interface{}) (err error) {
+	series, ok := batch.(map[string][]byte)
+	if !ok {
+		return fmt.Errorf("%w: batch type is not allowed", errorx.ErrInvalidArgument)
+	}
+	// sorted keys are appended to the same pages instead of splitting them
+	keys := make([]string, 0, len(series))
+	for k := range series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return b.Update(func(tx *bbolt.Tx) error {
+		bkt := tx.Bucket(bucket)
+		for _, k := range keys {
+			if err := bkt.Put([]byte(k), series[k]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// StoreQueueBatch loads a queue until a threshold to perform a bulk insertion
+func (b *Bolt) StoreQueueBatch(v interface{}) (err error) {
+	series, ok := v.(map[string][]byte)
+	if !ok {
+		return fmt.Errorf("%w: batch type is not allowed", errorx.ErrInvalidArgument)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.queue == nil {
+		b.queue = make(map[string][]byte)
+	}
+	if err = mergo.Merge(&b.queue, series, mergo.WithOverride); err != nil {
+		return
+	}
+	if b.counter >= queueSize {
+		if err = b.StoreBatch(b.queue); err != nil {
+			return
+		}
+		b.queue = make(map[string][]byte)
+		b.counter = 0
+	}
+	b.counter++
+	return
+}
+
+// Flush stores the queued entries
+func (b *Bolt) Flush() (err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.queue) == 0 {
+		return
+	}
+	if err = b.StoreBatch(b.queue); err != nil {
+		return
+	}
+	b.queue = make(map[string][]byte)
+	b.counter = 0
+	return
+}
+
+// Read extract required value by key
+func (b *Bolt) Read(key string) (value []byte, err error) {
+	err = b.View(func(tx *bbolt.Tx) error {
+		v := tx.Bucket(bucket).Get([]byte(key))
+		if v == nil {
+			return errorx.ErrKeyNotFound
+		}
+		// values are valid only for the life of the transaction
+		value = append([]byte{}, v...)
+		return nil
+	})
+	return
+}
+
+// scan calls fn for each key value matching the prefix, until fn returns false
+func (b *Bolt) scan(prefix string, fn func(k, v []byte) bool) error {
+	return b.View(func(tx *bbolt.Tx) error {
+		c := tx.Bucket(bucket).Cursor()
+		p := []byte(prefix)
+		for k, v := c.Seek(p); k != nil && bytes.HasPrefix(k, p); k, v = c.Next() {
+			if !fn(k, v) {
+				break
+			}
+		}
+		return nil
+	})
+}
+
+// ReadKeys returns all the keys
+func (b *Bolt) ReadKeys() (keys []string, err error) {
+	return b.ReadKeysWithPrefix("")
+}
+
+// ReadKeyValues returns all the keys and values
+func (b *Bolt) ReadKeyValues() (value map[string][]byte, err error) {
+	return b.ReadPrefixWithKey("")
+}
+
+// ReadKeysWithPrefix returns the keys matching the prefix
+func (b *Bolt) ReadKeysWithPrefix(prefix string) (keys []string, err error) {
+	err = b.scan(prefix, func(k, v []byte) bool {
+		keys = append(keys, string(k))
+		return true
+	})
+	return
+}
+
+// ReadPrefix returns the values of the keys matching the prefix
+func (b *Bolt) ReadPrefix(prefix string) (value [][]byte, err error) {
+	err = b.scan(prefix, func(k, v []byte) bool {
+		value = append(value, append([]byte{}, v...))
+		return true
+	})
+	return
+}
+
+// ReadFirstValueByPrefix returns the first value matched by prefix
+func (b *Bolt) ReadFirstValueByPrefix(prefix string) (value []byte, err error) {
+	err = b.scan(prefix, func(k, v []byte) bool {
+		value = append([]byte{}, v...)
+		return false
+	})
+	return
+}
+
+// ReadPrefixWithKey returns the keys and values matching the prefix
+func (b *Bolt) ReadPrefixWithKey(prefix string) (value map[string][]byte, err error) {
+	value = make(map[string][]byte)
+	err = b.scan(prefix, func(k, v []byte) bool {
+		value[string(k)] = append([]byte{}, v...)
+		return true
+	})
+	return
+}
+
+// IsStored returns true if the key is stored in db
+func (b *Bolt) IsStored(key string) bool {
+	stored := false
+	b.View(func(tx *bbolt.Tx) error {
+		stored = tx.Bucket(bucket).Get([]byte(key)) != nil
+		return nil
+	})
+	return stored
+}
+
+// Delete removes the key from the db, missing keys are ignored
+func (b *Bolt) Delete(key string) (err error) {
+	err = b.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bucket).Delete([]byte(key))
+	})
+	return
+}
+
+// Empty removes all the keys from the db
+func (b *Bolt) Empty() (err error) {
+	err = b.Update(func(tx *bbolt.Tx) error {
+		if err := tx.DeleteBucket(bucket); err != nil {
+			return err
+		}
+		if err := tx.DeleteBucket(expiries); err != nil && err != bbolt.ErrBucketNotFound {
+			return err
+		}
+		_, err := tx.CreateBucket(bucket)
+		return err
+	})
+	return
+}
+
+// Close flushes the queued batches and closes the db
+func (b *Bolt) Close() (err error) {
+	b.mu.Lock()
+	if len(b.queue) > 0 && !b.IsReadOnly() {
+		err = b.StoreBatch(b.queue)
+		b.queue = nil
+		b.counter = 0
+	}
+	b.mu.Unlock()
+	if e := b.DB.Close(); err == nil {
+		err = e
+	}
+	return
+}
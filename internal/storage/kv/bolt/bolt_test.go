@@ -0,0 +1,91 @@
+package bolt_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/xn3cr0nx/bitgodine/internal/storage/kv"
+	"github.com/xn3cr0nx/bitgodine/internal/storage/kv/bolt"
+	"github.com/xn3cr0nx/bitgodine/internal/storage/kv/kvtest"
+)
+
+var _ kv.DB = (*bolt.Bolt)(nil)
+
+var _ = Describe("Bolt", func() {
+	kvtest.Specs(func() (kv.DB, func()) {
+		dir, err := ioutil.TempDir("", "bolt")
+		Expect(err).ToNot(HaveOccurred())
+		db, err := bolt.NewBolt(bolt.Conf(filepath.Join(dir, "data", "test.db")), false)
+		Expect(err).ToNot(HaveOccurred())
+		return db, func() {
+			db.Close()
+			os.RemoveAll(dir)
+		}
+	})
+
+	Context("when using the db file", func() {
+		var (
+			dir string
+			db  *bolt.Bolt
+		)
+
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "bolt")
+			Expect(err).ToNot(HaveOccurred())
+			db, err = bolt.NewBolt(bolt.Conf(filepath.Join(dir, "data", "test.db")), false)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			db.Close()
+			os.RemoveAll(dir)
+		})
+
+		It("the db file is correctly initialized", func() {
+			Expect(filepath.Join(dir, "data", "test.db")).Should(BeARegularFile())
+		})
+
+		It("a second instance on the same file times out", func() {
+			_, err := bolt.NewBolt(bolt.Conf(filepath.Join(dir, "data", "test.db")), false)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("queued batches are stored on close", func() {
+			for i := 0; i < 50; i++ {
+				Expect(db.StoreQueueBatch(map[string][]byte{fmt.Sprintf("queue_%03d", i): []byte("v")})).To(Succeed())
+			}
+			Expect(db.Close()).To(Succeed())
+			var err error
+			db, err = bolt.NewBolt(bolt.Conf(filepath.Join(dir, "data", "test.db")), false)
+			Expect(err).ToNot(HaveOccurred())
+			keys, err := db.ReadKeysWithPrefix("queue_")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(keys).To(HaveLen(50))
+		})
+
+		It("the expired elements are deleted by the following insertion with a ttl", func() {
+			Expect(db.StoreWithTTL("expiring", []byte("a"), -time.Second)).To(Succeed())
+			Expect(db.StoreWithTTL("lasting", []byte("b"), time.Hour)).To(Succeed())
+			Expect(db.IsStored("expiring")).To(BeFalse())
+			Expect(db.IsStored("lasting")).To(BeTrue())
+		})
+
+		It("the db is correctly emptied", func() {
+			Expect(db.Store("test", []byte("v"))).To(Succeed())
+			Expect(db.StoreWithTTL("lasting", []byte("b"), time.Hour)).To(Succeed())
+			Expect(db.Empty()).To(Succeed())
+			keys, err := db.ReadKeys()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(keys).To(BeEmpty())
+
+			Expect(db.StoreWithTTL("lasting", []byte("b"), time.Hour)).To(Succeed())
+			Expect(db.IsStored("lasting")).To(BeTrue())
+		})
+	})
+})
@@ -0,0 +1,91 @@
+package bolt
+
+import (
+	"bytes"
+
+	"go.etcd.io/bbolt"
+
+	"github.com/xn3cr0nx/bitgodine/internal/storage/kv/iterator"
+)
+
+// boltIterator iterates keys within a read only transaction, kept open until the iterator is closed.
+// Long lived read transactions prevent bolt from growing the file, iterators should be closed soon
+type boltIterator struct {
+	opts   *iterator.Options
+	tx     *bbolt.Tx
+	c      *bbolt.Cursor
+	prefix []byte
+	count  int
+	k, v   []byte
+	key    string
+	value  []byte
+	err    error
+}
+
+// Iterate returns an iterator over the keys matching the prefix
+func (b *Bolt) Iterate(opts *iterator.Options) iterator.Iterator {
+	i := &boltIterator{opts: opts, prefix: []byte(opts.Prefix)}
+	if i.tx, i.err = b.Begin(false); i.err != nil {
+		return i
+	}
+	i.c = i.tx.Bucket(bucket).Cursor()
+	seek := []byte(opts.Seek())
+	i.k, i.v = i.c.Seek(seek)
+	if opts.Reverse {
+		// seek positions on the first key after, reversed iteration starts from the one before
+		if i.k == nil {
+			i.k, i.v = i.c.Last()
+		} else if bytes.Compare(i.k, seek) > 0 {
+			i.k, i.v = i.c.Prev()
+		}
+	}
+	return i
+}
+
+func (i *boltIterator) advance() {
+	if i.opts.Reverse {
+		i.k, i.v = i.c.Prev()
+	} else {
+		i.k, i.v = i.c.Next()
+	}
+}
+
+func (i *boltIterator) Next() bool {
+	if i.err != nil || i.tx == nil || i.opts.Done(i.count) {
+		return false
+	}
+	for ; i.k != nil && bytes.HasPrefix(i.k, i.prefix); i.advance() {
+		key := string(i.k)
+		if i.opts.Skip(key) {
+			continue
+		}
+		i.key, i.value = key, nil
+		if !i.opts.KeysOnly {
+			// values are valid only for the life of the transaction
+			i.value = append([]byte{}, i.v...)
+		}
+		i.count++
+		i.advance()
+		return true
+	}
+	return false
+}
+
+func (i *boltIterator) Key() string {
+	return i.key
+}
+
+func (i *boltIterator) Value() []byte {
+	return i.value
+}
+
+func (i *boltIterator) Err() error {
+	return i.err
+}
+
+func (i *boltIterator) Close() error {
+	if i.tx == nil {
+		return nil
+	}
+	return i.tx.Rollback()
+}
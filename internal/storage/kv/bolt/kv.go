@@ -0,0 +1,16 @@
+package bolt
+
+import (
+	"github.com/xn3cr0nx/bitgodine/pkg/cache"
+)
+
+// KV instance of key value store designed to treat block structs
+type KV struct {
+	*Bolt
+	cache *cache.Cache
+}
+
+// NewKV creates a new instance of KV
+func NewKV(b *Bolt, c *cache.Cache) (*KV, error) {
+	return &KV{b, c}, nil
+}
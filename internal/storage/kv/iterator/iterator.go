@@ -0,0 +1,145 @@
+package iterator
+
+import (
+	"sort"
+	"strings"
+)
+
+// Options of a prefix iteration. Keys are returned in lexicographic order, or reversed.
+// Start positions the iteration at the first key equal or after it (before it when reversed),
+// StartAfter resumes a previous iteration excluding the last seen key. Limit zero means unlimited
+type Options struct {
+	Prefix     string
+	Start      string
+	StartAfter string
+	Reverse    bool
+	Limit      int
+	KeysOnly   bool
+}
+
+// Iterator cursor over the keys matching a prefix. Next must be called before reading
+// the first key, and Close must be called to release the underlying resources
+//
+//	it := db.Iterate(&iterator.Options{Prefix: "prefix"})
+//	defer it.Close()
+//	for it.Next() {
+//		key, value := it.Key(), it.Value()
+//	}
+//	if err := it.Err(); err != nil {
+//	}
+type Iterator interface {
+	Next() bool
+	Key() string
+	Value() []byte
+	Err() error
+	Close() error
+}
+
+// Seek returns the key the iteration starts from, matching the prefix
+func (o *Options) Seek() string {
+	seek := o.Prefix
+	if o.Start != "" {
+		seek = o.Start
+	}
+	if o.StartAfter != "" {
+		seek = o.StartAfter
+	}
+	// clamp to the range of the keys with the prefix, the last is followed by prefix+0xff
+	if !o.Reverse && seek < o.Prefix {
+		seek = o.Prefix
+	}
+	if o.Reverse && (seek == o.Prefix || seek > o.Prefix+"\xff") {
+		seek = o.Prefix + "\xff"
+	}
+	return seek
+}
+
+// Skip returns true if the key must be skipped, only the one iteration starts after
+func (o *Options) Skip(key string) bool {
+	return o.StartAfter != "" && key == o.StartAfter
+}
+
+// Done returns true if the limit has been reached after count keys
+func (o *Options) Done(count int) bool {
+	return o.Limit > 0 && count >= o.Limit
+}
+
+// Sort sorts the keys matching the prefix according to the options and drops the ones
+// before the start, for stores not keeping keys ordered
+func (o *Options) Sort(keys []string) []string {
+	var filtered []string
+	for _, k := range keys {
+		if strings.HasPrefix(k, o.Prefix) {
+			filtered = append(filtered, k)
+		}
+	}
+	sort.Strings(filtered)
+	if o.Reverse {
+		for i, j := 0, len(filtered)-1; i < j; i, j = i+1, j-1 {
+			filtered[i], filtered[j] = filtered[j], filtered[i]
+		}
+	}
+
+	seek := o.Seek()
+	start := sort.Search(len(filtered), func(i int) bool {
+		if o.Reverse {
+			return filtered[i] <= seek
+		}
+		return filtered[i] >= seek
+	})
+	filtered = filtered[start:]
+	if len(filtered) > 0 && o.Skip(filtered[0]) {
+		filtered = filtered[1:]
+	}
+	if o.Limit > 0 && len(filtered) > o.Limit {
+		filtered = filtered[:o.Limit]
+	}
+	return filtered
+}
+
+// mapIterator iterator over an in memory map
+type mapIterator struct {
+	keys   []string
+	values map[string][]byte
+	index  int
+}
+
+// FromMap returns an iterator over the map, applying the options
+func FromMap(values map[string][]byte, opts *Options) Iterator {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	return &mapIterator{keys: opts.Sort(keys), values: values, index: -1}
+}
+
+func (m *mapIterator) Next() bool {
+	m.index++
+	return m.index < len(m.keys)
+}
+
+func (m *mapIterator) Key() string {
+	return m.keys[m.index]
+}
+
+func (m *mapIterator) Value() []byte {
+	return m.values[m.keys[m.index]]
+}
+
+func (m *mapIterator) Err() error {
+	return nil
+}
+
+func (m *mapIterator) Close() error {
+	return nil
+}
+
+// Keys reads all the keys of the iterator and closes it
+func Keys(it Iterator) (keys []string, err error) {
+	defer it.Close()
+	for it.Next() {
+		keys = append(keys, it.Key())
+	}
+	err = it.Err()
+	return
+}
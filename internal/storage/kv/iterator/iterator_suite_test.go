@@ -0,0 +1,13 @@
+package iterator_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestIterator(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Iterator Suite")
+}
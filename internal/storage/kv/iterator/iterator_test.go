@@ -0,0 +1,62 @@
+package iterator_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/xn3cr0nx/bitgodine/internal/storage/kv/iterator"
+)
+
+var _ = Describe("Iterator", func() {
+	values := map[string][]byte{
+		"a_1": []byte("1"),
+		"a_2": []byte("2"),
+		"a_3": []byte("3"),
+		"b_1": []byte("4"),
+	}
+
+	Context("when sorting keys", func() {
+		keys := []string{"b_1", "a_3", "a_1", "a_2"}
+
+		It("keys out of the prefix are dropped", func() {
+			Expect((&iterator.Options{Prefix: "a_"}).Sort(keys)).To(Equal([]string{"a_1", "a_2", "a_3"}))
+		})
+
+		It("keys are reversed", func() {
+			Expect((&iterator.Options{Prefix: "a_", Reverse: true}).Sort(keys)).To(Equal([]string{"a_3", "a_2", "a_1"}))
+		})
+
+		It("keys start from the start key", func() {
+			Expect((&iterator.Options{Prefix: "a_", Start: "a_2"}).Sort(keys)).To(Equal([]string{"a_2", "a_3"}))
+			Expect((&iterator.Options{Prefix: "a_", Start: "a_2", Reverse: true}).Sort(keys)).To(Equal([]string{"a_2", "a_1"}))
+		})
+
+		It("keys start after the last seen key", func() {
+			Expect((&iterator.Options{Prefix: "a_", StartAfter: "a_2"}).Sort(keys)).To(Equal([]string{"a_3"}))
+			Expect((&iterator.Options{Prefix: "a_", StartAfter: "a_2", Reverse: true}).Sort(keys)).To(Equal([]string{"a_1"}))
+		})
+
+		It("keys are limited", func() {
+			Expect((&iterator.Options{Prefix: "a_", Limit: 2}).Sort(keys)).To(Equal([]string{"a_1", "a_2"}))
+		})
+	})
+
+	Context("when iterating over a map", func() {
+		It("keys and values are returned", func() {
+			it := iterator.FromMap(values, &iterator.Options{Prefix: "a_", StartAfter: "a_1"})
+			defer it.Close()
+			Expect(it.Next()).To(BeTrue())
+			Expect(it.Key()).To(Equal("a_2"))
+			Expect(it.Value()).To(Equal([]byte("2")))
+			Expect(it.Next()).To(BeTrue())
+			Expect(it.Key()).To(Equal("a_3"))
+			Expect(it.Next()).To(BeFalse())
+			Expect(it.Err()).ToNot(HaveOccurred())
+		})
+
+		It("all the keys are read", func() {
+			keys, err := iterator.Keys(iterator.FromMap(values, &iterator.Options{}))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(keys).To(Equal([]string{"a_1", "a_2", "a_3", "b_1"}))
+		})
+	})
+})
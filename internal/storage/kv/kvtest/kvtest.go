@@ -0,0 +1,246 @@
+// Package kvtest holds the specs every kv.DB backend has to pass, run by the test suite of each backend
+package kvtest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/xn3cr0nx/bitgodine/internal/errorx"
+	"github.com/xn3cr0nx/bitgodine/internal/storage/kv"
+	"github.com/xn3cr0nx/bitgodine/internal/storage/kv/iterator"
+)
+
+// Specs registers the shared specs in the enclosing container. open returns a new empty db for each spec
+// along with the function releasing it
+func Specs(open func() (db kv.DB, release func())) {
+	var (
+		db      kv.DB
+		release func()
+	)
+
+	BeforeEach(func() {
+		db, release = open()
+	})
+
+	AfterEach(func() {
+		release()
+	})
+
+	Context("when inserting new elements in the db", func() {
+		It("a generic key value element is correctly inserted", func() {
+			Expect(db.Store("test", []byte("insert"))).To(Succeed())
+			Expect(db.IsStored("test")).To(BeTrue())
+		})
+
+		It("a key value element with uuid key and json encoded value is correctly inserted", func() {
+			value := struct {
+				Counter int
+				Type    string
+			}{10, "test"}
+			encoded, err := json.Marshal(&value)
+			Expect(err).ToNot(HaveOccurred())
+			key := uuid.New().String()
+			Expect(db.Store(key, encoded)).To(Succeed())
+			Expect(db.IsStored(key)).To(BeTrue())
+		})
+
+		It("a batch map of key string and value []byte elements are correctly inserted", func() {
+			batch := map[string][]byte{"test": []byte("insert"), uuid.New().String(): []byte("a"), uuid.New().String(): []byte("b")}
+			Expect(db.StoreBatch(batch)).To(Succeed())
+			keys, err := db.ReadKeys()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(keys).To(HaveLen(3))
+		})
+
+		It("a batch map of string int key value elements is not inserted and an error is thrown", func() {
+			err := db.StoreBatch(map[string]int{uuid.New().String(): 12})
+			Expect(err).To(HaveOccurred())
+			Expect(errors.Is(err, errorx.ErrInvalidArgument)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("type is not allowed"))
+		})
+
+		It("queued batches are stored over the threshold and on flush", func() {
+			for i := 0; i < 150; i++ {
+				Expect(db.StoreQueueBatch(map[string][]byte{fmt.Sprintf("queue_%03d", i): []byte("v")})).To(Succeed())
+			}
+			keys, err := db.ReadKeysWithPrefix("queue_")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(keys).To(HaveLen(101))
+
+			Expect(db.Flush()).To(Succeed())
+			keys, err = db.ReadKeysWithPrefix("queue_")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(keys).To(HaveLen(150))
+		})
+
+		It("an element inserted with a ttl is readable until it expires", func() {
+			Expect(db.StoreWithTTL("expiring", []byte("test"), time.Hour)).To(Succeed())
+			value, err := db.Read("expiring")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(value)).To(Equal("test"))
+		})
+	})
+
+	Context("when reading from the db", func() {
+		var UUID, element string
+
+		BeforeEach(func() {
+			UUID = uuid.New().String()
+			element = "test"
+			Expect(db.Store(UUID, []byte(element))).To(Succeed())
+		})
+
+		It("an existing element is correctly retrieved by uuid key", func() {
+			e, err := db.Read(UUID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(e)).To(Equal(element))
+		})
+
+		It("a not existing element is not retrieved by key", func() {
+			_, err := db.Read(uuid.New().String())
+			Expect(err).To(HaveOccurred())
+			Expect(errors.Is(err, errorx.ErrKeyNotFound)).To(BeTrue())
+			Expect(db.IsStored(uuid.New().String())).To(BeFalse())
+		})
+
+		It("db keys are correctly retrieve", func() {
+			keys, err := db.ReadKeys()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(keys).Should(ConsistOf([]string{UUID}))
+		})
+
+		It("db keys and values are correctly retrieve", func() {
+			keys, err := db.ReadKeyValues()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(keys).To(HaveLen(1))
+			Expect(keys).Should(HaveKeyWithValue(UUID, []byte(element)))
+		})
+	})
+
+	Context("when reading by prefix", func() {
+		BeforeEach(func() {
+			Expect(db.StoreBatch(map[string][]byte{
+				"addr_1A_0001": []byte("a"),
+				"addr_1A_0002": []byte("b"),
+				"addr_1B_0001": []byte("c"),
+				"addr_1":       []byte("d"),
+				"block_1":      []byte("e"),
+			})).To(Succeed())
+		})
+
+		It("keys are returned in order", func() {
+			keys, err := db.ReadKeysWithPrefix("addr_1A_")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(keys).To(Equal([]string{"addr_1A_0001", "addr_1A_0002"}))
+		})
+
+		It("values are returned in key order", func() {
+			values, err := db.ReadPrefix("addr_1")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(values).To(Equal([][]byte{[]byte("d"), []byte("a"), []byte("b"), []byte("c")}))
+		})
+
+		It("the first value is the one of the lowest key", func() {
+			value, err := db.ReadFirstValueByPrefix("addr_1B")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(value).To(Equal([]byte("c")))
+		})
+
+		It("a not matched prefix returns no value", func() {
+			value, err := db.ReadFirstValueByPrefix("tx_")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(value).To(BeNil())
+		})
+
+		It("keys and values are returned", func() {
+			values, err := db.ReadPrefixWithKey("block_")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(values).To(Equal(map[string][]byte{"block_1": []byte("e")}))
+		})
+	})
+
+	Context("when updating in the db", func() {
+		var UUID, element string
+
+		BeforeEach(func() {
+			UUID = uuid.New().String()
+			element = "test"
+			Expect(db.Store(UUID, []byte(element))).To(Succeed())
+		})
+
+		It("an element is correctly updated", func() {
+			Expect(db.Store(UUID, []byte(element+"updated"))).To(Succeed())
+			updated, err := db.Read(UUID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(updated)).To(Equal(element + "updated"))
+		})
+	})
+
+	Context("when deleting from the db", func() {
+		var UUID string
+
+		BeforeEach(func() {
+			UUID = uuid.New().String()
+			Expect(db.Store(UUID, []byte("test"))).To(Succeed())
+		})
+
+		It("an element is correctly delete", func() {
+			Expect(db.Delete(UUID)).To(Succeed())
+			_, err := db.Read(UUID)
+			Expect(err).To(HaveOccurred())
+			Expect(errors.Is(err, errorx.ErrKeyNotFound)).To(BeTrue())
+		})
+
+		It("using a not existing key is just a NOP", func() {
+			Expect(db.Delete(uuid.New().String())).To(Succeed())
+		})
+	})
+
+	Context("when iterating over a prefix", func() {
+		BeforeEach(func() {
+			for _, k := range []string{"a_1", "a_2", "a_3", "a_4", "b_1"} {
+				Expect(db.Store(k, []byte("v"+k))).To(Succeed())
+			}
+		})
+
+		It("keys are returned in order with their values", func() {
+			it := db.Iterate(&iterator.Options{Prefix: "a_"})
+			defer it.Close()
+			var keys []string
+			for it.Next() {
+				Expect(string(it.Value())).To(Equal("v" + it.Key()))
+				keys = append(keys, it.Key())
+			}
+			Expect(it.Err()).ToNot(HaveOccurred())
+			Expect(keys).To(Equal([]string{"a_1", "a_2", "a_3", "a_4"}))
+		})
+
+		It("keys are returned in reverse order", func() {
+			keys, err := iterator.Keys(db.Iterate(&iterator.Options{Prefix: "a_", Reverse: true}))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(keys).To(Equal([]string{"a_4", "a_3", "a_2", "a_1"}))
+		})
+
+		It("iteration resumes after the last seen key up to the limit", func() {
+			keys, err := iterator.Keys(db.Iterate(&iterator.Options{Prefix: "a_", StartAfter: "a_1", Limit: 2}))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(keys).To(Equal([]string{"a_2", "a_3"}))
+
+			keys, err = iterator.Keys(db.Iterate(&iterator.Options{Prefix: "a_", StartAfter: "a_3", Reverse: true}))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(keys).To(Equal([]string{"a_2", "a_1"}))
+		})
+
+		It("a prefix without keys returns nothing", func() {
+			keys, err := iterator.Keys(db.Iterate(&iterator.Options{Prefix: "c_"}))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(keys).To(BeEmpty())
+		})
+	})
+}
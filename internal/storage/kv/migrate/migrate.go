@@ -0,0 +1,63 @@
+package migrate
+
+import (
+	badgerdb "github.com/dgraph-io/badger/v3"
+
+	"github.com/xn3cr0nx/bitgodine/internal/storage/kv/badger"
+	"github.com/xn3cr0nx/bitgodine/internal/storage/kv/bolt"
+)
+
+// DefaultBatchSize number of keys written in a single bolt transaction
+const DefaultBatchSize = 10000
+
+// BadgerToBolt copies all the keys of the badger store into the bolt one. Keys are read in order from a
+// single badger snapshot and written in batches, syncing bolt only at the end of the copy.
+// progress, if not nil, is called after each batch with the number of copied keys
+func BadgerToBolt(src *badger.Badger, dst *bolt.Bolt, batchSize int, progress func(copied int)) (copied int, err error) {
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	dst.NoSync = true
+	defer func() {
+		dst.NoSync = false
+		if e := dst.Sync(); err == nil {
+			err = e
+		}
+	}()
+
+	batch := make(map[string][]byte, batchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := dst.StoreBatch(batch); err != nil {
+			return err
+		}
+		copied += len(batch)
+		batch = make(map[string][]byte, batchSize)
+		if progress != nil {
+			progress(copied)
+		}
+		return nil
+	}
+
+	err = src.View(func(txn *badgerdb.Txn) error {
+		it := txn.NewIterator(badgerdb.DefaultIteratorOptions)
+		defer it.Close()
+		for it.Rewind(); it.Valid(); it.Next() {
+			item := it.Item()
+			value, err := item.ValueCopy(nil)
+			if err != nil {
+				return err
+			}
+			batch[string(item.KeyCopy(nil))] = value
+			if len(batch) >= batchSize {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+		}
+		return flush()
+	})
+	return
+}
@@ -0,0 +1,64 @@
+package migrate_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/xn3cr0nx/bitgodine/internal/storage/kv/badger"
+	"github.com/xn3cr0nx/bitgodine/internal/storage/kv/bolt"
+	"github.com/xn3cr0nx/bitgodine/internal/storage/kv/migrate"
+)
+
+var _ = Describe("Migrate", func() {
+	var (
+		dir string
+		src *badger.Badger
+		dst *bolt.Bolt
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "migrate")
+		Expect(err).ToNot(HaveOccurred())
+		src, err = badger.NewBadger(badger.Conf(filepath.Join(dir, "badger")), false)
+		Expect(err).ToNot(HaveOccurred())
+		dst, err = bolt.NewBolt(bolt.Conf(filepath.Join(dir, "bolt", "test.db")), false)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		src.Close()
+		dst.Close()
+		os.RemoveAll(dir)
+	})
+
+	It("copies all the keys in batches", func() {
+		batch := make(map[string][]byte)
+		for i := 0; i < 25; i++ {
+			batch[fmt.Sprintf("addr_%02d", i)] = []byte(fmt.Sprintf("value_%d", i))
+		}
+		Expect(src.StoreBatch(batch)).To(Succeed())
+
+		var progress []int
+		copied, err := migrate.BadgerToBolt(src, dst, 10, func(copied int) {
+			progress = append(progress, copied)
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(copied).To(Equal(25))
+		Expect(progress).To(Equal([]int{10, 20, 25}))
+
+		values, err := dst.ReadPrefixWithKey("addr_")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(values).To(Equal(batch))
+	})
+
+	It("copies nothing from an empty store", func() {
+		copied, err := migrate.BadgerToBolt(src, dst, 0, nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(copied).To(BeZero())
+	})
+})
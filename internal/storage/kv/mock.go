@@ -2,7 +2,12 @@
 
 package kv
 
-import mock "github.com/stretchr/testify/mock"
+import (
+	time "time"
+
+	mock "github.com/stretchr/testify/mock"
+	iterator "github.com/xn3cr0nx/bitgodine/internal/storage/kv/iterator"
+)
 
 // DBMock is an autogenerated mock type for the DB type
 type DBMock struct {
@@ -55,6 +60,20 @@ func (_m *DBMock) Empty() error {
 	return r0
 }
 
+// Flush provides a mock function with given fields:
+func (_m *DBMock) Flush() error {
+	ret := _m.Called()
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func() error); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // IsStored provides a mock function with given fields: _a0
 func (_m *DBMock) IsStored(_a0 string) bool {
 	ret := _m.Called(_a0)
@@ -69,6 +88,22 @@ func (_m *DBMock) IsStored(_a0 string) bool {
 	return r0
 }
 
+// Iterate provides a mock function with given fields: _a0
+func (_m *DBMock) Iterate(_a0 *iterator.Options) iterator.Iterator {
+	ret := _m.Called(_a0)
+
+	var r0 iterator.Iterator
+	if rf, ok := ret.Get(0).(func(*iterator.Options) iterator.Iterator); ok {
+		r0 = rf(_a0)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(iterator.Iterator)
+		}
+	}
+
+	return r0
+}
+
 // Read provides a mock function with given fields: _a0
 func (_m *DBMock) Read(_a0 string) ([]byte, error) {
 	ret := _m.Called(_a0)
@@ -271,3 +306,17 @@ func (_m *DBMock) StoreQueueBatch(_a0 interface{}) error {
 
 	return r0
 }
+
+// StoreWithTTL provides a mock function with given fields: _a0, _a1, _a2
+func (_m *DBMock) StoreWithTTL(_a0 string, _a1 []byte, _a2 time.Duration) error {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, []byte, time.Duration) error); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
@@ -0,0 +1,145 @@
+package redis
+
+import (
+	ctx "context"
+	"fmt"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/xn3cr0nx/bitgodine/internal/errorx"
+	"github.com/xn3cr0nx/bitgodine/internal/storage/kv/iterator"
+)
+
+const (
+	// pageSize number of keys scanned and values read per round trip
+	pageSize = 1000
+	// MaxSortedKeys maximum number of keys matching the prefix an ordered iteration loads in memory
+	MaxSortedKeys = 100000
+)
+
+// globEscaper escapes the prefix in scan patterns
+var globEscaper = strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`, `]`, `\]`)
+
+// redisIterator reads keys in pages. Redis doesn't keep keys ordered: without ordering options keys are
+// streamed in scan order, otherwise the keys matching the prefix are scanned and sorted first.
+// Ordered iterations hold every key matching the prefix in memory, so they are refused with
+// ErrOutOfRange beyond MaxSortedKeys. SCAN may return a key more than once, keys are deduplicated
+type redisIterator struct {
+	r      *Redis
+	opts   *iterator.Options
+	c      ctx.Context
+	scan   *redis.ScanIterator
+	seen   map[string]struct{}
+	sorted []string
+	page   []string
+	values []interface{}
+	index  int
+	count  int
+	key    string
+	value  []byte
+	err    error
+}
+
+// Iterate returns an iterator over the keys matching the prefix
+func (r *Redis) Iterate(opts *iterator.Options) iterator.Iterator {
+	i := &redisIterator{r: r, opts: opts, c: ctx.Background(), seen: make(map[string]struct{})}
+	i.scan = r.Scan(i.c, 0, globEscaper.Replace(opts.Prefix)+"*", pageSize).Iterator()
+	if opts.Reverse || opts.Start != "" || opts.StartAfter != "" {
+		var keys []string
+		for i.scan.Next(i.c) {
+			if !i.first(i.scan.Val()) {
+				continue
+			}
+			if len(keys) == MaxSortedKeys {
+				i.err = fmt.Errorf("%w: more than %d keys with prefix %s to sort", errorx.ErrOutOfRange, MaxSortedKeys, opts.Prefix)
+				return i
+			}
+			keys = append(keys, i.scan.Val())
+		}
+		if i.err = i.scan.Err(); i.err != nil {
+			return i
+		}
+		i.sorted = opts.Sort(keys)
+		i.scan, i.seen = nil, nil
+	}
+	return i
+}
+
+// first returns true the first time the key is scanned
+func (i *redisIterator) first(key string) bool {
+	if _, ok := i.seen[key]; ok {
+		return false
+	}
+	i.seen[key] = struct{}{}
+	return true
+}
+
+// nextPage loads the next page of keys and their values
+func (i *redisIterator) nextPage() bool {
+	i.page, i.values, i.index = i.page[:0], nil, 0
+	if i.scan != nil {
+		for len(i.page) < pageSize && i.scan.Next(i.c) {
+			if i.first(i.scan.Val()) {
+				i.page = append(i.page, i.scan.Val())
+			}
+		}
+		if i.err = i.scan.Err(); i.err != nil {
+			return false
+		}
+	} else {
+		n := pageSize
+		if n > len(i.sorted) {
+			n = len(i.sorted)
+		}
+		i.page = append(i.page, i.sorted[:n]...)
+		i.sorted = i.sorted[n:]
+	}
+	if len(i.page) == 0 {
+		return false
+	}
+	if !i.opts.KeysOnly {
+		if i.values, i.err = i.r.MGet(i.c, i.page...).Result(); i.err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func (i *redisIterator) Next() bool {
+	for i.err == nil && !i.opts.Done(i.count) {
+		if i.index >= len(i.page) && !i.nextPage() {
+			return false
+		}
+		key := i.page[i.index]
+		i.index++
+		i.key, i.value = key, nil
+		if !i.opts.KeysOnly {
+			value, ok := i.values[i.index-1].(string)
+			if !ok {
+				// deleted after being scanned
+				continue
+			}
+			i.value = []byte(value)
+		}
+		i.count++
+		return true
+	}
+	return false
+}
+
+func (i *redisIterator) Key() string {
+	return i.key
+}
+
+func (i *redisIterator) Value() []byte {
+	return i.value
+}
+
+func (i *redisIterator) Err() error {
+	return i.err
+}
+
+func (i *redisIterator) Close() error {
+	return nil
+}
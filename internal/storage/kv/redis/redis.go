@@ -2,6 +2,7 @@ package redis
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/go-redis/redis/v8"
 	"github.com/imdario/mergo"
@@ -61,6 +62,12 @@ func (r *Redis) Store(key string, value []byte) (err error) {
 	return errorParser(e)
 }
 
+// StoreWithTTL insert new key-value in redis, expiring after ttl
+func (r *Redis) StoreWithTTL(key string, value []byte, ttl time.Duration) (err error) {
+	e := r.Set(ctx.Background(), key, value, ttl).Err()
+	return errorParser(e)
+}
+
 // StoreBatch insert new key-value in redis
 func (r *Redis) StoreBatch(batch interface{}) (err error) {
 	series := batch.(map[string][]byte)
@@ -97,6 +104,19 @@ func (r *Redis) StoreQueueBatch(v interface{}) (err error) {
 	return
 }
 
+// Flush stores the queued entries
+func (r *Redis) Flush() (err error) {
+	if len(queue) == 0 {
+		return
+	}
+	if err = r.StoreBatch(queue); err != nil {
+		return
+	}
+	queue = make(map[string][]byte, 0)
+	counter = 0
+	return
+}
+
 func (r *Redis) Read(key string) (value []byte, err error) {
 	val, err := r.Get(ctx.Background(), key).Result()
 	err = errorParser(err)
@@ -1,9 +1,13 @@
 package kv
 
 import (
+	"time"
+
 	"github.com/spf13/viper"
 
 	"github.com/xn3cr0nx/bitgodine/internal/storage/kv/badger"
+	"github.com/xn3cr0nx/bitgodine/internal/storage/kv/bolt"
+	"github.com/xn3cr0nx/bitgodine/internal/storage/kv/iterator"
 	"github.com/xn3cr0nx/bitgodine/internal/storage/kv/redis"
 )
 
@@ -12,6 +16,8 @@ type DB interface {
 	Store(string, []byte) error
 	StoreBatch(interface{}) error
 	StoreQueueBatch(interface{}) error
+	StoreWithTTL(string, []byte, time.Duration) error
+	Flush() error
 	Read(string) ([]byte, error)
 	ReadKeys() ([]string, error)
 	ReadKeyValues() (map[string][]byte, error)
@@ -20,6 +26,7 @@ type DB interface {
 	ReadFirstValueByPrefix(string) ([]byte, error)
 	ReadPrefixWithKey(string) (map[string][]byte, error)
 	IsStored(string) bool
+	Iterate(*iterator.Options) iterator.Iterator
 	Delete(string) error
 	Empty() error
 	Close() error
@@ -39,6 +46,12 @@ func NewDB() (db DB, err error) {
 			return
 		}
 		break
+	case "bolt":
+		db, err = bolt.NewBolt(bolt.Conf(viper.GetString("bolt")), false)
+		if err != nil {
+			return
+		}
+		break
 	case "redis":
 		db, err = redis.NewRedis(redis.Conf(viper.GetString("redis")))
 		if err != nil {
@@ -0,0 +1,228 @@
+package tag
+
+import (
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/xn3cr0nx/bitgodine/internal/cluster"
+)
+
+// Categories of the entities taxonomy, sanctions included
+const (
+	CategoryExchange   = "exchange"
+	CategoryMining     = "mining"
+	CategoryGambling   = "gambling"
+	CategoryService    = "service"
+	CategoryMixer      = "mixer"
+	CategoryDarknet    = "darknet"
+	CategoryScam       = "scam"
+	CategoryRansomware = "ransomware"
+	CategoryHistoric   = "historic"
+	CategoryIndividual = "individual"
+	CategoryOther      = "other"
+)
+
+// types maps the tag types found in the sources to the taxonomy categories
+var types = map[string]string{
+	"exchange":             CategoryExchange,
+	"exchanges":            CategoryExchange,
+	"pool":                 CategoryMining,
+	"pools":                CategoryMining,
+	"mining":               CategoryMining,
+	"gambling":             CategoryGambling,
+	"service":              CategoryService,
+	"services":             CategoryService,
+	"mixer":                CategoryMixer,
+	"mixing":               CategoryMixer,
+	"darknet":              CategoryDarknet,
+	"market":               CategoryDarknet,
+	"scam":                 CategoryScam,
+	"ransomware":           CategoryRansomware,
+	"sanctions":            Sanctions,
+	"historic":             CategoryHistoric,
+	"signed-messages":      CategoryIndividual,
+	"submitted-links":      CategoryIndividual,
+	"bitcoin-otc-profiles": CategoryIndividual,
+	"forum-profiles":       CategoryIndividual,
+}
+
+// Category returns the taxonomy category of the tag type
+func Category(t string) string {
+	if category, ok := types[strings.ToLower(strings.TrimSpace(t))]; ok {
+		return category
+	}
+	return CategoryOther
+}
+
+// confidences base confidence of the tags of each source
+var confidences = map[string]float64{
+	"ofac":                1,
+	"manual":              0.6,
+	"walletexplorer":      0.7,
+	"checkbitcoinaddress": 0.4,
+	"blockchain":          0.4,
+}
+
+// DefaultConfidence returns the confidence of a tag based on its source, higher if verified
+func DefaultConfidence(t *Model) float64 {
+	confidence, ok := confidences[t.Source]
+	if !ok {
+		confidence = 0.5
+	}
+	if t.Type == Sanctions {
+		confidence = 1
+	}
+	if t.Verified && confidence < 0.9 {
+		confidence = 0.9
+	}
+	return confidence
+}
+
+// NormalizeName reduces a nickname to the form aliases are matched with, dropping case,
+// protocol, trailing domain suffix and punctuation. "https://www.Binance.com" becomes "binance"
+func NormalizeName(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	name = strings.TrimPrefix(strings.TrimPrefix(name, "https://"), "http://")
+	name = strings.TrimPrefix(name, "www.")
+	for _, suffix := range []string{".com", ".io", ".net", ".org", ".info", ".co"} {
+		if len(name) > len(suffix) && strings.HasSuffix(name, suffix) {
+			name = strings.TrimSuffix(name, suffix)
+			break
+		}
+	}
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, name)
+}
+
+// Candidate entity a cluster may belong to, scored by the confidence of its tags
+type Candidate struct {
+	Entity   string   `json:"entity"`
+	Category string   `json:"category"`
+	Score    float64  `json:"score"`
+	Tags     int      `json:"tags"`
+	Verified bool     `json:"verified"`
+	Sources  []string `json:"sources,omitempty"`
+} //@name EntityCandidate
+
+// Resolution of the cluster label. Entity is the dominant candidate, with Confidence its share of the
+// overall score, Conflicts the other candidates. Tags are the valid tags of the cluster, the ones carrying
+// its labels when resolved from them
+type Resolution struct {
+	Entity     string      `json:"entity,omitempty"`
+	Category   string      `json:"category,omitempty"`
+	Confidence float64     `json:"confidence"`
+	Verified   bool        `json:"verified"`
+	Conflicts  []Candidate `json:"conflicts,omitempty"`
+	Tags       []Model     `json:"tags"`
+} //@name ClusterResolution
+
+// Resolve picks the dominant entity among the tags. Tags are grouped by the entity their nickname is
+// an alias of, entities being keyed by normalized alias, or else by normalized nickname. Each group is
+// scored by the sum of its tags confidence. Expired tags and tags without nickname don't take part
+func Resolve(tags []Model, entities map[string]Entity, at time.Time) (res Resolution) {
+	valid := []Model{}
+	for _, t := range tags {
+		if !t.Expired(at) {
+			valid = append(valid, t)
+		}
+	}
+	return resolve(valid, nil, entities)
+}
+
+// ResolveLabels picks the dominant entity among the materialized labels of a cluster as Resolve does,
+// each label standing for the tag of the member carrying it, weighted by the members supporting it
+func ResolveLabels(labels []cluster.Label, entities map[string]Entity) (res Resolution) {
+	tags, support := labelTags(labels)
+	return resolve(tags, support, entities)
+}
+
+// labelTags returns the tags of the members carrying the labels and the support of each
+func labelTags(labels []cluster.Label) (tags []Model, support []int) {
+	tags, support = make([]Model, len(labels)), make([]int, len(labels))
+	for i, l := range labels {
+		tags[i] = Model{
+			Address:    l.Address,
+			Message:    l.Message,
+			Nickname:   l.Nickname,
+			Type:       l.Type,
+			Verified:   l.Verified,
+			Confidence: l.Confidence,
+		}
+		support[i] = l.Support
+	}
+	return
+}
+
+// resolve ranks the candidate entities of the tags, each counted as many times as its support, once if missing
+func resolve(tags []Model, support []int, entities map[string]Entity) (res Resolution) {
+	res.Tags = tags
+	candidates := make(map[string]*Candidate)
+	sources := make(map[string]map[string]bool)
+	var total float64
+	for i, t := range tags {
+		name := NormalizeName(t.Nickname)
+		if name == "" {
+			continue
+		}
+		weight := 1
+		if support != nil && support[i] > 1 {
+			weight = support[i]
+		}
+
+		entity, known := entities[name]
+		if known {
+			name = NormalizeName(entity.Name)
+		}
+		c, ok := candidates[name]
+		if !ok {
+			c = &Candidate{Entity: strings.TrimSpace(t.Nickname), Category: Category(t.Type)}
+			candidates[name] = c
+			sources[name] = make(map[string]bool)
+		}
+		if known {
+			c.Entity, c.Category = entity.Name, entity.Category
+		}
+		confidence := t.Confidence
+		if confidence == 0 {
+			confidence = DefaultConfidence(&t)
+		}
+		c.Score += confidence * float64(weight)
+		c.Tags += weight
+		c.Verified = c.Verified || t.Verified
+		if t.Source != "" && !sources[name][t.Source] {
+			sources[name][t.Source] = true
+			c.Sources = append(c.Sources, t.Source)
+		}
+		total += confidence * float64(weight)
+	}
+
+	ranked := make([]Candidate, 0, len(candidates))
+	for _, c := range candidates {
+		ranked = append(ranked, *c)
+	}
+	if len(ranked) == 0 {
+		return
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Score != ranked[j].Score {
+			return ranked[i].Score > ranked[j].Score
+		}
+		return ranked[i].Entity < ranked[j].Entity
+	})
+
+	res.Entity = ranked[0].Entity
+	res.Category = ranked[0].Category
+	res.Verified = ranked[0].Verified
+	res.Confidence = ranked[0].Score / total
+	res.Conflicts = ranked[1:]
+	if len(res.Conflicts) == 0 {
+		res.Conflicts = nil
+	}
+	return
+}
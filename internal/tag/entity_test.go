@@ -0,0 +1,125 @@
+package tag
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/xn3cr0nx/bitgodine/internal/cluster"
+)
+
+type TestEntitySuite struct {
+	suite.Suite
+	now time.Time
+}
+
+func (suite *TestEntitySuite) SetupTest() {
+	suite.now = time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+}
+
+func (suite *TestEntitySuite) TestNormalizeName() {
+	assert.Equal(suite.T(), "binance", NormalizeName("https://www.Binance.com"))
+	assert.Equal(suite.T(), "huobi", NormalizeName(" Huobi.com "))
+	assert.Equal(suite.T(), "btce", NormalizeName("BTC-e"))
+	assert.Equal(suite.T(), "bitcompany", NormalizeName("Bit.Company.io"))
+	assert.Equal(suite.T(), "coinomiwallet", NormalizeName("Coinomi.Wallet"))
+	assert.Equal(suite.T(), "", NormalizeName("  "))
+}
+
+func (suite *TestEntitySuite) TestCategory() {
+	assert.Equal(suite.T(), CategoryMining, Category("Pool"))
+	assert.Equal(suite.T(), CategoryExchange, Category("exchanges"))
+	assert.Equal(suite.T(), Sanctions, Category("sanctions"))
+	assert.Equal(suite.T(), CategoryOther, Category("unknown"))
+}
+
+func (suite *TestEntitySuite) TestDefaultConfidence() {
+	assert.Equal(suite.T(), 0.5, DefaultConfidence(&Model{}))
+	assert.Equal(suite.T(), 0.7, DefaultConfidence(&Model{Source: "walletexplorer"}))
+	assert.Equal(suite.T(), 0.9, DefaultConfidence(&Model{Source: "checkbitcoinaddress", Verified: true}))
+	assert.Equal(suite.T(), 1.0, DefaultConfidence(&Model{Type: Sanctions}))
+}
+
+func (suite *TestEntitySuite) TestResolveDominant() {
+	tags := []Model{
+		{Address: "1A", Nickname: "Huobi.com", Type: "exchange", Source: "walletexplorer"},
+		{Address: "1B", Nickname: "huobi", Type: "exchange", Source: "manual", Verified: true},
+		{Address: "1C", Nickname: "SomePool", Type: "pool", Source: "checkbitcoinaddress"},
+		{Address: "1D", Message: "no nickname"},
+	}
+	res := Resolve(tags, nil, suite.now)
+
+	assert.Equal(suite.T(), "Huobi.com", res.Entity)
+	assert.Equal(suite.T(), CategoryExchange, res.Category)
+	assert.True(suite.T(), res.Verified)
+	assert.InDelta(suite.T(), 1.6/2.0, res.Confidence, 1e-9)
+	assert.Len(suite.T(), res.Tags, 4)
+	require.Len(suite.T(), res.Conflicts, 1)
+	assert.Equal(suite.T(), "SomePool", res.Conflicts[0].Entity)
+	assert.Equal(suite.T(), CategoryMining, res.Conflicts[0].Category)
+	assert.Equal(suite.T(), []string{"checkbitcoinaddress"}, res.Conflicts[0].Sources)
+}
+
+func (suite *TestEntitySuite) TestResolveAliases() {
+	entities := map[string]Entity{
+		"btce":   {Name: "BTC-e", Category: CategoryExchange},
+		"wexnz":  {Name: "BTC-e", Category: CategoryExchange},
+		"canton": {Name: "Canton", Category: CategoryScam},
+	}
+	tags := []Model{
+		{Address: "1A", Nickname: "btc-e", Confidence: 0.4},
+		{Address: "1B", Nickname: "wex.nz", Confidence: 0.4},
+		{Address: "1C", Nickname: "Canton", Confidence: 0.6},
+	}
+	res := Resolve(tags, entities, suite.now)
+
+	assert.Equal(suite.T(), "BTC-e", res.Entity)
+	assert.Equal(suite.T(), CategoryExchange, res.Category)
+	assert.False(suite.T(), res.Verified)
+	require.Len(suite.T(), res.Conflicts, 1)
+	assert.Equal(suite.T(), "Canton", res.Conflicts[0].Entity)
+	assert.Equal(suite.T(), 1, res.Conflicts[0].Tags)
+}
+
+func (suite *TestEntitySuite) TestResolveExpired() {
+	past, future := suite.now.Add(-time.Hour), suite.now.Add(time.Hour)
+	tags := []Model{
+		{Address: "1A", Nickname: "Old", Confidence: 1, ExpiresAt: &past},
+		{Address: "1B", Nickname: "Current", Confidence: 0.3, ExpiresAt: &future},
+	}
+	res := Resolve(tags, nil, suite.now)
+
+	assert.Equal(suite.T(), "Current", res.Entity)
+	assert.Equal(suite.T(), 1.0, res.Confidence)
+	assert.Nil(suite.T(), res.Conflicts)
+	assert.Len(suite.T(), res.Tags, 1)
+}
+
+func (suite *TestEntitySuite) TestResolveLabels() {
+	labels := []cluster.Label{
+		{Cluster: 1, Address: "1A", Nickname: "Huobi.com", Type: "exchange", Support: 3, Confidence: 0.7},
+		{Cluster: 1, Address: "1B", Nickname: "SomePool", Type: "pool", Support: 1, Confidence: 0.9},
+	}
+	res := ResolveLabels(labels, nil)
+
+	assert.Equal(suite.T(), "Huobi.com", res.Entity)
+	assert.Equal(suite.T(), CategoryExchange, res.Category)
+	assert.InDelta(suite.T(), 2.1/3.0, res.Confidence, 1e-9)
+	require.Len(suite.T(), res.Conflicts, 1)
+	assert.Equal(suite.T(), "SomePool", res.Conflicts[0].Entity)
+	require.Len(suite.T(), res.Tags, 2)
+	assert.Equal(suite.T(), "1A", res.Tags[0].Address)
+}
+
+func (suite *TestEntitySuite) TestResolveEmpty() {
+	res := Resolve(nil, nil, suite.now)
+	assert.Equal(suite.T(), "", res.Entity)
+	assert.Equal(suite.T(), []Model{}, res.Tags)
+}
+
+func TestEntity(t *testing.T) {
+	suite.Run(t, new(TestEntitySuite))
+}
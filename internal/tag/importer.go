@@ -0,0 +1,256 @@
+package tag
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/xn3cr0nx/bitgodine/internal/errorx"
+	"github.com/xn3cr0nx/bitgodine/pkg/validator"
+)
+
+// Import file formats
+const (
+	OFAC           = "ofac"
+	CSV            = "csv"
+	JSON           = "json"
+	WalletExplorer = "walletexplorer"
+)
+
+// Sanctions type of the tags imported from sanctions lists
+const Sanctions = "sanctions"
+
+// ofacCurrency prefix of the OFAC SDN id types of bitcoin addresses
+const ofacCurrency = "Digital Currency Address - XBT"
+
+// Mapping of the tag fields to the columns of csv files or the keys of json objects
+type Mapping struct {
+	Address  string
+	Message  string
+	Nickname string
+	Type     string
+	Link     string
+	Verified string
+}
+
+// WithDefaults maps the fields not mapped to the column with the same name
+func (m Mapping) WithDefaults() Mapping {
+	for field, name := range map[*string]string{
+		&m.Address:  "address",
+		&m.Message:  "message",
+		&m.Nickname: "nickname",
+		&m.Type:     "type",
+		&m.Link:     "link",
+		&m.Verified: "verified",
+	} {
+		if *field == "" {
+			*field = name
+		}
+	}
+	return m
+}
+
+// Parse reads the tags from a file in the format. Records without address are dropped,
+// those without type get the category
+func Parse(r io.Reader, format string, mapping Mapping, category string) (tags []Model, err error) {
+	var parsed []Model
+	switch format {
+	case OFAC:
+		parsed, err = ParseOFAC(r)
+	case CSV:
+		parsed, err = ParseCSV(r, mapping)
+	case JSON:
+		parsed, err = ParseJSON(r, mapping)
+	case WalletExplorer:
+		parsed, err = ParseWalletExplorer(r)
+	default:
+		err = fmt.Errorf("%w: unknown import format %s", errorx.ErrInvalidArgument, format)
+	}
+	if err != nil {
+		return
+	}
+	for _, t := range parsed {
+		if t.Address == "" {
+			continue
+		}
+		if t.Type == "" {
+			t.Type = category
+		}
+		tags = append(tags, t)
+	}
+	return
+}
+
+// addressValidator checks the imported addresses with the rule of the model
+var addressValidator = validator.NewValidator()
+
+// Validate returns the tags with a valid bitcoin address and the number of rejected ones
+func Validate(tags []Model) (valid []Model, rejected int) {
+	for _, t := range tags {
+		if err := addressValidator.Var(t.Address, "required,btc_addr|btc_addr_bech32"); err != nil {
+			rejected++
+			continue
+		}
+		valid = append(valid, t)
+	}
+	return
+}
+
+// key identifying a tag to deduplicate imports
+func key(t *Model) string {
+	return t.Address + "\x00" + t.Type + "\x00" + t.Message
+}
+
+// Dedupe returns the tags not in the existing ones, nor repeated
+func Dedupe(tags, existing []Model) (unique []Model) {
+	seen := make(map[string]bool, len(existing)+len(tags))
+	for i := range existing {
+		seen[key(&existing[i])] = true
+	}
+	for i := range tags {
+		k := key(&tags[i])
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		unique = append(unique, tags[i])
+	}
+	return
+}
+
+type sdnList struct {
+	Entries []struct {
+		UID       string   `xml:"uid"`
+		FirstName string   `xml:"firstName"`
+		LastName  string   `xml:"lastName"`
+		Programs  []string `xml:"programList>program"`
+		IDs       []struct {
+			Type   string `xml:"idType"`
+			Number string `xml:"idNumber"`
+		} `xml:"idList>id"`
+	} `xml:"sdnEntry"`
+}
+
+// ParseOFAC reads the bitcoin addresses of the OFAC SDN list entries
+func ParseOFAC(r io.Reader) (tags []Model, err error) {
+	var list sdnList
+	if err = xml.NewDecoder(r).Decode(&list); err != nil {
+		return nil, fmt.Errorf("%w: %v", errorx.ErrInvalidArgument, err)
+	}
+	for _, entry := range list.Entries {
+		name := strings.TrimSpace(entry.FirstName + " " + entry.LastName)
+		for _, id := range entry.IDs {
+			if !strings.HasPrefix(id.Type, ofacCurrency) {
+				continue
+			}
+			tags = append(tags, Model{
+				Address:  strings.TrimSpace(id.Number),
+				Message:  "OFAC SDN " + strings.Join(entry.Programs, ", "),
+				Nickname: name,
+				Type:     Sanctions,
+				Link:     "https://sanctionssearch.ofac.treas.gov/Details.aspx?id=" + entry.UID,
+				Verified: true,
+			})
+		}
+	}
+	return
+}
+
+// ParseCSV reads the tags from a csv file with a header row, mapping its columns
+func ParseCSV(r io.Reader, mapping Mapping) (tags []Model, err error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errorx.ErrInvalidArgument, err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+	if _, ok := columns[mapping.Address]; !ok {
+		return nil, fmt.Errorf("%w: missing address column %s", errorx.ErrInvalidArgument, mapping.Address)
+	}
+
+	for {
+		record, e := reader.Read()
+		if e == io.EOF {
+			break
+		}
+		if e != nil {
+			return nil, fmt.Errorf("%w: %v", errorx.ErrInvalidArgument, e)
+		}
+		field := func(column string) string {
+			if i, ok := columns[column]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		tags = append(tags, mapped(field, mapping))
+	}
+	return
+}
+
+// ParseJSON reads the tags from a json array of objects, mapping their keys
+func ParseJSON(r io.Reader, mapping Mapping) (tags []Model, err error) {
+	var records []map[string]interface{}
+	if err = json.NewDecoder(r).Decode(&records); err != nil {
+		return nil, fmt.Errorf("%w: %v", errorx.ErrInvalidArgument, err)
+	}
+	for _, record := range records {
+		field := func(key string) string {
+			switch v := record[key].(type) {
+			case string:
+				return strings.TrimSpace(v)
+			case nil:
+				return ""
+			default:
+				return fmt.Sprint(v)
+			}
+		}
+		tags = append(tags, mapped(field, mapping))
+	}
+	return
+}
+
+// ParseWalletExplorer reads the tags of a WalletExplorer dump, a csv file without header with
+// address, message, nickname, type, link and verified columns
+func ParseWalletExplorer(r io.Reader) (tags []Model, err error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 6
+	for {
+		record, e := reader.Read()
+		if e == io.EOF {
+			break
+		}
+		if e != nil {
+			return nil, fmt.Errorf("%w: %v", errorx.ErrInvalidArgument, e)
+		}
+		verified, _ := strconv.ParseBool(record[5])
+		tags = append(tags, Model{
+			Address:  record[0],
+			Message:  record[1],
+			Nickname: record[2],
+			Type:     record[3],
+			Link:     record[4],
+			Verified: verified,
+		})
+	}
+	return
+}
+
+func mapped(field func(string) string, mapping Mapping) Model {
+	verified, _ := strconv.ParseBool(field(mapping.Verified))
+	return Model{
+		Address:  field(mapping.Address),
+		Message:  field(mapping.Message),
+		Nickname: field(mapping.Nickname),
+		Type:     field(mapping.Type),
+		Link:     field(mapping.Link),
+		Verified: verified,
+	}
+}
@@ -0,0 +1,176 @@
+package tag
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/xn3cr0nx/bitgodine/internal/errorx"
+	"github.com/xn3cr0nx/bitgodine/pkg/validator"
+)
+
+const sdn = `<?xml version="1.0" standalone="yes"?>
+<sdnList xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xmlns="http://tempuri.org/sdnList.xsd">
+  <sdnEntry>
+    <uid>25308</uid>
+    <lastName>ALEKSEEV</lastName>
+    <firstName>Ivan</firstName>
+    <sdnType>Individual</sdnType>
+    <programList>
+      <program>CYBER2</program>
+    </programList>
+    <idList>
+      <id>
+        <uid>1</uid>
+        <idType>Digital Currency Address - XBT</idType>
+        <idNumber>1AjZPMsnmpdK2Rv9KQNfMurTXinscVro9V</idNumber>
+      </id>
+      <id>
+        <uid>2</uid>
+        <idType>Digital Currency Address - ETH</idType>
+        <idNumber>0x8576acc5c05d6ce88f4e49bf65bdf0c62f91353c</idNumber>
+      </id>
+      <id>
+        <uid>3</uid>
+        <idType>Passport</idType>
+        <idNumber>A123</idNumber>
+      </id>
+    </idList>
+  </sdnEntry>
+  <sdnEntry>
+    <uid>25309</uid>
+    <lastName>NO ADDRESS LTD</lastName>
+    <sdnType>Entity</sdnType>
+  </sdnEntry>
+</sdnList>`
+
+type importer struct {
+	Service
+	batch *Batch
+	tags  []Model
+}
+
+func (i *importer) ImportTags(batch *Batch, tags []Model) error {
+	i.batch, i.tags = batch, tags
+	batch.Imported = len(tags)
+	return nil
+}
+
+type TestImporterSuite struct {
+	suite.Suite
+}
+
+func (suite *TestImporterSuite) TestParseOFAC() {
+	tags, err := Parse(strings.NewReader(sdn), OFAC, Mapping{}, "")
+	require.Nil(suite.T(), err)
+	require.Len(suite.T(), tags, 1)
+	assert.Equal(suite.T(), "1AjZPMsnmpdK2Rv9KQNfMurTXinscVro9V", tags[0].Address)
+	assert.Equal(suite.T(), "Ivan ALEKSEEV", tags[0].Nickname)
+	assert.Equal(suite.T(), Sanctions, tags[0].Type)
+	assert.Equal(suite.T(), "OFAC SDN CYBER2", tags[0].Message)
+	assert.Contains(suite.T(), tags[0].Link, "25308")
+	assert.True(suite.T(), tags[0].Verified)
+}
+
+func (suite *TestImporterSuite) TestParseCSV() {
+	file := "wallet,label,kind\n1Exchange, Some exchange ,exchange\n,missing address,\n1Mixer,A mixer,\n"
+	tags, err := Parse(strings.NewReader(file), CSV, Mapping{Address: "wallet", Message: "label", Type: "kind"}.WithDefaults(), "service")
+	require.Nil(suite.T(), err)
+	require.Len(suite.T(), tags, 2)
+	assert.Equal(suite.T(), Model{Address: "1Exchange", Message: "Some exchange", Type: "exchange"}, tags[0])
+	assert.Equal(suite.T(), "service", tags[1].Type)
+
+	_, err = Parse(strings.NewReader(file), CSV, Mapping{}.WithDefaults(), "")
+	assert.True(suite.T(), errors.Is(err, errorx.ErrInvalidArgument))
+}
+
+func (suite *TestImporterSuite) TestParseJSON() {
+	file := `[{"address": "1Gambling", "name": "Dice", "verified": true}, {"address": "1Other", "name": 42}]`
+	tags, err := Parse(strings.NewReader(file), JSON, Mapping{Nickname: "name"}.WithDefaults(), "gambling")
+	require.Nil(suite.T(), err)
+	require.Len(suite.T(), tags, 2)
+	assert.Equal(suite.T(), Model{Address: "1Gambling", Nickname: "Dice", Type: "gambling", Verified: true}, tags[0])
+	assert.Equal(suite.T(), "42", tags[1].Nickname)
+}
+
+func (suite *TestImporterSuite) TestParseWalletExplorer() {
+	file := "1Pool,Mining pool payout,SomePool,mining,,true\n"
+	tags, err := Parse(strings.NewReader(file), WalletExplorer, Mapping{}, "")
+	require.Nil(suite.T(), err)
+	assert.Equal(suite.T(), []Model{{Address: "1Pool", Message: "Mining pool payout", Nickname: "SomePool", Type: "mining", Verified: true}}, tags)
+}
+
+func (suite *TestImporterSuite) TestUnknownFormat() {
+	_, err := Parse(strings.NewReader(""), "xls", Mapping{}, "")
+	assert.True(suite.T(), errors.Is(err, errorx.ErrInvalidArgument))
+}
+
+func (suite *TestImporterSuite) TestDedupe() {
+	tags := []Model{
+		{Address: "1A", Type: "exchange", Message: "a"},
+		{Address: "1A", Type: "exchange", Message: "a"},
+		{Address: "1A", Type: "exchange", Message: "b"},
+		{Address: "1B", Type: "exchange", Message: "a"},
+	}
+	existing := []Model{{Address: "1B", Type: "exchange", Message: "a"}}
+	unique := Dedupe(tags, existing)
+	require.Len(suite.T(), unique, 2)
+	assert.Equal(suite.T(), "a", unique[0].Message)
+	assert.Equal(suite.T(), "b", unique[1].Message)
+}
+
+func (suite *TestImporterSuite) TestValidate() {
+	tags := []Model{
+		{Address: "1AjZPMsnmpdK2Rv9KQNfMurTXinscVro9V", Message: "legacy"},
+		{Address: "bc1qar0srrr7xfkvy5l643lydnw9re59gtzzwf5mdq", Message: "bech32"},
+		{Address: "0x8576acc5c05d6ce88f4e49bf65bdf0c62f91353c", Message: "eth"},
+		{Address: "not an address", Message: "garbage"},
+	}
+	valid, rejected := Validate(tags)
+	require.Len(suite.T(), valid, 2)
+	assert.Equal(suite.T(), "legacy", valid[0].Message)
+	assert.Equal(suite.T(), "bech32", valid[1].Message)
+	assert.Equal(suite.T(), 2, rejected)
+}
+
+func (suite *TestImporterSuite) TestImportRoute() {
+	body := new(bytes.Buffer)
+	form := multipart.NewWriter(body)
+	require.Nil(suite.T(), form.WriteField("format", OFAC))
+	require.Nil(suite.T(), form.WriteField("source", "ofac"))
+	w, err := form.CreateFormFile("file", "sdn.xml")
+	require.Nil(suite.T(), err)
+	_, err = w.Write([]byte(sdn))
+	require.Nil(suite.T(), err)
+	require.Nil(suite.T(), form.Close())
+
+	e := echo.New()
+	e.Validator = validator.NewValidator()
+	req := httptest.NewRequest(http.MethodPost, "/tags/import", body)
+	req.Header.Set(echo.HeaderContentType, form.FormDataContentType())
+	rec := httptest.NewRecorder()
+	s := new(importer)
+	require.Nil(suite.T(), importTags(s)(e.NewContext(req, rec)))
+
+	assert.Equal(suite.T(), http.StatusOK, rec.Code)
+	assert.Equal(suite.T(), "ofac", s.batch.Source)
+	assert.Equal(suite.T(), "sdn.xml", s.batch.File)
+	assert.Len(suite.T(), s.tags, 1)
+	var batch Batch
+	require.Nil(suite.T(), json.Unmarshal(rec.Body.Bytes(), &batch))
+	assert.Equal(suite.T(), 1, batch.Imported)
+}
+
+func TestImporter(t *testing.T) {
+	suite.Run(t, new(TestImporterSuite))
+}
@@ -1,6 +1,8 @@
 package tag
 
 import (
+	"time"
+
 	"github.com/gofrs/uuid"
 	"gorm.io/gorm"
 )
@@ -8,16 +10,110 @@ import (
 // Model of tag struct with validation
 type Model struct {
 	gorm.Model
-	ID       uuid.UUID `json:"id" gorm:"primarykey;index;unique"`
-	Address  string    `json:"address" validate:"required,btc_addr|btc_addr_bech32" gorm:"size:64;index;not null"`
-	Message  string    `json:"message" validate:"required" gorm:"not null"`
-	Nickname string    `json:"nickname,omitempty" validate:"" gorm:"index;not null"`
-	Type     string    `json:"type,omitempty" validate:"" gorm:"index;not null"`
-	Link     string    `json:"link,omitempty" validate:""`
-	Verified bool      `json:"verified,omitempty" validate:"" gorm:"default:false"`
+	ID       uuid.UUID  `json:"id" gorm:"primarykey;index;unique"`
+	Address  string     `json:"address" validate:"required,btc_addr|btc_addr_bech32" gorm:"size:64;index;not null"`
+	Message  string     `json:"message" validate:"required" gorm:"not null"`
+	Nickname string     `json:"nickname,omitempty" validate:"" gorm:"index;not null"`
+	Type     string     `json:"type,omitempty" validate:"" gorm:"index;not null"`
+	Link     string     `json:"link,omitempty" validate:""`
+	Verified bool       `json:"verified,omitempty" validate:"" gorm:"default:false"`
+	Source   string     `json:"source,omitempty" validate:"" gorm:"index"`
+	BatchID  *uuid.UUID `json:"batch_id,omitempty" validate:"" gorm:"type:uuid;index"`
+
+	Confidence float64    `json:"confidence" validate:"omitempty,gte=0,lte=1"`
+	FirstSeen  time.Time  `json:"first_seen" validate:""`
+	LastSeen   time.Time  `json:"last_seen" validate:""`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty" validate:"" gorm:"index"`
 } //@name Tag
 
+// BeforeCreate generates the tag id and sets the defaults of confidence and seen times
+func (m *Model) BeforeCreate(tx *gorm.DB) (err error) {
+	if m.ID == uuid.Nil {
+		m.ID, err = uuid.NewV4()
+	}
+	if m.Confidence == 0 {
+		m.Confidence = DefaultConfidence(m)
+	}
+	if m.FirstSeen.IsZero() {
+		m.FirstSeen = time.Now()
+	}
+	if m.LastSeen.IsZero() {
+		m.LastSeen = m.FirstSeen
+	}
+	return
+}
+
+// Expired returns true if the tag expired at the time
+func (m *Model) Expired(at time.Time) bool {
+	return m.ExpiresAt != nil && !m.ExpiresAt.After(at)
+}
+
 // TableName defines default table name
 func (m Model) TableName() string {
 	return "tags"
 }
+
+// Batch of tags imported together, rolled back as a whole
+type Batch struct {
+	ID         uuid.UUID `json:"id" gorm:"primarykey"`
+	Source     string    `json:"source" gorm:"index;not null"`
+	Format     string    `json:"format" gorm:"not null"`
+	File       string    `json:"file"`
+	Imported   int       `json:"imported"`
+	Duplicated int       `json:"duplicated"`
+	Rejected   int       `json:"rejected"`
+	RolledBack bool      `json:"rolled_back" gorm:"default:false"`
+	CreatedAt  time.Time `json:"created_at"`
+} //@name TagBatch
+
+// BeforeCreate generates the batch id
+func (m *Batch) BeforeCreate(tx *gorm.DB) (err error) {
+	if m.ID == uuid.Nil {
+		m.ID, err = uuid.NewV4()
+	}
+	return
+}
+
+// TableName defines default table name
+func (m Batch) TableName() string {
+	return "tag_batches"
+}
+
+// Entity canonical entity many tags refer to, with its category in the taxonomy
+type Entity struct {
+	ID        uuid.UUID `json:"id" gorm:"primarykey"`
+	Name      string    `json:"name" validate:"required,max=128" gorm:"uniqueIndex;not null"`
+	Category  string    `json:"category" validate:"required,oneof=exchange mining gambling service mixer darknet scam ransomware sanctions historic individual other" gorm:"not null"`
+	Aliases   []Alias   `json:"aliases,omitempty" validate:"dive" gorm:"constraint:OnDelete:CASCADE;foreignKey:EntityID"`
+	CreatedAt time.Time `json:"created_at"`
+} //@name Entity
+
+// BeforeCreate generates the entity id
+func (m *Entity) BeforeCreate(tx *gorm.DB) (err error) {
+	if m.ID == uuid.Nil {
+		m.ID, err = uuid.NewV4()
+	}
+	return
+}
+
+// TableName defines default table name
+func (m Entity) TableName() string {
+	return "entities"
+}
+
+// Alias of an entity, matched against the normalized tags nickname
+type Alias struct {
+	Alias    string    `json:"alias" validate:"required,max=128" gorm:"primarykey"`
+	EntityID uuid.UUID `json:"-" gorm:"type:uuid;index;not null"`
+} //@name EntityAlias
+
+// BeforeSave normalizes the alias
+func (m *Alias) BeforeSave(tx *gorm.DB) (err error) {
+	m.Alias = NormalizeName(m.Alias)
+	return
+}
+
+// TableName defines default table name
+func (m Alias) TableName() string {
+	return "entity_aliases"
+}
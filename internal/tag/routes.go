@@ -1,18 +1,28 @@
 package tag
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/labstack/echo/v4"
+	"github.com/xn3cr0nx/bitgodine/internal/apikey"
+	"github.com/xn3cr0nx/bitgodine/internal/errorx"
+	"github.com/xn3cr0nx/bitgodine/internal/ratelimit"
 	"github.com/xn3cr0nx/bitgodine/pkg/validator"
 )
 
 // Routes mounts all /tags based routes on the main group
 func Routes(g *echo.Group, s Service) {
-	r := g.Group("/tags", validator.JWT())
+	r := g.Group("/tags", apikey.Auth(apikey.ReadTags), ratelimit.Middleware(ratelimit.Tags))
 
 	r.GET("", getTags(s))
-	r.POST("", createTag(s))
+	r.POST("", createTag(s), apikey.Auth(apikey.WriteTags), validator.Role(validator.Analyst))
+	r.PUT("/:id/verify", verifyTag(s), validator.Role(validator.Admin))
+	r.POST("/import", importTags(s), apikey.Auth(apikey.WriteTags), validator.Role(validator.Admin))
+	r.GET("/batches", getBatches(s), validator.Role(validator.Analyst))
+	r.DELETE("/batches/:id", rollbackBatch(s), apikey.Auth(apikey.WriteTags), validator.Role(validator.Admin))
+	r.GET("/entities", getEntities(s), validator.Role(validator.Analyst))
+	r.POST("/entities", createEntity(s), apikey.Auth(apikey.WriteTags), validator.Role(validator.Admin))
 	r.GET("/:address", getTagByAddress(s))
 	r.GET("/cluster/:address", getTaggedClusterByAddress(s))
 	r.GET("/cluster/:address/set", getTaggedClusterSetByAddress(s))
@@ -77,6 +87,14 @@ func createTag(s Service) func(echo.Context) error {
 		if err := validator.Struct(&c, b); err != nil {
 			return err
 		}
+		// only admins can vouch for a tag or set its confidence
+		if !validator.HasRole(c, validator.Admin) {
+			b.Verified = false
+			b.Confidence = 0
+		}
+		if b.Source == "" {
+			b.Source = "manual"
+		}
 
 		if err := s.CreateTag(b); err != nil {
 			return err
@@ -86,6 +104,48 @@ func createTag(s Service) func(echo.Context) error {
 	}
 }
 
+// verifyTag godoc
+// @ID verify-tag
+//
+// @Router /tags/{id}/verify [put]
+// @Summary Verify tag
+// @Description set the verified status of a tag
+// @Tags tags
+//
+// @Security ApiKeyAuth
+//
+// @Accept  json
+// @Produce  json
+//
+// @Param id path string true "tag id"
+// @Param verified query boolean false "Verified status, true by default"
+//
+// @Success 200 {string} ok
+// @Success 500 {string} string
+func verifyTag(s Service) func(echo.Context) error {
+	return func(c echo.Context) error {
+		id := c.Param("id")
+		if err := c.Echo().Validator.(*validator.CustomValidator).Var(id, "required,uuid"); err != nil {
+			return err
+		}
+
+		type Query struct {
+			Verified string `query:"verified" validate:"omitempty,oneof=true false"`
+		}
+		q := new(Query)
+		if err := validator.Struct(&c, q); err != nil {
+			return err
+		}
+		verified := q.Verified != "false"
+
+		if err := s.VerifyTag(id, verified); err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, "ok")
+	}
+}
+
 // getTagsByAddress godoc
 // @ID get-tags-by-address
 //
@@ -165,8 +225,8 @@ func getTaggedClusterByAddress(s Service) func(echo.Context) error {
 // @ID get-tagged-cluster-set-by-address
 //
 // @Router /tags/cluster/:address/set [get]
-// @Summary Get tag by address
-// @Description get list of tagged cluster set based on address param
+// @Summary Get cluster label by address
+// @Description get the label of the cluster the address belongs to, resolved from its tags with the conflicting entities
 // @Tags tags
 //
 // @Security ApiKeyAuth
@@ -176,7 +236,7 @@ func getTaggedClusterByAddress(s Service) func(echo.Context) error {
 //
 // @Param address path string true "address"
 //
-// @Success 200 {array} Model
+// @Success 200 {object} Resolution
 // @Success 500 {string} string
 func getTaggedClusterSetByAddress(s Service) func(echo.Context) error {
 	return func(c echo.Context) error {
@@ -185,11 +245,216 @@ func getTaggedClusterSetByAddress(s Service) func(echo.Context) error {
 			return err
 		}
 
-		clusters, err := s.GetTaggedClusterSet(address)
+		res, err := s.GetTaggedClusterSet(address)
 		if err != nil {
 			return err
 		}
 
-		return c.JSON(http.StatusOK, clusters)
+		return c.JSON(http.StatusOK, res)
+	}
+}
+
+// importTags godoc
+// @ID import-tags
+//
+// @Router /tags/import [post]
+// @Summary Import tags
+// @Description import the tags of a file as a new batch, skipping those already present with the same address, type and message
+// @Tags tags
+//
+// @Security ApiKeyAuth
+//
+// @Accept  multipart/form-data
+// @Produce  json
+//
+// @Param file formData file true "file to import"
+// @Param format formData string true "file format, ofac, csv, json or walletexplorer"
+// @Param source formData string true "source of the tags"
+// @Param type formData string false "type of the tags without one"
+// @Param address_column formData string false "address column or key, address by default"
+// @Param message_column formData string false "message column or key, message by default"
+// @Param nickname_column formData string false "nickname column or key, nickname by default"
+// @Param type_column formData string false "type column or key, type by default"
+// @Param link_column formData string false "link column or key, link by default"
+// @Param verified_column formData string false "verified column or key, verified by default"
+//
+// @Success 200 {object} Batch
+// @Success 500 {string} string
+func importTags(s Service) func(echo.Context) error {
+	return func(c echo.Context) error {
+		type Form struct {
+			Format   string `form:"format" validate:"required,oneof=ofac csv json walletexplorer"`
+			Source   string `form:"source" validate:"required,max=64"`
+			Type     string `form:"type" validate:"omitempty,max=64"`
+			Address  string `form:"address_column" validate:"omitempty"`
+			Message  string `form:"message_column" validate:"omitempty"`
+			Nickname string `form:"nickname_column" validate:"omitempty"`
+			TypeCol  string `form:"type_column" validate:"omitempty"`
+			Link     string `form:"link_column" validate:"omitempty"`
+			Verified string `form:"verified_column" validate:"omitempty"`
+		}
+		q := new(Form)
+		if err := validator.Struct(&c, q); err != nil {
+			return err
+		}
+		mapping := Mapping{
+			Address:  q.Address,
+			Message:  q.Message,
+			Nickname: q.Nickname,
+			Type:     q.TypeCol,
+			Link:     q.Link,
+			Verified: q.Verified,
+		}.WithDefaults()
+
+		header, err := c.FormFile("file")
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "missing file")
+		}
+		file, err := header.Open()
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		tags, err := Parse(file, q.Format, mapping, q.Type)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		batch := &Batch{Source: q.Source, Format: q.Format, File: header.Filename}
+		if err := s.ImportTags(batch, tags); err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, batch)
+	}
+}
+
+// getBatches godoc
+// @ID get-tag-batches
+//
+// @Router /tags/batches [get]
+// @Summary Get tag import batches
+// @Description get the tag import batches, most recent first
+// @Tags tags
+//
+// @Security ApiKeyAuth
+//
+// @Accept  json
+// @Produce  json
+//
+// @Param source query string false "Source"
+//
+// @Success 200 {array} Batch
+// @Success 500 {string} string
+func getBatches(s Service) func(echo.Context) error {
+	return func(c echo.Context) error {
+		type Query struct {
+			Source string `query:"source" validate:"omitempty"`
+		}
+		q := new(Query)
+		if err := validator.Struct(&c, q); err != nil {
+			return err
+		}
+
+		batches, err := s.GetBatches(q.Source)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, batches)
+	}
+}
+
+// rollbackBatch godoc
+// @ID rollback-tag-batch
+//
+// @Router /tags/batches/{id} [delete]
+// @Summary Rollback tag import batch
+// @Description delete the tags imported with the batch
+// @Tags tags
+//
+// @Security ApiKeyAuth
+//
+// @Accept  json
+// @Produce  json
+//
+// @Param id path string true "batch id"
+//
+// @Success 200 {object} map[string]int64
+// @Success 500 {string} string
+func rollbackBatch(s Service) func(echo.Context) error {
+	return func(c echo.Context) error {
+		id := c.Param("id")
+		if err := c.Echo().Validator.(*validator.CustomValidator).Var(id, "required,uuid"); err != nil {
+			return err
+		}
+
+		deleted, err := s.RollbackBatch(id)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, map[string]int64{"deleted": deleted})
+	}
+}
+
+// getEntities godoc
+// @ID get-tag-entities
+//
+// @Router /tags/entities [get]
+// @Summary Get entities
+// @Description get the entities cluster labels are resolved to, with their aliases
+// @Tags tags
+//
+// @Security ApiKeyAuth
+//
+// @Accept  json
+// @Produce  json
+//
+// @Success 200 {array} Entity
+// @Success 500 {string} string
+func getEntities(s Service) func(echo.Context) error {
+	return func(c echo.Context) error {
+		entities, err := s.GetEntities()
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, entities)
+	}
+}
+
+// createEntity godoc
+// @ID create-tag-entity
+//
+// @Router /tags/entities [post]
+// @Summary Create entity
+// @Description create a new entity with the aliases tag nicknames are matched with
+// @Tags tags
+//
+// @Security ApiKeyAuth
+//
+// @Accept  json
+// @Produce  json
+//
+// @Param model body Entity true "entity model"
+//
+// @Success 200 {object} Entity
+// @Success 500 {string} string
+func createEntity(s Service) func(echo.Context) error {
+	return func(c echo.Context) error {
+		e := new(Entity)
+		if err := validator.Struct(&c, e); err != nil {
+			return err
+		}
+
+		if err := s.CreateEntity(e); err != nil {
+			if errors.Is(err, errorx.ErrInvalidArgument) {
+				return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+			}
+			return err
+		}
+
+		return c.JSON(http.StatusOK, e)
 	}
 }
@@ -1,13 +1,18 @@
 package tag
 
 import (
+	"errors"
+	"fmt"
 	"os"
+	"time"
 
 	"github.com/fatih/color"
 	"github.com/fatih/structs"
+	"github.com/gofrs/uuid"
 	"github.com/olekukonko/tablewriter"
+	"gorm.io/gorm"
 
-	// "github.com/xn3cr0nx/bitgodine/internal/cluster"
+	"github.com/xn3cr0nx/bitgodine/internal/cluster"
 	"github.com/xn3cr0nx/bitgodine/internal/errorx"
 	"github.com/xn3cr0nx/bitgodine/internal/storage/db/postgres"
 	"github.com/xn3cr0nx/bitgodine/pkg/cache"
@@ -18,9 +23,15 @@ import (
 type Service interface {
 	GetTags(output bool) (tags []Model, err error)
 	CreateTag(t *Model) (err error)
+	VerifyTag(ID string, verified bool) (err error)
 	GetTag(address string, output bool) (tags []Model, err error)
 	GetTaggedCluster(address string) (clusters []TaggedCluster, err error)
-	GetTaggedClusterSet(address string) (clusters []Model, err error)
+	GetTaggedClusterSet(address string) (res Resolution, err error)
+	CreateEntity(e *Entity) (err error)
+	GetEntities() (entities []Entity, err error)
+	ImportTags(batch *Batch, tags []Model) (err error)
+	GetBatches(source string) (batches []Batch, err error)
+	RollbackBatch(ID string) (deleted int64, err error)
 }
 
 type service struct {
@@ -68,13 +79,34 @@ func (s *service) GetTags(output bool) (tags []Model, err error) {
 
 // CreateTag creates a new tag record
 func (s *service) CreateTag(t *Model) (err error) {
-	err = s.Repository.Model(&Model{}).Create(t).Error
+	if err = s.Repository.Model(&Model{}).Create(t).Error; err != nil {
+		return
+	}
+	err = s.touch(t.Address)
+	return
+}
+
+// VerifyTag sets the verified status of a tag
+func (s *service) VerifyTag(ID string, verified bool) (err error) {
+	res := s.Repository.Model(&Model{}).Where("id = ?", ID).Update("verified", verified)
+	if err = res.Error; err != nil {
+		return
+	}
+	if res.RowsAffected == 0 {
+		err = fmt.Errorf("tag %s %w", ID, errorx.ErrNotFound)
+		return
+	}
+	var addresses []string
+	if err = s.Repository.Model(&Model{}).Where("id = ?", ID).Pluck("address", &addresses).Error; err != nil {
+		return
+	}
+	err = s.touch(addresses...)
 	return
 }
 
 // GetTag retrieve tags related to passed address
 func (s *service) GetTag(address string, output bool) (tags []Model, err error) {
-	if err = s.Repository.Where("address = ?", address).Find(&tags).Error; err != nil {
+	if err = s.Repository.Where("address = ?", address).Where("expires_at IS NULL OR expires_at > ?", time.Now()).Find(&tags).Error; err != nil {
 		return
 	}
 
@@ -100,28 +132,244 @@ func (s *service) GetTaggedCluster(address string) (clusters []TaggedCluster, er
 	return
 }
 
-// GetTaggedClusterSet retrieves crossed data between clusters and tags
-func (s *service) GetTaggedClusterSet(address string) (clusters []Model, err error) {
-	if cached, ok := s.Cache.Get("ct_" + address); ok {
-		clusters = cached.([]Model)
+// clusterKey cache key of the resolved label of the cluster
+func clusterKey(cluster uint64) string {
+	return fmt.Sprintf("ct_%d", cluster)
+}
+
+// GetTaggedClusterSet resolves the label of the cluster the address belongs to from its materialized
+// labels, reporting the conflicting entities
+func (s *service) GetTaggedClusterSet(address string) (res Resolution, err error) {
+	clusters, err := cluster.Of(s.Repository.DB, []string{address})
+	if err != nil {
+		return
+	}
+	if len(clusters) == 0 {
+		res = ResolveLabels(nil, nil)
+		return
+	}
+	id := clusters[0]
+	if cached, ok := s.Cache.Get(clusterKey(id)); ok {
+		res = cached.(Resolution)
+		return
+	}
+
+	var labels []cluster.Label
+	if err = s.Repository.Where("cluster = ?", id).Find(&labels).Error; err != nil {
+		return
+	}
+	tags, _ := labelTags(labels)
+	entities, err := s.aliases(tags)
+	if err != nil {
+		return
+	}
+	res = ResolveLabels(labels, entities)
+
+	if !s.Cache.Set(clusterKey(id), res, 1) {
+		logger.Error("Cache", errorx.ErrCache, logger.Params{"address": address, "cluster": id})
+	}
+	return
+}
+
+// touch refreshes the labels of the clusters the tagged addresses belong to, invalidating
+// their cached labels
+func (s *service) touch(addresses ...string) (err error) {
+	clusters, err := cluster.Of(s.Repository.DB, addresses)
+	if err != nil || len(clusters) == 0 {
 		return
 	}
+	if err = cluster.NewService(s.Repository, s.Cache).RefreshLabels(clusters...); err != nil {
+		return
+	}
+	if s.Cache != nil {
+		for _, id := range clusters {
+			s.Cache.Del(clusterKey(id))
+		}
+	}
+	return
+}
 
-	err = s.Repository.Raw(`SELECT tags.message, tags.type FROM "tags" 
-		LEFT JOIN "clusters" 
-		ON tags.address=clusters.address 
-		WHERE clusters.cluster=(
-			SELECT cluster FROM clusters WHERE address = ? LIMIT 1
-		) GROUP BY tags.message, tags.type`, address).Scan(&clusters).Error
+// aliases returns the entities the tags nickname are alias of, by normalized alias
+func (s *service) aliases(tags []Model) (entities map[string]Entity, err error) {
+	entities = make(map[string]Entity)
+	var names []string
+	for _, t := range tags {
+		if name := NormalizeName(t.Nickname); name != "" {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return
+	}
 
-	if !s.Cache.Set("ct_"+address, clusters, 1) {
-		logger.Error("Cache", errorx.ErrCache, logger.Params{"address": address})
+	var aliases []Alias
+	if err = s.Repository.Where("alias IN ?", names).Find(&aliases).Error; err != nil {
+		return
+	}
+	ids := make([]uuid.UUID, 0, len(aliases))
+	for _, a := range aliases {
+		ids = append(ids, a.EntityID)
+	}
+	var list []Entity
+	if len(ids) > 0 {
+		if err = s.Repository.Where("id IN ?", ids).Find(&list).Error; err != nil {
+			return
+		}
+	}
+	byID := make(map[uuid.UUID]Entity, len(list))
+	for _, e := range list {
+		byID[e.ID] = e
+	}
+	for _, a := range aliases {
+		if e, ok := byID[a.EntityID]; ok {
+			entities[a.Alias] = e
+		}
 	}
 	return
 }
 
+// CreateEntity creates a new entity with its aliases, its own name included
+func (s *service) CreateEntity(e *Entity) (err error) {
+	name := NormalizeName(e.Name)
+	if name == "" {
+		return fmt.Errorf("%w: entity name %s", errorx.ErrInvalidArgument, e.Name)
+	}
+	own := false
+	for _, a := range e.Aliases {
+		own = own || NormalizeName(a.Alias) == name
+	}
+	if !own {
+		e.Aliases = append(e.Aliases, Alias{Alias: e.Name})
+	}
+	err = s.Repository.Create(e).Error
+	return
+}
+
+// GetEntities retrieves the entities with their aliases
+func (s *service) GetEntities() (entities []Entity, err error) {
+	err = s.Repository.Preload("Aliases").Order("name").Find(&entities).Error
+	return
+}
+
 // insert into tags (address, message, nickname, type) values ('18VaKMJciWuk61MjPraRouiAqvoPQmrCmc', 'test1', 'binance', 1);
 // insert into tags (address, message, nickname, type) values ('1CYxSkLRUqe3cpVDLa8u9UKdetyfEM5gby', 'test2', 'bitfinxe', 1);
 // insert into tags (address, message, nickname, type) values ('1vXfhQpD7adQuNePT3k3pnRKFjP58EdpC', 'test3', 'okex', 1);
 // insert into tags (address, message, nickname, type) values ('1FeexV6bAHb8ybZjqQMjJrcCrHGW9sb6uF', 'test4', 'jeez', 1);
 // insert into clusters (address, cluster) values ('1FeexV6bAHb8ybZjqQMjJrcCrHGW9sb6uF', 250538);
+
+// importChunk number of addresses looked up at once deduplicating an import
+const importChunk = 1000
+
+// Fresh returns the tags not already stored with the same address, type and message, nor repeated.
+// The tags already stored are seen again, their last seen time is updated
+func Fresh(db *gorm.DB, tags []Model) (fresh []Model, err error) {
+	unique := Dedupe(tags, nil)
+	for from := 0; from < len(unique); from += importChunk {
+		to := from + importChunk
+		if to > len(unique) {
+			to = len(unique)
+		}
+		incoming := make(map[string]bool, to-from)
+		addresses := make([]string, 0, to-from)
+		for i := range unique[from:to] {
+			incoming[key(&unique[from+i])] = true
+			addresses = append(addresses, unique[from+i].Address)
+		}
+		var existing []Model
+		if err = db.Select("id", "address", "type", "message").Where("address IN ?", addresses).Find(&existing).Error; err != nil {
+			return
+		}
+		var seen []uuid.UUID
+		for i := range existing {
+			if incoming[key(&existing[i])] {
+				seen = append(seen, existing[i].ID)
+			}
+		}
+		if len(seen) > 0 {
+			if err = db.Model(&Model{}).Where("id IN ?", seen).Update("last_seen", time.Now()).Error; err != nil {
+				return
+			}
+		}
+		fresh = append(fresh, Dedupe(unique[from:to], existing)...)
+	}
+	return
+}
+
+// ImportTags stores as a new batch the tags not already present with the same address, type and message.
+// Tags without a valid bitcoin address are rejected
+func (s *service) ImportTags(batch *Batch, tags []Model) (err error) {
+	tags, batch.Rejected = Validate(tags)
+	err = s.Repository.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(batch).Error; err != nil {
+			return err
+		}
+
+		fresh, err := Fresh(tx, tags)
+		if err != nil {
+			return err
+		}
+
+		for i := range fresh {
+			fresh[i].Source = batch.Source
+			fresh[i].BatchID = &batch.ID
+		}
+		if len(fresh) > 0 {
+			if err := tx.CreateInBatches(fresh, 500).Error; err != nil {
+				return err
+			}
+		}
+
+		batch.Imported = len(fresh)
+		batch.Duplicated = len(tags) - len(fresh)
+		return tx.Save(batch).Error
+	})
+	if err != nil {
+		return
+	}
+	logger.Info("Tags", "Tags imported", logger.Params{"batch": batch.ID.String(), "source": batch.Source, "imported": batch.Imported, "duplicated": batch.Duplicated, "rejected": batch.Rejected})
+
+	addresses := make([]string, 0, len(tags))
+	for _, t := range tags {
+		addresses = append(addresses, t.Address)
+	}
+	err = s.touch(addresses...)
+	return
+}
+
+// GetBatches returns the import batches, optionally filtered by source, most recent first
+func (s *service) GetBatches(source string) (batches []Batch, err error) {
+	query := s.Repository.Order("created_at desc")
+	if source != "" {
+		query = query.Where("source = ?", source)
+	}
+	err = query.Find(&batches).Error
+	return
+}
+
+// RollbackBatch deletes the tags imported with the batch
+func (s *service) RollbackBatch(ID string) (deleted int64, err error) {
+	var addresses []string
+	err = s.Repository.Transaction(func(tx *gorm.DB) error {
+		var batch Batch
+		if err := tx.Where("id = ?", ID).First(&batch).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("batch %s %w", ID, errorx.ErrNotFound)
+			}
+			return err
+		}
+		if err := tx.Model(&Model{}).Distinct("address").Where("batch_id = ?", batch.ID).Pluck("address", &addresses).Error; err != nil {
+			return err
+		}
+		res := tx.Unscoped().Where("batch_id = ?", batch.ID).Delete(&Model{})
+		if res.Error != nil {
+			return res.Error
+		}
+		deleted = res.RowsAffected
+		return tx.Model(&batch).Update("rolled_back", true).Error
+	})
+	if err != nil {
+		return
+	}
+	err = s.touch(addresses...)
+	return
+}
@@ -2,10 +2,13 @@ package trace
 
 // Cluster struct to classify the address in a certain cluster
 type Cluster struct {
-	Type     string `json:"type,omitempty"`
-	Message  string `json:"message,omitempty"`
-	Nickname string `json:"nickname,omitempty"`
-	Verified bool   `json:"verified,omitempty"`
+	Type       string   `json:"type,omitempty"`
+	Message    string   `json:"message,omitempty"`
+	Nickname   string   `json:"nickname,omitempty"`
+	Verified   bool     `json:"verified,omitempty"`
+	Confidence float64  `json:"confidence,omitempty"`
+	Conflicts  []string `json:"conflicts,omitempty"`
+	Risk       float64  `json:"risk,omitempty"`
 }
 
 // Trace between ouput and spending tx for tracing
@@ -29,4 +32,6 @@ type Next struct {
 type Flow struct {
 	Traces     []map[string]Trace `json:"traces"`
 	Occurences []string           `json:"occurences"`
+	Partial    bool               `json:"partial,omitempty"`
+	Cursor     string             `json:"cursor,omitempty"`
 }
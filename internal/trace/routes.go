@@ -1,17 +1,26 @@
 package trace
 
 import (
+	"errors"
 	"net/http"
+	"strings"
+	"time"
 
+	"github.com/xn3cr0nx/bitgodine/internal/apikey"
+	"github.com/xn3cr0nx/bitgodine/internal/errorx"
+	"github.com/xn3cr0nx/bitgodine/internal/ratelimit"
+	"github.com/xn3cr0nx/bitgodine/internal/tx"
 	"github.com/xn3cr0nx/bitgodine/pkg/validator"
 
 	"github.com/labstack/echo/v4"
+	"github.com/spf13/viper"
 )
 
 // Routes mounts /trace based routes on the main group
 func Routes(g *echo.Group, s Service) {
-	r := g.Group("/trace", validator.JWT())
+	r := g.Group("/trace", apikey.Auth(apikey.RunAnalysis), ratelimit.Middleware(ratelimit.Trace))
 	r.GET("/address/:address", trace(s))
+	r.GET("/taint/:source", taint(s))
 }
 
 // trace godoc
@@ -30,14 +39,22 @@ func Routes(g *echo.Group, s Service) {
 // @Param address path string true "Address"
 // @Param limit query int false "Limit"
 // @Param skip query int false "Skip"
+// @Param direction query string false "Tracing direction, backward returns the tree of funds sources" Enums(forward, backward)
+// @Param depth query int false "Max hops followed"
+// @Param nodes query int false "Max transactions followed, when reached the partial flow is returned with a cursor"
+// @Param cursor query string false "Cursor returned by a partial tracing to resume it"
 //
 // @Success 200 {object} Flow
 // @Success 500 {string} string
 func trace(s Service) func(echo.Context) error {
 	return func(c echo.Context) error {
 		type Query struct {
-			Limit int `query:"limit" validate:"omitempty,gt=0"`
-			Skip  int `query:"skip" validate:"omitempty,gte=0"`
+			Limit     int    `query:"limit" validate:"omitempty,gt=0"`
+			Skip      int    `query:"skip" validate:"omitempty,gte=0"`
+			Direction string `query:"direction" validate:"omitempty,oneof=forward backward"`
+			Depth     int    `query:"depth" validate:"omitempty,gt=0,lte=20"`
+			Nodes     int    `query:"nodes" validate:"omitempty,gt=0"`
+			Cursor    string `query:"cursor" validate:"omitempty,base64url"`
 		}
 		q := new(Query)
 		if err := validator.Struct(&c, q); err != nil {
@@ -52,8 +69,115 @@ func trace(s Service) func(echo.Context) error {
 			return err
 		}
 
-		res, err := s.TraceAddress(address, q.Limit, q.Skip)
+		params := &TraceParams{
+			MaxDepth: q.Depth,
+			MaxNodes: q.Nodes,
+			Workers:  viper.GetInt("server.trace.workers"),
+			Timeout:  viper.GetDuration("server.trace.timeout"),
+			Cursor:   q.Cursor,
+		}
+		if max := viper.GetInt("server.trace.nodes"); max > 0 && (params.MaxNodes == 0 || params.MaxNodes > max) {
+			params.MaxNodes = max
+		}
+
+		if q.Direction == string(Backward) {
+			res, err := s.TraceAddressSources(c.Request().Context(), address, q.Limit, q.Skip, params)
+			if err != nil {
+				return err
+			}
+			return c.JSON(http.StatusOK, res)
+		}
+
+		// the request context is canceled when the client disconnects
+		res, err := s.TraceAddress(c.Request().Context(), address, q.Limit, q.Skip, params)
+		if err != nil {
+			if errors.Is(err, errorx.ErrInvalidArgument) {
+				return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+			}
+			return err
+		}
+		return c.JSON(http.StatusOK, res)
+	}
+}
+
+// taint godoc
+// @ID taint
+//
+// @Router /trace/taint/{source} [get]
+// @Summary Taint analysis
+// @Description propagate taint from an address, a transaction or an outpoint (txid:vout) returning the tainted fraction reaching outputs and clusters
+// @Tags trace
+//
+// @Security ApiKeyAuth
+//
+// @Accept  json
+// @Produce  json
+//
+// @Param source path string true "Address, transaction hash or outpoint"
+// @Param model query string false "Taint model" Enums(poison, haircut, fifo, tiho)
+// @Param direction query string false "Taint direction" Enums(forward, backward)
+// @Param depth query int false "Max depth"
+// @Param min_value query int false "Min tainted value in satoshi to keep following an output"
+// @Param min_fraction query number false "Min tainted fraction to keep following an output"
+// @Param from query int false "Ignore transactions before this unix timestamp"
+// @Param to query int false "Ignore transactions after this unix timestamp"
+// @Param nodes query int false "Max transactions loaded, when reached the partial taint is returned"
+//
+// @Success 200 {object} Taint
+// @Success 500 {string} string
+func taint(s Service) func(echo.Context) error {
+	return func(c echo.Context) error {
+		type Query struct {
+			Model       string  `query:"model" validate:"omitempty,oneof=poison haircut fifo tiho"`
+			Direction   string  `query:"direction" validate:"omitempty,oneof=forward backward"`
+			Depth       int     `query:"depth" validate:"omitempty,gt=0,lte=100"`
+			MinValue    int64   `query:"min_value" validate:"omitempty,gte=0"`
+			MinFraction float64 `query:"min_fraction" validate:"omitempty,gte=0,lte=1"`
+			From        int64   `query:"from" validate:"omitempty,gte=0"`
+			To          int64   `query:"to" validate:"omitempty,gtefield=From"`
+			Nodes       int     `query:"nodes" validate:"omitempty,gt=0"`
+		}
+		q := new(Query)
+		if err := validator.Struct(&c, q); err != nil {
+			return err
+		}
+
+		source := c.Param("source")
+		if strings.Contains(source, ":") {
+			if _, _, err := ParseOutpoint(source); err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+			}
+		} else if !tx.IsID(source) {
+			if err := c.Echo().Validator.(*validator.CustomValidator).Var(source, "required,btc_addr|btc_addr_bech32"); err != nil {
+				return err
+			}
+		}
+
+		params := &TaintParams{
+			Model:       Model(q.Model),
+			Direction:   Direction(q.Direction),
+			MaxDepth:    q.Depth,
+			MaxNodes:    q.Nodes,
+			Workers:     viper.GetInt("server.trace.workers"),
+			Timeout:     viper.GetDuration("server.trace.timeout"),
+			MinValue:    q.MinValue,
+			MinFraction: q.MinFraction,
+		}
+		if q.From > 0 {
+			params.From = time.Unix(q.From, 0)
+		}
+		if q.To > 0 {
+			params.To = time.Unix(q.To, 0)
+		}
+		if max := viper.GetInt("server.trace.nodes"); max > 0 && (params.MaxNodes == 0 || params.MaxNodes > max) {
+			params.MaxNodes = max
+		}
+
+		res, err := s.Taint(c.Request().Context(), source, params)
 		if err != nil {
+			if errors.Is(err, errorx.ErrInvalidArgument) {
+				return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+			}
 			return err
 		}
 		return c.JSON(http.StatusOK, res)
@@ -0,0 +1,212 @@
+package trace
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/xn3cr0nx/bitgodine/internal/address"
+	"github.com/xn3cr0nx/bitgodine/internal/errorx"
+	"github.com/xn3cr0nx/bitgodine/internal/tx"
+)
+
+// DefaultSourcesDepth default number of hops followed backward looking for funds sources
+const DefaultSourcesDepth = 3
+
+// Reasons why backward tracing stopped on a source
+const (
+	StopCoinbase = "coinbase"
+	StopTagged   = "tagged"
+	StopAbuse    = "abuse"
+	StopDepth    = "depth"
+	StopVisited  = "visited"
+	StopLimit    = "limit"
+)
+
+// Source output funding the traced address, directly or through previous hops
+type Source struct {
+	TxID     string    `json:"txid"`
+	Vout     uint32    `json:"vout"`
+	Address  string    `json:"address"`
+	Amount   float64   `json:"amount"`
+	Clusters []Cluster `json:"clusters"`
+	Stop     string    `json:"stop,omitempty"`
+	Sources  []Source  `json:"sources,omitempty"`
+}
+
+// SourceTree backward tracing result, the tree of outputs funding the address
+type SourceTree struct {
+	Address    string   `json:"address"`
+	Sources    []Source `json:"sources"`
+	Occurences []string `json:"occurences"`
+	Partial    bool     `json:"partial,omitempty"`
+}
+
+// expansion source node still to be expanded with the outputs spent by the transaction that created it
+type expansion struct {
+	node        *Source
+	transaction tx.Tx
+	depth       int
+	children    []Source
+	spent       []tx.Tx
+	done        bool
+}
+
+// TraceAddressSources follows funds backward from the outputs received by the address, walking the inputs
+// of the funding transactions up to depth hops. Tracing stops at coinbase transactions and at tagged
+// or abuse reported clusters. Sources not expanded within the nodes limit or the deadline are marked
+// with the limit stop and the tree as partial
+func (s *service) TraceAddressSources(ctx context.Context, addr string, limit, skip int, params *TraceParams) (tree *SourceTree, err error) {
+	if params.MaxDepth == 0 {
+		params.MaxDepth = DefaultSourcesDepth
+	}
+	if params.MaxNodes == 0 {
+		params.MaxNodes = DefaultTraceNodes
+	}
+	if params.Workers == 0 {
+		params.Workers = DefaultTraceWorkers
+	}
+	if params.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, params.Timeout)
+		defer cancel()
+	}
+
+	occurences, err := address.NewService(s.Kv, s.Cache).GetOccurences(addr)
+	if err != nil {
+		return
+	}
+	tree = &SourceTree{
+		Address:    addr,
+		Sources:    []Source{},
+		Occurences: occurences,
+	}
+
+	txService := tx.NewService(s.Kv, s.Cache)
+	visited := make(map[string]bool)
+	var funding []tx.Tx
+	for i, occurence := range occurences {
+		if i < limit*skip || i > skip*limit+(limit-1) {
+			continue
+		}
+		transaction, e := txService.GetFromHash(occurence)
+		if e != nil {
+			err = e
+			return
+		}
+		// only transactions funding the address, the others are spending from it
+		for o, out := range transaction.Vout {
+			if out.ScriptpubkeyAddress != addr {
+				continue
+			}
+			root := Source{
+				TxID:    transaction.TxID,
+				Vout:    uint32(o),
+				Address: addr,
+				Amount:  satToBtc(out.Value),
+			}
+			if root.Clusters, _, _, err = s.labels(addr); err != nil {
+				return
+			}
+			visited[fmt.Sprintf("%s:%d", root.TxID, root.Vout)] = true
+			tree.Sources = append(tree.Sources, root)
+			funding = append(funding, transaction)
+		}
+	}
+
+	// sources are expanded level by level, pointers are taken once their parent slice is complete
+	var frontier []*expansion
+	for i := range tree.Sources {
+		frontier = append(frontier, &expansion{node: &tree.Sources[i], transaction: funding[i]})
+	}
+	nodes := 0
+	for len(frontier) > 0 && nodes < params.MaxNodes && ctx.Err() == nil {
+		size := len(frontier)
+		if size > params.MaxNodes-nodes {
+			size = params.MaxNodes - nodes
+		}
+		if err = pool(ctx, size, params.Workers, func(i int) error {
+			return s.expandSources(frontier[i], txService, params.MaxDepth)
+		}); err != nil {
+			return nil, err
+		}
+
+		var next []*expansion
+		for i, e := range frontier {
+			if i >= size || !e.done {
+				next = append(next, e)
+				continue
+			}
+			nodes++
+			if len(e.children) == 0 {
+				continue
+			}
+			e.node.Sources = e.children
+			for j := range e.node.Sources {
+				child := &e.node.Sources[j]
+				key := fmt.Sprintf("%s:%d", child.TxID, child.Vout)
+				if child.Stop != "" {
+					continue
+				}
+				if visited[key] {
+					child.Stop = StopVisited
+					continue
+				}
+				visited[key] = true
+				next = append(next, &expansion{node: child, transaction: e.spent[j], depth: e.depth + 1})
+			}
+		}
+		frontier = next
+	}
+
+	for _, e := range frontier {
+		e.node.Stop = StopLimit
+		tree.Partial = true
+	}
+	return
+}
+
+// expandSources retrieves the outputs spent by the transaction that created the source node
+func (s *service) expandSources(e *expansion, txService tx.Service, maxDepth int) (err error) {
+	if len(e.transaction.Vin) > 0 && e.transaction.Vin[0].IsCoinbase {
+		e.node.Stop = StopCoinbase
+		e.done = true
+		return
+	}
+	if e.depth >= maxDepth {
+		e.node.Stop = StopDepth
+		e.done = true
+		return
+	}
+
+	for _, in := range e.transaction.Vin {
+		spent, err := txService.GetFromHash(in.TxID)
+		if err != nil {
+			return err
+		}
+		if int(in.Vout) >= len(spent.Vout) {
+			return fmt.Errorf("%w: output %d of %s", errorx.ErrOutOfRange, in.Vout, in.TxID)
+		}
+		out := spent.Vout[in.Vout]
+		child := Source{
+			TxID:    in.TxID,
+			Vout:    in.Vout,
+			Address: out.ScriptpubkeyAddress,
+			Amount:  satToBtc(out.Value),
+		}
+		clusters, tagged, abused, err := s.labels(out.ScriptpubkeyAddress)
+		if err != nil {
+			return err
+		}
+		child.Clusters = clusters
+		switch {
+		case abused:
+			child.Stop = StopAbuse
+		case tagged:
+			child.Stop = StopTagged
+		}
+		e.children = append(e.children, child)
+		e.spent = append(e.spent, spent)
+	}
+	e.done = true
+	return
+}
@@ -0,0 +1,46 @@
+package trace
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (suite *TestTaintSuite) TestTraceAddressSources() {
+	tree, err := suite.service.TraceAddressSources(context.Background(), "1Big", 5, 0, &TraceParams{})
+	require.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "1Big", tree.Address)
+	require.Len(suite.T(), tree.Sources, 1)
+
+	root := tree.Sources[0]
+	assert.Equal(suite.T(), suite.mixing.TxID, root.TxID)
+	assert.Equal(suite.T(), 0.0015, root.Amount)
+	assert.Empty(suite.T(), root.Stop)
+	require.Len(suite.T(), root.Sources, 2)
+	for i, source := range root.Sources {
+		assert.Equal(suite.T(), suite.mixing.Vin[i].TxID, source.TxID)
+		assert.Equal(suite.T(), 0.001, source.Amount)
+		assert.Equal(suite.T(), StopCoinbase, source.Stop)
+		assert.Empty(suite.T(), source.Sources)
+	}
+	assert.Equal(suite.T(), "1Source", root.Sources[0].Address)
+}
+
+func (suite *TestTaintSuite) TestTraceAddressSourcesBounds() {
+	tree, err := suite.service.TraceAddressSources(context.Background(), "1Big", 5, 0, &TraceParams{MaxNodes: 1})
+	require.Nil(suite.T(), err)
+	assert.True(suite.T(), tree.Partial)
+	require.Len(suite.T(), tree.Sources, 1)
+	require.Len(suite.T(), tree.Sources[0].Sources, 2)
+	for _, source := range tree.Sources[0].Sources {
+		assert.Equal(suite.T(), StopLimit, source.Stop)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	tree, err = suite.service.TraceAddressSources(ctx, "1Big", 5, 0, &TraceParams{})
+	require.Nil(suite.T(), err)
+	assert.True(suite.T(), tree.Partial)
+	assert.Equal(suite.T(), StopLimit, tree.Sources[0].Stop)
+}
@@ -0,0 +1,596 @@
+package trace
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xn3cr0nx/bitgodine/internal/address"
+	"github.com/xn3cr0nx/bitgodine/internal/block"
+	"github.com/xn3cr0nx/bitgodine/internal/cluster"
+	"github.com/xn3cr0nx/bitgodine/internal/errorx"
+	"github.com/xn3cr0nx/bitgodine/internal/tx"
+)
+
+// Model taint propagation model, defining how taint flows from the inputs to the outputs of a transaction
+type Model string
+
+const (
+	// Poison every output of a transaction receiving tainted funds is fully tainted
+	Poison Model = "poison"
+	// Haircut taint is spread among outputs proportionally to the tainted share of the inputs
+	Haircut Model = "haircut"
+	// FIFO tainted satoshis are matched in order from inputs to outputs (first in first out)
+	FIFO Model = "fifo"
+	// TIHO taint in highest out, taint is assigned to the highest value outputs first
+	TIHO Model = "tiho"
+)
+
+// Direction taint propagation direction
+type Direction string
+
+const (
+	// Forward follows tainted funds through spending transactions
+	Forward Direction = "forward"
+	// Backward follows tainted funds through funding transactions
+	Backward Direction = "backward"
+)
+
+// DefaultTaintDepth default maximum number of hops followed by the taint engine
+const DefaultTaintDepth = 10
+
+// TaintParams thresholds and options for the taint engine
+type TaintParams struct {
+	Model       Model
+	Direction   Direction
+	MaxDepth    int
+	MaxNodes    int
+	Workers     int
+	Timeout     time.Duration
+	MinValue    int64
+	MinFraction float64
+	From        time.Time
+	To          time.Time
+}
+
+// TaintedOutput tainted amount reaching an output
+type TaintedOutput struct {
+	TxID     string  `json:"txid"`
+	Vout     uint32  `json:"vout"`
+	Address  string  `json:"address"`
+	Value    int64   `json:"value"`
+	Tainted  int64   `json:"tainted"`
+	Fraction float64 `json:"fraction"`
+	Depth    int     `json:"depth"`
+	Cluster  *uint64 `json:"cluster,omitempty"`
+}
+
+// TaintedCluster tainted amount reaching a cluster
+type TaintedCluster struct {
+	Cluster  uint64  `json:"cluster"`
+	Value    int64   `json:"value"`
+	Tainted  int64   `json:"tainted"`
+	Fraction float64 `json:"fraction"`
+	Outputs  int     `json:"outputs"`
+}
+
+// Taint result of the taint analysis
+type Taint struct {
+	Source    string           `json:"source"`
+	Model     Model            `json:"model"`
+	Direction Direction        `json:"direction"`
+	Tainted   int64            `json:"tainted"`
+	Outputs   []TaintedOutput  `json:"outputs"`
+	Clusters  []TaintedCluster `json:"clusters"`
+	Partial   bool             `json:"partial,omitempty"`
+}
+
+type outpoint struct {
+	txid string
+	vout uint32
+}
+
+func (o outpoint) String() string {
+	return fmt.Sprintf("%s:%d", o.txid, o.vout)
+}
+
+// delta new taint reaching an outpoint
+type delta struct {
+	outpoint
+	amount int64
+}
+
+// tainting transaction reached by the taint, loaded with the outputs spent by its inputs
+type tainting struct {
+	transaction tx.Tx
+	prevouts    []tx.Output
+	skip        bool
+}
+
+// ParseOutpoint parses an outpoint in the txid:vout format
+func ParseOutpoint(text string) (txid string, vout uint32, err error) {
+	parts := strings.Split(text, ":")
+	if len(parts) != 2 || !tx.IsID(parts[0]) {
+		err = fmt.Errorf("%w: outpoint must be in txid:vout format", errorx.ErrInvalidArgument)
+		return
+	}
+	v, err := strconv.ParseUint(parts[1], 10, 32)
+	if err != nil {
+		err = fmt.Errorf("%w: %s", errorx.ErrInvalidArgument, err.Error())
+		return
+	}
+	txid, vout = parts[0], uint32(v)
+	return
+}
+
+// Distribute spreads the taint of the sources over the targets based on the model.
+// Sources are the inputs and targets the outputs when going forward, the other way around backward.
+// Taint that doesn't reach any target (e.g. the fee) is lost.
+func Distribute(model Model, sources, taint, targets []int64) (distributed []int64) {
+	distributed = make([]int64, len(targets))
+	var total, sourcesValue, targetsValue int64
+	for i := range sources {
+		total += taint[i]
+		sourcesValue += sources[i]
+	}
+	for _, v := range targets {
+		targetsValue += v
+	}
+	if total <= 0 {
+		return
+	}
+
+	switch model {
+	case Poison:
+		copy(distributed, targets)
+	case Haircut:
+		base := sourcesValue
+		if targetsValue > base {
+			base = targetsValue
+		}
+		if base == 0 {
+			return
+		}
+		for j, v := range targets {
+			distributed[j] = int64(float64(v) * float64(total) / float64(base))
+		}
+	case FIFO:
+		// the tainted part of each source is considered to be its first satoshis
+		type interval struct{ start, end int64 }
+		var tainted []interval
+		var pos int64
+		for i, v := range sources {
+			if taint[i] > 0 {
+				t := taint[i]
+				if t > v {
+					t = v
+				}
+				tainted = append(tainted, interval{pos, pos + t})
+			}
+			pos += v
+		}
+		pos = 0
+		for j, v := range targets {
+			for _, r := range tainted {
+				start, end := r.start, r.end
+				if pos > start {
+					start = pos
+				}
+				if pos+v < end {
+					end = pos + v
+				}
+				if end > start {
+					distributed[j] += end - start
+				}
+			}
+			pos += v
+		}
+	case TIHO:
+		order := make([]int, len(targets))
+		for j := range order {
+			order[j] = j
+		}
+		sort.SliceStable(order, func(a, b int) bool {
+			return targets[order[a]] > targets[order[b]]
+		})
+		remaining := total
+		for _, j := range order {
+			if remaining <= 0 {
+				break
+			}
+			amount := targets[j]
+			if amount > remaining {
+				amount = remaining
+			}
+			distributed[j] = amount
+			remaining -= amount
+		}
+	}
+	return
+}
+
+// Taint propagates taint from an address, the outputs of a transaction or an outpoint (txid:vout) based on the
+// chosen model and direction, returning the tainted amount reaching each output and cluster. Propagation is
+// bounded by depth, number of transactions loaded and context deadline, the result is marked partial when the
+// taint is cut by the last two
+func (s *service) Taint(ctx context.Context, source string, params *TaintParams) (taint *Taint, err error) {
+	if params.Model == "" {
+		params.Model = Haircut
+	}
+	if params.Direction == "" {
+		params.Direction = Forward
+	}
+	if params.MaxDepth == 0 {
+		params.MaxDepth = DefaultTaintDepth
+	}
+	if params.MaxNodes == 0 {
+		params.MaxNodes = DefaultTraceNodes
+	}
+	if params.Workers == 0 {
+		params.Workers = DefaultTraceWorkers
+	}
+	if params.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, params.Timeout)
+		defer cancel()
+	}
+
+	sources, err := s.taintSources(source)
+	if err != nil {
+		return
+	}
+
+	taint = &Taint{
+		Source:    source,
+		Model:     params.Model,
+		Direction: params.Direction,
+	}
+	tainted := make(map[outpoint]*TaintedOutput)
+	var frontier []delta
+	for _, src := range sources {
+		taint.Tainted += src.Value
+		o := outpoint{src.TxID, src.Vout}
+		tainted[o] = src
+		frontier = append(frontier, delta{o, src.Value})
+	}
+
+	txService := tx.NewService(s.Kv, s.Cache)
+	nodes, depth := 0, 0
+	for ; len(frontier) > 0 && depth < params.MaxDepth && nodes < params.MaxNodes && ctx.Err() == nil; depth++ {
+		// forward taint reaches the transactions spending the tainted outputs
+		spending := make([]*tx.Tx, len(frontier))
+		if params.Direction == Forward {
+			err = pool(ctx, len(frontier), params.Workers, func(i int) error {
+				t, e := txService.GetSpendingFromHash(frontier[i].txid, frontier[i].vout)
+				if e != nil {
+					if errors.Is(e, errorx.ErrKeyNotFound) {
+						return nil
+					}
+					return e
+				}
+				spending[i] = &t
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+			if ctx.Err() != nil {
+				break
+			}
+		}
+
+		// group taint reaching the same transaction, indexed by input (forward) or output (backward)
+		next := make(map[string]map[uint32]int64)
+		var order []string
+		for i, d := range frontier {
+			txid, index := d.txid, d.vout
+			if params.Direction == Forward {
+				if spending[i] == nil {
+					continue
+				}
+				txid = spending[i].TxID
+				for k, in := range spending[i].Vin {
+					if in.TxID == d.txid && in.Vout == d.vout {
+						index = uint32(k)
+					}
+				}
+			}
+			if _, ok := next[txid]; !ok {
+				next[txid] = make(map[uint32]int64)
+				order = append(order, txid)
+			}
+			next[txid][index] += d.amount
+		}
+		// the taint reaching transactions beyond the nodes limit is not followed
+		if len(order) > params.MaxNodes-nodes {
+			order = order[:params.MaxNodes-nodes]
+			taint.Partial = true
+		}
+
+		loaded := make([]*tainting, len(order))
+		err = pool(ctx, len(order), params.Workers, func(i int) error {
+			transaction, e := txService.GetFromHash(order[i])
+			if e != nil {
+				return e
+			}
+			if params.Direction == Backward && len(transaction.Vin) > 0 && transaction.Vin[0].IsCoinbase {
+				loaded[i] = &tainting{skip: true}
+				return nil
+			}
+			if ok, e := s.inTimeRange(order[i], params); e != nil || !ok {
+				loaded[i] = &tainting{skip: true}
+				return nil
+			}
+			prevouts, e := s.prevouts(&transaction)
+			if e != nil {
+				return e
+			}
+			loaded[i] = &tainting{transaction: transaction, prevouts: prevouts}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		frontier = nil
+		for i, txid := range order {
+			if loaded[i] == nil {
+				// not loaded before the context was done
+				taint.Partial = true
+				continue
+			}
+			nodes++
+			if loaded[i].skip {
+				continue
+			}
+			transaction, prevouts := loaded[i].transaction, loaded[i].prevouts
+
+			outputs := make([]int64, len(transaction.Vout))
+			for o, out := range transaction.Vout {
+				outputs[o] = out.Value
+			}
+			inputs := make([]int64, len(prevouts))
+			for k, prev := range prevouts {
+				inputs[k] = prev.Value
+			}
+
+			sources, targets := inputs, outputs
+			if params.Direction == Backward {
+				sources, targets = outputs, inputs
+			}
+			vector := make([]int64, len(sources))
+			for index, amount := range next[txid] {
+				vector[index] = amount
+			}
+
+			for j, amount := range Distribute(params.Model, sources, vector, targets) {
+				if amount <= 0 {
+					continue
+				}
+				o := outpoint{transaction.TxID, uint32(j)}
+				out := transaction.Vout[j]
+				if params.Direction == Backward {
+					o = outpoint{transaction.Vin[j].TxID, transaction.Vin[j].Vout}
+					out = prevouts[j]
+				}
+
+				t, ok := tainted[o]
+				if !ok {
+					t = &TaintedOutput{
+						TxID:    o.txid,
+						Vout:    o.vout,
+						Address: out.ScriptpubkeyAddress,
+						Value:   out.Value,
+						Depth:   depth + 1,
+					}
+					tainted[o] = t
+				}
+				if t.Tainted+amount > t.Value {
+					amount = t.Value - t.Tainted
+				}
+				t.Tainted += amount
+				if t.Value > 0 {
+					t.Fraction = float64(t.Tainted) / float64(t.Value)
+				}
+
+				if amount <= 0 || amount < params.MinValue || t.Fraction < params.MinFraction {
+					continue
+				}
+				frontier = append(frontier, delta{o, amount})
+			}
+		}
+	}
+	// taint still pending below the depth limit was cut by the nodes limit or the deadline
+	if len(frontier) > 0 && depth < params.MaxDepth {
+		taint.Partial = true
+	}
+
+	for _, t := range tainted {
+		taint.Outputs = append(taint.Outputs, *t)
+	}
+	sort.Slice(taint.Outputs, func(i, j int) bool {
+		if taint.Outputs[i].Depth != taint.Outputs[j].Depth {
+			return taint.Outputs[i].Depth < taint.Outputs[j].Depth
+		}
+		if taint.Outputs[i].TxID != taint.Outputs[j].TxID {
+			return taint.Outputs[i].TxID < taint.Outputs[j].TxID
+		}
+		return taint.Outputs[i].Vout < taint.Outputs[j].Vout
+	})
+
+	err = s.taintClusters(taint)
+	return
+}
+
+// taintSources returns the outputs the taint starts from, all the outputs received by the address, all the
+// outputs of the transaction or the passed outpoint
+func (s *service) taintSources(source string) (sources []*TaintedOutput, err error) {
+	txService := tx.NewService(s.Kv, s.Cache)
+	if strings.Contains(source, ":") || tx.IsID(source) {
+		txid, vout, all := source, uint32(0), true
+		if strings.Contains(source, ":") {
+			if txid, vout, err = ParseOutpoint(source); err != nil {
+				return
+			}
+			all = false
+		}
+		transaction, e := txService.GetFromHash(txid)
+		if e != nil {
+			err = e
+			return
+		}
+		if !all && int(vout) >= len(transaction.Vout) {
+			err = fmt.Errorf("%w: output %d of %s", errorx.ErrOutOfRange, vout, txid)
+			return
+		}
+		for o, out := range transaction.Vout {
+			if !all && uint32(o) != vout {
+				continue
+			}
+			sources = append(sources, &TaintedOutput{
+				TxID:     txid,
+				Vout:     uint32(o),
+				Address:  out.ScriptpubkeyAddress,
+				Value:    out.Value,
+				Tainted:  out.Value,
+				Fraction: 1,
+			})
+		}
+		return
+	}
+
+	occurences, err := address.NewService(s.Kv, s.Cache).GetOccurences(source)
+	if err != nil {
+		return
+	}
+	for _, occurence := range occurences {
+		transaction, e := txService.GetFromHash(occurence)
+		if e != nil {
+			err = e
+			return
+		}
+		for o, out := range transaction.Vout {
+			if out.ScriptpubkeyAddress != source {
+				continue
+			}
+			sources = append(sources, &TaintedOutput{
+				TxID:     transaction.TxID,
+				Vout:     uint32(o),
+				Address:  out.ScriptpubkeyAddress,
+				Value:    out.Value,
+				Tainted:  out.Value,
+				Fraction: 1,
+			})
+		}
+	}
+	if len(sources) == 0 {
+		err = fmt.Errorf("%w: no outputs received by %s", errorx.ErrNotFound, source)
+	}
+	return
+}
+
+// prevouts returns the outputs spent by the transaction inputs
+func (s *service) prevouts(transaction *tx.Tx) (prevouts []tx.Output, err error) {
+	txService := tx.NewService(s.Kv, s.Cache)
+	prevouts = make([]tx.Output, len(transaction.Vin))
+	for i, in := range transaction.Vin {
+		if in.IsCoinbase {
+			continue
+		}
+		spent, e := txService.GetFromHash(in.TxID)
+		if e != nil {
+			err = e
+			return
+		}
+		if int(in.Vout) >= len(spent.Vout) {
+			err = fmt.Errorf("%w: output %d of %s", errorx.ErrOutOfRange, in.Vout, in.TxID)
+			return
+		}
+		prevouts[i] = spent.Vout[in.Vout]
+	}
+	return
+}
+
+// inTimeRange checks the transaction block time is included in the time thresholds
+func (s *service) inTimeRange(txid string, params *TaintParams) (ok bool, err error) {
+	if params.From.IsZero() && params.To.IsZero() {
+		return true, nil
+	}
+	blockService := block.NewService(s.Kv, s.Cache)
+	height, err := blockService.GetTxBlockHeight(txid)
+	if err != nil {
+		return
+	}
+	b, err := blockService.ReadFromHeight(height)
+	if err != nil {
+		return
+	}
+	if !params.From.IsZero() && b.Timestamp.Before(params.From) {
+		return
+	}
+	if !params.To.IsZero() && b.Timestamp.After(params.To) {
+		return
+	}
+	return true, nil
+}
+
+// taintClusters aggregates the tainted outputs by cluster
+func (s *service) taintClusters(taint *Taint) (err error) {
+	if s.Repository == nil || s.Repository.DB == nil {
+		return
+	}
+	addresses := make(map[string]struct{})
+	var list []string
+	for _, out := range taint.Outputs {
+		if out.Address == "" {
+			continue
+		}
+		if _, ok := addresses[out.Address]; !ok {
+			addresses[out.Address] = struct{}{}
+			list = append(list, out.Address)
+		}
+	}
+	if len(list) == 0 {
+		return
+	}
+
+	var clusters []cluster.Model
+	if err = s.Repository.Where("address IN ?", list).Find(&clusters).Error; err != nil {
+		return
+	}
+	membership := make(map[string]uint64, len(clusters))
+	for _, c := range clusters {
+		membership[c.Address] = c.Cluster
+	}
+
+	aggregate := make(map[uint64]*TaintedCluster)
+	var order []uint64
+	for i, out := range taint.Outputs {
+		id, ok := membership[out.Address]
+		if !ok {
+			continue
+		}
+		taint.Outputs[i].Cluster = &id
+		c, ok := aggregate[id]
+		if !ok {
+			c = &TaintedCluster{Cluster: id}
+			aggregate[id] = c
+			order = append(order, id)
+		}
+		c.Value += out.Value
+		c.Tainted += out.Tainted
+		c.Outputs++
+	}
+	for _, id := range order {
+		c := aggregate[id]
+		if c.Value > 0 {
+			c.Fraction = float64(c.Tainted) / float64(c.Value)
+		}
+		taint.Clusters = append(taint.Clusters, *c)
+	}
+	return
+}
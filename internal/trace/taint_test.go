@@ -0,0 +1,175 @@
+package trace
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/xn3cr0nx/bitgodine/internal/errorx"
+	"github.com/xn3cr0nx/bitgodine/internal/storage/kv"
+	"github.com/xn3cr0nx/bitgodine/internal/storage/kv/iterator"
+	"github.com/xn3cr0nx/bitgodine/internal/tx"
+	"github.com/xn3cr0nx/bitgodine/pkg/cache"
+	"github.com/xn3cr0nx/bitgodine/pkg/encoding"
+	"github.com/xn3cr0nx/bitgodine/pkg/logger"
+)
+
+type TestTaintSuite struct {
+	suite.Suite
+	db      *kv.DBMock
+	service *service
+	source  tx.Tx
+	clean   tx.Tx
+	mixing  tx.Tx
+}
+
+func txid(c string) string {
+	return strings.Repeat(c, 64)
+}
+
+func (suite *TestTaintSuite) SetupSuite() {
+	logger.Setup()
+
+	c, err := cache.NewCache(nil)
+	require.Nil(suite.T(), err)
+	suite.db = kv.NewDBMock()
+	suite.service = NewService(nil, suite.db, c)
+
+	suite.source = tx.Tx{
+		TxID: txid("a"),
+		Vin:  []tx.Input{{IsCoinbase: true}},
+		Vout: []tx.Output{{ScriptpubkeyAddress: "1Source", Value: 100000}},
+	}
+	suite.clean = tx.Tx{
+		TxID: txid("c"),
+		Vin:  []tx.Input{{IsCoinbase: true}},
+		Vout: []tx.Output{{ScriptpubkeyAddress: "1Clean", Value: 100000}},
+	}
+	suite.mixing = tx.Tx{
+		TxID: txid("b"),
+		Vin: []tx.Input{
+			{TxID: suite.source.TxID, Vout: 0},
+			{TxID: suite.clean.TxID, Vout: 0},
+		},
+		Vout: []tx.Output{
+			{ScriptpubkeyAddress: "1Big", Value: 150000, Index: 0},
+			{ScriptpubkeyAddress: "1Small", Value: 40000, Index: 1},
+		},
+	}
+	for _, t := range []tx.Tx{suite.source, suite.clean, suite.mixing} {
+		b, err := encoding.Marshal(t)
+		require.Nil(suite.T(), err)
+		suite.db.On("Read", t.TxID).Return(b, nil)
+	}
+	suite.db.On("Read", suite.source.TxID+"_0").Return([]byte(suite.mixing.TxID), nil)
+	occurences := map[string][]byte{"1Big_" + suite.mixing.TxID: []byte("1")}
+	suite.db.On("Iterate", mock.Anything).Return(func(opts *iterator.Options) iterator.Iterator {
+		return iterator.FromMap(occurences, opts)
+	})
+	suite.db.On("Read", mock.Anything).Return(nil, errorx.ErrKeyNotFound)
+	suite.db.On("ReadFirstValueByPrefix", mock.Anything).Return(nil, nil)
+}
+
+func (suite *TestTaintSuite) TestDistribute() {
+	inputs := []int64{100000, 100000}
+	taint := []int64{100000, 0}
+	outputs := []int64{150000, 40000}
+
+	assert.Equal(suite.T(), []int64{150000, 40000}, Distribute(Poison, inputs, taint, outputs))
+	assert.Equal(suite.T(), []int64{75000, 20000}, Distribute(Haircut, inputs, taint, outputs))
+	assert.Equal(suite.T(), []int64{100000, 0}, Distribute(FIFO, inputs, taint, outputs))
+	assert.Equal(suite.T(), []int64{50000, 40000}, Distribute(FIFO, inputs, []int64{0, 100000}, outputs))
+	assert.Equal(suite.T(), []int64{100000, 0}, Distribute(TIHO, inputs, taint, outputs))
+	assert.Equal(suite.T(), []int64{150000, 30000}, Distribute(TIHO, inputs, []int64{100000, 80000}, outputs))
+	assert.Equal(suite.T(), []int64{0, 0}, Distribute(Poison, inputs, []int64{0, 0}, outputs))
+
+	// backward, outputs are the sources and inputs the targets
+	assert.Equal(suite.T(), []int64{50000, 50000}, Distribute(Haircut, outputs, []int64{0, 100000}, inputs))
+	assert.Equal(suite.T(), []int64{0, 40000}, Distribute(FIFO, outputs, []int64{0, 40000}, []int64{150000, 40000}))
+}
+
+func (suite *TestTaintSuite) TestParseOutpoint() {
+	txid, vout, err := ParseOutpoint(suite.source.TxID + ":3")
+	require.Nil(suite.T(), err)
+	assert.Equal(suite.T(), suite.source.TxID, txid)
+	assert.Equal(suite.T(), uint32(3), vout)
+
+	_, _, err = ParseOutpoint("notatxid:3")
+	assert.NotNil(suite.T(), err)
+	_, _, err = ParseOutpoint(suite.source.TxID + ":x")
+	assert.NotNil(suite.T(), err)
+}
+
+func (suite *TestTaintSuite) TestTaintForward() {
+	taint, err := suite.service.Taint(context.Background(), suite.source.TxID+":0", &TaintParams{Model: Haircut})
+	require.Nil(suite.T(), err)
+	assert.Equal(suite.T(), int64(100000), taint.Tainted)
+	require.Len(suite.T(), taint.Outputs, 3)
+	assert.Equal(suite.T(), float64(1), taint.Outputs[0].Fraction)
+	assert.Equal(suite.T(), "1Big", taint.Outputs[1].Address)
+	assert.Equal(suite.T(), int64(75000), taint.Outputs[1].Tainted)
+	assert.Equal(suite.T(), 0.5, taint.Outputs[1].Fraction)
+	assert.Equal(suite.T(), 1, taint.Outputs[1].Depth)
+	assert.Equal(suite.T(), int64(20000), taint.Outputs[2].Tainted)
+}
+
+func (suite *TestTaintSuite) TestTaintForwardThresholds() {
+	taint, err := suite.service.Taint(context.Background(), suite.source.TxID+":0", &TaintParams{Model: Haircut, MaxDepth: 1, MinValue: 30000})
+	require.Nil(suite.T(), err)
+	assert.Len(suite.T(), taint.Outputs, 3)
+
+	taint, err = suite.service.Taint(context.Background(), suite.source.TxID+":0", &TaintParams{Model: TIHO})
+	require.Nil(suite.T(), err)
+	require.Len(suite.T(), taint.Outputs, 2)
+	assert.Equal(suite.T(), "1Big", taint.Outputs[1].Address)
+}
+
+func (suite *TestTaintSuite) TestTaintBackward() {
+	taint, err := suite.service.Taint(context.Background(), suite.mixing.TxID+":1", &TaintParams{Model: Haircut, Direction: Backward})
+	require.Nil(suite.T(), err)
+	require.Len(suite.T(), taint.Outputs, 3)
+	for _, out := range taint.Outputs[1:] {
+		assert.Equal(suite.T(), int64(20000), out.Tainted)
+		assert.Equal(suite.T(), 1, out.Depth)
+	}
+
+	taint, err = suite.service.Taint(context.Background(), suite.mixing.TxID+":0", &TaintParams{Model: Poison, Direction: Backward})
+	require.Nil(suite.T(), err)
+	require.Len(suite.T(), taint.Outputs, 3)
+	assert.Equal(suite.T(), float64(1), taint.Outputs[1].Fraction)
+	assert.Equal(suite.T(), float64(1), taint.Outputs[2].Fraction)
+}
+
+func (suite *TestTaintSuite) TestTaintTransaction() {
+	taint, err := suite.service.Taint(context.Background(), suite.mixing.TxID, &TaintParams{Model: Haircut, Direction: Backward})
+	require.Nil(suite.T(), err)
+	assert.Equal(suite.T(), int64(190000), taint.Tainted)
+	require.Len(suite.T(), taint.Outputs, 4)
+	for _, out := range taint.Outputs[2:] {
+		assert.Equal(suite.T(), int64(95000), out.Tainted)
+		assert.Equal(suite.T(), 1, out.Depth)
+	}
+}
+
+func (suite *TestTaintSuite) TestTaintBounds() {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	taint, err := suite.service.Taint(ctx, suite.source.TxID+":0", &TaintParams{Model: Haircut})
+	require.Nil(suite.T(), err)
+	assert.True(suite.T(), taint.Partial)
+	assert.Len(suite.T(), taint.Outputs, 1)
+
+	taint, err = suite.service.Taint(context.Background(), suite.mixing.TxID+":0", &TaintParams{Model: Haircut, Direction: Backward, MaxNodes: 1})
+	require.Nil(suite.T(), err)
+	assert.True(suite.T(), taint.Partial)
+	assert.Len(suite.T(), taint.Outputs, 3)
+}
+
+func TestTaint(t *testing.T) {
+	suite.Run(t, new(TestTaintSuite))
+}
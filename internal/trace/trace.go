@@ -1,11 +1,16 @@
 package trace
 
 import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"math"
 	"strings"
-	"sync"
+	"time"
 
 	"github.com/xn3cr0nx/bitgodine/internal/address"
 	"github.com/xn3cr0nx/bitgodine/internal/errorx"
@@ -14,6 +19,7 @@ import (
 	"github.com/xn3cr0nx/bitgodine/internal/tx"
 	"github.com/xn3cr0nx/bitgodine/pkg/cache"
 
+	"github.com/spf13/viper"
 	"github.com/xn3cr0nx/bitgodine/internal/abuse"
 	"github.com/xn3cr0nx/bitgodine/internal/analysis"
 	"github.com/xn3cr0nx/bitgodine/internal/heuristics"
@@ -23,8 +29,9 @@ import (
 
 // Service interface exports available methods for block service
 type Service interface {
-	TraceAddress(address string, limit, skip int) (tracing *Flow, err error)
-	followFlow(flow map[string]Trace, transaction tx.Tx, vout uint32, depth int, lock *sync.RWMutex) (err error)
+	TraceAddress(ctx context.Context, address string, limit, skip int, params *TraceParams) (tracing *Flow, err error)
+	TraceAddressSources(ctx context.Context, address string, limit, skip int, params *TraceParams) (tree *SourceTree, err error)
+	Taint(ctx context.Context, source string, params *TaintParams) (taint *Taint, err error)
 }
 
 type service struct {
@@ -42,8 +49,91 @@ func NewService(r *postgres.Pg, k kv.DB, c *cache.Cache) *service {
 	}
 }
 
-func (s *service) TraceAddress(addr string, limit, skip int) (tracing *Flow, err error) {
-	fmt.Println("Tracing address", addr)
+// Default limits applied to forward tracing
+const (
+	DefaultTraceDepth   = 10
+	DefaultTraceNodes   = 1000
+	DefaultTraceWorkers = 8
+)
+
+// TraceParams bounds applied to forward tracing execution
+type TraceParams struct {
+	MaxDepth int
+	MaxNodes int
+	Workers  int
+	Timeout  time.Duration
+	Cursor   string
+}
+
+// pending transaction output still to be followed
+type pending struct {
+	Trace int    `json:"t"`
+	TxID  string `json:"txid"`
+	Vout  uint32 `json:"vout"`
+	Depth int    `json:"d"`
+}
+
+// step result of following a pending output
+type step struct {
+	pending
+	trace Trace
+	next  []pending
+	done  bool
+}
+
+// encodeCursor serializes the frontier signed with the auth secret, so that a resumed tracing can only
+// start from outputs pending in a previous one
+func encodeCursor(frontier []pending) (cursor string, err error) {
+	b, err := json.Marshal(frontier)
+	if err != nil {
+		return
+	}
+	cursor = base64.URLEncoding.EncodeToString(append(b, sign(b)...))
+	return
+}
+
+func decodeCursor(cursor string) (frontier []pending, err error) {
+	b, err := base64.URLEncoding.DecodeString(cursor)
+	if err != nil || len(b) < sha256.Size {
+		err = fmt.Errorf("%w: invalid cursor", errorx.ErrInvalidArgument)
+		return
+	}
+	payload, mac := b[:len(b)-sha256.Size], b[len(b)-sha256.Size:]
+	if !hmac.Equal(mac, sign(payload)) {
+		err = fmt.Errorf("%w: invalid cursor signature", errorx.ErrInvalidArgument)
+		return
+	}
+	if err = json.Unmarshal(payload, &frontier); err != nil {
+		err = fmt.Errorf("%w: invalid cursor", errorx.ErrInvalidArgument)
+	}
+	return
+}
+
+func sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(viper.GetString("auth.secret")))
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// TraceAddress follows the flow of funds received by the address through the outputs picked by majority voting.
+// Tracing is bounded by depth, number of followed nodes and context deadline. When the nodes limit or the deadline is hit
+// the partial flow is returned together with a cursor to resume the tracing from the pending outputs
+func (s *service) TraceAddress(ctx context.Context, addr string, limit, skip int, params *TraceParams) (tracing *Flow, err error) {
+	if params.MaxDepth == 0 {
+		params.MaxDepth = DefaultTraceDepth
+	}
+	if params.MaxNodes == 0 {
+		params.MaxNodes = DefaultTraceNodes
+	}
+	if params.Workers == 0 {
+		params.Workers = DefaultTraceWorkers
+	}
+	if params.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, params.Timeout)
+		defer cancel()
+	}
+
 	occurences, err := address.NewService(s.Kv, s.Cache).GetOccurences(addr)
 	if err != nil {
 		return
@@ -52,27 +142,36 @@ func (s *service) TraceAddress(addr string, limit, skip int) (tracing *Flow, err
 		Traces:     make([]map[string]Trace, limit),
 		Occurences: occurences,
 	}
+	for i := range tracing.Traces {
+		tracing.Traces[i] = make(map[string]Trace)
+	}
 
-	var g errgroup.Group
 	txService := tx.NewService(s.Kv, s.Cache)
-	for i, occurence := range occurences {
-		if i < limit*skip || i > skip*limit+(limit-1) {
-			continue
+	var frontier []pending
+	if params.Cursor != "" {
+		if frontier, err = decodeCursor(params.Cursor); err != nil {
+			return
 		}
-
-		occ := occurence
-		flow := make(map[string]Trace)
-
-		lock := sync.RWMutex{}
-		index := i
-
-		g.Go(func() error {
-			occ = strings.Replace(occ, addr+"_", "", 1)
-			transaction, err := txService.GetFromHash(occ)
-			if err != nil {
-				return err
+		for _, p := range frontier {
+			if p.Trace < 0 || p.Trace >= limit {
+				err = fmt.Errorf("%w: cursor doesn't match limit", errorx.ErrInvalidArgument)
+				return
+			}
+			if p.Depth < 0 || p.Depth >= params.MaxDepth {
+				err = fmt.Errorf("%w: cursor doesn't match depth", errorx.ErrInvalidArgument)
+				return
+			}
+		}
+	} else {
+		for i, occurence := range occurences {
+			if i < limit*skip || i > skip*limit+(limit-1) {
+				continue
+			}
+			transaction, e := txService.GetFromHash(occurence)
+			if e != nil {
+				err = e
+				return
 			}
-
 			// find output with sought address
 			vout := uint32(0)
 			for o, out := range transaction.Vout {
@@ -80,32 +179,119 @@ func (s *service) TraceAddress(addr string, limit, skip int) (tracing *Flow, err
 					vout = uint32(o)
 				}
 			}
+			frontier = append(frontier, pending{Trace: i % limit, TxID: transaction.TxID, Vout: vout})
+		}
+	}
+
+	w := &walker{
+		service:  s,
+		tx:       txService,
+		analysis: analysis.NewService(s.Kv, s.Cache),
+	}
+	// outputs pending in the cursor were already visited by the previous tracing
+	visited := make(map[string]bool)
+	for _, p := range frontier {
+		visited[fmt.Sprintf("%d_%s:%d", p.Trace, p.TxID, p.Vout)] = true
+	}
+	nodes := 0
+	for len(frontier) > 0 && nodes < params.MaxNodes && ctx.Err() == nil {
+		size := len(frontier)
+		if size > params.MaxNodes-nodes {
+			size = params.MaxNodes - nodes
+		}
+		steps := make([]step, size)
+		for i := range steps {
+			steps[i].pending = frontier[i]
+		}
+		rest := frontier[size:]
+
+		if err = w.walk(ctx, steps, params.Workers); err != nil {
+			return nil, err
+		}
 
-			if err := s.followFlow(flow, transaction, vout, 0, &lock); err != nil {
-				return err
+		frontier = nil
+		for _, st := range steps {
+			if !st.done {
+				frontier = append(frontier, st.pending)
+				continue
+			}
+			nodes++
+			tracing.Traces[st.Trace][fmt.Sprintf("%s:%d", st.TxID, st.Vout)] = st.trace
+			for _, n := range st.next {
+				key := fmt.Sprintf("%d_%s:%d", n.Trace, n.TxID, n.Vout)
+				if n.Depth >= params.MaxDepth || visited[key] {
+					continue
+				}
+				visited[key] = true
+				frontier = append(frontier, n)
+			}
+		}
+		frontier = append(frontier, rest...)
+	}
+
+	if len(frontier) > 0 {
+		tracing.Partial = true
+		if tracing.Cursor, err = encodeCursor(frontier); err != nil {
+			return nil, err
+		}
+	}
+	return
+}
+
+// walker follows pending outputs sharing services between steps
+type walker struct {
+	service  *service
+	tx       tx.Service
+	analysis analysis.Service
+}
+
+// walk executes the steps through a bounded pool of workers, steps not executed before the context is done are left undone
+func (w *walker) walk(ctx context.Context, steps []step, workers int) (err error) {
+	return pool(ctx, len(steps), workers, func(j int) error {
+		if err := w.follow(&steps[j]); err != nil {
+			return err
+		}
+		steps[j].done = true
+		return nil
+	})
+}
+
+// pool runs job for each index in [0, n) on a bounded number of workers. Once the context is done
+// the remaining indexes are skipped, the first job error stops the pool
+func pool(ctx context.Context, n, workers int, job func(int) error) (err error) {
+	g, gctx := errgroup.WithContext(ctx)
+	jobs := make(chan int)
+	for i := 0; i < workers; i++ {
+		g.Go(func() error {
+			for j := range jobs {
+				if gctx.Err() != nil {
+					continue
+				}
+				if err := job(j); err != nil {
+					return err
+				}
 			}
-			tracing.Traces[index%limit] = flow
 			return nil
 		})
 	}
-	if err = g.Wait(); err != nil {
-		return nil, err
+feed:
+	for j := 0; j < n; j++ {
+		select {
+		case jobs <- j:
+		case <-gctx.Done():
+			break feed
+		}
 	}
-
-	return
+	close(jobs)
+	return g.Wait()
 }
 
-func (s *service) followFlow(flow map[string]Trace, transaction tx.Tx, vout uint32, depth int, lock *sync.RWMutex) (err error) {
-	analysisService := analysis.NewService(s.Kv, s.Cache)
-	changes, err := analysisService.AnalyzeTx(transaction.TxID, heuristics.FromListToMask(heuristics.List()), "reliability")
+// follow applies the heuristics to the transaction and retrieves the spending transactions of the likely outputs
+func (w *walker) follow(st *step) (err error) {
+	st.trace = Trace{TxID: st.TxID, Next: []Next{}}
+	changes, err := w.analysis.AnalyzeTx(st.TxID, heuristics.FromListToMask(heuristics.List()), "reliability")
 	if err != nil {
 		if errors.Is(err, analysis.ErrUnfeasibleTx) {
-			lock.Lock()
-			flow[fmt.Sprintf("%s:%d", transaction.TxID, vout)] = Trace{
-				TxID: transaction.TxID,
-				Next: []Next{},
-			}
-			lock.Unlock()
 			return nil
 		}
 		return
@@ -113,110 +299,117 @@ func (s *service) followFlow(flow map[string]Trace, transaction tx.Tx, vout uint
 	likelihood, err := analysis.MajorityVotingOutput(changes.(heuristics.Map))
 	if err != nil {
 		if errors.Is(err, analysis.ErrUnfeasibleTx) {
-			lock.Lock()
-			flow[fmt.Sprintf("%s:%d", transaction.TxID, vout)] = Trace{
-				TxID: transaction.TxID,
-				Next: []Next{},
-			}
-			lock.Unlock()
 			return nil
 		}
-		return err
+		return
 	}
 
-	var g errgroup.Group
-	var next []Next
-	nextLock := sync.RWMutex{}
-	txService := tx.NewService(s.Kv, s.Cache)
-	for out, perc := range likelihood {
-		output := out
-		percentages := perc
-		g.Go(func() error {
-			spending, e := txService.GetSpendingFromHash(transaction.TxID, output)
-			if e != nil {
-				if errors.Is(err, errorx.ErrKeyNotFound) {
-					return nil
-				}
-				return e
-			}
-			var localNext []Next
-			tagService := tag.NewService(s.Repository, s.Cache)
-			for mask, percentage := range percentages {
-				clusters := []Cluster{}
-
-				var g errgroup.Group
-				g.Go(func() (err error) {
-					tags, err := tagService.GetTaggedClusterSet(transaction.Vout[output].ScriptpubkeyAddress)
-					if err != nil {
-						if !strings.Contains(err.Error(), "cluster not found") {
-							return err
-						}
-					}
-					for _, tag := range tags {
-						clusters = append(clusters, Cluster{
-							Type:     tag.Type,
-							Message:  tag.Message + " " + tag.Link,
-							Nickname: tag.Nickname,
-							Verified: tag.Verified,
-						})
-					}
-					return
-				})
-				g.Go(func() (err error) {
-					abuseService := abuse.NewService(s.Repository, s.Cache)
-					abuses, err := abuseService.GetAbusedClusterSet(transaction.Vout[output].ScriptpubkeyAddress)
-					if err != nil {
-						if !strings.Contains(err.Error(), "cluster not found") {
-							return err
-						}
-					}
-					for _, abuse := range abuses {
-						clusters = append(clusters, Cluster{
-							Type:     "abuse",
-							Message:  abuse.Description,
-							Nickname: abuse.Abuser,
-							Verified: false,
-						})
-					}
-					return
-				})
-				if err = g.Wait(); err != nil {
-					return err
-				}
-
-				localNext = append(localNext, Next{
-					TxID:     spending.TxID,
-					Vout:     output,
-					Receiver: transaction.Vout[output].ScriptpubkeyAddress,
-					Amount:   satToBtc(transaction.Vout[output].Value),
-					Weight:   percentage,
-					Analysis: fmt.Sprintf("%b", mask[0]),
-					Clusters: clusters,
-				})
-				err = s.followFlow(flow, spending, output, depth+1, lock)
-				if err != nil {
-					return err
-				}
-			}
-			nextLock.Lock()
-			next = append(next, localNext...)
-			nextLock.Unlock()
-			return nil
-		})
-	}
-	if err = g.Wait(); err != nil {
+	transaction, err := w.tx.GetFromHash(st.TxID)
+	if err != nil {
 		return
 	}
-	lock.Lock()
-	flow[fmt.Sprintf("%s:%d", transaction.TxID, vout)] = Trace{
-		TxID: transaction.TxID,
-		Next: next,
+	for output, percentages := range likelihood {
+		if int(output) >= len(transaction.Vout) {
+			continue
+		}
+		spending, e := w.tx.GetSpendingFromHash(transaction.TxID, output)
+		if e != nil {
+			if errors.Is(e, errorx.ErrKeyNotFound) {
+				continue
+			}
+			return e
+		}
+		clusters, _, _, e := w.service.labels(transaction.Vout[output].ScriptpubkeyAddress)
+		if e != nil {
+			return e
+		}
+		for mask, percentage := range percentages {
+			st.trace.Next = append(st.trace.Next, Next{
+				TxID:     spending.TxID,
+				Vout:     output,
+				Receiver: transaction.Vout[output].ScriptpubkeyAddress,
+				Amount:   satToBtc(transaction.Vout[output].Value),
+				Weight:   percentage,
+				Analysis: fmt.Sprintf("%b", mask[0]),
+				Clusters: clusters,
+			})
+		}
+		st.next = append(st.next, pending{Trace: st.Trace, TxID: spending.TxID, Vout: output, Depth: st.Depth + 1})
 	}
-	lock.Unlock()
-
-	return nil
+	return
 }
 
 func satToBtc(amount int64) float64 {
 	return float64(amount) * math.Pow(10, -8)
 }
+
+// labels retrieves tags and abuses of the cluster the address belongs to
+func (s *service) labels(address string) (clusters []Cluster, tagged, abused bool, err error) {
+	clusters = []Cluster{}
+	if address == "" || s.Repository == nil {
+		return
+	}
+
+	res, err := tag.NewService(s.Repository, s.Cache).GetTaggedClusterSet(address)
+	if err != nil {
+		if !strings.Contains(err.Error(), "cluster not found") {
+			return
+		}
+		err = nil
+	}
+	if res.Entity != "" {
+		// the cluster is labelled with the dominant entity, the others reported as conflicts
+		label := Cluster{
+			Type:       res.Category,
+			Nickname:   res.Entity,
+			Verified:   res.Verified,
+			Confidence: res.Confidence,
+		}
+		for _, c := range res.Conflicts {
+			label.Conflicts = append(label.Conflicts, c.Entity)
+		}
+		clusters = append(clusters, label)
+	} else {
+		for _, tag := range res.Tags {
+			clusters = append(clusters, Cluster{
+				Type:     tag.Type,
+				Message:  tag.Message + " " + tag.Link,
+				Nickname: tag.Nickname,
+				Verified: tag.Verified,
+			})
+		}
+	}
+
+	abuses, err := abuse.NewService(s.Repository, s.Cache).GetAbusedClusterSet(address)
+	if err != nil {
+		if !strings.Contains(err.Error(), "cluster not found") {
+			return
+		}
+		err = nil
+	}
+	if len(abuses) > 0 {
+		risk, e := abuse.NewService(s.Repository, s.Cache).GetRisk(address)
+		if e != nil {
+			err = e
+			return
+		}
+		// a label for each abuser and category, scored with the cluster risk
+		reported := make(map[string]bool)
+		for _, a := range abuses {
+			if reported[a.Abuser+"\x00"+a.Category] {
+				continue
+			}
+			reported[a.Abuser+"\x00"+a.Category] = true
+			clusters = append(clusters, Cluster{
+				Type:     "abuse",
+				Message:  a.Category,
+				Nickname: a.Abuser,
+				Verified: false,
+				Risk:     risk.ClusterRisk.Score,
+			})
+		}
+	}
+
+	tagged, abused = len(res.Tags) > 0, len(abuses) > 0
+	return
+}
@@ -0,0 +1,60 @@
+package trace
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xn3cr0nx/bitgodine/internal/errorx"
+)
+
+func (suite *TestTaintSuite) TestTraceAddressCanceled() {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	flow, err := suite.service.TraceAddress(ctx, "1Big", 5, 0, &TraceParams{})
+	require.Nil(suite.T(), err)
+	assert.True(suite.T(), flow.Partial)
+	assert.NotEmpty(suite.T(), flow.Cursor)
+	assert.Empty(suite.T(), flow.Traces[0])
+
+	frontier, err := decodeCursor(flow.Cursor)
+	require.Nil(suite.T(), err)
+	require.Len(suite.T(), frontier, 1)
+	assert.Equal(suite.T(), suite.mixing.TxID, frontier[0].TxID)
+
+	flow, err = suite.service.TraceAddress(context.Background(), "1Big", 5, 0, &TraceParams{Cursor: flow.Cursor, Workers: 1})
+	require.Nil(suite.T(), err)
+	assert.False(suite.T(), flow.Partial)
+	assert.Contains(suite.T(), flow.Traces[0], suite.mixing.TxID+":0")
+}
+
+func (suite *TestTaintSuite) TestTraceAddressInvalidCursor() {
+	_, err := suite.service.TraceAddress(context.Background(), "1Big", 5, 0, &TraceParams{Cursor: "!"})
+	assert.True(suite.T(), errors.Is(err, errorx.ErrInvalidArgument))
+
+	cursor, err := encodeCursor([]pending{{Trace: 10, TxID: suite.mixing.TxID}})
+	require.Nil(suite.T(), err)
+	_, err = suite.service.TraceAddress(context.Background(), "1Big", 5, 0, &TraceParams{Cursor: cursor})
+	assert.True(suite.T(), errors.Is(err, errorx.ErrInvalidArgument))
+
+	cursor, err = encodeCursor([]pending{{TxID: suite.mixing.TxID, Depth: 10}})
+	require.Nil(suite.T(), err)
+	_, err = suite.service.TraceAddress(context.Background(), "1Big", 5, 0, &TraceParams{Cursor: cursor})
+	assert.True(suite.T(), errors.Is(err, errorx.ErrInvalidArgument))
+
+	// a cursor crafted without the signing secret is rejected
+	forged := base64.URLEncoding.EncodeToString(append([]byte(`[{"t":0,"txid":"`+suite.mixing.TxID+`","vout":0,"d":0}]`), make([]byte, sha256.Size)...))
+	_, err = suite.service.TraceAddress(context.Background(), "1Big", 5, 0, &TraceParams{Cursor: forged})
+	assert.True(suite.T(), errors.Is(err, errorx.ErrInvalidArgument))
+}
+
+func (suite *TestTaintSuite) TestTraceAddressMaxNodes() {
+	flow, err := suite.service.TraceAddress(context.Background(), "1Big", 5, 0, &TraceParams{MaxNodes: 1})
+	require.Nil(suite.T(), err)
+	assert.Len(suite.T(), flow.Traces[0], 1)
+}
@@ -0,0 +1,61 @@
+package tx
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcjson"
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+
+	"github.com/xn3cr0nx/bitgodine/internal/errorx"
+)
+
+// ErrBroadcastDisabled returned broadcasting without a configured client
+var ErrBroadcastDisabled = fmt.Errorf("broadcast disabled: %w", errorx.ErrConfig)
+
+// Broadcaster relays transactions to the network, as the bitcoin RPC client does
+type Broadcaster interface {
+	SendRawTransaction(tx *wire.MsgTx, allowHighFees bool) (*chainhash.Hash, error)
+}
+
+// GetRawFromHash returns the transaction serialized as on the network
+func (s *service) GetRawFromHash(hash string) (raw []byte, err error) {
+	tx, err := s.GetFromHash(hash)
+	if err != nil {
+		return
+	}
+	return tx.Raw()
+}
+
+// Broadcast relays the hex encoded transaction to the network returning its id.
+// Transactions rejected by the node are invalid arguments
+func (s *service) Broadcast(raw string) (txid string, err error) {
+	if s.Client == nil {
+		err = ErrBroadcastDisabled
+		return
+	}
+	b, err := hex.DecodeString(strings.TrimSpace(raw))
+	if err != nil {
+		err = fmt.Errorf("%w: %s", errorx.ErrInvalidArgument, err)
+		return
+	}
+	msg := new(wire.MsgTx)
+	if err = msg.Deserialize(bytes.NewReader(b)); err != nil {
+		err = fmt.Errorf("%w: %s", errorx.ErrInvalidArgument, err)
+		return
+	}
+	hash, err := s.Client.SendRawTransaction(msg, false)
+	if err != nil {
+		var rpcErr *btcjson.RPCError
+		if errors.As(err, &rpcErr) {
+			err = fmt.Errorf("%w: %s", errorx.ErrInvalidArgument, rpcErr.Message)
+		}
+		return
+	}
+	txid = hash.String()
+	return
+}
@@ -0,0 +1,35 @@
+package tx
+
+// dustLimit outputs value up to which outputs are not taken as coinjoin denominations
+const dustLimit = 2730
+
+// IsCoinjoin returns true if the transaction looks like a coinjoin. Participants are estimated as half
+// of the outputs, each funding the transaction from a distinct transaction and receiving an output of
+// the denomination, the most frequent output value, besides the change
+func (t *Tx) IsCoinjoin() bool {
+	if len(t.Vin) < 2 || len(t.Vout) < 3 {
+		return false
+	}
+	participants := (len(t.Vout) + 1) / 2
+	if participants > len(t.Vin) {
+		return false
+	}
+
+	funding := make(map[string]bool, len(t.Vin))
+	for _, in := range t.Vin {
+		funding[in.TxID] = true
+	}
+	if participants > len(funding) {
+		return false
+	}
+
+	values := make(map[int64]int)
+	var denomination int64
+	for _, out := range t.Vout {
+		values[out.Value]++
+		if values[out.Value] > values[denomination] || (values[out.Value] == values[denomination] && out.Value > denomination) {
+			denomination = out.Value
+		}
+	}
+	return denomination > dustLimit && values[denomination] == participants
+}
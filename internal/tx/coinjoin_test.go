@@ -0,0 +1,57 @@
+package tx_test
+
+import (
+	"strings"
+
+	"github.com/xn3cr0nx/bitgodine/internal/tx"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Testing coinjoin detection", func() {
+	inputs := func(n int) (vin []tx.Input) {
+		for i := 0; i < n; i++ {
+			vin = append(vin, tx.Input{TxID: strings.Repeat(string(rune('a'+i)), 64)})
+		}
+		return
+	}
+	outputs := func(values ...int64) (vout []tx.Output) {
+		for _, v := range values {
+			vout = append(vout, tx.Output{Value: v})
+		}
+		return
+	}
+
+	It("Should detect equal outputs with change", func() {
+		t := tx.Tx{Vin: inputs(3), Vout: outputs(100000, 100000, 100000, 5320, 71200)}
+		Expect(t.IsCoinjoin()).To(BeTrue())
+	})
+
+	It("Should detect a participant without change", func() {
+		t := tx.Tx{Vin: inputs(4), Vout: outputs(5000000, 5000000, 5000000, 5000000, 12000, 340000, 98000)}
+		Expect(t.IsCoinjoin()).To(BeTrue())
+	})
+
+	It("Should not detect more equal outputs than participants", func() {
+		t := tx.Tx{Vin: inputs(5), Vout: outputs(5000000, 5000000, 5000000, 5000000, 5000000)}
+		Expect(t.IsCoinjoin()).To(BeFalse())
+	})
+
+	It("Should not detect a payment with change", func() {
+		t := tx.Tx{Vin: inputs(2), Vout: outputs(150000, 40000, 2000)}
+		Expect(t.IsCoinjoin()).To(BeFalse())
+	})
+
+	It("Should not detect inputs from the same transaction", func() {
+		vin := inputs(1)
+		vin = append(vin, vin[0], vin[0])
+		t := tx.Tx{Vin: vin, Vout: outputs(100000, 100000, 100000, 5000)}
+		Expect(t.IsCoinjoin()).To(BeFalse())
+	})
+
+	It("Should ignore dust denominations", func() {
+		t := tx.Tx{Vin: inputs(3), Vout: outputs(546, 546, 546, 90000)}
+		Expect(t.IsCoinjoin()).To(BeFalse())
+	})
+})
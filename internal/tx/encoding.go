@@ -0,0 +1,171 @@
+package tx
+
+import (
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/btcsuite/btcd/txscript"
+
+	"github.com/xn3cr0nx/bitgodine/pkg/encoding"
+)
+
+// Marshal encodes the transaction in the compact binary format. Scripts are stored as bytes
+// and asm strings matching their script are left out, to be derived lazily when needed
+func Marshal(t *Tx) []byte {
+	w := encoding.NewWriter(256)
+	w.Hex(t.TxID)
+	w.Varint(int64(t.Version))
+	w.Uvarint(uint64(t.Locktime))
+	w.Float32(t.Size)
+	w.Float32(t.Weight)
+	w.Float32(t.Fee)
+
+	w.Uvarint(uint64(len(t.Vin)))
+	for _, in := range t.Vin {
+		w.Hex(in.TxID)
+		w.Uvarint(uint64(in.Vout))
+		w.Bool(in.IsCoinbase)
+		w.Hex(in.Scriptsig)
+		writeAsm(w, in.Scriptsig, in.ScriptsigAsm)
+		w.String(in.InnerRedeemscriptAsm)
+		w.String(in.InnerWitnessscriptAsm)
+		w.Uvarint(uint64(in.Sequence))
+		w.Uvarint(uint64(len(in.Witness)))
+		for _, wtn := range in.Witness {
+			w.String(wtn)
+		}
+		w.Uvarint(uint64(in.Prevout))
+	}
+
+	w.Uvarint(uint64(len(t.Vout)))
+	for _, out := range t.Vout {
+		w.Hex(out.Scriptpubkey)
+		writeAsm(w, out.Scriptpubkey, out.ScriptpubkeyAsm)
+		w.String(out.ScriptpubkeyType)
+		w.String(out.ScriptpubkeyAddress)
+		w.Varint(out.Value)
+		w.Uvarint(uint64(out.Index))
+	}
+
+	w.Uvarint(uint64(len(t.Status)))
+	for _, s := range t.Status {
+		w.Bool(s.Confirmed)
+		w.Varint(int64(s.BlockHeight))
+		w.Hex(s.BlockHash)
+		w.Time(s.BlockTime)
+	}
+	return w.Bytes()
+}
+
+// Unmarshal decodes a transaction stored either in the binary format or in the legacy one.
+// Asm left out encoding is kept empty, it's derived from the script serializing the transaction
+func Unmarshal(data []byte, t *Tx) error {
+	if !encoding.IsBinary(data) {
+		return encoding.UnmarshalLegacy(data, t)
+	}
+
+	r := encoding.NewReader(data)
+	*t = Tx{
+		TxID:     r.Hex(),
+		Version:  int32(r.Varint()),
+		Locktime: uint32(r.Uvarint()),
+		Size:     r.Float32(),
+		Weight:   r.Float32(),
+		Fee:      r.Float32(),
+	}
+
+	if n := r.Len(); n > 0 {
+		t.Vin = make([]Input, n)
+	}
+	for i := range t.Vin {
+		in := &t.Vin[i]
+		in.TxID = r.Hex()
+		in.Vout = uint32(r.Uvarint())
+		in.IsCoinbase = r.Bool()
+		in.Scriptsig = r.Hex()
+		in.ScriptsigAsm = readAsm(r)
+		in.InnerRedeemscriptAsm = r.String()
+		in.InnerWitnessscriptAsm = r.String()
+		in.Sequence = uint32(r.Uvarint())
+		if n := r.Len(); n > 0 {
+			in.Witness = make([]string, n)
+		}
+		for w := range in.Witness {
+			in.Witness[w] = r.String()
+		}
+		in.Prevout = uint32(r.Uvarint())
+	}
+
+	if n := r.Len(); n > 0 {
+		t.Vout = make([]Output, n)
+	}
+	for o := range t.Vout {
+		out := &t.Vout[o]
+		out.Scriptpubkey = r.Hex()
+		out.ScriptpubkeyAsm = readAsm(r)
+		out.ScriptpubkeyType = r.String()
+		out.ScriptpubkeyAddress = r.String()
+		out.Value = r.Varint()
+		out.Index = uint32(r.Uvarint())
+	}
+
+	if n := r.Len(); n > 0 {
+		t.Status = make([]Status, n)
+	}
+	for s := range t.Status {
+		status := &t.Status[s]
+		status.Confirmed = r.Bool()
+		status.BlockHeight = int32(r.Varint())
+		status.BlockHash = r.Hex()
+		status.BlockTime = r.Time()
+	}
+	return r.Err()
+}
+
+// Disasm returns the asm of the hex encoded script, or the disassembling error as the parser does
+func Disasm(script string) string {
+	b, err := hex.DecodeString(script)
+	if err != nil {
+		return err.Error()
+	}
+	asm, err := txscript.DisasmString(b)
+	if err != nil {
+		return err.Error()
+	}
+	return asm
+}
+
+// writeAsm stores the asm only if it doesn't match the script
+func writeAsm(w *encoding.Writer, script, asm string) {
+	if asm == "" || asm == Disasm(script) {
+		w.Bool(false)
+		return
+	}
+	w.Bool(true)
+	w.String(asm)
+}
+
+func readAsm(r *encoding.Reader) string {
+	if !r.Bool() {
+		return ""
+	}
+	return r.String()
+}
+
+// MarshalJSON derives the scriptsig asm if it's missing
+func (in Input) MarshalJSON() ([]byte, error) {
+	type input Input
+	if in.ScriptsigAsm == "" && in.Scriptsig != "" {
+		in.ScriptsigAsm = Disasm(in.Scriptsig)
+	}
+	return json.Marshal(input(in))
+}
+
+// MarshalJSON derives the scriptpubkey asm if it's missing
+func (out Output) MarshalJSON() ([]byte, error) {
+	type output Output
+	if out.ScriptpubkeyAsm == "" && out.Scriptpubkey != "" {
+		out.ScriptpubkeyAsm = Disasm(out.Scriptpubkey)
+	}
+	return json.Marshal(output(out))
+}
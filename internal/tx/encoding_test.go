@@ -0,0 +1,191 @@
+package tx_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/btcsuite/btcd/chaincfg"
+	"github.com/btcsuite/btcd/txscript"
+	"github.com/btcsuite/btcutil"
+
+	"github.com/xn3cr0nx/bitgodine/internal/test"
+	"github.com/xn3cr0nx/bitgodine/internal/tx"
+	"github.com/xn3cr0nx/bitgodine/pkg/encoding"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// parsed returns the transactions of block 181 as the parser models them
+func parsed() (txs []tx.Tx) {
+	blk, err := btcutil.NewBlockFromBytes(test.Block181Bytes)
+	if err != nil {
+		panic(err)
+	}
+	blockTime := blk.MsgBlock().Header.Timestamp
+	for _, t := range blk.Transactions() {
+		model := tx.Tx{
+			TxID:     t.Hash().String(),
+			Version:  t.MsgTx().Version,
+			Locktime: t.MsgTx().LockTime,
+			Status:   []tx.Status{{Confirmed: true, BlockHeight: 181, BlockHash: blk.Hash().String(), BlockTime: blockTime}},
+		}
+		for _, in := range t.MsgTx().TxIn {
+			script := fmt.Sprintf("%X", in.SignatureScript)
+			model.Vin = append(model.Vin, tx.Input{
+				TxID:         in.PreviousOutPoint.Hash.String(),
+				Vout:         in.PreviousOutPoint.Index,
+				Scriptsig:    script,
+				ScriptsigAsm: tx.Disasm(script),
+				Sequence:     in.Sequence,
+			})
+		}
+		for o, out := range t.MsgTx().TxOut {
+			class, addr, _, _ := txscript.ExtractPkScriptAddrs(out.PkScript, &chaincfg.MainNetParams)
+			script := fmt.Sprintf("%X", out.PkScript)
+			model.Vout = append(model.Vout, tx.Output{
+				Scriptpubkey:        script,
+				ScriptpubkeyAsm:     tx.Disasm(script),
+				ScriptpubkeyType:    class.String(),
+				ScriptpubkeyAddress: addr[0].EncodeAddress(),
+				Value:               out.Value,
+				Index:               uint32(o),
+			})
+		}
+		txs = append(txs, model)
+	}
+	return
+}
+
+var _ = Describe("Testing transactions binary encoding", func() {
+	var (
+		txs []tx.Tx
+	)
+
+	BeforeEach(func() {
+		txs = parsed()
+	})
+
+	It("Should round trip parsed transactions deriving asm lazily", func() {
+		for _, t := range txs {
+			var decoded tx.Tx
+			Expect(tx.Unmarshal(tx.Marshal(&t), &decoded)).To(Succeed())
+			Expect(decoded.Vout[0].ScriptpubkeyAsm).To(BeEmpty())
+			Expect(decoded.Vout[0].Scriptpubkey).To(Equal(t.Vout[0].Scriptpubkey))
+			Expect(decoded.Status[0].BlockTime.Equal(t.Status[0].BlockTime)).To(BeTrue())
+			decoded.Status[0].BlockTime = t.Status[0].BlockTime
+
+			expected, err := json.Marshal(t)
+			Expect(err).ToNot(HaveOccurred())
+			actual, err := json.Marshal(decoded)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(actual).To(MatchJSON(expected))
+		}
+	})
+
+	It("Should be smaller than the legacy encoding", func() {
+		legacy, err := encoding.Marshal(txs[1])
+		Expect(err).ToNot(HaveOccurred())
+		Expect(len(tx.Marshal(&txs[1]))).To(BeNumerically("<", len(legacy)/2))
+	})
+
+	It("Should keep asm not matching the script and raw scripts", func() {
+		t := tx.Tx{
+			TxID: "not hex",
+			Vin:  []tx.Input{{Scriptsig: "raw script", ScriptsigAsm: "custom", Witness: []string{"w1", ""}}},
+			Vout: []tx.Output{{Scriptpubkey: "76a9", ScriptpubkeyAsm: ""}},
+		}
+		var decoded tx.Tx
+		Expect(tx.Unmarshal(tx.Marshal(&t), &decoded)).To(Succeed())
+		Expect(decoded).To(Equal(t))
+	})
+
+	It("Should decode legacy records", func() {
+		legacy, err := encoding.Marshal(txs[0])
+		Expect(err).ToNot(HaveOccurred())
+		var decoded tx.Tx
+		Expect(tx.Unmarshal(legacy, &decoded)).To(Succeed())
+		Expect(decoded.TxID).To(Equal(txs[0].TxID))
+		Expect(decoded.Vout).To(Equal(txs[0].Vout))
+
+		legacy, err = json.Marshal(txs[0])
+		Expect(err).ToNot(HaveOccurred())
+		Expect(tx.Unmarshal(legacy, &decoded)).To(Succeed())
+		Expect(decoded.Vin).To(Equal(txs[0].Vin))
+	})
+
+	It("Should fail on truncated records", func() {
+		encoded := tx.Marshal(&txs[1])
+		var decoded tx.Tx
+		err := tx.Unmarshal(encoded[:len(encoded)/2], &decoded)
+		Expect(err).To(MatchError(encoding.ErrMalformed))
+	})
+})
+
+func BenchmarkMarshalBinary(b *testing.B) {
+	txs := parsed()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		tx.Marshal(&txs[i%len(txs)])
+	}
+}
+
+func BenchmarkMarshalLegacy(b *testing.B) {
+	txs := parsed()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := encoding.Marshal(txs[i%len(txs)]); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkMarshalJSON(b *testing.B) {
+	txs := parsed()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := json.Marshal(txs[i%len(txs)]); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkUnmarshalBinary(b *testing.B) {
+	benchmarkUnmarshal(b, func(t *tx.Tx) ([]byte, error) { return tx.Marshal(t), nil }, tx.Unmarshal)
+}
+
+func BenchmarkUnmarshalLegacy(b *testing.B) {
+	benchmarkUnmarshal(b, func(t *tx.Tx) ([]byte, error) { return encoding.Marshal(t) }, func(data []byte, t *tx.Tx) error {
+		return encoding.Unmarshal(data, t)
+	})
+}
+
+func BenchmarkUnmarshalJSON(b *testing.B) {
+	benchmarkUnmarshal(b, func(t *tx.Tx) ([]byte, error) { return json.Marshal(t) }, func(data []byte, t *tx.Tx) error {
+		return json.Unmarshal(data, t)
+	})
+}
+
+func benchmarkUnmarshal(b *testing.B, marshal func(*tx.Tx) ([]byte, error), unmarshal func([]byte, *tx.Tx) error) {
+	var records [][]byte
+	size := 0
+	for _, t := range parsed() {
+		t := t
+		encoded, err := marshal(&t)
+		if err != nil {
+			b.Fatal(err)
+		}
+		records = append(records, encoded)
+		size += len(encoded)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var t tx.Tx
+		if err := unmarshal(records[i%len(records)], &t); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(size)/float64(len(records)), "bytes/record")
+}
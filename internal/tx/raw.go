@@ -0,0 +1,57 @@
+package tx
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/btcsuite/btcd/chaincfg/chainhash"
+	"github.com/btcsuite/btcd/wire"
+
+	"github.com/xn3cr0nx/bitgodine/internal/errorx"
+)
+
+// MsgTx rebuilds the wire transaction from the stored one. Scripts are stored as hex and
+// witness items as raw bytes, as the parser prepares them
+func (t *Tx) MsgTx() (*wire.MsgTx, error) {
+	msg := wire.NewMsgTx(t.Version)
+	msg.LockTime = t.Locktime
+	for _, in := range t.Vin {
+		prev, err := chainhash.NewHashFromStr(in.TxID)
+		if err != nil {
+			return nil, fmt.Errorf("%w: input %s: %s", errorx.ErrInvalidArgument, in.TxID, err)
+		}
+		script, err := hex.DecodeString(in.Scriptsig)
+		if err != nil {
+			return nil, fmt.Errorf("%w: scriptsig: %s", errorx.ErrInvalidArgument, err)
+		}
+		txIn := wire.NewTxIn(wire.NewOutPoint(prev, in.Vout), script, nil)
+		txIn.Sequence = in.Sequence
+		for _, w := range in.Witness {
+			txIn.Witness = append(txIn.Witness, []byte(w))
+		}
+		msg.AddTxIn(txIn)
+	}
+	for _, out := range t.Vout {
+		script, err := hex.DecodeString(out.Scriptpubkey)
+		if err != nil {
+			return nil, fmt.Errorf("%w: scriptpubkey: %s", errorx.ErrInvalidArgument, err)
+		}
+		msg.AddTxOut(wire.NewTxOut(out.Value, script))
+	}
+	return msg, nil
+}
+
+// Raw returns the transaction serialized as on the network, witness included
+func (t *Tx) Raw() ([]byte, error) {
+	msg, err := t.MsgTx()
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	buf.Grow(msg.SerializeSize())
+	if err := msg.Serialize(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
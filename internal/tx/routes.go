@@ -1,42 +1,30 @@
 package tx
 
 import (
+	"encoding/hex"
 	"errors"
+	"io/ioutil"
 	"net/http"
 
+	"github.com/xn3cr0nx/bitgodine/internal/apikey"
 	"github.com/xn3cr0nx/bitgodine/internal/errorx"
+	"github.com/xn3cr0nx/bitgodine/internal/ratelimit"
 	"github.com/xn3cr0nx/bitgodine/pkg/validator"
 
 	"github.com/labstack/echo/v4"
 )
 
+// MaxBroadcastSize size limit of the raw transaction hex accepted for broadcast
+const MaxBroadcastSize = 4 << 20
+
 // Routes mounts all /tx based routes on the main group
 func Routes(g *echo.Group, s Service) {
-	r := g.Group("/tx", validator.JWT())
+	r := g.Group("/tx", apikey.Auth(apikey.ReadBlocks), ratelimit.Middleware(ratelimit.Blocks))
 	r.GET("/:txid", txID(s))
 	r.GET("/:txid/status", txIDStatus(s))
 
-	// TODO: generate btcutil block and return hex conversion
-	// r.GET("/:txid/hex", func(c echo.Context) error {
-	// 	txid := c.Param("txid")
-	// 	if err := c.Echo().Validator.(*validator.CustomValidator).Var(txid, "required"); err != nil {
-	// 		return err
-	// 	}
-	// db := c.Get("db")
-	// t, err := db.(kv.DB).GetTx(txid)
-	// 	if err != nil {
-	// 		if errors.Is(err, errorx.ErrKeyNotFound) {
-	// 			err = echo.NewHTTPError(http.StatusNotFound, err)
-	// 		}
-	// 		return err
-	// 	}
-	// 	return c.JSON(http.StatusOK, fmt.Sprintf("%X", t))
-	// })
-
-	// TODO: returns something like this
-	// {"block_height":142765,"merkle":["e4b7dc58ff92d7dc12429c13d2b3f55b498e25276c49ca607c2da4701570219e","92c70fb36d67e5cb391f7b4ebbbd1517c5530829c1e746e2f56dfff3f91b6cd5","baeb3d1f777f9314fdc9c4358abb5b2b96f47420255688a29e9ff2354a7c3f31","9e3465fa50ab32eff60d969827fb9a508d5bbe04fcd5de5eb8651cabeabf0e13","5352038e4e4f9325126faff1ecc8273dec78d1122bbf454b23078b73a8049e49","770c13987c512869ca926f4c84f3f1ca030750e69f5024afa478921eab88111b"],"pos":0}
-	// r.GET("/:txid/merkle-proof", func(c echo.Context) error {
-	//}
+	r.GET("/:txid/hex", txIDHex(s))
+	r.GET("/:txid/raw", txIDRaw(s))
 
 	// TODO: retrieve spent output
 	// r.GET("/tx/:txid/outspend/:vout", func(c echo.Context) error {
@@ -76,10 +64,7 @@ func Routes(g *echo.Group, s Service) {
 		return c.JSON(http.StatusOK, "OK")
 	})
 
-	// // TODO: receive hex and broadcast tx
-	// r.POST("", func(c echo.Context) error {
-	// 	return c.JSON(http.StatusOK, "OK")
-	// })
+	r.POST("", broadcast(s), apikey.Auth(apikey.BroadcastTxs))
 }
 
 // txID godoc
@@ -149,3 +134,109 @@ func txIDStatus(s Service) func(echo.Context) error {
 		return c.JSON(http.StatusOK, t.Status)
 	}
 }
+
+// txIDHex godoc
+// @ID tx-id-hex
+//
+// @Router /tx/{txid}/hex [get]
+// @Summary Tx hex from id
+// @Description get the raw transaction as hex
+// @Tags tx
+//
+// @Security ApiKeyAuth
+//
+// @Produce  plain
+//
+// @Param txid path string true "Transaction id"
+//
+// @Success 200 {string} string
+// @Success 404 {string} string
+// @Success 500 {string} string
+func txIDHex(s Service) func(echo.Context) error {
+	return func(c echo.Context) error {
+		txid := c.Param("txid")
+		if err := c.Echo().Validator.(*validator.CustomValidator).Var(txid, "required"); err != nil {
+			return err
+		}
+		raw, err := s.GetRawFromHash(txid)
+		if err != nil {
+			return err
+		}
+		return c.String(http.StatusOK, hex.EncodeToString(raw))
+	}
+}
+
+// txIDRaw godoc
+// @ID tx-id-raw
+//
+// @Router /tx/{txid}/raw [get]
+// @Summary Tx raw from id
+// @Description get the raw transaction as binary
+// @Tags tx
+//
+// @Security ApiKeyAuth
+//
+// @Produce  octet-stream
+//
+// @Param txid path string true "Transaction id"
+//
+// @Success 200 {string} string
+// @Success 404 {string} string
+// @Success 500 {string} string
+func txIDRaw(s Service) func(echo.Context) error {
+	return func(c echo.Context) error {
+		txid := c.Param("txid")
+		if err := c.Echo().Validator.(*validator.CustomValidator).Var(txid, "required"); err != nil {
+			return err
+		}
+		raw, err := s.GetRawFromHash(txid)
+		if err != nil {
+			return err
+		}
+		return c.Blob(http.StatusOK, echo.MIMEOctetStream, raw)
+	}
+}
+
+// broadcast godoc
+// @ID tx-broadcast
+//
+// @Router /tx [post]
+// @Summary Broadcast tx
+// @Description broadcast the raw transaction, sent as hex in the request body up to 4MB, through the bitcoin client. Returns the transaction id.
+// @Description Api keys need the broadcast:txs scope besides read:blocks
+// @Tags tx
+//
+// @Security ApiKeyAuth
+//
+// @Accept  plain
+// @Produce  plain
+//
+// @Param tx body string true "Raw transaction hex"
+//
+// @Success 200 {string} string
+// @Success 400 {string} string
+// @Success 413 {string} string
+// @Success 503 {string} string
+// @Success 500 {string} string
+func broadcast(s Service) func(echo.Context) error {
+	return func(c echo.Context) error {
+		body, err := ioutil.ReadAll(http.MaxBytesReader(c.Response(), c.Request().Body, MaxBroadcastSize))
+		if err != nil {
+			if len(body) >= MaxBroadcastSize {
+				return echo.NewHTTPError(http.StatusRequestEntityTooLarge, err.Error())
+			}
+			return err
+		}
+		txid, err := s.Broadcast(string(body))
+		if err != nil {
+			switch {
+			case errors.Is(err, errorx.ErrInvalidArgument):
+				return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+			case errors.Is(err, ErrBroadcastDisabled):
+				return echo.NewHTTPError(http.StatusServiceUnavailable, err.Error())
+			}
+			return err
+		}
+		return c.String(http.StatusOK, txid)
+	}
+}
@@ -1,10 +1,11 @@
 package tx
 
 import (
+	"fmt"
+
 	"github.com/xn3cr0nx/bitgodine/internal/errorx"
 	"github.com/xn3cr0nx/bitgodine/internal/storage/kv"
 	"github.com/xn3cr0nx/bitgodine/pkg/cache"
-	"github.com/xn3cr0nx/bitgodine/pkg/encoding"
 	"github.com/xn3cr0nx/bitgodine/pkg/logger"
 )
 
@@ -15,11 +16,15 @@ type Service interface {
 	GetSpentOutputFromHash(hash string, vout uint32) (output Output, err error)
 	GetSpendingFromHash(hash string, vout uint32) (transaction Tx, err error)
 	IsSpent(tx string, index uint32) bool
+	GetRawFromHash(hash string) (raw []byte, err error)
+	Broadcast(raw string) (txid string, err error)
 }
 
 type service struct {
 	Kv    kv.DB
 	Cache *cache.Cache
+	// Client relays the transactions broadcasted, broadcasting is disabled if not set
+	Client Broadcaster
 }
 
 // NewService instantiates a new Service layer for customer
@@ -36,7 +41,7 @@ func read(db kv.DB, hash string) (transaction Tx, err error) {
 	if err != nil {
 		return
 	}
-	if err = encoding.Unmarshal(r, &transaction); err != nil {
+	if err = Unmarshal(r, &transaction); err != nil {
 		return
 	}
 	return
@@ -44,7 +49,7 @@ func read(db kv.DB, hash string) (transaction Tx, err error) {
 
 // readFollowing retrieves spending tx of the output based on hash and index
 func readFollowing(db kv.DB, hash string, vout uint32) (transaction string, err error) {
-	bytes, err := db.Read(hash + "_" + string(vout))
+	bytes, err := db.Read(hash + "_" + fmt.Sprint(vout))
 	if err != nil {
 		return
 	}
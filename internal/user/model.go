@@ -4,12 +4,14 @@ import (
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/lib/pq"
 	"gorm.io/gorm"
 
 	"github.com/xn3cr0nx/bitgodine/internal/analysis"
+	"github.com/xn3cr0nx/bitgodine/internal/apikey"
+	"github.com/xn3cr0nx/bitgodine/internal/investigation"
 	"github.com/xn3cr0nx/bitgodine/internal/password"
 	"github.com/xn3cr0nx/bitgodine/internal/user/preferences"
+	"github.com/xn3cr0nx/bitgodine/internal/watchlist"
 )
 
 // Model user struct with validation
@@ -18,19 +20,23 @@ type Model struct {
 	ID        uuid.UUID `json:"id" gorm:"primarykey;index;unique"`
 	Email     string    `json:"email" validate:"required,email" gorm:"index"`
 	Username  string    `json:"username" validate:"required" gorm:"index"`
-	Password  string    `json:"password" validate:"required,min=10"`
+	Password  string    `json:"-" validate:"required,min=10"`
 	FirstName string    `json:"first_name" validate:"required,min=2"`
 	LastName  string    `json:"last_name" validate:"required,min=2"`
 
 	LastLogin time.Time `json:"last_login" validate:""`
 
-	IsActive  bool           `json:"is_active,omitempty" gorm:"default:false"`
-	Lang      string         `json:"lang,omitempty" gorm:"default:'en'"`
-	IsBlocked bool           `json:"is_blocked" gorm:"default:false"`
-	APIKeys   pq.StringArray `json:"api_keys,omitempty" gorm:"type:varchar(255)[]"`
+	IsActive  bool   `json:"is_active,omitempty" gorm:"default:false"`
+	Lang      string `json:"lang,omitempty" gorm:"default:'en'"`
+	IsBlocked bool   `json:"is_blocked" gorm:"default:false"`
+	Role      string `json:"role" gorm:"index;default:'viewer'"`
+	Plan      string `json:"plan" gorm:"default:'free'"`
 
-	Preferences preferences.Model `gorm:"constraint:OnDelete:CASCADE;foreignKey:UserID"`
-	Analysis    []analysis.Model  `gorm:"constraint:OnDelete:CASCADE;foreignKey:UserID"`
+	Preferences    preferences.Model     `gorm:"constraint:OnDelete:CASCADE;foreignKey:UserID"`
+	Analysis       []analysis.Model      `gorm:"constraint:OnDelete:CASCADE;foreignKey:UserID"`
+	Watchlists     []watchlist.Model     `gorm:"constraint:OnDelete:CASCADE;foreignKey:UserID"`
+	APIKeys        []apikey.Model        `gorm:"constraint:OnDelete:CASCADE;foreignKey:UserID"`
+	Investigations []investigation.Model `gorm:"constraint:OnDelete:CASCADE;foreignKey:UserID"`
 } //@name User
 
 // BeforeCreate encrypt the password before creating
@@ -49,3 +55,22 @@ func (m *Model) BeforeCreate(tx *gorm.DB) (err error) {
 func (m *Model) TableName() string {
 	return "users"
 }
+
+// UpdateBody body request to update the profile of the user
+type UpdateBody struct {
+	Username  string `json:"username" validate:"required,min=2"`
+	FirstName string `json:"first_name" validate:"required,min=2"`
+	LastName  string `json:"last_name" validate:"required,min=2"`
+	Lang      string `json:"lang" validate:"omitempty,len=2,alpha"`
+}
+
+// DeleteBody body request to delete the account, confirmed by password
+type DeleteBody struct {
+	Password string `json:"password" validate:"required"`
+}
+
+// PreferencesBody body request to update the preferences of the user
+type PreferencesBody struct {
+	Theme   string `json:"theme" validate:"required,alpha"`
+	Gravity bool   `json:"gravity"`
+}
@@ -17,3 +17,8 @@ type Model struct {
 func (m *Model) TableName() string {
 	return "preferences"
 }
+
+// Default returns the preferences of users who never set them
+func Default(userID uuid.UUID) *Model {
+	return &Model{UserID: userID, Theme: "blue", Gravity: true}
+}
@@ -1,7 +1,225 @@
 package user
 
-import "github.com/labstack/echo/v4"
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/xn3cr0nx/bitgodine/pkg/validator"
+)
 
 // Routes mounts user routes on the main group
 func Routes(g *echo.Group, s Service) {
+	r := g.Group("/user/me", validator.JWT())
+
+	r.GET("", getMe(s))
+	r.PUT("", updateMe(s))
+	r.DELETE("", deleteMe(s))
+	r.GET("/preferences", getPreferences(s))
+	r.PUT("/preferences", updatePreferences(s))
+	r.DELETE("/preferences", resetPreferences(s))
+}
+
+// getMe godoc
+// @ID get-me
+//
+// @Router /user/me [get]
+// @Summary Get profile
+// @Description get the profile of the authenticated user
+// @Tags user
+//
+// @Security ApiKeyAuth
+//
+// @Accept  json
+// @Produce  json
+//
+// @Success 200 {object} Model
+// @Success 500 {string} string
+func getMe(s Service) func(echo.Context) error {
+	return func(c echo.Context) error {
+		ID, err := validator.UserID(c)
+		if err != nil {
+			return err
+		}
+
+		user, err := s.GetProfile(ID)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, user)
+	}
+}
+
+// updateMe godoc
+// @ID update-me
+//
+// @Router /user/me [put]
+// @Summary Update profile
+// @Description update the personal data of the authenticated user
+// @Tags user
+//
+// @Security ApiKeyAuth
+//
+// @Accept  json
+// @Produce  json
+//
+// @Param user body UpdateBody true "update body"
+//
+// @Success 200 {object} Model
+// @Success 500 {string} string
+func updateMe(s Service) func(echo.Context) error {
+	return func(c echo.Context) error {
+		ID, err := validator.UserID(c)
+		if err != nil {
+			return err
+		}
+
+		b := new(UpdateBody)
+		if err := validator.Struct(&c, b); err != nil {
+			return err
+		}
+
+		user, err := s.UpdateProfile(ID, b)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, user)
+	}
+}
+
+// deleteMe godoc
+// @ID delete-me
+//
+// @Router /user/me [delete]
+// @Summary Delete account
+// @Description permanently delete the account of the authenticated user and the owned resources
+// @Tags user
+//
+// @Security ApiKeyAuth
+//
+// @Accept  json
+// @Produce  json
+//
+// @Param user body DeleteBody true "delete body"
+//
+// @Success 200 {string} ok
+// @Success 500 {string} string
+func deleteMe(s Service) func(echo.Context) error {
+	return func(c echo.Context) error {
+		ID, err := validator.UserID(c)
+		if err != nil {
+			return err
+		}
+
+		b := new(DeleteBody)
+		if err := validator.Struct(&c, b); err != nil {
+			return err
+		}
+
+		if err := s.DeleteUser(ID, b.Password); err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, "ok")
+	}
+}
+
+// getPreferences godoc
+// @ID get-preferences
+//
+// @Router /user/me/preferences [get]
+// @Summary Get preferences
+// @Description get the preferences of the authenticated user
+// @Tags user
+//
+// @Security ApiKeyAuth
+//
+// @Accept  json
+// @Produce  json
+//
+// @Success 200 {object} preferences.Model
+// @Success 500 {string} string
+func getPreferences(s Service) func(echo.Context) error {
+	return func(c echo.Context) error {
+		ID, err := validator.UserID(c)
+		if err != nil {
+			return err
+		}
+
+		prefs, err := s.GetPreferences(ID)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, prefs)
+	}
+}
+
+// updatePreferences godoc
+// @ID update-preferences
+//
+// @Router /user/me/preferences [put]
+// @Summary Update preferences
+// @Description set the preferences of the authenticated user
+// @Tags user
+//
+// @Security ApiKeyAuth
+//
+// @Accept  json
+// @Produce  json
+//
+// @Param preferences body PreferencesBody true "preferences body"
+//
+// @Success 200 {object} preferences.Model
+// @Success 500 {string} string
+func updatePreferences(s Service) func(echo.Context) error {
+	return func(c echo.Context) error {
+		ID, err := validator.UserID(c)
+		if err != nil {
+			return err
+		}
+
+		b := new(PreferencesBody)
+		if err := validator.Struct(&c, b); err != nil {
+			return err
+		}
+
+		prefs, err := s.UpdatePreferences(ID, b)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, prefs)
+	}
+}
+
+// resetPreferences godoc
+// @ID reset-preferences
+//
+// @Router /user/me/preferences [delete]
+// @Summary Reset preferences
+// @Description restore the default preferences of the authenticated user
+// @Tags user
+//
+// @Security ApiKeyAuth
+//
+// @Accept  json
+// @Produce  json
+//
+// @Success 200 {string} ok
+// @Success 500 {string} string
+func resetPreferences(s Service) func(echo.Context) error {
+	return func(c echo.Context) error {
+		ID, err := validator.UserID(c)
+		if err != nil {
+			return err
+		}
+
+		if err := s.ResetPreferences(ID); err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, "ok")
+	}
 }
@@ -1,9 +1,22 @@
 package user
 
 import (
+	"errors"
+	"fmt"
+	"net/http"
 	"time"
 
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+
+	"github.com/xn3cr0nx/bitgodine/internal/analysis"
+	"github.com/xn3cr0nx/bitgodine/internal/apikey"
+	"github.com/xn3cr0nx/bitgodine/internal/errorx"
+	"github.com/xn3cr0nx/bitgodine/internal/investigation"
+	"github.com/xn3cr0nx/bitgodine/internal/password"
 	"github.com/xn3cr0nx/bitgodine/internal/storage/db/postgres"
+	"github.com/xn3cr0nx/bitgodine/internal/user/preferences"
+	"github.com/xn3cr0nx/bitgodine/internal/watchlist"
 )
 
 // Service interface exports available methods for user service
@@ -12,7 +25,12 @@ type Service interface {
 	GetUserByEmail(email string) (user *Model, err error)
 	CreateUser(user *Model) (err error)
 	NewLogin(ID string) (time.Time, error)
-	NewAPIKey(ID, key string) (err error)
+	GetProfile(ID string) (user *Model, err error)
+	UpdateProfile(ID string, body *UpdateBody) (user *Model, err error)
+	DeleteUser(ID, password string) (err error)
+	GetPreferences(ID string) (prefs *preferences.Model, err error)
+	UpdatePreferences(ID string, body *PreferencesBody) (prefs *preferences.Model, err error)
+	ResetPreferences(ID string) (err error)
 }
 
 type service struct {
@@ -61,12 +79,100 @@ func (s *service) NewLogin(ID string) (time.Time, error) {
 	return t, err
 }
 
-func (s *service) NewAPIKey(ID, key string) (err error) {
-	user := Model{}
-	if err = s.Repository.Model(&Model{}).Where("id = ?", ID).Select("api_keys").Find(&user).Error; err != nil {
+// GetProfile retrieves the user, failing if it doesn't exist
+func (s *service) GetProfile(ID string) (user *Model, err error) {
+	user = new(Model)
+	if err = s.Repository.Preload("Preferences").Where("id = ?", ID).First(user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			err = fmt.Errorf("user %s %w", ID, errorx.ErrNotFound)
+		}
+		user = nil
+	}
+	return
+}
+
+// UpdateProfile updates the personal data of the user. Email, password and role have their own flows
+func (s *service) UpdateProfile(ID string, body *UpdateBody) (user *Model, err error) {
+	fields := map[string]interface{}{
+		"username":   body.Username,
+		"first_name": body.FirstName,
+		"last_name":  body.LastName,
+	}
+	if body.Lang != "" {
+		fields["lang"] = body.Lang
+	}
+	if err = s.Repository.Model(&Model{}).Where("id = ?", ID).Updates(fields).Error; err != nil {
 		return
 	}
+	return s.GetProfile(ID)
+}
+
+// DeleteUser permanently deletes the user and the owned resources, after checking the password.
+// Alerts raised for the user and investigation shares granted to the user are deleted as well
+func (s *service) DeleteUser(ID, pass string) (err error) {
+	user, err := s.GetProfile(ID)
+	if err != nil {
+		return
+	}
+	if !password.Verify(user.Password, pass) {
+		return echo.NewHTTPError(http.StatusUnauthorized, echo.ErrUnauthorized)
+	}
+	err = s.Repository.Transaction(func(tx *gorm.DB) error {
+		owned := tx.Model(&investigation.Model{}).Select("id").Where("user_id = ?", user.ID)
+		if err := tx.Unscoped().Where("user_id = ? OR investigation_id IN (?)", user.ID, owned).Delete(&investigation.Share{}).Error; err != nil {
+			return err
+		}
+		for _, model := range []interface{}{
+			&watchlist.Alert{},
+			&watchlist.Model{},
+			&investigation.Model{},
+			&apikey.Model{},
+			&analysis.Model{},
+			&preferences.Model{},
+		} {
+			if err := tx.Unscoped().Where("user_id = ?", user.ID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Unscoped().Delete(user).Error
+	})
+	return
+}
+
+// GetPreferences retrieves the preferences of the user, defaults if never set
+func (s *service) GetPreferences(ID string) (prefs *preferences.Model, err error) {
+	user, err := s.GetProfile(ID)
+	if err != nil {
+		return
+	}
+	prefs = &user.Preferences
+	if prefs.UserID != user.ID {
+		prefs = preferences.Default(user.ID)
+	}
+	return
+}
+
+// UpdatePreferences creates or updates the preferences of the user
+func (s *service) UpdatePreferences(ID string, body *PreferencesBody) (prefs *preferences.Model, err error) {
+	user, err := s.GetProfile(ID)
+	if err != nil {
+		return
+	}
+	prefs = new(preferences.Model)
+	if err = s.Repository.Where("user_id = ?", user.ID).Attrs(preferences.Model{UserID: user.ID}).FirstOrCreate(prefs).Error; err != nil {
+		return
+	}
+	// updated with a map since gravity false is a zero value
+	if err = s.Repository.Model(&preferences.Model{}).Where("user_id = ?", user.ID).
+		Updates(map[string]interface{}{"theme": body.Theme, "gravity": body.Gravity}).Error; err != nil {
+		return
+	}
+	prefs.Theme, prefs.Gravity = body.Theme, body.Gravity
+	return
+}
 
-	err = s.Repository.Model(&Model{}).Where("id = ?", ID).Update("api_keys", append(user.APIKeys, key)).Error
+// ResetPreferences deletes the preferences of the user, restoring defaults
+func (s *service) ResetPreferences(ID string) (err error) {
+	err = s.Repository.Unscoped().Where("user_id = ?", ID).Delete(&preferences.Model{}).Error
 	return
 }
@@ -0,0 +1,115 @@
+package watchlist
+
+import (
+	"strconv"
+
+	"github.com/xn3cr0nx/bitgodine/internal/address"
+	"github.com/xn3cr0nx/bitgodine/internal/tx"
+	"github.com/xn3cr0nx/bitgodine/pkg/logger"
+)
+
+// Event activity of an address in a block. Received events refer to the output created by TxID,
+// spent events to the input Vout of TxID spending an output of the address
+type Event struct {
+	Kind      string `json:"kind"`
+	Height    int32  `json:"height"`
+	BlockHash string `json:"block_hash"`
+	TxID      string `json:"txid"`
+	Vout      uint32 `json:"vout"`
+	Address   string `json:"address"`
+	Value     int64  `json:"value"`
+}
+
+// Labels clusters and tags nicknames of the addresses involved in a block
+type Labels struct {
+	Clusters map[string]uint64
+	Tags     map[string][]string
+}
+
+// Events extracts received and spent events from the transactions of a block. Spent outputs are resolved
+// through prevout, inputs whose spent output can't be resolved are skipped without losing the block events
+func Events(height int32, hash string, transactions []tx.Tx, prevout address.Prevout) (events []Event) {
+	for _, transaction := range transactions {
+		for i, in := range transaction.Vin {
+			if in.IsCoinbase {
+				continue
+			}
+			out, err := prevout(in.TxID, in.Vout)
+			if err != nil {
+				logger.Warn("Watchlist", "unresolved prevout "+in.TxID+":"+strconv.Itoa(int(in.Vout)), logger.Params{"txid": transaction.TxID, "error": err.Error()})
+				continue
+			}
+			if out.ScriptpubkeyAddress == "" {
+				continue
+			}
+			events = append(events, Event{
+				Kind:      Spent,
+				Height:    height,
+				BlockHash: hash,
+				TxID:      transaction.TxID,
+				Vout:      uint32(i),
+				Address:   out.ScriptpubkeyAddress,
+				Value:     out.Value,
+			})
+		}
+		for o, out := range transaction.Vout {
+			if out.ScriptpubkeyAddress == "" {
+				continue
+			}
+			events = append(events, Event{
+				Kind:      Received,
+				Height:    height,
+				BlockHash: hash,
+				TxID:      transaction.TxID,
+				Vout:      uint32(o),
+				Address:   out.ScriptpubkeyAddress,
+				Value:     out.Value,
+			})
+		}
+	}
+	return
+}
+
+// Match returns an alert for each event involving an entity watched by an active watchlist
+func Match(events []Event, watchlists []Model, labels Labels) (alerts []Alert) {
+	for _, w := range watchlists {
+		if !w.Active {
+			continue
+		}
+		for _, e := range events {
+			if !matches(&w, e.Address, labels) {
+				continue
+			}
+			alerts = append(alerts, Alert{
+				WatchlistID: w.ID,
+				UserID:      w.UserID,
+				Kind:        e.Kind,
+				Height:      e.Height,
+				BlockHash:   e.BlockHash,
+				TxID:        e.TxID,
+				Vout:        e.Vout,
+				Address:     e.Address,
+				Value:       e.Value,
+			})
+		}
+	}
+	return
+}
+
+// matches checks whether the address is part of the entity watched by the watchlist
+func matches(w *Model, address string, labels Labels) bool {
+	switch w.Type {
+	case Address:
+		return w.Value == address
+	case Cluster:
+		cluster, ok := labels.Clusters[address]
+		return ok && strconv.FormatUint(cluster, 10) == w.Value
+	case Tag:
+		for _, nickname := range labels.Tags[address] {
+			if nickname == w.Value {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,136 @@
+package watchlist
+
+import (
+	"fmt"
+	"net"
+	"net/mail"
+	"net/url"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/xn3cr0nx/bitgodine/internal/errorx"
+)
+
+// Watched entities types
+const (
+	Address = "address"
+	Cluster = "cluster"
+	Tag     = "tag"
+)
+
+// Delivery channels of the alerts
+const (
+	Email   = "email"
+	Webhook = "webhook"
+	Kafka   = "kafka"
+)
+
+// Activity kinds of a watched entity
+const (
+	Received = "received"
+	Spent    = "spent"
+)
+
+// Model watchlist struct with validation. Value is the watched address, the cluster id or the tag nickname
+// depending on Type, and Target is the email address or the webhook url the alerts are delivered to
+type Model struct {
+	gorm.Model
+	ID      uuid.UUID `json:"id" gorm:"primarykey;index;unique"`
+	UserID  uuid.UUID `json:"user_id" gorm:"index;not null"`
+	Name    string    `json:"name,omitempty" validate:""`
+	Type    string    `json:"type" validate:"required,oneof=address cluster tag" gorm:"index;not null"`
+	Value   string    `json:"value" validate:"required" gorm:"index;not null"`
+	Channel string    `json:"channel" validate:"required,oneof=email webhook kafka" gorm:"not null"`
+	Target  string    `json:"target,omitempty" validate:"required_unless=Channel kafka"`
+	Active  bool      `json:"active" validate:"" gorm:"default:true"`
+
+	Alerts []Alert `json:"-" gorm:"constraint:OnDelete:CASCADE;foreignKey:WatchlistID"`
+} //@name Watchlist
+
+// BeforeCreate generates the watchlist id
+func (m *Model) BeforeCreate(tx *gorm.DB) (err error) {
+	m.ID = uuid.New()
+	return
+}
+
+// TableName defines default table name
+func (m *Model) TableName() string {
+	return "watchlists"
+}
+
+// ValidateTarget checks the target matches the channel, an email address for emails and an https url
+// for webhooks. Webhooks to hosts that are not public are rejected, the check is repeated on delivery
+func (m *Model) ValidateTarget() (err error) {
+	switch m.Channel {
+	case Email:
+		addr, e := mail.ParseAddress(m.Target)
+		if e != nil || addr.Address != m.Target {
+			err = fmt.Errorf("%w: target must be an email address", errorx.ErrInvalidArgument)
+		}
+	case Webhook:
+		u, e := url.Parse(m.Target)
+		if e != nil || u.Scheme != "https" || u.Hostname() == "" || u.User != nil {
+			return fmt.Errorf("%w: target must be an https url", errorx.ErrInvalidArgument)
+		}
+		host := strings.ToLower(u.Hostname())
+		if ip := net.ParseIP(host); (ip != nil && !public(ip)) || host == "localhost" || strings.HasSuffix(host, ".localhost") {
+			err = fmt.Errorf("%w: webhook host %s is not public", errorx.ErrInvalidArgument, host)
+		}
+	}
+	return
+}
+
+// reserved networks webhooks can't be delivered to
+var reserved = func() (networks []*net.IPNet) {
+	for _, cidr := range []string{
+		"0.0.0.0/8", "10.0.0.0/8", "100.64.0.0/10", "127.0.0.0/8", "169.254.0.0/16", "172.16.0.0/12",
+		"192.168.0.0/16", "::/128", "::1/128", "fc00::/7", "fe80::/10",
+	} {
+		_, n, _ := net.ParseCIDR(cidr)
+		networks = append(networks, n)
+	}
+	return
+}()
+
+// public checks the ip is not a loopback, private, link-local or multicast address
+func public(ip net.IP) bool {
+	if ip.IsMulticast() || ip.IsUnspecified() {
+		return false
+	}
+	for _, n := range reserved {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// Alert history of watched entities activity
+type Alert struct {
+	gorm.Model
+	ID          uuid.UUID `json:"id" gorm:"primarykey;index;unique"`
+	WatchlistID uuid.UUID `json:"watchlist_id" gorm:"index;not null"`
+	UserID      uuid.UUID `json:"user_id" gorm:"index;not null"`
+	Kind        string    `json:"kind" gorm:"not null"`
+	Height      int32     `json:"height" gorm:"index"`
+	BlockHash   string    `json:"block_hash"`
+	TxID        string    `json:"txid" gorm:"size:64;index"`
+	Vout        uint32    `json:"vout"`
+	Address     string    `json:"address" gorm:"size:64"`
+	Value       int64     `json:"value"`
+	Delivered   bool      `json:"delivered" gorm:"default:false"`
+	Error       string    `json:"error,omitempty"`
+} //@name Alert
+
+// BeforeCreate generates the alert id
+func (m *Alert) BeforeCreate(tx *gorm.DB) (err error) {
+	m.ID = uuid.New()
+	return
+}
+
+// TableName defines default table name
+func (m *Alert) TableName() string {
+	return "alerts"
+}
@@ -0,0 +1,134 @@
+package watchlist
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/sendgrid/sendgrid-go/helpers/mail"
+
+	"github.com/xn3cr0nx/bitgodine/internal/errorx"
+	"github.com/xn3cr0nx/bitgodine/pkg/mailer"
+)
+
+// Notifier interface to deliver the alerts raised by a watchlist
+type Notifier interface {
+	Notify(ctx context.Context, watchlist *Model, alerts []Alert) error
+}
+
+// Notification payload delivered through webhooks and kafka
+type Notification struct {
+	Watchlist *Model  `json:"watchlist"`
+	Alerts    []Alert `json:"alerts"`
+}
+
+// MailNotifier delivers alerts by email to the watchlist target
+type MailNotifier struct {
+	Client *mailer.Client
+}
+
+// Notify sends a single email listing all the alerts
+func (n *MailNotifier) Notify(ctx context.Context, watchlist *Model, alerts []Alert) (err error) {
+	from := mail.NewEmail("Bitgodine", n.Client.Email)
+	to := mail.NewEmail("", watchlist.Target)
+	subject := fmt.Sprintf("New activity on watched %s %s", watchlist.Type, watchlist.Value)
+
+	var content strings.Builder
+	for _, alert := range alerts {
+		fmt.Fprintf(&content, "block %d: %s %d satoshi %s:%d (%s)\n", alert.Height, alert.Address, alert.Value, alert.TxID, alert.Vout, alert.Kind)
+	}
+
+	resp, err := n.Client.Client.Send(mail.NewSingleEmail(from, subject, to, content.String(), ""))
+	if err != nil {
+		return
+	}
+	if resp.StatusCode >= 400 {
+		err = fmt.Errorf("mailer responded with status %d: %s", resp.StatusCode, resp.Body)
+	}
+	return
+}
+
+// WebhookNotifier delivers alerts posting the notification to the watchlist target url
+type WebhookNotifier struct {
+	Client *http.Client
+}
+
+// NewWebhookNotifier returns a webhook notifier giving up on slow endpoints after timeout. Connections are
+// checked once resolved, so that webhooks, their redirects included, can't reach loopback, private or
+// link-local addresses
+func NewWebhookNotifier(timeout time.Duration) *WebhookNotifier {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, c syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !public(ip) {
+				return fmt.Errorf("%w: webhook address %s is not public", errorx.ErrInvalidArgument, host)
+			}
+			return nil
+		},
+	}
+	return &WebhookNotifier{Client: &http.Client{
+		Timeout:   timeout,
+		Transport: &http.Transport{DialContext: dialer.DialContext, TLSHandshakeTimeout: timeout},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if req.URL.Scheme != "https" {
+				return fmt.Errorf("%w: webhook redirected to %s", errorx.ErrInvalidArgument, req.URL.Scheme)
+			}
+			return nil
+		},
+	}}
+}
+
+// Notify posts the notification to the webhook, any non 2xx response is a delivery failure
+func (n *WebhookNotifier) Notify(ctx context.Context, watchlist *Model, alerts []Alert) (err error) {
+	if err = watchlist.ValidateTarget(); err != nil {
+		return
+	}
+	body, err := json.Marshal(Notification{watchlist, alerts})
+	if err != nil {
+		return
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, watchlist.Target, bytes.NewReader(body))
+	if err != nil {
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := n.Client.Do(req)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		err = fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return
+}
+
+// Publisher interface of the broker the kafka notifier pushes to
+type Publisher interface {
+	Push(ctx context.Context, key, value string) (err error)
+}
+
+// KafkaNotifier delivers alerts pushing the notification to a kafka topic, keyed by watchlist
+type KafkaNotifier struct {
+	Publisher Publisher
+}
+
+// Notify pushes the notification to the topic
+func (n *KafkaNotifier) Notify(ctx context.Context, watchlist *Model, alerts []Alert) (err error) {
+	value, err := json.Marshal(Notification{watchlist, alerts})
+	if err != nil {
+		return
+	}
+	err = n.Publisher.Push(ctx, watchlist.ID.String(), string(value))
+	return
+}
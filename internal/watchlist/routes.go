@@ -0,0 +1,257 @@
+package watchlist
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/xn3cr0nx/bitgodine/internal/errorx"
+	"github.com/xn3cr0nx/bitgodine/pkg/validator"
+)
+
+// Routes mounts all /watchlists based routes on the main group
+func Routes(g *echo.Group, s Service) {
+	r := g.Group("/watchlists", validator.JWT())
+
+	r.GET("", getWatchlists(s))
+	r.POST("", createWatchlist(s))
+	r.GET("/alerts", getAlerts(s))
+	r.GET("/:id", getWatchlist(s))
+	r.PUT("/:id", updateWatchlist(s))
+	r.DELETE("/:id", deleteWatchlist(s))
+}
+
+// getWatchlists godoc
+// @ID get-watchlists
+//
+// @Router /watchlists [get]
+// @Summary Get watchlists list
+// @Description get the watchlists of the authenticated user
+// @Tags watchlists
+//
+// @Security ApiKeyAuth
+//
+// @Accept  json
+// @Produce  json
+//
+// @Success 200 {array} Model
+// @Success 500 {string} string
+func getWatchlists(s Service) func(echo.Context) error {
+	return func(c echo.Context) error {
+		ID, err := validator.UserID(c)
+		if err != nil {
+			return err
+		}
+
+		watchlists, err := s.GetWatchlists(ID)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, watchlists)
+	}
+}
+
+// createWatchlist godoc
+// @ID create-watchlist
+//
+// @Router /watchlists [post]
+// @Summary Create watchlist
+// @Description watch an address, a cluster or a tag, alerting on new activity through email, webhook or kafka
+// @Tags watchlists
+//
+// @Security ApiKeyAuth
+//
+// @Accept  json
+// @Produce  json
+//
+// @Param model body Model true "watchlist model"
+//
+// @Success 201 {object} Model
+// @Success 500 {string} string
+func createWatchlist(s Service) func(echo.Context) error {
+	return func(c echo.Context) error {
+		ID, err := validator.UserID(c)
+		if err != nil {
+			return err
+		}
+
+		b := new(Model)
+		if err := validator.Struct(&c, b); err != nil {
+			return err
+		}
+
+		if err := s.CreateWatchlist(ID, b); err != nil {
+			if errors.Is(err, errorx.ErrInvalidArgument) {
+				return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+			}
+			return err
+		}
+
+		return c.JSON(http.StatusCreated, b)
+	}
+}
+
+// getAlerts godoc
+// @ID get-watchlists-alerts
+//
+// @Router /watchlists/alerts [get]
+// @Summary Get alerts history
+// @Description get the alerts raised by the watchlists of the authenticated user, most recent first
+// @Tags watchlists
+//
+// @Security ApiKeyAuth
+//
+// @Accept  json
+// @Produce  json
+//
+// @Param limit query int false "Limit"
+// @Param skip query int false "Skip"
+//
+// @Success 200 {array} Alert
+// @Success 500 {string} string
+func getAlerts(s Service) func(echo.Context) error {
+	return func(c echo.Context) error {
+		ID, err := validator.UserID(c)
+		if err != nil {
+			return err
+		}
+
+		type Query struct {
+			Limit int `query:"limit" validate:"omitempty,gt=0"`
+			Skip  int `query:"skip" validate:"omitempty,gte=0"`
+		}
+		q := new(Query)
+		if err := validator.Struct(&c, q); err != nil {
+			return err
+		}
+		if q.Limit == 0 {
+			q.Limit = 50
+		}
+
+		alerts, err := s.GetAlerts(ID, q.Limit, q.Skip)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, alerts)
+	}
+}
+
+// getWatchlist godoc
+// @ID get-watchlist
+//
+// @Router /watchlists/{id} [get]
+// @Summary Get watchlist
+// @Description get a watchlist of the authenticated user by id
+// @Tags watchlists
+//
+// @Security ApiKeyAuth
+//
+// @Accept  json
+// @Produce  json
+//
+// @Param id path string true "watchlist id"
+//
+// @Success 200 {object} Model
+// @Success 500 {string} string
+func getWatchlist(s Service) func(echo.Context) error {
+	return func(c echo.Context) error {
+		ID, err := validator.UserID(c)
+		if err != nil {
+			return err
+		}
+		id := c.Param("id")
+		if err := c.Echo().Validator.(*validator.CustomValidator).Var(id, "required,uuid"); err != nil {
+			return err
+		}
+
+		watchlist, err := s.GetWatchlist(ID, id)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, watchlist)
+	}
+}
+
+// updateWatchlist godoc
+// @ID update-watchlist
+//
+// @Router /watchlists/{id} [put]
+// @Summary Update watchlist
+// @Description update the watched entity, the delivery channel or the status of a watchlist
+// @Tags watchlists
+//
+// @Security ApiKeyAuth
+//
+// @Accept  json
+// @Produce  json
+//
+// @Param id path string true "watchlist id"
+// @Param model body Model true "watchlist model"
+//
+// @Success 200 {object} Model
+// @Success 500 {string} string
+func updateWatchlist(s Service) func(echo.Context) error {
+	return func(c echo.Context) error {
+		ID, err := validator.UserID(c)
+		if err != nil {
+			return err
+		}
+		id := c.Param("id")
+		if err := c.Echo().Validator.(*validator.CustomValidator).Var(id, "required,uuid"); err != nil {
+			return err
+		}
+
+		b := new(Model)
+		if err := validator.Struct(&c, b); err != nil {
+			return err
+		}
+
+		if err := s.UpdateWatchlist(ID, id, b); err != nil {
+			if errors.Is(err, errorx.ErrInvalidArgument) {
+				return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+			}
+			return err
+		}
+
+		return c.JSON(http.StatusOK, b)
+	}
+}
+
+// deleteWatchlist godoc
+// @ID delete-watchlist
+//
+// @Router /watchlists/{id} [delete]
+// @Summary Delete watchlist
+// @Description delete a watchlist of the authenticated user
+// @Tags watchlists
+//
+// @Security ApiKeyAuth
+//
+// @Accept  json
+// @Produce  json
+//
+// @Param id path string true "watchlist id"
+//
+// @Success 200 {string} ok
+// @Success 500 {string} string
+func deleteWatchlist(s Service) func(echo.Context) error {
+	return func(c echo.Context) error {
+		ID, err := validator.UserID(c)
+		if err != nil {
+			return err
+		}
+		id := c.Param("id")
+		if err := c.Echo().Validator.(*validator.CustomValidator).Var(id, "required,uuid"); err != nil {
+			return err
+		}
+
+		if err := s.DeleteWatchlist(ID, id); err != nil {
+			return err
+		}
+
+		return c.JSON(http.StatusOK, "ok")
+	}
+}
@@ -0,0 +1,194 @@
+package watchlist
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/xn3cr0nx/bitgodine/internal/block"
+	"github.com/xn3cr0nx/bitgodine/internal/errorx"
+	"github.com/xn3cr0nx/bitgodine/internal/storage/db/postgres"
+	"github.com/xn3cr0nx/bitgodine/internal/tx"
+	"github.com/xn3cr0nx/bitgodine/pkg/logger"
+)
+
+// Delivery defaults of the watcher
+const (
+	// DefaultDeliveryTimeout default time given to a notifier to deliver the alerts of a block
+	DefaultDeliveryTimeout = 10 * time.Second
+	// DefaultDeliveryWorkers default number of deliveries executed concurrently
+	DefaultDeliveryWorkers = 4
+	// DeliveryQueue number of deliveries waiting for a worker, further ones are recorded as failed
+	DeliveryQueue = 1000
+)
+
+// ErrDeliveryQueueFull the alerts couldn't be queued for delivery
+var ErrDeliveryQueueFull = errors.New("delivery queue full")
+
+// Watcher matches the activity of each parsed block against the active watchlists,
+// storing the raised alerts and delivering them through the watchlist channel.
+// Deliveries are executed by a pool of workers, so slow channels don't hold the parser
+type Watcher struct {
+	Repository *postgres.Pg
+	Blocks     block.Service
+	Notifiers  map[string]Notifier
+	Timeout    time.Duration
+	queue      chan delivery
+}
+
+// delivery alerts raised by a watchlist waiting to be notified
+type delivery struct {
+	watchlist Model
+	alerts    []Alert
+}
+
+// NewWatcher returns a new watcher delivering alerts through the passed notifiers, keyed by channel.
+// Spent outputs are resolved through the block service storing the parsed blocks
+func NewWatcher(r *postgres.Pg, blocks block.Service, notifiers map[string]Notifier) *Watcher {
+	return &Watcher{
+		Repository: r,
+		Blocks:     blocks,
+		Notifiers:  notifiers,
+		Timeout:    DefaultDeliveryTimeout,
+		queue:      make(chan delivery, DeliveryQueue),
+	}
+}
+
+// Start runs the workers delivering the queued alerts
+func (w *Watcher) Start(workers int) {
+	if workers <= 0 {
+		workers = DefaultDeliveryWorkers
+	}
+	for i := 0; i < workers; i++ {
+		go func() {
+			for d := range w.queue {
+				if err := w.deliver(&d.watchlist, d.alerts, w.notify(&d.watchlist, d.alerts)); err != nil {
+					logger.Error("Watchlist", err, logger.Params{"watchlist": d.watchlist.ID.String()})
+				}
+			}
+		}()
+	}
+}
+
+// OnBlock raises the alerts for the activity of watched entities in the block. Delivery failures
+// don't fail the block, they are recorded in the alerts history once the alerts are delivered
+func (w *Watcher) OnBlock(height int32, hash string, transactions []tx.Tx) (err error) {
+	var watchlists []Model
+	if err = w.Repository.Where("active = ?", true).Find(&watchlists).Error; err != nil {
+		return
+	}
+	if len(watchlists) == 0 {
+		return
+	}
+
+	events := Events(height, hash, transactions, w.Blocks.Prevout(transactions))
+	labels, err := w.labels(events, watchlists)
+	if err != nil {
+		return
+	}
+
+	alerts := Match(events, watchlists, labels)
+	for i := range watchlists {
+		var raised []Alert
+		for _, alert := range alerts {
+			if alert.WatchlistID == watchlists[i].ID {
+				raised = append(raised, alert)
+			}
+		}
+		if len(raised) == 0 {
+			continue
+		}
+		if err = w.Deliver(&watchlists[i], raised); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// Deliver stores the alerts and queues them for delivery through the watchlist channel. When the queue
+// is full the alerts are recorded as not delivered
+func (w *Watcher) Deliver(watchlist *Model, alerts []Alert) (err error) {
+	if err = w.Repository.Create(&alerts).Error; err != nil {
+		return
+	}
+	select {
+	case w.queue <- delivery{*watchlist, alerts}:
+	default:
+		err = w.deliver(watchlist, alerts, ErrDeliveryQueueFull)
+	}
+	return
+}
+
+// deliver records the outcome of the delivery of the alerts
+func (w *Watcher) deliver(watchlist *Model, alerts []Alert, delivery error) (err error) {
+	update := map[string]interface{}{"delivered": delivery == nil}
+	if delivery != nil {
+		logger.Error("Watchlist", delivery, logger.Params{"watchlist": watchlist.ID.String(), "channel": watchlist.Channel})
+		update["error"] = delivery.Error()
+	}
+	ids := make([]interface{}, len(alerts))
+	for i, alert := range alerts {
+		ids[i] = alert.ID
+	}
+	err = w.Repository.Model(&Alert{}).Where("id IN ?", ids).Updates(update).Error
+	return
+}
+
+func (w *Watcher) notify(watchlist *Model, alerts []Alert) error {
+	notifier, ok := w.Notifiers[watchlist.Channel]
+	if !ok {
+		return fmt.Errorf("%w: no notifier configured for %s channel", errorx.ErrConfig, watchlist.Channel)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), w.Timeout)
+	defer cancel()
+	return notifier.Notify(ctx, watchlist, alerts)
+}
+
+// labels resolves clusters and tags of the addresses involved in the events, only if some watchlist needs them
+func (w *Watcher) labels(events []Event, watchlists []Model) (labels Labels, err error) {
+	labels = Labels{Clusters: make(map[string]uint64), Tags: make(map[string][]string)}
+	var clusters, tags bool
+	for _, watchlist := range watchlists {
+		clusters = clusters || watchlist.Type == Cluster
+		tags = tags || watchlist.Type == Tag
+	}
+	if !clusters && !tags {
+		return
+	}
+
+	seen := make(map[string]bool)
+	var addresses []string
+	for _, e := range events {
+		if !seen[e.Address] {
+			seen[e.Address] = true
+			addresses = append(addresses, e.Address)
+		}
+	}
+
+	if clusters {
+		var rows []struct {
+			Address string
+			Cluster uint64
+		}
+		if err = w.Repository.Table("clusters").Select("address, cluster").Where("address IN ?", addresses).Scan(&rows).Error; err != nil {
+			return
+		}
+		for _, row := range rows {
+			labels.Clusters[row.Address] = row.Cluster
+		}
+	}
+	if tags {
+		var rows []struct {
+			Address  string
+			Nickname string
+		}
+		if err = w.Repository.Table("tags").Select("address, nickname").Where("address IN ?", addresses).Scan(&rows).Error; err != nil {
+			return
+		}
+		for _, row := range rows {
+			labels.Tags[row.Address] = append(labels.Tags[row.Address], row.Nickname)
+		}
+	}
+	return
+}
@@ -0,0 +1,107 @@
+package watchlist
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/xn3cr0nx/bitgodine/internal/errorx"
+	"github.com/xn3cr0nx/bitgodine/internal/storage/db/postgres"
+	"github.com/xn3cr0nx/bitgodine/pkg/cache"
+	"github.com/xn3cr0nx/bitgodine/pkg/validator"
+)
+
+// Service interface exports available methods for watchlist service
+type Service interface {
+	GetWatchlists(userID string) (watchlists []Model, err error)
+	GetWatchlist(userID, ID string) (watchlist *Model, err error)
+	CreateWatchlist(userID string, watchlist *Model) (err error)
+	UpdateWatchlist(userID, ID string, watchlist *Model) (err error)
+	DeleteWatchlist(userID, ID string) (err error)
+	GetAlerts(userID string, limit, skip int) (alerts []Alert, err error)
+}
+
+type service struct {
+	Repository *postgres.Pg
+	Cache      *cache.Cache
+}
+
+// NewService instantiates a new Service layer for customer
+func NewService(r *postgres.Pg, c *cache.Cache) *service {
+	return &service{
+		Repository: r,
+		Cache:      c,
+	}
+}
+
+// GetWatchlists retrieves the watchlists of the user
+func (s *service) GetWatchlists(userID string) (watchlists []Model, err error) {
+	err = s.Repository.Where("user_id = ?", userID).Find(&watchlists).Error
+	return
+}
+
+// GetWatchlist retrieves a watchlist of the user by id
+func (s *service) GetWatchlist(userID, ID string) (watchlist *Model, err error) {
+	watchlist = new(Model)
+	if err = s.Repository.Where("id = ? AND user_id = ?", ID, userID).First(watchlist).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			err = fmt.Errorf("watchlist %s %w", ID, errorx.ErrNotFound)
+		}
+		watchlist = nil
+	}
+	return
+}
+
+// CreateWatchlist creates a new watchlist owned by the user
+func (s *service) CreateWatchlist(userID string, watchlist *Model) (err error) {
+	if watchlist.UserID, err = validator.ParseUUID(userID); err != nil {
+		return
+	}
+	if err = watchlist.ValidateTarget(); err != nil {
+		return
+	}
+	watchlist.Active = true
+	err = s.Repository.Model(&Model{}).Create(watchlist).Error
+	return
+}
+
+// UpdateWatchlist updates the watched entity, the delivery channel and the status of the watchlist
+func (s *service) UpdateWatchlist(userID, ID string, watchlist *Model) (err error) {
+	if err = watchlist.ValidateTarget(); err != nil {
+		return
+	}
+	stored, err := s.GetWatchlist(userID, ID)
+	if err != nil {
+		return
+	}
+	stored.Name = watchlist.Name
+	stored.Type = watchlist.Type
+	stored.Value = watchlist.Value
+	stored.Channel = watchlist.Channel
+	stored.Target = watchlist.Target
+	stored.Active = watchlist.Active
+	if err = s.Repository.Save(stored).Error; err != nil {
+		return
+	}
+	*watchlist = *stored
+	return
+}
+
+// DeleteWatchlist deletes a watchlist of the user
+func (s *service) DeleteWatchlist(userID, ID string) (err error) {
+	res := s.Repository.Where("id = ? AND user_id = ?", ID, userID).Delete(&Model{})
+	if err = res.Error; err != nil {
+		return
+	}
+	if res.RowsAffected == 0 {
+		err = fmt.Errorf("watchlist %s %w", ID, errorx.ErrNotFound)
+	}
+	return
+}
+
+// GetAlerts retrieves the alerts history of the user, most recent first
+func (s *service) GetAlerts(userID string, limit, skip int) (alerts []Alert, err error) {
+	err = s.Repository.Where("user_id = ?", userID).Order("created_at desc").Limit(limit).Offset(limit * skip).Find(&alerts).Error
+	return
+}
@@ -0,0 +1,237 @@
+package watchlist
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/xn3cr0nx/bitgodine/internal/block"
+	"github.com/xn3cr0nx/bitgodine/internal/errorx"
+	"github.com/xn3cr0nx/bitgodine/internal/storage/kv"
+	"github.com/xn3cr0nx/bitgodine/internal/tx"
+	"github.com/xn3cr0nx/bitgodine/pkg/cache"
+	"github.com/xn3cr0nx/bitgodine/pkg/encoding"
+	"github.com/xn3cr0nx/bitgodine/pkg/logger"
+	"github.com/xn3cr0nx/bitgodine/pkg/mailer"
+)
+
+type TestWatchlistSuite struct {
+	suite.Suite
+	db        *kv.DBMock
+	cache     *cache.Cache
+	funding   tx.Tx
+	spending  tx.Tx
+	watchlist Model
+}
+
+type publisher struct {
+	key, value string
+}
+
+func (p *publisher) Push(ctx context.Context, key, value string) error {
+	p.key, p.value = key, value
+	return nil
+}
+
+func (suite *TestWatchlistSuite) SetupSuite() {
+	logger.Setup()
+
+	c, err := cache.NewCache(nil)
+	require.Nil(suite.T(), err)
+	suite.cache = c
+	suite.db = kv.NewDBMock()
+
+	suite.funding = tx.Tx{
+		TxID: strings.Repeat("a", 64),
+		Vin:  []tx.Input{{IsCoinbase: true}},
+		Vout: []tx.Output{{ScriptpubkeyAddress: "1Watched", Value: 100000}},
+	}
+	suite.spending = tx.Tx{
+		TxID: strings.Repeat("b", 64),
+		Vin:  []tx.Input{{TxID: suite.funding.TxID, Vout: 0}},
+		Vout: []tx.Output{
+			{ScriptpubkeyAddress: "1Payee", Value: 60000},
+			{ScriptpubkeyAddress: "1Change", Value: 39000},
+		},
+	}
+	b, err := encoding.Marshal(suite.funding)
+	require.Nil(suite.T(), err)
+	suite.db.On("Read", suite.funding.TxID).Return(b, nil)
+	suite.db.On("Read", mock.Anything).Return(nil, errorx.ErrKeyNotFound)
+
+	suite.watchlist = Model{
+		ID:      uuid.New(),
+		UserID:  uuid.New(),
+		Type:    Address,
+		Value:   "1Watched",
+		Channel: Webhook,
+		Active:  true,
+	}
+}
+
+func (suite *TestWatchlistSuite) events(transactions ...tx.Tx) []Event {
+	return Events(10, "hash", transactions, block.NewService(suite.db, suite.cache).Prevout(transactions))
+}
+
+func (suite *TestWatchlistSuite) TestEvents() {
+	events := suite.events(suite.spending)
+	require.Len(suite.T(), events, 3)
+	assert.Equal(suite.T(), Event{Kind: Spent, Height: 10, BlockHash: "hash", TxID: suite.spending.TxID, Vout: 0, Address: "1Watched", Value: 100000}, events[0])
+	assert.Equal(suite.T(), Received, events[1].Kind)
+	assert.Equal(suite.T(), "1Payee", events[1].Address)
+	assert.Equal(suite.T(), uint32(1), events[2].Vout)
+
+	// unresolved inputs are skipped, the other events of the block are kept
+	missing := tx.Tx{
+		TxID: strings.Repeat("c", 64),
+		Vin:  []tx.Input{{TxID: suite.funding.TxID, Vout: 4}, {TxID: strings.Repeat("d", 64), Vout: 0}},
+		Vout: []tx.Output{{ScriptpubkeyAddress: "1Payee", Value: 1000}},
+	}
+	events = suite.events(missing)
+	require.Len(suite.T(), events, 1)
+	assert.Equal(suite.T(), Received, events[0].Kind)
+
+	// outputs created in the same block are resolved before they are stored
+	queued := tx.Tx{TxID: strings.Repeat("e", 64), Vin: []tx.Input{{TxID: missing.TxID, Vout: 0}}}
+	events = suite.events(missing, queued)
+	require.Len(suite.T(), events, 2)
+	assert.Equal(suite.T(), Spent, events[1].Kind)
+	assert.Equal(suite.T(), "1Payee", events[1].Address)
+}
+
+func (suite *TestWatchlistSuite) TestMatch() {
+	events := suite.events(suite.funding, suite.spending)
+
+	cluster := Model{ID: uuid.New(), Type: Cluster, Value: "42", Active: true}
+	tag := Model{ID: uuid.New(), Type: Tag, Value: "exchange", Active: true}
+	inactive := Model{ID: uuid.New(), Type: Address, Value: "1Payee", Active: false}
+	labels := Labels{
+		Clusters: map[string]uint64{"1Payee": 42, "1Change": 7},
+		Tags:     map[string][]string{"1Change": {"mixer", "exchange"}},
+	}
+
+	alerts := Match(events, []Model{suite.watchlist, cluster, tag, inactive}, labels)
+	require.Len(suite.T(), alerts, 4)
+	assert.Equal(suite.T(), suite.watchlist.ID, alerts[0].WatchlistID)
+	assert.Equal(suite.T(), suite.watchlist.UserID, alerts[0].UserID)
+	assert.Equal(suite.T(), Received, alerts[0].Kind)
+	assert.Equal(suite.T(), suite.funding.TxID, alerts[0].TxID)
+	assert.Equal(suite.T(), Spent, alerts[1].Kind)
+	assert.Equal(suite.T(), suite.spending.TxID, alerts[1].TxID)
+	assert.Equal(suite.T(), cluster.ID, alerts[2].WatchlistID)
+	assert.Equal(suite.T(), "1Payee", alerts[2].Address)
+	assert.Equal(suite.T(), tag.ID, alerts[3].WatchlistID)
+	assert.Equal(suite.T(), "1Change", alerts[3].Address)
+}
+
+func (suite *TestWatchlistSuite) TestMailNotifier() {
+	notifier := &MailNotifier{Client: &mailer.Client{Client: mailer.NewMockClient("test"), Email: "alerts@bitgodine.com"}}
+	w := suite.watchlist
+	w.Channel, w.Target = Email, "user@bitgodine.com"
+	err := notifier.Notify(context.Background(), &w, []Alert{{Kind: Received, Address: "1Watched", Value: 100000}})
+	assert.Nil(suite.T(), err)
+}
+
+func (suite *TestWatchlistSuite) TestWebhookNotifier() {
+	var received Notification
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(suite.T(), "application/json", r.Header.Get("Content-Type"))
+		require.Nil(suite.T(), json.NewDecoder(r.Body).Decode(&received))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	// the test server listens on loopback, delivered through its own client skipping the address checks
+	w := suite.watchlist
+	w.Target = strings.Replace(server.URL, "127.0.0.1", "example.com", 1)
+	client := server.Client()
+	client.Transport.(*http.Transport).DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return net.Dial(network, server.Listener.Addr().String())
+	}
+	notifier := &WebhookNotifier{Client: client}
+	err := notifier.Notify(context.Background(), &w, []Alert{{Kind: Spent, Address: "1Watched", Value: 100000}})
+	require.Nil(suite.T(), err)
+	assert.Equal(suite.T(), w.ID, received.Watchlist.ID)
+	require.Len(suite.T(), received.Alerts, 1)
+	assert.Equal(suite.T(), Spent, received.Alerts[0].Kind)
+
+	// the dialer refuses the loopback address the name resolves to
+	guarded := NewWebhookNotifier(time.Second)
+	guarded.Client.Transport.(*http.Transport).TLSClientConfig = client.Transport.(*http.Transport).TLSClientConfig
+	resolver := guarded.Client.Transport.(*http.Transport).DialContext
+	guarded.Client.Transport.(*http.Transport).DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return resolver(ctx, network, server.Listener.Addr().String())
+	}
+	err = guarded.Notify(context.Background(), &w, nil)
+	assert.True(suite.T(), errors.Is(err, errorx.ErrInvalidArgument))
+
+	w.Target = server.URL
+	assert.True(suite.T(), errors.Is(notifier.Notify(context.Background(), &w, nil), errorx.ErrInvalidArgument))
+
+	failing := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+	w.Target = strings.Replace(failing.URL, "127.0.0.1", "example.com", 1)
+	client = failing.Client()
+	client.Transport.(*http.Transport).DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return net.Dial(network, failing.Listener.Addr().String())
+	}
+	assert.NotNil(suite.T(), (&WebhookNotifier{Client: client}).Notify(context.Background(), &w, nil))
+}
+
+func (suite *TestWatchlistSuite) TestValidateTarget() {
+	valid := []Model{
+		{Channel: Email, Target: "user@bitgodine.com"},
+		{Channel: Webhook, Target: "https://hooks.bitgodine.com/alerts"},
+		{Channel: Webhook, Target: "https://8.8.8.8/alerts"},
+		{Channel: Kafka},
+	}
+	for _, m := range valid {
+		assert.Nil(suite.T(), m.ValidateTarget(), m.Target)
+	}
+	invalid := []Model{
+		{Channel: Email, Target: "https://hooks.bitgodine.com"},
+		{Channel: Email, Target: "User <user@bitgodine.com>"},
+		{Channel: Webhook, Target: "user@bitgodine.com"},
+		{Channel: Webhook, Target: "http://hooks.bitgodine.com/alerts"},
+		{Channel: Webhook, Target: "https://127.0.0.1/alerts"},
+		{Channel: Webhook, Target: "https://10.0.0.8/alerts"},
+		{Channel: Webhook, Target: "https://169.254.169.254/latest/meta-data"},
+		{Channel: Webhook, Target: "https://[::1]/alerts"},
+		{Channel: Webhook, Target: "https://localhost/alerts"},
+	}
+	for _, m := range invalid {
+		assert.True(suite.T(), errors.Is(m.ValidateTarget(), errorx.ErrInvalidArgument), m.Target)
+	}
+}
+
+func (suite *TestWatchlistSuite) TestKafkaNotifier() {
+	p := new(publisher)
+	notifier := &KafkaNotifier{Publisher: p}
+	w := suite.watchlist
+	w.Channel = Kafka
+	require.Nil(suite.T(), notifier.Notify(context.Background(), &w, []Alert{{Kind: Received, Address: "1Watched"}}))
+	assert.Equal(suite.T(), w.ID.String(), p.key)
+
+	var notification Notification
+	require.Nil(suite.T(), json.Unmarshal([]byte(p.value), &notification))
+	require.Len(suite.T(), notification.Alerts, 1)
+	assert.Equal(suite.T(), "1Watched", notification.Alerts[0].Address)
+}
+
+func TestWatchlist(t *testing.T) {
+	suite.Run(t, new(TestWatchlistSuite))
+}
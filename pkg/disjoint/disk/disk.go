@@ -7,6 +7,7 @@ import (
 
 	"github.com/xn3cr0nx/bitgodine/internal/errorx"
 	"github.com/xn3cr0nx/bitgodine/internal/storage/kv"
+	"github.com/xn3cr0nx/bitgodine/internal/storage/kv/iterator"
 	"github.com/xn3cr0nx/bitgodine/pkg/logger"
 )
 
@@ -60,16 +61,13 @@ func RestorePersistentSet(d *DisjointSet) (err error) {
 		d.rank[i] = binary.LittleEndian.Uint64(r)
 	}
 
-	addresses, err := d.storage.ReadKeysWithPrefix("addr")
-	for _, address := range addresses {
-		cluster, e := d.storage.Read(address)
-		if e != nil {
-			return e
-		}
-		d.hashMap.Store(address[4:], binary.LittleEndian.Uint64(cluster))
+	it := d.storage.Iterate(&iterator.Options{Prefix: "addr"})
+	defer it.Close()
+	for it.Next() {
+		d.hashMap.Store(it.Key()[4:], binary.LittleEndian.Uint64(it.Value()))
 	}
 
-	return
+	return it.Err()
 }
 
 // GetSize returns the number of elements in the set
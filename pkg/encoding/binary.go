@@ -0,0 +1,291 @@
+package encoding
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math"
+	"time"
+)
+
+// BinaryV1 format version byte prepended to binary encoded records. Legacy records start
+// with a msgpack map or a json object, so the first byte tells the formats apart
+const BinaryV1 byte = 0x01
+
+// ErrMalformed returned decoding a truncated or corrupted binary record
+var ErrMalformed = errors.New("malformed binary record")
+
+const (
+	hexRaw byte = iota
+	hexLower
+	hexUpper
+)
+
+// IsBinary returns true if the record is binary encoded
+func IsBinary(data []byte) bool {
+	return len(data) > 0 && data[0] == BinaryV1
+}
+
+// UnmarshalLegacy decodes a record stored before binary encoding, either msgpack or json
+func UnmarshalLegacy(data []byte, v interface{}) error {
+	if len(data) > 0 && data[0] == '{' {
+		return json.Unmarshal(data, v)
+	}
+	return Unmarshal(data, v)
+}
+
+// Writer appends varint encoded fields to a buffer
+type Writer struct {
+	buf []byte
+}
+
+// NewWriter returns a writer with the format version byte already written
+func NewWriter(size int) *Writer {
+	w := &Writer{buf: make([]byte, 0, size)}
+	w.buf = append(w.buf, BinaryV1)
+	return w
+}
+
+// Bytes returns the encoded record
+func (w *Writer) Bytes() []byte {
+	return w.buf
+}
+
+// Uvarint writes an unsigned integer
+func (w *Writer) Uvarint(v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	w.buf = append(w.buf, b[:binary.PutUvarint(b[:], v)]...)
+}
+
+// Varint writes a signed integer
+func (w *Writer) Varint(v int64) {
+	var b [binary.MaxVarintLen64]byte
+	w.buf = append(w.buf, b[:binary.PutVarint(b[:], v)]...)
+}
+
+// Bool writes a boolean as a single byte
+func (w *Writer) Bool(v bool) {
+	if v {
+		w.buf = append(w.buf, 1)
+		return
+	}
+	w.buf = append(w.buf, 0)
+}
+
+// Float32 writes a float in 4 bytes
+func (w *Writer) Float32(v float32) {
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], math.Float32bits(v))
+	w.buf = append(w.buf, b[:]...)
+}
+
+// Raw writes length prefixed bytes
+func (w *Writer) Raw(v []byte) {
+	w.Uvarint(uint64(len(v)))
+	w.buf = append(w.buf, v...)
+}
+
+// String writes a length prefixed string
+func (w *Writer) String(v string) {
+	w.Uvarint(uint64(len(v)))
+	w.buf = append(w.buf, v...)
+}
+
+// Hex writes a hex string as its decoded bytes, halving its size. The letter case is kept
+// and strings not being valid hex are written as they are
+func (w *Writer) Hex(v string) {
+	kind := hexKind(v)
+	w.buf = append(w.buf, kind)
+	if kind == hexRaw {
+		w.String(v)
+		return
+	}
+	w.Uvarint(uint64(len(v) / 2))
+	w.buf = append(w.buf, make([]byte, len(v)/2)...)
+	hex.Decode(w.buf[len(w.buf)-len(v)/2:], []byte(v))
+}
+
+// Time writes a time as seconds and nanoseconds, zero time included
+func (w *Writer) Time(v time.Time) {
+	w.Bool(v.IsZero())
+	if v.IsZero() {
+		return
+	}
+	w.Varint(v.Unix())
+	w.Uvarint(uint64(v.Nanosecond()))
+}
+
+// hexKind returns the case of the hex string, raw if it isn't a valid one
+func hexKind(v string) byte {
+	if len(v) == 0 || len(v)%2 != 0 {
+		return hexRaw
+	}
+	lower, upper := false, false
+	for i := 0; i < len(v); i++ {
+		c := v[i]
+		switch {
+		case c >= '0' && c <= '9':
+		case c >= 'a' && c <= 'f':
+			lower = true
+		case c >= 'A' && c <= 'F':
+			upper = true
+		default:
+			return hexRaw
+		}
+	}
+	if lower && upper {
+		return hexRaw
+	}
+	if upper {
+		return hexUpper
+	}
+	return hexLower
+}
+
+// Reader reads fields written by Writer. The first error is kept and returned by Err,
+// following reads return zero values
+type Reader struct {
+	buf []byte
+	err error
+}
+
+// NewReader returns a reader over a binary record, checking the format version
+func NewReader(data []byte) *Reader {
+	r := &Reader{buf: data}
+	if !IsBinary(data) {
+		r.err = ErrMalformed
+		return r
+	}
+	r.buf = r.buf[1:]
+	return r
+}
+
+// Err returns the first error occurred reading
+func (r *Reader) Err() error {
+	return r.err
+}
+
+func (r *Reader) fail() {
+	r.err = ErrMalformed
+	r.buf = nil
+}
+
+// Uvarint reads an unsigned integer
+func (r *Reader) Uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(r.buf)
+	if n <= 0 {
+		r.fail()
+		return 0
+	}
+	r.buf = r.buf[n:]
+	return v
+}
+
+// Varint reads a signed integer
+func (r *Reader) Varint() int64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(r.buf)
+	if n <= 0 {
+		r.fail()
+		return 0
+	}
+	r.buf = r.buf[n:]
+	return v
+}
+
+// Len reads a length, bounded by the remaining bytes to avoid huge allocations on corrupted records
+func (r *Reader) Len() int {
+	l := r.Uvarint()
+	if l > uint64(len(r.buf)) {
+		r.fail()
+		return 0
+	}
+	return int(l)
+}
+
+// Bool reads a boolean
+func (r *Reader) Bool() bool {
+	b := r.next(1)
+	return len(b) == 1 && b[0] == 1
+}
+
+// Float32 reads a float
+func (r *Reader) Float32() float32 {
+	b := r.next(4)
+	if len(b) < 4 {
+		return 0
+	}
+	return math.Float32frombits(binary.LittleEndian.Uint32(b))
+}
+
+// Raw reads length prefixed bytes, copied out of the record
+func (r *Reader) Raw() []byte {
+	b := r.next(r.Len())
+	if len(b) == 0 {
+		return nil
+	}
+	return append([]byte(nil), b...)
+}
+
+// String reads a length prefixed string
+func (r *Reader) String() string {
+	return string(r.next(r.Len()))
+}
+
+// Hex reads a hex string
+func (r *Reader) Hex() string {
+	kind := r.next(1)
+	if len(kind) == 0 {
+		return ""
+	}
+	switch kind[0] {
+	case hexRaw:
+		return r.String()
+	case hexLower:
+		return hex.EncodeToString(r.next(r.Len()))
+	case hexUpper:
+		return upperHex(r.next(r.Len()))
+	}
+	r.fail()
+	return ""
+}
+
+// Time reads a time
+func (r *Reader) Time() time.Time {
+	if r.Bool() || r.err != nil {
+		return time.Time{}
+	}
+	sec := r.Varint()
+	nsec := r.Uvarint()
+	return time.Unix(sec, int64(nsec))
+}
+
+// next returns the following n bytes
+func (r *Reader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n > len(r.buf) {
+		r.fail()
+		return nil
+	}
+	b := r.buf[:n]
+	r.buf = r.buf[n:]
+	return b
+}
+
+func upperHex(b []byte) string {
+	const digits = "0123456789ABCDEF"
+	s := make([]byte, len(b)*2)
+	for i, c := range b {
+		s[i*2] = digits[c>>4]
+		s[i*2+1] = digits[c&0x0f]
+	}
+	return string(s)
+}
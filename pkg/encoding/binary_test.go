@@ -0,0 +1,79 @@
+package encoding_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/xn3cr0nx/bitgodine/pkg/encoding"
+)
+
+var _ = Describe("Binary", func() {
+	Context("Testing binary records", func() {
+		It("Should read back written fields", func() {
+			now := time.Now()
+			w := NewWriter(0)
+			w.Uvarint(300)
+			w.Varint(-5)
+			w.Bool(true)
+			w.Float32(1.5)
+			w.String("text")
+			w.Raw([]byte{1, 2})
+			w.Hex("00ff")
+			w.Hex("00FF")
+			w.Hex("0aF")
+			w.Time(now)
+			w.Time(time.Time{})
+
+			data := w.Bytes()
+			Expect(IsBinary(data)).To(BeTrue())
+			r := NewReader(data)
+			Expect(r.Uvarint()).To(Equal(uint64(300)))
+			Expect(r.Varint()).To(Equal(int64(-5)))
+			Expect(r.Bool()).To(BeTrue())
+			Expect(r.Float32()).To(Equal(float32(1.5)))
+			Expect(r.String()).To(Equal("text"))
+			Expect(r.Raw()).To(Equal([]byte{1, 2}))
+			Expect(r.Hex()).To(Equal("00ff"))
+			Expect(r.Hex()).To(Equal("00FF"))
+			Expect(r.Hex()).To(Equal("0aF"))
+			Expect(r.Time().Equal(now)).To(BeTrue())
+			Expect(r.Time().IsZero()).To(BeTrue())
+			Expect(r.Err()).ToNot(HaveOccurred())
+		})
+
+		It("Should store hex strings as bytes", func() {
+			w := NewWriter(0)
+			w.Hex("00112233445566778899aabbccddeeff")
+			Expect(len(w.Bytes())).To(Equal(1 + 1 + 1 + 16))
+		})
+
+		It("Should fail on malformed records", func() {
+			r := NewReader(Mock{"legacy"}.Encoded())
+			Expect(r.Err()).To(MatchError(ErrMalformed))
+
+			w := NewWriter(0)
+			w.String("truncated")
+			r = NewReader(w.Bytes()[:4])
+			Expect(r.String()).To(BeEmpty())
+			Expect(r.Uvarint()).To(BeZero())
+			Expect(r.Err()).To(MatchError(ErrMalformed))
+		})
+
+		It("Should decode legacy records", func() {
+			var m Mock
+			Expect(UnmarshalLegacy(Mock{"legacy"}.Encoded(), &m)).To(Succeed())
+			Expect(m.Field).To(Equal("legacy"))
+			Expect(UnmarshalLegacy([]byte(`{"Field":"json"}`), &m)).To(Succeed())
+			Expect(m.Field).To(Equal("json"))
+		})
+	})
+})
+
+// Encoded returns the legacy encoding of the mock
+func (m Mock) Encoded() []byte {
+	data, err := Marshal(m)
+	Expect(err).ToNot(HaveOccurred())
+	return data
+}
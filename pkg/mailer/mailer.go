@@ -2,6 +2,7 @@ package mailer
 
 import (
 	"errors"
+	"fmt"
 
 	"github.com/spf13/viper"
 
@@ -52,109 +53,27 @@ func NewClient(conf *Config) (*Client, error) {
 	return c, nil
 }
 
-// func Resend(c *echo.Context) {
-// 	action := "Mailer.Resend"
-// 	log.LogRequest(c, action, nil)
-// 	var body struct {
-// 		EmailNonce string `json:"email_nonce" binding:"required"`
-// 	}
-// 	err := c.ShouldBindJSON(&body)
-// 	if err != nil {
-// 		log.LogError(c, action, err, nil)
-// 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-// 		return
-// 	}
-// 	// val, err := db.Redis().HGetAll(fmt.Sprintf("email_nonce:%s", body.EmailNonce)).Result()
-// 	val, err := db.Redis().HGetAll(strings.Join([]string{"email_nonce", body.EmailNonce}, ":")).Result()
-// 	if err != nil {
-// 		log.LogError(c, action, err, nil)
-// 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-// 		return
-// 	}
-// 	if len(val) == 0 {
-// 		err := errors.New("No such email")
-// 		log.LogError(c, action, err, nil)
-// 		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
-// 		return
-// 	}
-// 	switch val["MAILER_FUNCTION"] {
-// 	case "UserVerification":
-// 		{
-// 			_, err := UserVerification(val["name"], val["email"], val["url"], c)
-// 			if err != nil {
-// 				log.LogError(c, action, err, nil)
-// 				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-// 				return
-// 			}
-// 		}
-// 	case "ResetPassword":
-// 		{
-// 			_, err := ResetPasswordReq(val["name"], val["surname"], val["email"], val["url"], val["token"], c)
-// 			if err != nil {
-// 				log.LogError(c, action, err, nil)
-// 				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-// 				return
-// 			}
-// 		}
-// 	}
-// 	_, err = db.Redis().Del(strings.Join([]string{"email_nonce", body.EmailNonce}, ":")).Result()
-// 	if err != nil {
-// 		log.LogError(c, action, err, nil)
-// 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-// 		return
-// 	}
-
-// 	c.JSON(http.StatusOK, gin.H{
-// 		"success": true,
-// 		"message": "Email resent",
-// 	})
-// }
+// send sends a plain text email from the service address
+func (c *Client) send(name, email, subject, content string) (*rest.Response, error) {
+	from := mail.NewEmail("Bitgodine", c.Email)
+	to := mail.NewEmail(name, email)
+	return c.Client.Send(mail.NewSingleEmail(from, subject, to, content, ""))
+}
 
-// func UserVerification(name, email, url string, c *echo.Context) (*rest.Response, error) {
-// 	from := mail.NewEmail("BUULL EXCHANGE", sg.Email)
-// 	subject := "Verify your email address"
-// 	to := mail.NewEmail(name, email)
-// 	request := mail.NewV3Mail()
-// 	request.SetFrom(from)
-// 	request.Subject = subject
-// 	request.SetTemplateID("942897ef-8124-44a1-a906-517c05718774")
-// 	subs := mail.NewPersonalization()
-// 	subs.AddTos(to)
-// 	subs.Subject = subject
-// 	subs.SetSubstitution("<%url%>", url)
-// 	request.AddPersonalizations(subs)
-// 	return sg.Client.Send(request)
-// }
+// UserVerification sends the link to verify the email address and activate the account
+func (c *Client) UserVerification(name, email, url string) (*rest.Response, error) {
+	content := fmt.Sprintf("Hi %s,\n\nverify your email address to activate your Bitgodine account: %s\n", name, url)
+	return c.send(name, email, "Verify your email address", content)
+}
 
-// func ResetPasswordReq(name, surname, email, url, token string, c *echo.Context) (*rest.Response, error) {
-// 	from := mail.NewEmail("BUULL EXCHANGE", sg.Email)
-// 	subject := "Password Recovery Request"
-// 	to := mail.NewEmail(name, email)
-// 	request := mail.NewV3Mail()
-// 	request.SetFrom(from)
-// 	request.Subject = subject
-// 	request.SetTemplateID("14df6eb3-5d3c-4f16-99ac-7c2cb0575f48")
-// 	subs := mail.NewPersonalization()
-// 	subs.AddTos(to)
-// 	subs.Subject = subject
-// 	subs.SetSubstitution("<%url%>", url)
-// 	subs.SetSubstitution("<%name%>", name)
-// 	subs.SetSubstitution("<%surname%>", surname)
-// 	request.AddPersonalizations(subs)
-// 	return sg.Client.Send(request)
-// }
+// ResetPasswordReq sends the link to choose a new password
+func (c *Client) ResetPasswordReq(name, email, url string) (*rest.Response, error) {
+	content := fmt.Sprintf("Hi %s,\n\nchoose a new password for your Bitgodine account: %s\nIf you didn't request a password reset ignore this email.\n", name, url)
+	return c.send(name, email, "Password Recovery Request", content)
+}
 
-// func ResetPassword(email string, c *echo.Context) (*rest.Response, error) {
-// 	from := mail.NewEmail("BUULL EXCHANGE", sg.Email)
-// 	subject := "Password Recovery Successful"
-// 	to := mail.NewEmail("", email)
-// 	request := mail.NewV3Mail()
-// 	request.SetFrom(from)
-// 	request.Subject = subject
-// 	request.SetTemplateID("3559a6c3-3013-40cc-a84c-0f714b1dd055")
-// 	subs := mail.NewPersonalization()
-// 	subs.AddTos(to)
-// 	subs.Subject = subject
-// 	request.AddPersonalizations(subs)
-// 	return sg.Client.Send(request)
-// }
+// ResetPassword notifies the password has been changed
+func (c *Client) ResetPassword(name, email string) (*rest.Response, error) {
+	content := fmt.Sprintf("Hi %s,\n\nthe password of your Bitgodine account has been changed.\n", name)
+	return c.send(name, email, "Password Recovery Successful", content)
+}
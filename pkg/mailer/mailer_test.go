@@ -2,11 +2,38 @@ package mailer_test
 
 import (
 	. "github.com/onsi/ginkgo"
-	// . "github.com/onsi/gomega"
+	. "github.com/onsi/gomega"
 
-	// . "github.com/bqtx/exchange-services/pkg/mailer"
+	. "github.com/xn3cr0nx/bitgodine/pkg/mailer"
 )
 
 var _ = Describe("Mailer", func() {
+	var client *Client
 
+	BeforeEach(func() {
+		client = &Client{Client: NewMockClient("test"), Email: "noreply@bitgodine.com"}
+	})
+
+	It("Should send the email verification", func() {
+		res, err := client.UserVerification("Satoshi", "satoshi@gmx.com", "http://localhost/api/activate/token")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(res.StatusCode).To(Equal(200))
+
+		sent := client.Client.(*MockClient).Sent
+		Expect(sent).To(HaveLen(1))
+		Expect(sent[0].Personalizations[0].To[0].Address).To(Equal("satoshi@gmx.com"))
+		Expect(sent[0].Content[0].Value).To(ContainSubstring("http://localhost/api/activate/token"))
+	})
+
+	It("Should send the password reset request", func() {
+		res, err := client.ResetPasswordReq("Satoshi", "satoshi@gmx.com", "http://localhost/reset?token=token")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(res.StatusCode).To(Equal(200))
+	})
+
+	It("Should send the password reset confirmation", func() {
+		res, err := client.ResetPassword("Satoshi", "satoshi@gmx.com")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(res.StatusCode).To(Equal(200))
+	})
 })
@@ -6,9 +6,10 @@ import (
 	"github.com/sendgrid/sendgrid-go/helpers/mail"
 )
 
-// MockClient mocks sendgrid client
+// MockClient mocks sendgrid client, keeping the emails sent
 type MockClient struct {
 	sendgrid.Client
+	Sent []*mail.SGMailV3
 }
 
 // NewMockClient returns a mock instance of sendgrid client
@@ -18,6 +19,7 @@ func NewMockClient(key string) *MockClient {
 
 // Send mock function that always return positive reponse for send action
 func (sg *MockClient) Send(email *mail.SGMailV3) (resp *rest.Response, err error) {
+	sg.Sent = append(sg.Sent, email)
 	resp = &rest.Response{
 		StatusCode: 200,
 	}
@@ -42,3 +42,11 @@ func configure(conf *Config) (err error) {
 	fmt.Println("Prometheus server running on :9464")
 	return
 }
+
+// Get returns the configured meter, or the global one doing nothing until a meter is configured
+func Get(name string) metric.Meter {
+	if meter != nil {
+		return *meter
+	}
+	return otel.Meter(name)
+}
@@ -8,11 +8,12 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/xn3cr0nx/bitgodine/internal/errorx"
 	"github.com/xn3cr0nx/bitgodine/internal/httpx"
 	"github.com/xn3cr0nx/bitgodine/internal/jwt"
 
+	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
-	"github.com/labstack/echo/v4/middleware"
 	"github.com/spf13/viper"
 )
 
@@ -34,7 +35,69 @@ func JWT() func(echo.HandlerFunc) echo.HandlerFunc {
 			}
 		}
 	}
-	return middleware.JWTWithConfig(jwt.Config())
+	return jwt.Middleware()
+}
+
+// UserID extracts the id of the user authenticated by the JWT middleware, routes owning resources require it
+func UserID(c echo.Context) (string, error) {
+	if c.Get("user") == nil {
+		return "", echo.NewHTTPError(http.StatusUnauthorized, "missing authentication token")
+	}
+	claims, err := jwt.Decode(c.Get("user"))
+	if err != nil {
+		return "", err
+	}
+	return claims.ID, nil
+}
+
+// ParseUUID parses the id of a resource, malformed ids are invalid arguments
+func ParseUUID(ID string) (id uuid.UUID, err error) {
+	if id, err = uuid.Parse(ID); err != nil {
+		err = fmt.Errorf("%w: %s", errorx.ErrInvalidArgument, err.Error())
+	}
+	return
+}
+
+// Roles of the users, admins are granted every role
+const (
+	Admin   = "admin"
+	Analyst = "analyst"
+	Viewer  = "viewer"
+)
+
+// HasRole checks the authenticated user has one of the roles. With authentication disabled every role is granted
+func HasRole(c echo.Context, roles ...string) bool {
+	if !viper.GetBool("server.auth.enabled") {
+		return true
+	}
+	if c.Get("user") == nil {
+		return false
+	}
+	claims, err := jwt.Decode(c.Get("user"))
+	if err != nil {
+		return false
+	}
+	if claims.Role == Admin {
+		return true
+	}
+	for _, role := range roles {
+		if claims.Role == role {
+			return true
+		}
+	}
+	return false
+}
+
+// Role middleware checks the user role is authorized to query the route, it must follow JWT middleware
+func Role(roles ...string) func(echo.HandlerFunc) echo.HandlerFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if !HasRole(c, roles...) {
+				return echo.NewHTTPError(http.StatusForbidden, "role not authorized")
+			}
+			return next(c)
+		}
+	}
 }
 
 // Recaptcha middleware to validate recaptcha input
@@ -0,0 +1,59 @@
+package validator
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	token "github.com/dgrijalva/jwt-go"
+	"github.com/labstack/echo/v4"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/xn3cr0nx/bitgodine/internal/jwt"
+)
+
+type TestMiddlewaresSuite struct {
+	suite.Suite
+}
+
+func (suite *TestMiddlewaresSuite) TearDownTest() {
+	viper.Set("server.auth.enabled", false)
+}
+
+func (suite *TestMiddlewaresSuite) context(role string) echo.Context {
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodPost, "/", nil), httptest.NewRecorder())
+	if role != "" {
+		c.Set("user", &token.Token{Claims: &jwt.CustomClaims{ID: "user-id", Role: role}, Valid: true})
+	}
+	return c
+}
+
+func (suite *TestMiddlewaresSuite) TestHasRole() {
+	assert.True(suite.T(), HasRole(suite.context(""), Admin))
+
+	viper.Set("server.auth.enabled", true)
+	assert.False(suite.T(), HasRole(suite.context(""), Viewer))
+	assert.True(suite.T(), HasRole(suite.context(Analyst), Analyst))
+	assert.False(suite.T(), HasRole(suite.context(Viewer), Analyst))
+	assert.False(suite.T(), HasRole(suite.context(Analyst), Admin))
+	assert.True(suite.T(), HasRole(suite.context(Admin), Analyst))
+}
+
+func (suite *TestMiddlewaresSuite) TestRole() {
+	viper.Set("server.auth.enabled", true)
+	handler := Role(Analyst)(func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+
+	assert.Nil(suite.T(), handler(suite.context(Analyst)))
+	err := handler(suite.context(Viewer))
+	if assert.NotNil(suite.T(), err) {
+		assert.Equal(suite.T(), http.StatusForbidden, err.(*echo.HTTPError).Code)
+	}
+}
+
+func TestMiddlewares(t *testing.T) {
+	suite.Run(t, new(TestMiddlewaresSuite))
+}